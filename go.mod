@@ -189,12 +189,14 @@ require (
 
 require (
 	github.com/Azure/go-ansiterm v0.0.0-20210617225240-d185dfc1b5a1 // indirect
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358 // indirect
 	github.com/Microsoft/go-winio v0.6.1 // indirect
 	github.com/beevik/etree v1.3.0 // indirect
 	github.com/crewjam/httperr v0.2.0 // indirect
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
 	github.com/docker/go-metrics v0.0.1 // indirect
 	github.com/docker/libtrust v0.0.0-20160708172513-aabc10ec26b7 // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.5 // indirect
 	github.com/go-jose/go-jose/v3 v3.0.1 // indirect
 	github.com/go-ole/go-ole v1.2.6 // indirect
 	github.com/gorilla/mux v1.8.0 // indirect
@@ -208,7 +210,10 @@ require (
 	golang.org/x/mod v0.14.0 // indirect
 )
 
-require golang.org/x/sync v0.5.0
+require (
+	github.com/go-ldap/ldap/v3 v3.4.6
+	golang.org/x/sync v0.5.0
+)
 
 // Determined AI's CircleCI doesn't have access to "github.hpe.com/hpe/hpc-ard-launcher-go",
 // so the build will fail in CircleCI.  Therefore, we had to do a "git clone" of the
@@ -59,6 +59,58 @@ func (a *apiServer) GetAgents(
 	return resp, api.Paginate(&resp.Pagination, &resp.Agents, req.Offset, req.Limit)
 }
 
+func (a *apiServer) GetClusterUtilization(
+	_ context.Context, _ *apiv1.GetClusterUtilizationRequest,
+) (*apiv1.GetClusterUtilizationResponse, error) {
+	resp, err := a.m.rm.GetClusterUtilization()
+	switch {
+	case errors.Is(err, rmerrors.ErrNotSupported):
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	case err != nil:
+		return nil, err
+	default:
+		return resp, nil
+	}
+}
+
+func (a *apiServer) CleanupOrphanedDispatches(
+	ctx context.Context, _ *apiv1.CleanupOrphanedDispatchesRequest,
+) (*apiv1.CleanupOrphanedDispatchesResponse, error) {
+	if err := a.canUpdateAgents(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := a.m.rm.CleanupOrphanedDispatches()
+	switch {
+	case errors.Is(err, rmerrors.ErrNotSupported):
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	case err != nil:
+		return nil, err
+	default:
+		return resp, nil
+	}
+}
+
+func (a *apiServer) GetDispatchUsage(
+	ctx context.Context, _ *apiv1.GetDispatchUsageRequest,
+) (*apiv1.GetDispatchUsageResponse, error) {
+	user, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.m.canGetUsageDetails(ctx, user); err != nil {
+		return nil, err
+	}
+	resp, err := a.m.rm.GetDispatchUsage()
+	switch {
+	case errors.Is(err, rmerrors.ErrNotSupported):
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	case err != nil:
+		return nil, err
+	default:
+		return resp, nil
+	}
+}
+
 func (a *apiServer) GetAgent(
 	ctx context.Context, req *apiv1.GetAgentRequest,
 ) (*apiv1.GetAgentResponse, error) {
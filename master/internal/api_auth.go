@@ -25,6 +25,10 @@ import (
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 )
 
+func init() {
+	grpcutil.RegisterUnauthenticatedMethod("/determined.api.v1.Determined/Login")
+}
+
 func (a *apiServer) Login(
 	ctx context.Context, req *apiv1.LoginRequest,
 ) (*apiv1.LoginResponse, error) {
@@ -94,6 +98,9 @@ func (a *apiServer) Logout(
 			"cannot manually logout of an allocation session")
 	}
 
+	if err := grpcutil.RevokeUserToken(ctx, userSession); err != nil {
+		return nil, err
+	}
 	err = user.DeleteSessionByID(ctx, userSession.ID)
 	return &apiv1.LogoutResponse{}, err
 }
@@ -96,11 +96,14 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 		resources.Slots = 0
 	}
 
+	slurmArgs, pbsArgs := model.ParseJustSbatchArgs(configBytes)
 	poolName, launchWarnings, err := a.m.ResolveResources(
 		resources.ResourcePool,
 		resources.Slots,
 		int(cmdSpec.Metadata.WorkspaceID),
 		true,
+		slurmArgs,
+		pbsArgs,
 	)
 	if err != nil {
 		return nil, launchWarnings, err
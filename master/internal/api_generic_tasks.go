@@ -103,10 +103,13 @@ func (a *apiServer) getGenericTaskLaunchParameters(
 		return nil, nil, nil, fmt.Errorf("resource slots must be >= 0")
 	}
 	isSingleNode := resources.IsSingleNode != nil && *resources.IsSingleNode
+	slurmArgs, pbsArgs := model.ParseJustSbatchArgs(configBytes)
 	poolName, launchWarnings, err := a.m.ResolveResources(resources.ResourcePool,
 		resources.Slots,
 		int(proj.WorkspaceId),
-		isSingleNode)
+		isSingleNode,
+		slurmArgs,
+		pbsArgs)
 	if err != nil {
 		return nil, nil, nil, err
 	}
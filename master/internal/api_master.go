@@ -26,6 +26,11 @@ import (
 
 var masterLogsBatchMissWaitTime = time.Second
 
+func init() {
+	grpcutil.RegisterUnauthenticatedMethod("/determined.api.v1.Determined/GetMaster")
+	grpcutil.RegisterUnauthenticatedMethod("/determined.api.v1.Determined/GetTelemetry")
+}
+
 func (a *apiServer) GetMaster(
 	_ context.Context, _ *apiv1.GetMasterRequest,
 ) (*apiv1.GetMasterResponse, error) {
@@ -40,19 +45,20 @@ func (a *apiServer) GetMaster(
 	}
 
 	masterResp := &apiv1.GetMasterResponse{
-		Version:               version.Version,
-		MasterId:              a.m.MasterID,
-		ClusterId:             a.m.ClusterID,
-		ClusterName:           a.m.config.ClusterName,
-		TelemetryEnabled:      a.m.config.Telemetry.Enabled && a.m.config.Telemetry.SegmentWebUIKey != "",
-		ExternalLoginUri:      a.m.config.InternalConfig.ExternalSessions.LoginURI,
-		ExternalLogoutUri:     a.m.config.InternalConfig.ExternalSessions.LogoutURI,
-		Branding:              brand,
-		RbacEnabled:           config.GetAuthZConfig().IsRBACUIEnabled(),
-		StrictJobQueueControl: config.GetAuthZConfig().StrictJobQueueControl,
-		Product:               product,
-		UserManagementEnabled: !a.m.config.InternalConfig.ExternalSessions.Enabled(),
-		FeatureSwitches:       a.m.config.FeatureSwitches,
+		Version:                     version.Version,
+		MasterId:                    a.m.MasterID,
+		ClusterId:                   a.m.ClusterID,
+		ClusterName:                 a.m.config.ClusterName,
+		TelemetryEnabled:            a.m.config.Telemetry.Enabled && a.m.config.Telemetry.SegmentWebUIKey != "",
+		ExternalLoginUri:            a.m.config.InternalConfig.ExternalSessions.LoginURI,
+		ExternalLogoutUri:           a.m.config.InternalConfig.ExternalSessions.LogoutURI,
+		Branding:                    brand,
+		RbacEnabled:                 config.GetAuthZConfig().IsRBACUIEnabled(),
+		StrictJobQueueControl:       config.GetAuthZConfig().StrictJobQueueControl,
+		Product:                     product,
+		UserManagementEnabled:       !a.m.config.InternalConfig.ExternalSessions.Enabled(),
+		FeatureSwitches:             a.m.config.FeatureSwitches,
+		ResourceManagerCapabilities: a.m.rm.Capabilities(),
 	}
 	sso.AddProviderInfoToMasterResponse(a.m.config, masterResp)
 
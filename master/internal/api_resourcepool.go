@@ -2,15 +2,20 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/determined-ai/determined/master/internal/api"
 	"github.com/determined-ai/determined/master/internal/authz"
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
 	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/rm/rmerrors"
 	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
 	"github.com/determined-ai/determined/master/pkg/set"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
@@ -249,6 +254,51 @@ func (a *apiServer) canUserModifyWorkspaces(ctx context.Context, ids []int32) er
 	return nil
 }
 
+func (a *apiServer) PauseResourcePool(
+	ctx context.Context, req *apiv1.PauseResourcePoolRequest,
+) (*apiv1.PauseResourcePoolResponse, error) {
+	if err := a.canUpdateAgents(ctx); err != nil {
+		return nil, err
+	}
+	err := a.m.rm.PauseResourcePool(rm.ResourcePoolName(req.ResourcePoolName))
+	if errors.Is(err, rmerrors.ErrNotSupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return &apiv1.PauseResourcePoolResponse{}, nil
+}
+
+func (a *apiServer) ResumeResourcePool(
+	ctx context.Context, req *apiv1.ResumeResourcePoolRequest,
+) (*apiv1.ResumeResourcePoolResponse, error) {
+	if err := a.canUpdateAgents(ctx); err != nil {
+		return nil, err
+	}
+	err := a.m.rm.ResumeResourcePool(rm.ResourcePoolName(req.ResourcePoolName))
+	if errors.Is(err, rmerrors.ErrNotSupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return &apiv1.ResumeResourcePoolResponse{}, nil
+}
+
+func (a *apiServer) GetResourcePoolPartition(
+	ctx context.Context, req *apiv1.GetResourcePoolPartitionRequest,
+) (*apiv1.GetResourcePoolPartitionResponse, error) {
+	if _, _, err := grpcutil.GetUser(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := a.m.rm.GetResourcePoolPartition(rm.ResourcePoolName(req.ResourcePoolName))
+	if errors.Is(err, rmerrors.ErrNotSupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (a *apiServer) resourcePoolsAsConfigs() ([]config.ResourcePoolConfig, error) {
 	resp, err := a.m.rm.GetResourcePools()
 	if err != nil {
@@ -1,23 +1,37 @@
 package internal
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/uptrace/bun"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/db/bunutils"
 	"github.com/determined-ai/determined/master/internal/experiment"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
+	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/internal/storage"
 	"github.com/determined-ai/determined/master/internal/trials"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/internal/workspace"
+	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 	"github.com/determined-ai/determined/master/pkg/set"
@@ -67,6 +81,10 @@ func (a *apiServer) RunPrepareForReporting(
 	}, nil
 }
 
+// defaultSearchRunsStreamBatchSize is the number of runs in each SearchRunsStream
+// response if the request does not specify a batch size.
+const defaultSearchRunsStreamBatchSize = 1000
+
 func (a *apiServer) SearchRuns(
 	ctx context.Context, req *apiv1.SearchRunsRequest,
 ) (*apiv1.SearchRunsResponse, error) {
@@ -77,50 +95,521 @@ func (a *apiServer) SearchRuns(
 
 	resp := &apiv1.SearchRunsResponse{}
 	var runs []*runv1.FlatRun
+	query, err := a.searchRunsQuery(ctx, *curUser, req.ProjectId, req.Filter, req.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if req.UpdatedSince != nil {
+		query = query.Where("r.updated_at >= ?", req.UpdatedSince.AsTime())
+	}
+	query = query.Model(&runs)
+
+	// The watermark must be taken before searching, so that no change it covers is
+	// missed by the search.
+	watermark, err := runsUpdatedWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination, err := runPagedBunExperimentsQuery(ctx, query, int(req.Offset), int(req.Limit))
+	if err != nil {
+		return nil, err
+	}
+	resp.Pagination = pagination
+	resp.Runs = runs
+	resp.NextUpdatedSince = timestamppb.New(watermark)
+	return resp, nil
+}
+
+// runsUpdatedWatermark returns the time from which runs must be searched to find every
+// change that is not yet visible. The updated_at of a run is the start time of the
+// transaction that changed it, so this is the start of the oldest transaction still in
+// progress, or the current database time if there is none.
+func runsUpdatedWatermark(ctx context.Context) (time.Time, error) {
+	var watermark time.Time
+	if err := db.Bun().NewRaw(`
+SELECT LEAST(now(), min(xact_start)) FROM pg_stat_activity WHERE datname = current_database()
+`).Scan(ctx, &watermark); err != nil {
+		return time.Time{}, fmt.Errorf("getting the runs updated watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+func (a *apiServer) SearchRunsStream(
+	req *apiv1.SearchRunsStreamRequest, resp apiv1.Determined_SearchRunsStreamServer,
+) error {
+	ctx := resp.Context()
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get the user: %s", err)
+	}
+	if req.BatchSize < 0 {
+		return status.Errorf(codes.InvalidArgument, "batch_size must not be negative")
+	}
+	batchSize := int(req.BatchSize)
+	if batchSize == 0 {
+		batchSize = defaultSearchRunsStreamBatchSize
+	}
+
+	query, err := a.searchRunsQuery(ctx, *curUser, req.ProjectId, req.Filter, req.Sort)
+	if err != nil {
+		return err
+	}
+	rows, err := query.Model((*runv1.FlatRun)(nil)).Rows(ctx)
+	if err != nil {
+		return fmt.Errorf("searching runs: %w", err)
+	}
+	defer rows.Close()
+
+	batch := make([]*runv1.FlatRun, 0, batchSize)
+	for rows.Next() {
+		run := &runv1.FlatRun{}
+		if err := db.Bun().ScanRow(ctx, rows, run); err != nil {
+			return fmt.Errorf("reading run from db: %w", err)
+		}
+		batch = append(batch, run)
+		if len(batch) == batchSize {
+			if err := resp.Send(&apiv1.SearchRunsStreamResponse{Runs: batch}); err != nil {
+				return err
+			}
+			batch = make([]*runv1.FlatRun, 0, batchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("searching runs: %w", err)
+	}
+	if len(batch) > 0 {
+		return resp.Send(&apiv1.SearchRunsStreamResponse{Runs: batch})
+	}
+	return nil
+}
+
+const exportRunHyperparametersColumn = "hp.*"
+
+// exportRunColumns maps the run columns of an export to their value for a run.
+var exportRunColumns = map[string]func(*runv1.FlatRun) any{
+	"id":                  func(r *runv1.FlatRun) any { return r.Id },
+	"state":               func(r *runv1.FlatRun) any { return strings.TrimPrefix(r.State.String(), "STATE_") },
+	"startTime":           func(r *runv1.FlatRun) any { return exportRunTime(r.StartTime) },
+	"endTime":             func(r *runv1.FlatRun) any { return exportRunTime(r.EndTime) },
+	"duration":            func(r *runv1.FlatRun) any { return exportRunOptional(r.Duration) },
+	"userId":              func(r *runv1.FlatRun) any { return exportRunOptional(r.UserId) },
+	"projectId":           func(r *runv1.FlatRun) any { return r.ProjectId },
+	"projectName":         func(r *runv1.FlatRun) any { return r.ProjectName },
+	"workspaceId":         func(r *runv1.FlatRun) any { return r.WorkspaceId },
+	"workspaceName":       func(r *runv1.FlatRun) any { return r.WorkspaceName },
+	"searcherMetricValue": func(r *runv1.FlatRun) any { return exportRunOptional(r.SearcherMetricValue) },
+	"checkpointSize":      func(r *runv1.FlatRun) any { return r.CheckpointSize },
+	"checkpointCount":     func(r *runv1.FlatRun) any { return r.CheckpointCount },
+	"externalRunId":       func(r *runv1.FlatRun) any { return exportRunOptional(r.ExternalRunId) },
+	"labels":              func(r *runv1.FlatRun) any { return r.Labels },
+	"experimentId": func(r *runv1.FlatRun) any {
+		if r.Experiment == nil {
+			return nil
+		}
+		return r.Experiment.Id
+	},
+	"experimentName": func(r *runv1.FlatRun) any {
+		if r.Experiment == nil {
+			return nil
+		}
+		return r.Experiment.Name
+	},
+}
+
+// defaultExportRunColumns are the columns of an export that does not list any.
+var defaultExportRunColumns = []string{
+	"id", "experimentId", "experimentName", "projectId", "projectName", "workspaceName",
+	"state", "startTime", "endTime", "duration", "searcherMetricValue", "checkpointSize",
+	"checkpointCount", "externalRunId", "labels", exportRunHyperparametersColumn,
+}
+
+// ExportRuns streams the runs matching a search as CSV or JSON lines, with the same filter
+// and sort as SearchRuns, so that large result sets can be downloaded in one request.
+func (a *apiServer) ExportRuns(
+	req *apiv1.ExportRunsRequest, resp apiv1.Determined_ExportRunsServer,
+) error {
+	ctx := resp.Context()
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get the user: %s", err)
+	}
+	columns := req.Columns
+	if len(columns) == 0 {
+		columns = defaultExportRunColumns
+	}
+	for _, col := range columns {
+		if err := validateExportRunColumn(col); err != nil {
+			return err
+		}
+	}
+
+	query, err := a.searchRunsQuery(ctx, *curUser, req.ProjectId, req.Filter, req.Sort)
+	if err != nil {
+		return err
+	}
+
+	// The runs are read in a repeatable read transaction, so that the header of a CSV export
+	// names the hyperparameters of the same runs that are then streamed.
+	return db.Bun().RunInTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(ctx context.Context, tx bun.Tx) error {
+			return exportRuns(ctx, tx, query.Conn(tx), req.Format, columns, resp)
+		})
+}
+
+// exportRuns streams the runs returned by a search query in the given format.
+func exportRuns(
+	ctx context.Context, tx bun.Tx, query *bun.SelectQuery, format apiv1.ExportRunsFormat,
+	columns []string, resp apiv1.Determined_ExportRunsServer,
+) error {
+	var buf bytes.Buffer
+	var writeRun func(run *runv1.FlatRun) error
+	flush := func() error { return nil }
+	switch format {
+	case apiv1.ExportRunsFormat_EXPORT_RUNS_FORMAT_UNSPECIFIED,
+		apiv1.ExportRunsFormat_EXPORT_RUNS_FORMAT_CSV:
+		// The header of a CSV export must name every hyperparameter up front.
+		if slices.Contains(columns, exportRunHyperparametersColumn) {
+			hps, err := exportRunHyperparameterNames(ctx, tx, query)
+			if err != nil {
+				return err
+			}
+			columns = expandExportRunHyperparameters(columns, hps)
+		}
+		w := csv.NewWriter(&buf)
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+		writeRun = func(run *runv1.FlatRun) error {
+			fields := exportRunFields(run, columns)
+			record := make([]string, 0, len(fields))
+			for _, f := range fields {
+				cell, err := exportRunCSVCell(f.value)
+				if err != nil {
+					return err
+				}
+				record = append(record, cell)
+			}
+			return w.Write(record)
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	case apiv1.ExportRunsFormat_EXPORT_RUNS_FORMAT_JSONL:
+		writeRun = func(run *runv1.FlatRun) error {
+			return writeExportRunJSON(&buf, exportRunFields(run, columns))
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "unsupported export format: %s", format)
+	}
+
+	send := func() error {
+		if err := flush(); err != nil {
+			return fmt.Errorf("writing runs export: %w", err)
+		}
+		if buf.Len() == 0 {
+			return nil
+		}
+		if err := resp.Send(&apiv1.ExportRunsResponse{Data: buf.String()}); err != nil {
+			return err
+		}
+		buf.Reset()
+		return nil
+	}
+	count := 0
+	if err := scanExportRuns(ctx, query, func(run *runv1.FlatRun) error {
+		if err := writeRun(run); err != nil {
+			return fmt.Errorf("writing run %d to export: %w", run.Id, err)
+		}
+		count++
+		if count%defaultSearchRunsStreamBatchSize == 0 {
+			return send()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return send()
+}
+
+// exportRunHyperparameterNames returns the names of the hyperparameters of the runs returned
+// by a search query, sorted, as flattenRunHyperparameters names them.
+func exportRunHyperparameterNames(
+	ctx context.Context, tx bun.Tx, query *bun.SelectQuery,
+) ([]string, error) {
+	var names []string
+	if err := tx.NewRaw(`
+WITH RECURSIVE hp(name, value) AS (
+	SELECT e.key, e.value
+	FROM (?) AS runs, jsonb_each(CASE WHEN jsonb_typeof(runs.hyperparameters) = 'object' THEN runs.hyperparameters ELSE '{}' END) AS e
+	UNION ALL
+	SELECT hp.name || '.' || e.key, e.value
+	FROM hp, jsonb_each(CASE WHEN jsonb_typeof(hp.value) = 'object' THEN hp.value ELSE '{}' END) AS e
+)
+SELECT DISTINCT name FROM hp
+WHERE jsonb_typeof(value) <> 'object' OR value = '{}'::jsonb`,
+		query.Model((*runv1.FlatRun)(nil))).Scan(ctx, &names); err != nil {
+		return nil, fmt.Errorf("listing the hyperparameters of the runs: %w", err)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// validateExportRunColumn checks that a column of an export names a run column, a
+// hyperparameter or a summary metric.
+func validateExportRunColumn(col string) error {
+	if _, ok := exportRunColumns[col]; ok {
+		return nil
+	}
+	if strings.HasPrefix(col, "hp.") && len(col) > len("hp.") {
+		return nil
+	}
+	if _, _, _, err := parseMetricsName(col); err == nil {
+		return nil
+	}
+	return status.Errorf(codes.InvalidArgument, "invalid export column: %s", col)
+}
+
+// scanExportRuns calls fn for each run returned by a search query, in order.
+func scanExportRuns(
+	ctx context.Context, query *bun.SelectQuery, fn func(*runv1.FlatRun) error,
+) error {
+	rows, err := query.Model((*runv1.FlatRun)(nil)).Rows(ctx)
+	if err != nil {
+		return fmt.Errorf("searching runs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		run := &runv1.FlatRun{}
+		if err := db.Bun().ScanRow(ctx, rows, run); err != nil {
+			return fmt.Errorf("reading run from db: %w", err)
+		}
+		if err := fn(run); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("searching runs: %w", err)
+	}
+	return nil
+}
+
+// expandExportRunHyperparameters replaces the hyperparameters column of an export with a
+// column per hyperparameter.
+func expandExportRunHyperparameters(columns, hps []string) []string {
+	var expanded []string
+	for _, col := range columns {
+		if col != exportRunHyperparametersColumn {
+			expanded = append(expanded, col)
+			continue
+		}
+		for _, hp := range hps {
+			expanded = append(expanded, "hp."+hp)
+		}
+	}
+	return expanded
+}
+
+type exportRunField struct {
+	name  string
+	value any
+}
+
+// exportRunFields returns the values of the columns of an export for a run. The
+// hyperparameters column expands to the hyperparameters of the run, sorted by name.
+func exportRunFields(run *runv1.FlatRun, columns []string) []exportRunField {
+	var hps map[string]any
+	var flatHps map[string]any
+	if run.Hyperparameters != nil {
+		hps = run.Hyperparameters.AsMap()
+		flatHps = flattenRunHyperparameters(run)
+	}
+	var metrics map[string]any
+	if run.SummaryMetrics != nil {
+		metrics = run.SummaryMetrics.AsMap()
+	}
+
+	fields := make([]exportRunField, 0, len(columns))
+	for _, col := range columns {
+		switch value, ok := exportRunColumns[col]; {
+		case ok:
+			fields = append(fields, exportRunField{col, value(run)})
+		case col == exportRunHyperparametersColumn:
+			names := make([]string, 0, len(flatHps))
+			for name := range flatHps {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				fields = append(fields, exportRunField{"hp." + name, flatHps[name]})
+			}
+		case strings.HasPrefix(col, "hp."):
+			// The name of a hyperparameter may itself contain dots, so it is looked up by its
+			// full dotted path before being walked as nested maps, e.g. "hp.optimizer" for a
+			// nested hyperparameter.
+			_, name, _ := strings.Cut(col, ".")
+			value, ok := flatHps[name]
+			if !ok {
+				value = lookupExportRunValue(hps, strings.Split(name, "."))
+			}
+			fields = append(fields, exportRunField{col, value})
+		default:
+			group, name, qualifier, err := parseMetricsName(col)
+			if err != nil {
+				// Columns are validated before the export starts.
+				fields = append(fields, exportRunField{col, nil})
+				continue
+			}
+			fields = append(fields, exportRunField{
+				col, lookupExportRunValue(metrics, []string{group, name, qualifier}),
+			})
+		}
+	}
+	return fields
+}
+
+// flattenRunHyperparameters returns the hyperparameters of a run keyed by their dotted
+// path, e.g. "optimizer.lr".
+func flattenRunHyperparameters(run *runv1.FlatRun) map[string]any {
+	flat := map[string]any{}
+	if run.Hyperparameters == nil {
+		return flat
+	}
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+				flatten(prefix+k+".", nested)
+				continue
+			}
+			flat[prefix+k] = v
+		}
+	}
+	flatten("", run.Hyperparameters.AsMap())
+	return flat
+}
+
+// lookupExportRunValue returns the value at a path of nested maps, or nil if there is none.
+func lookupExportRunValue(m map[string]any, path []string) any {
+	var value any = m
+	for _, key := range path {
+		nested, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if value, ok = nested[key]; !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+func exportRunOptional[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func exportRunTime(t *timestamppb.Timestamp) any {
+	if t == nil {
+		return nil
+	}
+	return t.AsTime().Format(time.RFC3339Nano)
+}
+
+// exportRunCSVCell formats a value of an export as a CSV cell. Missing values are empty and
+// lists and objects are written as JSON.
+func exportRunCSVCell(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bs), nil
+	}
+}
+
+// writeExportRunJSON writes a run of an export as a JSON object on its own line, with its
+// fields in column order.
+func writeExportRunJSON(buf *bytes.Buffer, fields []exportRunField) error {
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// searchRunsQuery returns the query for the runs visible to the user that match the
+// project, filter and sort of a search.
+func (a *apiServer) searchRunsQuery(
+	ctx context.Context, curUser model.User, projectID *int32, filter, sort *string,
+) (*bun.SelectQuery, error) {
 	query := db.Bun().NewSelect().
-		Model(&runs).
 		ModelTableExpr("runs AS r").
 		Apply(getRunsColumns)
 
 	var proj *projectv1.Project
-	if req.ProjectId != nil {
-		proj, err = a.GetProjectByID(ctx, *req.ProjectId, *curUser)
+	var err error
+	if projectID != nil {
+		proj, err = a.GetProjectByID(ctx, *projectID, curUser)
 		if err != nil {
 			return nil, err
 		}
 
-		query = query.Where("r.project_id = ?", req.ProjectId)
+		query = query.Where("r.project_id = ?", projectID)
 	}
 	if query, err = experiment.AuthZProvider.Get().
-		FilterExperimentsQuery(ctx, *curUser, proj, query,
+		FilterExperimentsQuery(ctx, curUser, proj, query,
 			[]rbacv1.PermissionType{rbacv1.PermissionType_PERMISSION_TYPE_VIEW_EXPERIMENT_METADATA},
 		); err != nil {
 		return nil, err
 	}
 
-	if req.Filter != nil {
-		query, err = filterRunQuery(query, req.Filter)
+	if filter != nil {
+		query, err = filterRunQuery(query, filter)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if req.Sort != nil {
-		err = sortRuns(req.Sort, query)
+	if sort != nil {
+		err = sortRuns(sort, query)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		query.OrderExpr("id ASC")
 	}
-
-	pagination, err := runPagedBunExperimentsQuery(ctx, query, int(req.Offset), int(req.Limit))
-	if err != nil {
-		return nil, err
-	}
-	resp.Pagination = pagination
-	resp.Runs = runs
-	return resp, nil
+	return query, nil
 }
 
 func getRunsColumns(q *bun.SelectQuery) *bun.SelectQuery {
@@ -144,6 +633,8 @@ func getRunsColumns(q *bun.SelectQuery) *bun.SelectQuery {
 		ColumnExpr("w.name AS workspace_name").
 		ColumnExpr("(w.archived OR p.archived) AS parent_archived").
 		ColumnExpr("p.name AS project_name").
+		Column("r.moved_from_project_id").
+		ColumnExpr("proto_time(r.moved_at) AS moved_time").
 		ColumnExpr(`jsonb_build_object(
 			'searcher_type', e.config->'searcher'->>'name',
 			'searcher_metric', e.config->'searcher'->>'metric',
@@ -168,30 +659,14 @@ func sortRuns(sortString *string, runQuery *bun.SelectQuery) error {
 	}
 	sortByMap := map[string]string{
 		"asc":  "ASC",
-		"desc": "DESC NULLS LAST",
+		"desc": "DESC",
 	}
-	orderColMap := map[string]string{
-		"id":                    "id",
-		"experimentDescription": "e.config->>'description'",
-		"experimentName":        "e.config->>'name'",
-		"searcherType":          "e.config->'searcher'->>'name'",
-		"searcherMetric":        "e.config->'searcher'->>'metric'",
-		"startTime":             "r.start_time",
-		"endTime":               "r.end_time",
-		"state":                 "r.state",
-		"experimentProgress":    "COALESCE(e.progress, 0)",
-		"user":                  "COALESCE(u.username, u.display_name)",
-		"forkedFrom":            "e.parent_id",
-		"resourcePool":          "e.config->'resources'->>'resource_pool'",
-		"projectId":             "r.project_id",
-		"checkpointSize":        "checkpoint_size",
-		"checkpointCount":       "checkpoint_count",
-		"duration":              "duration",
-		"searcherMetricsVal":    "r.searcher_metric_val",
-		"externalExperimentId":  "e.external_experiment_id",
-		"externalRunId":         "r.external_run_id",
-		"experimentId":          "e.id",
-		"isExpMultitrial":       "((SELECT COUNT(*) FROM runs r WHERE e.id = r.experiment_id) > 1)",
+	// Runs without a value for the sort column are placed last unless requested otherwise,
+	// in either direction.
+	nullOrderMap := map[string]string{
+		"":           "NULLS LAST",
+		"nullslast":  "NULLS LAST",
+		"nullsfirst": "NULLS FIRST",
 	}
 	sortParams := strings.Split(*sortString, ",")
 	hasIDSort := false
@@ -200,10 +675,14 @@ func sortRuns(sortString *string, runQuery *bun.SelectQuery) error {
 		if len(paramDetail) != 2 {
 			return status.Errorf(codes.InvalidArgument, "invalid sort parameter: %s", sortParam)
 		}
-		if _, ok := sortByMap[paramDetail[1]]; !ok {
-			return status.Errorf(codes.InvalidArgument, "invalid sort direction: %s", paramDetail[1])
+		direction, nullOrder, hasNullOrder := strings.Cut(paramDetail[1], ":")
+		if _, ok := sortByMap[direction]; !ok {
+			return status.Errorf(codes.InvalidArgument, "invalid sort direction: %s", direction)
 		}
-		sortDirection := sortByMap[paramDetail[1]]
+		if _, ok := nullOrderMap[nullOrder]; !ok || (hasNullOrder && nullOrder == "") {
+			return status.Errorf(codes.InvalidArgument, "invalid sort null ordering: %s", nullOrder)
+		}
+		sortDirection := sortByMap[direction] + " " + nullOrderMap[nullOrder]
 		switch {
 		case strings.HasPrefix(paramDetail[0], "hp."):
 			param := strings.ReplaceAll(paramDetail[0], "'", "")
@@ -216,6 +695,15 @@ func sortRuns(sortString *string, runQuery *bun.SelectQuery) error {
 			hpQuery := strings.Join(hp, "->")
 			queryArgs = append(queryArgs, bun.Safe(sortDirection))
 			runQuery.OrderExpr(fmt.Sprintf(`r.hparams->%s ?`, hpQuery), queryArgs...)
+		case strings.HasPrefix(paramDetail[0], "metrics."):
+			metricGroup, metricName, metricQualifier, err := parseMetricsName(
+				strings.TrimPrefix(paramDetail[0], "metrics."))
+			if err != nil {
+				return err
+			}
+			// Sort by the JSON value, like hyperparameters, so that numbers sort numerically.
+			runQuery.OrderExpr("r.summary_metrics->?->?->? ?",
+				metricGroup, metricName, metricQualifier, bun.Safe(sortDirection))
 		case strings.Contains(paramDetail[0], "."):
 			metricGroup, metricName, metricQualifier, err := parseMetricsName(paramDetail[0])
 			if err != nil {
@@ -224,12 +712,12 @@ func sortRuns(sortString *string, runQuery *bun.SelectQuery) error {
 			runQuery.OrderExpr("r.summary_metrics->?->?->>? ?",
 				metricGroup, metricName, metricQualifier, bun.Safe(sortDirection))
 		default:
-			if _, ok := orderColMap[paramDetail[0]]; !ok {
-				return status.Errorf(codes.InvalidArgument, "invalid sort col: %s", paramDetail[0])
+			col, err := runSortColumnToSQL(paramDetail[0])
+			if err != nil {
+				return err
 			}
-			hasIDSort = hasIDSort || paramDetail[0] == "id"
-			runQuery.OrderExpr(
-				fmt.Sprintf("%s %s", orderColMap[paramDetail[0]], sortDirection))
+			hasIDSort = hasIDSort || col == "id"
+			runQuery.OrderExpr(fmt.Sprintf("%s %s", col, sortDirection))
 		}
 	}
 	if !hasIDSort {
@@ -238,6 +726,43 @@ func sortRuns(sortString *string, runQuery *bun.SelectQuery) error {
 	return nil
 }
 
+// runSortColumnToSQL returns the SQL expression to sort runs by a column.
+func runSortColumnToSQL(columnName string) (string, error) {
+	// To prevent SQL injection this function should never
+	// return a user generated field name
+	orderColMap := map[string]string{
+		"id":                    "id",
+		"experimentDescription": "e.config->>'description'",
+		"experimentName":        "e.config->>'name'",
+		"searcherType":          "e.config->'searcher'->>'name'",
+		"searcherMetric":        "e.config->'searcher'->>'metric'",
+		"startTime":             "r.start_time",
+		"endTime":               "r.end_time",
+		"state":                 "r.state",
+		"experimentProgress":    "COALESCE(e.progress, 0)",
+		"user":                  "COALESCE(u.username, u.display_name)",
+		"forkedFrom":            "e.parent_id",
+		"resourcePool":          "e.config->'resources'->>'resource_pool'",
+		"projectId":             "r.project_id",
+		"checkpointSize":        "checkpoint_size",
+		"checkpointCount":       "checkpoint_count",
+		"duration":              "duration",
+		"searcherMetricValue":   "r.searcher_metric_value",
+		"externalExperimentId":  "e.external_experiment_id",
+		"externalRunId":         "r.external_run_id",
+		"experimentId":          "e.id",
+		"isExpMultitrial":       "((SELECT COUNT(*) FROM runs r WHERE e.id = r.experiment_id) > 1)",
+	}
+	col, ok := orderColMap[resolveRunColumnName(columnName)]
+	if !ok {
+		return "", status.Errorf(codes.InvalidArgument, "invalid sort col: %s", columnName)
+	}
+	return col, nil
+}
+
+// filterRunQuery applies the filter expression to a runs query. Runs of archived
+// experiments are excluded unless the filter sets showArchived; SearchRuns and
+// MoveRuns both filter through here so that a filter selects the same runs in each.
 func filterRunQuery(getQ *bun.SelectQuery, filter *string) (*bun.SelectQuery, error) {
 	var efr experimentFilterRoot
 	err := json.Unmarshal([]byte(*filter), &efr)
@@ -245,7 +770,7 @@ func filterRunQuery(getQ *bun.SelectQuery, filter *string) (*bun.SelectQuery, er
 		return nil, err
 	}
 	getQ = getQ.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
-		_, err = efr.toSQL(q)
+		_, err = efr.toRunSQL(q)
 		return q
 	}).WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
 		if !efr.ShowArchived {
@@ -331,6 +856,10 @@ func (a *apiServer) MoveRuns(
 	var expMoveIds []int32
 	for _, check := range runChecks {
 		visibleIDs.Insert(check.ID)
+		if req.SourceProjectId == req.DestinationProjectId {
+			results = append(results, alreadyInProjectResult(check.ID, req.DestinationProjectId))
+			continue
+		}
 		if check.Archived {
 			results = append(results, &apiv1.RunActionResult{
 				Error: "Run is archived.",
@@ -351,14 +880,28 @@ func (a *apiServer) MoveRuns(
 		validIDs = append(validIDs, check.ID)
 	}
 	if req.Filter == nil {
+		var missingIDs []int32
 		for _, originalID := range req.RunIds {
 			if !visibleIDs.Contains(originalID) {
-				results = append(results, &apiv1.RunActionResult{
-					Error: fmt.Sprintf("Run with id '%d' not found in project with id '%d'", originalID, req.SourceProjectId),
-					Id:    originalID,
-				})
+				missingIDs = append(missingIDs, originalID)
 			}
 		}
+		// Runs that are already in the destination project, such as when a move is
+		// retried, are reported as moved rather than not found.
+		movedIDs, err := runsInProject(ctx, *curUser, missingIDs, req.DestinationProjectId)
+		if err != nil {
+			return nil, err
+		}
+		for _, missingID := range missingIDs {
+			if movedIDs.Contains(missingID) {
+				results = append(results, alreadyInProjectResult(missingID, req.DestinationProjectId))
+				continue
+			}
+			results = append(results, &apiv1.RunActionResult{
+				Error: fmt.Sprintf("Run with id '%d' not found in project with id '%d'", missingID, req.SourceProjectId),
+				Id:    missingID,
+			})
+		}
 	}
 	if len(validIDs) > 0 {
 		expMoveResults, err := experiment.MoveExperiments(ctx, expMoveIds, nil, req.DestinationProjectId)
@@ -372,8 +915,14 @@ func (a *apiServer) MoveRuns(
 			}
 		}
 		var acceptedIDs []int32
-		if _, err = db.Bun().NewUpdate().Table("runs").
-			Set("project_id = ?", req.DestinationProjectId).
+		updateQ := db.Bun().NewUpdate().Table("runs").
+			Set("project_id = ?", req.DestinationProjectId)
+		if req.RecordProvenance {
+			updateQ = updateQ.
+				Set("moved_from_project_id = ?", req.SourceProjectId).
+				Set("moved_at = now()")
+		}
+		if _, err = updateQ.
 			Where("runs.id IN (?)", bun.In(validIDs)).
 			Where("runs.experiment_id NOT IN (?)", bun.In(failedExpMoveIds)).
 			Returning("runs.id").
@@ -404,3 +953,497 @@ func (a *apiServer) MoveRuns(
 	}
 	return &apiv1.MoveRunsResponse{Results: results}, nil
 }
+
+func (a *apiServer) ArchiveRuns(
+	ctx context.Context, req *apiv1.ArchiveRunsRequest,
+) (*apiv1.ArchiveRunsResponse, error) {
+	results, err := archiveUnarchiveRuns(ctx, true, req.RunIds, req.ProjectId, req.Filter,
+		req.SkipMultitrial)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.ArchiveRunsResponse{Results: results}, nil
+}
+
+func (a *apiServer) UnarchiveRuns(
+	ctx context.Context, req *apiv1.UnarchiveRunsRequest,
+) (*apiv1.UnarchiveRunsResponse, error) {
+	results, err := archiveUnarchiveRuns(ctx, false, req.RunIds, req.ProjectId, req.Filter,
+		req.SkipMultitrial)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.UnarchiveRunsResponse{Results: results}, nil
+}
+
+// archiveUnarchiveRuns archives or unarchives the experiments of the runs in the project
+// that are selected by ID or, if set, by the filter. A run is archived with its
+// experiment, so archiving a run of a multi-trial experiment archives every run of it,
+// unless skipMultitrial is set and such runs are skipped.
+func archiveUnarchiveRuns(
+	ctx context.Context, archive bool, runIDs []int32, projectID int32, filter *string,
+	skipMultitrial bool,
+) ([]*apiv1.RunActionResult, error) {
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	action := "archive"
+	if !archive {
+		action = "unarchive"
+	}
+
+	var runChecks []archiveRunOKResult
+	getQ := db.Bun().NewSelect().
+		ModelTableExpr("runs AS r").
+		Model(&runChecks).
+		Column("r.id").
+		ColumnExpr("COALESCE(e.archived, FALSE) AS archived").
+		ColumnExpr("r.experiment_id as exp_id").
+		ColumnExpr("((SELECT COUNT(*) FROM runs r WHERE e.id = r.experiment_id) > 1) as is_multitrial").
+		Join("LEFT JOIN experiments e ON r.experiment_id=e.id").
+		Join("JOIN projects p ON r.project_id = p.id").
+		Join("JOIN workspaces w ON p.workspace_id = w.id").
+		Where("r.project_id = ?", projectID)
+
+	if filter == nil {
+		getQ = getQ.Where("r.id IN (?)", bun.In(runIDs))
+	} else {
+		getQ, err = filterRunQuery(getQ, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if getQ, err = experiment.AuthZProvider.Get().FilterExperimentsQuery(ctx, *curUser, nil, getQ,
+		[]rbacv1.PermissionType{
+			rbacv1.PermissionType_PERMISSION_TYPE_VIEW_EXPERIMENT_METADATA,
+			rbacv1.PermissionType_PERMISSION_TYPE_UPDATE_EXPERIMENT_METADATA,
+		}); err != nil {
+		return nil, err
+	}
+
+	err = getQ.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*apiv1.RunActionResult
+	visibleIDs := set.New[int32]()
+	for _, check := range runChecks {
+		visibleIDs.Insert(check.ID)
+		switch {
+		case check.ExpID == nil:
+			results = append(results, &apiv1.RunActionResult{
+				Error: fmt.Sprintf("Run has no experiment to %s.", action),
+				Id:    check.ID,
+			})
+		case check.Archived && archive:
+			results = append(results, &apiv1.RunActionResult{
+				Error: "Run is already archived.",
+				Id:    check.ID,
+			})
+		case !check.Archived && !archive:
+			results = append(results, &apiv1.RunActionResult{
+				Error: "Run is not archived.",
+				Id:    check.ID,
+			})
+		case check.IsMultitrial && skipMultitrial:
+			results = append(results, &apiv1.RunActionResult{
+				Error: fmt.Sprintf("Skipping run '%d' (part of multi-trial).", check.ID),
+				Id:    check.ID,
+			})
+		default:
+			results = append(results, archiveUnarchiveRun(ctx, archive, check.ID, *check.ExpID))
+		}
+	}
+	if filter == nil {
+		for _, originalID := range runIDs {
+			if !visibleIDs.Contains(originalID) {
+				results = append(results, &apiv1.RunActionResult{
+					Error: fmt.Sprintf("Run with id '%d' not found in project with id '%d'",
+						originalID, projectID),
+					Id: originalID,
+				})
+			}
+		}
+	}
+	return results, nil
+}
+
+// archiveUnarchiveRun sets the archived flag of the experiment of a run in its own
+// transaction, so that a failure for one run does not undo the others. Runs of an
+// experiment that is not in a terminal state may not be archived or unarchived.
+func archiveUnarchiveRun(
+	ctx context.Context, archive bool, runID, expID int32,
+) *apiv1.RunActionResult {
+	var updated bool
+	err := db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().Table("experiments").
+			Set("archived = ?", archive).
+			Where("id = ?", expID).
+			Where("state IN (?)", bun.In(model.StatesToStrings(model.TerminalStates))).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		updated = rows > 0
+		return nil
+	})
+	switch {
+	case err != nil:
+		action := "archive"
+		if !archive {
+			action = "unarchive"
+		}
+		return &apiv1.RunActionResult{
+			Error: fmt.Sprintf("Failed to %s associated experiment: %s", action, err),
+			Id:    runID,
+		}
+	case !updated:
+		return &apiv1.RunActionResult{
+			Error: "Run's experiment is not in terminal state.",
+			Id:    runID,
+		}
+	default:
+		return &apiv1.RunActionResult{Error: "", Id: runID}
+	}
+}
+
+// deleteRunOKResult is what is checked of a run before deleting it.
+type deleteRunOKResult struct {
+	ID               int32
+	ExpID            *int32
+	ExpRunCount      int
+	Terminal         bool
+	HasModelVersions bool
+	IsMultitrial     bool
+}
+
+func (a *apiServer) DeleteRuns(
+	ctx context.Context, req *apiv1.DeleteRunsRequest,
+) (*apiv1.DeleteRunsResponse, error) {
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var runChecks []deleteRunOKResult
+	getQ := db.Bun().NewSelect().
+		ModelTableExpr("runs AS r").
+		Model(&runChecks).
+		Column("r.id").
+		ColumnExpr("r.experiment_id as exp_id").
+		ColumnExpr("(SELECT COUNT(*) FROM runs r WHERE e.id = r.experiment_id) as exp_run_count").
+		ColumnExpr("r.state IN (?) AS terminal", bun.In(model.StatesToStrings(model.TerminalStates))).
+		ColumnExpr(`EXISTS(
+			SELECT 1 FROM run_id_task_id rt
+			JOIN checkpoints_v2 c ON c.task_id = rt.task_id
+			JOIN model_versions mv ON mv.checkpoint_uuid = c.uuid
+			WHERE rt.run_id = r.id) AS has_model_versions`).
+		ColumnExpr("((SELECT COUNT(*) FROM runs r WHERE e.id = r.experiment_id) > 1) as is_multitrial").
+		Join("LEFT JOIN experiments e ON r.experiment_id=e.id").
+		Join("JOIN projects p ON r.project_id = p.id").
+		Join("JOIN workspaces w ON p.workspace_id = w.id").
+		Where("r.project_id = ?", req.ProjectId)
+
+	if req.Filter == nil {
+		getQ = getQ.Where("r.id IN (?)", bun.In(req.RunIds))
+	} else {
+		getQ, err = filterRunQuery(getQ, req.Filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if getQ, err = experiment.AuthZProvider.Get().FilterExperimentsQuery(ctx, *curUser, nil, getQ,
+		[]rbacv1.PermissionType{
+			rbacv1.PermissionType_PERMISSION_TYPE_VIEW_EXPERIMENT_METADATA,
+			rbacv1.PermissionType_PERMISSION_TYPE_DELETE_EXPERIMENT,
+		}); err != nil {
+		return nil, err
+	}
+
+	err = getQ.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*apiv1.RunActionResult
+	visibleIDs := set.New[int32]()
+	var validRuns []deleteRunOKResult
+	for _, check := range runChecks {
+		visibleIDs.Insert(check.ID)
+		switch {
+		case !check.Terminal:
+			results = append(results, &apiv1.RunActionResult{
+				Error: "Run is not in terminal state.",
+				Id:    check.ID,
+			})
+		case check.HasModelVersions:
+			results = append(results, &apiv1.RunActionResult{
+				Error: "Run has checkpoints registered as model versions.",
+				Id:    check.ID,
+			})
+		case check.IsMultitrial && req.SkipMultitrial:
+			results = append(results, &apiv1.RunActionResult{
+				Error: fmt.Sprintf("Skipping run '%d' (part of multi-trial).", check.ID),
+				Id:    check.ID,
+			})
+		default:
+			validRuns = append(validRuns, check)
+		}
+	}
+	if req.Filter == nil {
+		for _, originalID := range req.RunIds {
+			if !visibleIDs.Contains(originalID) {
+				results = append(results, &apiv1.RunActionResult{
+					Error: fmt.Sprintf("Run with id '%d' not found in project with id '%d'",
+						originalID, req.ProjectId),
+					Id: originalID,
+				})
+			}
+		}
+	}
+
+	// The checkpoints and jobs of the runs are cleaned up before their rows are deleted, since
+	// nothing refers to them afterwards. A run whose resources could not be cleaned up is kept.
+	var validIDs []int32
+	cleanUpErrs := a.cleanUpDeletedRuns(ctx, curUser, validRuns)
+	for _, run := range validRuns {
+		if err, ok := cleanUpErrs[run.ID]; ok {
+			results = append(results, &apiv1.RunActionResult{
+				Error: fmt.Sprintf("Failed to clean up run: %s", err),
+				Id:    run.ID,
+			})
+			continue
+		}
+		validIDs = append(validIDs, run.ID)
+	}
+
+	if len(validIDs) > 0 {
+		deletedIDs, taskIDs, err := db.DeleteRuns(ctx, validIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, deletedID := range deletedIDs {
+			results = append(results, &apiv1.RunActionResult{
+				Error: "",
+				Id:    deletedID,
+			})
+		}
+
+		// The runs are gone whether or not their logs are, so failing to delete the logs
+		// does not fail the request.
+		trialIDs := make([]int, 0, len(deletedIDs))
+		for _, id := range deletedIDs {
+			trialIDs = append(trialIDs, int(id))
+		}
+		if err := a.m.trialLogBackend.DeleteTrialLogs(trialIDs); err != nil {
+			log.WithError(err).Errorf("deleting trial logs of runs %v", deletedIDs)
+		}
+		if err := a.m.taskLogBackend.DeleteTaskLogs(taskIDs); err != nil {
+			log.WithError(err).Errorf("deleting task logs of runs %v", deletedIDs)
+		}
+	}
+	return &apiv1.DeleteRunsResponse{Results: results}, nil
+}
+
+// cleanUpDeletedRuns garbage collects the checkpoints of runs that are about to be deleted,
+// and deletes the jobs of the experiments that are left without runs, the way deleting an
+// experiment does. It returns the errors of the runs that could not be cleaned up.
+func (a *apiServer) cleanUpDeletedRuns(
+	ctx context.Context, curUser *model.User, runs []deleteRunOKResult,
+) map[int32]error {
+	runsByExp := map[int32][]deleteRunOKResult{}
+	for _, run := range runs {
+		if run.ExpID != nil {
+			runsByExp[*run.ExpID] = append(runsByExp[*run.ExpID], run)
+		}
+	}
+
+	var mu sync.Mutex
+	errs := map[int32]error{}
+	sema := make(chan struct{}, maxConcurrentDeletes)
+	var g errgroup.Group
+	for expID, expRuns := range runsByExp {
+		expID, expRuns := expID, expRuns
+		g.Go(func() error {
+			sema <- struct{}{}
+			defer func() { <-sema }()
+
+			if err := a.cleanUpExperimentRuns(ctx, curUser, int(expID), expRuns); err != nil {
+				log.WithError(err).Errorf("failed to clean up runs of experiment %d", expID)
+				mu.Lock()
+				defer mu.Unlock()
+				for _, run := range expRuns {
+					errs[run.ID] = err
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return errs
+}
+
+func (a *apiServer) cleanUpExperimentRuns(
+	ctx context.Context, curUser *model.User, expID int, runs []deleteRunOKResult,
+) error {
+	exp, err := db.ExperimentByID(ctx, expID)
+	if err != nil {
+		return err
+	}
+	runIDs := make([]int32, 0, len(runs))
+	for _, run := range runs {
+		runIDs = append(runIDs, run.ID)
+	}
+	// The experiment is deleted along with its last run, so its tensorboards and job go too.
+	expDeleted := len(runs) >= runs[0].ExpRunCount
+
+	checkpoints, err := db.RunCheckpointsToGC(ctx, runIDs)
+	if err != nil {
+		return err
+	}
+	if len(checkpoints) > 0 {
+		workspaceIDs, err := workspace.WorkspacesIDsByExperimentIDs(ctx, []int{expID})
+		if err != nil {
+			return err
+		}
+		agentUserGroup, err := user.GetAgentUserGroup(ctx, *exp.OwnerID, workspaceIDs[0])
+		if err != nil {
+			return err
+		}
+		taskSpec := *a.m.taskSpec
+		if err := runCheckpointGCForCheckpoints(
+			a.m.rm, a.m.db, exp.JobID, exp.StartTime,
+			&taskSpec, exp.ID, exp.Config, checkpoints,
+			[]string{fullDeleteGlob}, expDeleted, agentUserGroup, curUser, nil,
+		); err != nil {
+			return fmt.Errorf("garbage collecting checkpoints: %w", err)
+		}
+	}
+
+	if !expDeleted {
+		return nil
+	}
+	resp, err := a.m.rm.DeleteJob(sproto.DeleteJob{JobID: exp.JobID})
+	if err != nil {
+		return fmt.Errorf("requesting cleanup of resource manager resources: %w", err)
+	}
+	if err := <-resp.Err; err != nil {
+		return fmt.Errorf("cleaning up resource manager resources: %w", err)
+	}
+	return nil
+}
+
+func validateRunTags(tags []string) error {
+	if len(tags) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one tag is required")
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return status.Error(codes.InvalidArgument, "tags must not be empty")
+		}
+	}
+	return nil
+}
+
+func (a *apiServer) AddRunTags(
+	ctx context.Context, req *apiv1.AddRunTagsRequest,
+) (*apiv1.AddRunTagsResponse, error) {
+	// TODO(runs) run specific RBAC.
+	if err := trials.CanGetTrialsExperimentAndCheckCanDoAction(ctx, int(req.RunId),
+		experiment.AuthZProvider.Get().CanEditExperimentsMetadata); err != nil {
+		return nil, err
+	}
+	if err := validateRunTags(req.Tags); err != nil {
+		return nil, err
+	}
+
+	tags, err := db.AddRunTags(ctx, req.RunId, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.AddRunTagsResponse{Tags: tags}, nil
+}
+
+func (a *apiServer) RemoveRunTags(
+	ctx context.Context, req *apiv1.RemoveRunTagsRequest,
+) (*apiv1.RemoveRunTagsResponse, error) {
+	// TODO(runs) run specific RBAC.
+	if err := trials.CanGetTrialsExperimentAndCheckCanDoAction(ctx, int(req.RunId),
+		experiment.AuthZProvider.Get().CanEditExperimentsMetadata); err != nil {
+		return nil, err
+	}
+	if err := validateRunTags(req.Tags); err != nil {
+		return nil, err
+	}
+
+	tags, err := db.RemoveRunTags(ctx, req.RunId, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.RemoveRunTagsResponse{Tags: tags}, nil
+}
+
+func (a *apiServer) GetRunTags(
+	ctx context.Context, req *apiv1.GetRunTagsRequest,
+) (*apiv1.GetRunTagsResponse, error) {
+	// TODO(runs) run specific RBAC.
+	if err := trials.CanGetTrialsExperimentAndCheckCanDoAction(ctx, int(req.RunId),
+		experiment.AuthZProvider.Get().CanGetExperimentArtifacts); err != nil {
+		return nil, err
+	}
+
+	tags, err := db.RunTags(ctx, req.RunId)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.GetRunTagsResponse{Tags: tags}, nil
+}
+
+// alreadyInProjectResult is the successful result of moving a run to the project it
+// is already in.
+func alreadyInProjectResult(runID, projectID int32) *apiv1.RunActionResult {
+	return &apiv1.RunActionResult{
+		Error:   "",
+		Id:      runID,
+		Message: ptrs.Ptr(fmt.Sprintf("Run is already in project with id '%d'.", projectID)),
+	}
+}
+
+// runsInProject returns which of the runs, among those visible to the user, are in the
+// project.
+func runsInProject(
+	ctx context.Context, curUser model.User, runIDs []int32, projectID int32,
+) (set.Set[int32], error) {
+	result := set.New[int32]()
+	if len(runIDs) == 0 {
+		return result, nil
+	}
+
+	var ids []int32
+	q := db.Bun().NewSelect().
+		ModelTableExpr("runs AS r").
+		Column("r.id").
+		Join("JOIN projects p ON r.project_id = p.id").
+		Where("r.id IN (?)", bun.In(runIDs)).
+		Where("r.project_id = ?", projectID)
+	q, err := experiment.AuthZProvider.Get().FilterExperimentsQuery(ctx, curUser, nil, q,
+		[]rbacv1.PermissionType{rbacv1.PermissionType_PERMISSION_TYPE_VIEW_EXPERIMENT_METADATA})
+	if err != nil {
+		return nil, err
+	}
+	if err := q.Scan(ctx, &ids); err != nil {
+		return nil, fmt.Errorf("getting runs in project %d: %w", projectID, err)
+	}
+	for _, id := range ids {
+		result.Insert(id)
+	}
+	return result, nil
+}
@@ -6,12 +6,17 @@ package internal
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
 
+	apiPkg "github.com/determined-ai/determined/master/internal/api"
 	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
@@ -91,6 +96,51 @@ func TestSearchRunsSort(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, int32(exp2.ID), resp.Runs[0].Experiment.Id)
 	require.Equal(t, int32(exp.ID), resp.Runs[1].Experiment.Id)
+
+	// Add a third experiment without the sorted hyperparameter.
+	exp3 := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+
+	task3 := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+	require.NoError(t, db.AddTask(ctx, task3))
+	require.NoError(t, db.AddTrial(ctx, &model.Trial{
+		State:        model.PausedState,
+		ExperimentID: exp3.ID,
+		StartTime:    time.Now(),
+		HParams:      map[string]any{"test1": map[string]any{"test2": 3}},
+	}, task3.TaskID))
+
+	for _, c := range []struct {
+		sort    string
+		wantIDs []int
+	}{
+		{sort: "hp.global_batch_size=asc", wantIDs: []int{exp.ID, exp2.ID, exp3.ID}},
+		{sort: "hp.global_batch_size=desc", wantIDs: []int{exp2.ID, exp.ID, exp3.ID}},
+		{sort: "hp.global_batch_size=asc:nullslast", wantIDs: []int{exp.ID, exp2.ID, exp3.ID}},
+		{sort: "hp.global_batch_size=asc:nullsfirst", wantIDs: []int{exp3.ID, exp.ID, exp2.ID}},
+		{sort: "hp.global_batch_size=desc:nullsfirst", wantIDs: []int{exp3.ID, exp2.ID, exp.ID}},
+		// All runs share the same state, so the secondary key breaks the tie.
+		{sort: "state=asc,id=desc", wantIDs: []int{exp3.ID, exp2.ID, exp.ID}},
+		{sort: "state=desc,id=asc", wantIDs: []int{exp.ID, exp2.ID, exp3.ID}},
+		{sort: "state=asc,hp.test1.test2=desc", wantIDs: []int{exp2.ID, exp3.ID, exp.ID}},
+	} {
+		resp, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+			ProjectId: req.ProjectId,
+			Sort:      ptrs.Ptr(c.sort),
+		})
+		require.NoError(t, err, c.sort)
+		require.Len(t, resp.Runs, len(c.wantIDs), c.sort)
+		for i, id := range c.wantIDs {
+			require.Equal(t, int32(id), resp.Runs[i].Experiment.Id, c.sort)
+		}
+	}
+
+	for _, sort := range []string{"id=asc:nulls", "id=asc:", "id=nullsfirst"} {
+		_, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+			ProjectId: req.ProjectId,
+			Sort:      ptrs.Ptr(sort),
+		})
+		require.ErrorContains(t, err, "invalid sort", sort)
+	}
 }
 
 func TestSearchRunsFilter(t *testing.T) {
@@ -127,7 +177,7 @@ func TestSearchRunsFilter(t *testing.T) {
 	hyperparameters2 := map[string]any{"global_batch_size": 2, "test1": map[string]any{"test2": 5}}
 
 	// Add second experiment
-	exp2 := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+	exp2 := createTestExpWithProjectID(t, api, curUser, projectIDInt, "second")
 
 	task2 := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
 	require.NoError(t, db.AddTask(ctx, task2))
@@ -172,6 +222,30 @@ func TestSearchRunsFilter(t *testing.T) {
 				`"location":"LOCATION_TYPE_RUN","operator":"=","type":"COLUMN_TYPE_NUMBER","value":%d}],`+
 				`"conjunction":"and","kind":"group"},"showArchived":false}`, int32(exp2.ID)),
 		},
+		"ExperimentColOperator": {
+			expectedNumRuns: 1,
+			filter: fmt.Sprintf(`{"filterGroup":{"children":[{"columnName":"id","kind":"field",`+
+				`"location":"LOCATION_TYPE_EXPERIMENT","operator":"=","type":"COLUMN_TYPE_NUMBER","value":%d}],`+
+				`"conjunction":"and","kind":"group"},"showArchived":false}`, int32(exp2.ID)),
+		},
+		"ExperimentColContains": {
+			expectedNumRuns: 2,
+			filter: `{"filterGroup":{"children":[{"columnName":"description","kind":"field",` +
+				`"location":"LOCATION_TYPE_EXPERIMENT","operator":"contains","type":"COLUMN_TYPE_TEXT","value":"des"}],` +
+				`"conjunction":"and","kind":"group"},"showArchived":false}`,
+		},
+		"ExperimentColNotContains": {
+			expectedNumRuns: 1,
+			filter: `{"filterGroup":{"children":[{"columnName":"tags","kind":"field",` +
+				`"location":"LOCATION_TYPE_EXPERIMENT","operator":"notContains","type":"COLUMN_TYPE_TEXT","value":"second"}],` +
+				`"conjunction":"and","kind":"group"},"showArchived":false}`,
+		},
+		"ExperimentColProjectId": {
+			expectedNumRuns: 2,
+			filter: fmt.Sprintf(`{"filterGroup":{"children":[{"columnName":"projectId","kind":"field",`+
+				`"location":"LOCATION_TYPE_EXPERIMENT","operator":"=","type":"COLUMN_TYPE_NUMBER","value":%d}],`+
+				`"conjunction":"and","kind":"group"},"showArchived":false}`, projectID),
+		},
 		"HyperParamEmpty": {
 			expectedNumRuns: 0,
 			filter: `{"filterGroup":{"children":[{"columnName":"hp.global_batch_size","kind":"field",` +
@@ -245,6 +319,288 @@ func TestSearchRunsFilter(t *testing.T) {
 			require.Len(t, resp.Runs, testVars.expectedNumRuns)
 		})
 	}
+
+	// Experiment columns that describe trials are not filterable for runs.
+	_, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: req.ProjectId,
+		Filter: ptrs.Ptr(`{"filterGroup":{"children":[{"columnName":"externalTrialId","kind":"field",` +
+			`"location":"LOCATION_TYPE_EXPERIMENT","operator":"=","type":"COLUMN_TYPE_TEXT","value":"a"}],` +
+			`"conjunction":"and","kind":"group"},"showArchived":false}`),
+	})
+	require.ErrorContains(t, err, "invalid experiment column externalTrialId for runs")
+}
+
+func TestSearchRunsMetrics(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	projectID := int32(projectIDInt)
+
+	// The losses sort differently as numbers and as text.
+	var runIDs []int32
+	for _, summaryMetrics := range []map[string]any{
+		{"validation_metrics": map[string]any{"loss": map[string]any{"min": 9, "last": 12}}},
+		{"validation_metrics": map[string]any{"loss": map[string]any{"min": 10, "last": 11}}},
+		{},
+	} {
+		exp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		trial := &model.Trial{
+			State:        model.PausedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+		}
+		require.NoError(t, db.AddTrial(ctx, trial, task.TaskID))
+		_, err := db.Bun().NewUpdate().Table("runs").
+			Set("summary_metrics = ?", summaryMetrics).
+			Where("id = ?", trial.ID).
+			Exec(ctx)
+		require.NoError(t, err)
+		runIDs = append(runIDs, int32(trial.ID))
+	}
+
+	for _, c := range []struct {
+		sort    string
+		wantIDs []int32
+	}{
+		{sort: "metrics.validation.loss.min=asc", wantIDs: []int32{runIDs[0], runIDs[1], runIDs[2]}},
+		{sort: "metrics.validation.loss.min=desc", wantIDs: []int32{runIDs[1], runIDs[0], runIDs[2]}},
+		{sort: "metrics.validation.loss.min=asc:nullsfirst", wantIDs: []int32{runIDs[2], runIDs[0], runIDs[1]}},
+		{sort: "metrics.validation.loss.last=asc", wantIDs: []int32{runIDs[1], runIDs[0], runIDs[2]}},
+	} {
+		resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+			ProjectId: &projectID,
+			Sort:      ptrs.Ptr(c.sort),
+		})
+		require.NoError(t, err, c.sort)
+		require.Len(t, resp.Runs, len(c.wantIDs), c.sort)
+		for i, id := range c.wantIDs {
+			require.Equal(t, id, resp.Runs[i].Id, c.sort)
+		}
+	}
+
+	_, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &projectID,
+		Sort:      ptrs.Ptr("metrics.loss=asc"),
+	})
+	require.ErrorContains(t, err, "loss is not a valid metrics id")
+
+	metricFilter := func(column, operator, columnType, value string) string {
+		return fmt.Sprintf(`{"filterGroup":{"children":[{"columnName":%q,"kind":"field",`+
+			`"location":"LOCATION_TYPE_RUN_METRICS","operator":%q,"type":%q,"value":%s}],`+
+			`"conjunction":"and","kind":"group"},"showArchived":false}`, column, operator, columnType, value)
+	}
+	tests := map[string]struct {
+		wantIDs []int32
+		filter  string
+	}{
+		"MetricEmpty": {
+			wantIDs: []int32{runIDs[2]},
+			filter:  metricFilter("metrics.validation.loss.min", "isEmpty", "COLUMN_TYPE_NUMBER", "null"),
+		},
+		"MetricNotEmpty": {
+			wantIDs: []int32{runIDs[0], runIDs[1]},
+			filter:  metricFilter("metrics.validation.loss.min", "notEmpty", "COLUMN_TYPE_NUMBER", "null"),
+		},
+		"MetricContains": {
+			wantIDs: []int32{runIDs[1]},
+			filter:  metricFilter("metrics.validation.loss.min", "contains", "COLUMN_TYPE_NUMBER", "10"),
+		},
+		"MetricNotContains": {
+			wantIDs: []int32{runIDs[0]},
+			filter:  metricFilter("metrics.validation.loss.min", "notContains", "COLUMN_TYPE_NUMBER", "10"),
+		},
+		"MetricOperator": {
+			wantIDs: []int32{runIDs[1]},
+			filter:  metricFilter("metrics.validation.loss.min", ">", "COLUMN_TYPE_NUMBER", "9.5"),
+		},
+		"MetricTextOperator": {
+			wantIDs: []int32{runIDs[0]},
+			filter:  metricFilter("metrics.validation.loss.last", "=", "COLUMN_TYPE_TEXT", `"12"`),
+		},
+	}
+	for testCase, testVars := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+				ProjectId: &projectID,
+				Sort:      ptrs.Ptr("id=asc"),
+				Filter:    ptrs.Ptr(testVars.filter),
+			})
+			require.NoError(t, err)
+			var ids []int32
+			for _, r := range resp.Runs {
+				ids = append(ids, r.Id)
+			}
+			require.Equal(t, testVars.wantIDs, ids)
+		})
+	}
+}
+
+func TestSearchRunsStream(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	projectID := int32(projectIDInt)
+
+	for i := 1; i <= 3; i++ {
+		exp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		require.NoError(t, db.AddTrial(ctx, &model.Trial{
+			State:        model.PausedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+			HParams:      map[string]any{"global_batch_size": i},
+		}, task.TaskID))
+	}
+
+	streamRunIDs := func(req *apiv1.SearchRunsStreamRequest) ([]int32, []int) {
+		stream := &mockStream[*apiv1.SearchRunsStreamResponse]{ctx: ctx}
+		require.NoError(t, api.SearchRunsStream(req, stream))
+		var ids []int32
+		var batchSizes []int
+		for _, resp := range stream.getData() {
+			batchSizes = append(batchSizes, len(resp.Runs))
+			for _, r := range resp.Runs {
+				ids = append(ids, r.Id)
+			}
+		}
+		return ids, batchSizes
+	}
+
+	// The streamed runs match those of SearchRuns with the same sort and filter.
+	sort := ptrs.Ptr("hp.global_batch_size=desc")
+	searchResp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{ProjectId: &projectID, Sort: sort})
+	require.NoError(t, err)
+	require.Len(t, searchResp.Runs, 3)
+	var searchIDs []int32
+	for _, r := range searchResp.Runs {
+		searchIDs = append(searchIDs, r.Id)
+	}
+
+	ids, batchSizes := streamRunIDs(&apiv1.SearchRunsStreamRequest{
+		ProjectId: &projectID,
+		Sort:      sort,
+		BatchSize: 2,
+	})
+	require.Equal(t, searchIDs, ids)
+	require.Equal(t, []int{2, 1}, batchSizes)
+
+	ids, batchSizes = streamRunIDs(&apiv1.SearchRunsStreamRequest{ProjectId: &projectID})
+	require.Len(t, ids, 3)
+	require.Equal(t, []int{3}, batchSizes)
+
+	filter := ptrs.Ptr(`{"filterGroup":{"children":[{"columnName":"hp.global_batch_size",` +
+		`"kind":"field","location":"LOCATION_TYPE_RUN_HYPERPARAMETERS","operator":"<=",` +
+		`"type":"COLUMN_TYPE_NUMBER","value":2}],"conjunction":"and","kind":"group"},` +
+		`"showArchived":false}`)
+	ids, _ = streamRunIDs(&apiv1.SearchRunsStreamRequest{
+		ProjectId: &projectID,
+		Sort:      sort,
+		Filter:    filter,
+	})
+	require.Equal(t, searchIDs[1:], ids)
+
+	// Nothing is sent if no runs match.
+	emptyFilter := ptrs.Ptr(`{"filterGroup":{"children":[{"columnName":"hp.global_batch_size",` +
+		`"kind":"field","location":"LOCATION_TYPE_RUN_HYPERPARAMETERS","operator":">",` +
+		`"type":"COLUMN_TYPE_NUMBER","value":3}],"conjunction":"and","kind":"group"},` +
+		`"showArchived":false}`)
+	ids, batchSizes = streamRunIDs(&apiv1.SearchRunsStreamRequest{
+		ProjectId: &projectID,
+		Filter:    emptyFilter,
+	})
+	require.Empty(t, ids)
+	require.Empty(t, batchSizes)
+
+	err = api.SearchRunsStream(&apiv1.SearchRunsStreamRequest{BatchSize: -1},
+		&mockStream[*apiv1.SearchRunsStreamResponse]{ctx: ctx})
+	require.ErrorContains(t, err, "batch_size must not be negative")
+}
+
+func TestExportRuns(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	projectID := int32(projectIDInt)
+
+	hparams := []map[string]any{
+		{"global_batch_size": 1, "optimizer": map[string]any{"lr": 0.5}},
+		{"global_batch_size": 2, "optimizer": map[string]any{"lr": 0.25, "name": "a, b"}},
+	}
+	for _, hp := range hparams {
+		exp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		require.NoError(t, db.AddTrial(ctx, &model.Trial{
+			State:        model.PausedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+			HParams:      hp,
+		}, task.TaskID))
+	}
+	searchResp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &projectID,
+		Sort:      ptrs.Ptr("id=asc"),
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResp.Runs, 2)
+	id1, id2 := searchResp.Runs[0].Id, searchResp.Runs[1].Id
+
+	export := func(req *apiv1.ExportRunsRequest) string {
+		stream := &mockStream[*apiv1.ExportRunsResponse]{ctx: ctx}
+		require.NoError(t, api.ExportRuns(req, stream))
+		var data strings.Builder
+		for _, resp := range stream.getData() {
+			data.WriteString(resp.Data)
+		}
+		return data.String()
+	}
+
+	// The CSV header names every hyperparameter of the exported runs.
+	csv := export(&apiv1.ExportRunsRequest{
+		ProjectId: &projectID,
+		Sort:      ptrs.Ptr("id=desc"),
+		Columns:   []string{"id", "state", "hp.*"},
+	})
+	require.Equal(t, "id,state,hp.global_batch_size,hp.optimizer.lr,hp.optimizer.name\n"+
+		fmt.Sprintf("%d,PAUSED,2,0.25,\"a, b\"\n", id2)+
+		fmt.Sprintf("%d,PAUSED,1,0.5,\n", id1), csv)
+
+	// JSON lines keep the column order and only list the hyperparameters of each run.
+	jsonl := export(&apiv1.ExportRunsRequest{
+		ProjectId: &projectID,
+		Sort:      ptrs.Ptr("id=asc"),
+		Format:    apiv1.ExportRunsFormat_EXPORT_RUNS_FORMAT_JSONL,
+		Columns:   []string{"hp.*", "id", "validation.loss.min"},
+	})
+	require.Equal(t,
+		fmt.Sprintf(`{"hp.global_batch_size":1,"hp.optimizer.lr":0.5,"id":%d,`+
+			`"validation.loss.min":null}`+"\n", id1)+
+			fmt.Sprintf(`{"hp.global_batch_size":2,"hp.optimizer.lr":0.25,"hp.optimizer.name":"a, b",`+
+				`"id":%d,"validation.loss.min":null}`+"\n", id2),
+		jsonl)
+
+	// The filter of SearchRuns applies to the export.
+	filter := ptrs.Ptr(`{"filterGroup":{"children":[{"columnName":"hp.global_batch_size",` +
+		`"kind":"field","location":"LOCATION_TYPE_RUN_HYPERPARAMETERS","operator":"=",` +
+		`"type":"COLUMN_TYPE_NUMBER","value":2}],"conjunction":"and","kind":"group"},` +
+		`"showArchived":false}`)
+	csv = export(&apiv1.ExportRunsRequest{
+		ProjectId: &projectID,
+		Filter:    filter,
+		Columns:   []string{"id"},
+	})
+	require.Equal(t, fmt.Sprintf("id\n%d\n", id2), csv)
+
+	// Every run column and hyperparameter is exported by default.
+	csv = export(&apiv1.ExportRunsRequest{ProjectId: &projectID, Filter: filter})
+	header, _, _ := strings.Cut(csv, "\n")
+	require.Equal(t, "id,experimentId,experimentName,projectId,projectName,workspaceName,"+
+		"state,startTime,endTime,duration,searcherMetricValue,checkpointSize,checkpointCount,"+
+		"externalRunId,labels,hp.global_batch_size,hp.optimizer.lr,hp.optimizer.name", header)
+
+	err = api.ExportRuns(&apiv1.ExportRunsRequest{Columns: []string{"nope"}},
+		&mockStream[*apiv1.ExportRunsResponse]{ctx: ctx})
+	require.ErrorContains(t, err, "invalid export column: nope")
 }
 
 func TestMoveRunsIds(t *testing.T) {
@@ -300,6 +656,108 @@ func TestMoveRunsIds(t *testing.T) {
 	require.Equal(t, destprojectID, exp.ProjectId)
 }
 
+func TestMoveRunsRecordProvenance(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	sourceprojectID := int32(1)
+	destprojectID := int32(projectIDInt)
+
+	run1, _ := createTestTrial(t, api, curUser)
+	run2, _ := createTestTrial(t, api, curUser)
+
+	// Provenance is only recorded when requested.
+	before := time.Now()
+	for _, c := range []struct {
+		runID            int32
+		recordProvenance bool
+	}{
+		{runID: int32(run1.ID), recordProvenance: true},
+		{runID: int32(run2.ID), recordProvenance: false},
+	} {
+		moveResp, err := api.MoveRuns(ctx, &apiv1.MoveRunsRequest{
+			RunIds:               []int32{c.runID},
+			SourceProjectId:      sourceprojectID,
+			DestinationProjectId: destprojectID,
+			RecordProvenance:     c.recordProvenance,
+		})
+		require.NoError(t, err)
+		require.Len(t, moveResp.Results, 1)
+		require.Equal(t, "", moveResp.Results[0].Error)
+	}
+
+	resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &destprojectID,
+		Sort:      ptrs.Ptr("id=asc"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 2)
+
+	require.Equal(t, int32(run1.ID), resp.Runs[0].Id)
+	require.NotNil(t, resp.Runs[0].MovedFromProjectId)
+	require.Equal(t, sourceprojectID, *resp.Runs[0].MovedFromProjectId)
+	require.NotNil(t, resp.Runs[0].MovedTime)
+	require.WithinDuration(t, before, resp.Runs[0].MovedTime.AsTime(), time.Minute)
+
+	require.Equal(t, int32(run2.ID), resp.Runs[1].Id)
+	require.Nil(t, resp.Runs[1].MovedFromProjectId)
+	require.Nil(t, resp.Runs[1].MovedTime)
+}
+
+func TestMoveRunsAlreadyInDestination(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	sourceprojectID := int32(1)
+	destprojectID := int32(projectIDInt)
+
+	run, _ := createTestTrial(t, api, curUser)
+	moveReq := &apiv1.MoveRunsRequest{
+		RunIds:               []int32{int32(run.ID)},
+		SourceProjectId:      sourceprojectID,
+		DestinationProjectId: destprojectID,
+	}
+
+	moveResp, err := api.MoveRuns(ctx, moveReq)
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Equal(t, "", moveResp.Results[0].Error)
+	require.Nil(t, moveResp.Results[0].Message)
+
+	// Retrying the move succeeds without moving the run again.
+	moveResp, err = api.MoveRuns(ctx, moveReq)
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Equal(t, "", moveResp.Results[0].Error)
+	require.Equal(t, int32(run.ID), moveResp.Results[0].Id)
+	require.Equal(t, fmt.Sprintf("Run is already in project with id '%d'.", destprojectID),
+		moveResp.GetResults()[0].GetMessage())
+
+	// As does moving the run within the destination project.
+	moveResp, err = api.MoveRuns(ctx, &apiv1.MoveRunsRequest{
+		RunIds:               []int32{int32(run.ID)},
+		SourceProjectId:      destprojectID,
+		DestinationProjectId: destprojectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Equal(t, "", moveResp.Results[0].Error)
+	require.NotNil(t, moveResp.Results[0].Message)
+
+	// Runs that are in neither project are still not found.
+	moveResp, err = api.MoveRuns(ctx, &apiv1.MoveRunsRequest{
+		RunIds:               []int32{-1},
+		SourceProjectId:      sourceprojectID,
+		DestinationProjectId: destprojectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Contains(t, moveResp.Results[0].Error, "not found")
+
+	resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{ProjectId: &destprojectID})
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 1)
+	require.Equal(t, int32(run.ID), resp.Runs[0].Id)
+}
+
 func setUpMultiTrialExperiments(ctx context.Context, t *testing.T, api *apiServer, curUser model.User,
 ) (int32, int32, int32, int32, int32) {
 	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
@@ -490,3 +948,550 @@ func TestMoveRunsFilter(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, resp.Runs, 1)
 }
+
+func TestMoveRunsFilterArchived(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	_, projectID2Int := createProjectAndWorkspace(ctx, t, api)
+	sourceprojectID := int32(projectIDInt)
+	destprojectID := int32(projectID2Int)
+
+	archivedExp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+	activeExp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+	for _, exp := range []*model.Experiment{archivedExp, activeExp} {
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		require.NoError(t, db.AddTrial(ctx, &model.Trial{
+			State:        model.PausedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+			HParams:      map[string]any{"global_batch_size": 1},
+		}, task.TaskID))
+	}
+	_, err := db.Bun().NewUpdate().Table("experiments").
+		Set("archived = true").
+		Where("id = ?", archivedExp.ID).
+		Exec(ctx)
+	require.NoError(t, err)
+
+	filter := func(showArchived bool) *string {
+		return ptrs.Ptr(fmt.Sprintf(`{"filterGroup":{"children":[{"columnName":"hp.global_batch_size",`+
+			`"kind":"field","location":"LOCATION_TYPE_RUN_HYPERPARAMETERS","operator":"notEmpty",`+
+			`"type":"COLUMN_TYPE_NUMBER","value":null}],"conjunction":"and","kind":"group"},`+
+			`"showArchived":%t}`, showArchived))
+	}
+
+	// Without showArchived, the filter selects only the run of the active experiment
+	// in both SearchRuns and MoveRuns.
+	searchResp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &sourceprojectID,
+		Filter:    filter(false),
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResp.Runs, 1)
+	activeRunID := searchResp.Runs[0].Id
+
+	moveResp, err := api.MoveRuns(ctx, &apiv1.MoveRunsRequest{
+		SourceProjectId:      sourceprojectID,
+		DestinationProjectId: destprojectID,
+		Filter:               filter(false),
+	})
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Equal(t, "", moveResp.Results[0].Error)
+	require.Equal(t, activeRunID, moveResp.Results[0].Id)
+
+	// With showArchived, the run of the archived experiment is selected in both, but
+	// it may not be moved.
+	searchResp, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &sourceprojectID,
+		Filter:    filter(true),
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResp.Runs, 1)
+	archivedRunID := searchResp.Runs[0].Id
+
+	moveResp, err = api.MoveRuns(ctx, &apiv1.MoveRunsRequest{
+		SourceProjectId:      sourceprojectID,
+		DestinationProjectId: destprojectID,
+		Filter:               filter(true),
+	})
+	require.NoError(t, err)
+	require.Len(t, moveResp.Results, 1)
+	require.Equal(t, "Run is archived.", moveResp.Results[0].Error)
+	require.Equal(t, archivedRunID, moveResp.Results[0].Id)
+
+	// Only the run of the active experiment is in the new project.
+	searchResp, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &destprojectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResp.Runs, 1)
+	require.Equal(t, activeRunID, searchResp.Runs[0].Id)
+}
+
+// setUpCompletedRuns creates a project with the given number of completed
+// single-trial experiments and returns the project ID and the IDs of their runs.
+func setUpCompletedRuns(
+	ctx context.Context, t *testing.T, api *apiServer, curUser model.User, count int,
+) (int32, []int32) {
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	for i := 0; i < count; i++ {
+		exp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		require.NoError(t, db.AddTrial(ctx, &model.Trial{
+			State:        model.CompletedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+		}, task.TaskID))
+		_, err := db.Bun().NewUpdate().Table("experiments").
+			Set("state = ?", model.CompletedState).
+			Where("id = ?", exp.ID).
+			Exec(ctx)
+		require.NoError(t, err)
+	}
+
+	resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: ptrs.Ptr(int32(projectIDInt)),
+		Sort:      ptrs.Ptr("id=asc"),
+	})
+	require.NoError(t, err)
+	var runIDs []int32
+	for _, run := range resp.Runs {
+		runIDs = append(runIDs, run.Id)
+	}
+	return int32(projectIDInt), runIDs
+}
+
+// allRunsFilter returns a filter that selects every run, with or without showArchived.
+func allRunsFilter(showArchived bool) *string {
+	return ptrs.Ptr(fmt.Sprintf(`{"filterGroup":{"children":[],"conjunction":"and",`+
+		`"kind":"group"},"showArchived":%t}`, showArchived))
+}
+
+// searchRunsShowArchived returns the IDs of the runs of the project that are searched
+// with or without showArchived.
+func searchRunsShowArchived(
+	ctx context.Context, t *testing.T, api *apiServer, projectID int32, showArchived bool,
+) []int32 {
+	resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &projectID,
+		Filter:    allRunsFilter(showArchived),
+		Sort:      ptrs.Ptr("id=asc"),
+	})
+	require.NoError(t, err)
+	var runIDs []int32
+	for _, run := range resp.Runs {
+		runIDs = append(runIDs, run.Id)
+	}
+	return runIDs
+}
+
+func TestArchiveUnarchiveRunsIds(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	projectID, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 2)
+
+	archiveResp, err := api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:    []int32{runIDs[0]},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 1)
+	require.Equal(t, "", archiveResp.Results[0].Error)
+	require.Equal(t, runIDs[0], archiveResp.Results[0].Id)
+
+	// The archived run is only searched with showArchived.
+	require.Equal(t, []int32{runIDs[1]}, searchRunsShowArchived(ctx, t, api, projectID, false))
+	require.Equal(t, runIDs, searchRunsShowArchived(ctx, t, api, projectID, true))
+
+	// Archiving it again fails, as does unarchiving the other run.
+	archiveResp, err = api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:    []int32{runIDs[0]},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 1)
+	require.Equal(t, "Run is already archived.", archiveResp.Results[0].Error)
+
+	unarchiveResp, err := api.UnarchiveRuns(ctx, &apiv1.UnarchiveRunsRequest{
+		RunIds:    []int32{runIDs[1]},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, unarchiveResp.Results, 1)
+	require.Equal(t, "Run is not archived.", unarchiveResp.Results[0].Error)
+
+	unarchiveResp, err = api.UnarchiveRuns(ctx, &apiv1.UnarchiveRunsRequest{
+		RunIds:    []int32{runIDs[0], -1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, unarchiveResp.Results, 2)
+	require.Equal(t, "", unarchiveResp.Results[0].Error)
+	require.Equal(t, runIDs[0], unarchiveResp.Results[0].Id)
+	require.Equal(t, fmt.Sprintf("Run with id '-1' not found in project with id '%d'", projectID),
+		unarchiveResp.Results[1].Error)
+
+	require.Equal(t, runIDs, searchRunsShowArchived(ctx, t, api, projectID, false))
+}
+
+func TestArchiveUnarchiveRunsFilter(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	projectID, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 2)
+
+	// If provided with a filter, ArchiveRuns ignores the run ids.
+	archiveResp, err := api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:    []int32{runIDs[0]},
+		ProjectId: projectID,
+		Filter:    allRunsFilter(false),
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 2)
+	for _, res := range archiveResp.Results {
+		require.Equal(t, "", res.Error)
+	}
+	require.Empty(t, searchRunsShowArchived(ctx, t, api, projectID, false))
+	require.Equal(t, runIDs, searchRunsShowArchived(ctx, t, api, projectID, true))
+
+	// Archived runs are only selected by a filter with showArchived.
+	unarchiveResp, err := api.UnarchiveRuns(ctx, &apiv1.UnarchiveRunsRequest{
+		ProjectId: projectID,
+		Filter:    allRunsFilter(false),
+	})
+	require.NoError(t, err)
+	require.Empty(t, unarchiveResp.Results)
+
+	unarchiveResp, err = api.UnarchiveRuns(ctx, &apiv1.UnarchiveRunsRequest{
+		ProjectId: projectID,
+		Filter:    allRunsFilter(true),
+	})
+	require.NoError(t, err)
+	require.Len(t, unarchiveResp.Results, 2)
+	for _, res := range unarchiveResp.Results {
+		require.Equal(t, "", res.Error)
+	}
+	require.Equal(t, runIDs, searchRunsShowArchived(ctx, t, api, projectID, false))
+}
+
+func TestArchiveRunsMultiTrial(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	projectID, _, runID1, runID2, expID := setUpMultiTrialExperiments(ctx, t, api, curUser)
+
+	// Runs of an experiment that is not in a terminal state may not be archived.
+	archiveResp, err := api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:    []int32{runID1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 1)
+	require.Equal(t, "Run's experiment is not in terminal state.", archiveResp.Results[0].Error)
+
+	_, err = db.Bun().NewUpdate().Table("experiments").
+		Set("state = ?", model.CompletedState).
+		Where("id = ?", expID).
+		Exec(ctx)
+	require.NoError(t, err)
+
+	archiveResp, err = api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:         []int32{runID1},
+		ProjectId:      projectID,
+		SkipMultitrial: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 1)
+	require.Equal(t, fmt.Sprintf("Skipping run '%d' (part of multi-trial).", runID1),
+		archiveResp.Results[0].Error)
+
+	// Without skipping, archiving one run archives every run of the experiment.
+	archiveResp, err = api.ArchiveRuns(ctx, &apiv1.ArchiveRunsRequest{
+		RunIds:    []int32{runID1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, archiveResp.Results, 1)
+	require.Equal(t, "", archiveResp.Results[0].Error)
+
+	require.Empty(t, searchRunsShowArchived(ctx, t, api, projectID, false))
+
+	unarchiveResp, err := api.UnarchiveRuns(ctx, &apiv1.UnarchiveRunsRequest{
+		RunIds:    []int32{runID2},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, unarchiveResp.Results, 1)
+	require.Equal(t, "", unarchiveResp.Results[0].Error)
+
+	require.Equal(t, []int32{runID1, runID2}, searchRunsShowArchived(ctx, t, api, projectID, false))
+}
+
+// requireRunsDeleted checks that the runs and their task associations are gone.
+func requireRunsDeleted(ctx context.Context, t *testing.T, runIDs ...int32) {
+	count, err := db.Bun().NewSelect().Table("runs").
+		Where("id IN (?)", bun.In(runIDs)).
+		Count(ctx)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = db.Bun().NewSelect().Table("run_id_task_id").
+		Where("run_id IN (?)", bun.In(runIDs)).
+		Count(ctx)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestDeleteRunsIds(t *testing.T) {
+	mockRM := MockRM()
+	api, curUser, ctx := setupAPITest(t, nil, mockRM)
+	projectID, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 2)
+	run, err := db.TrialByID(ctx, int(runIDs[0]))
+	require.NoError(t, err)
+	exp, err := db.ExperimentByID(ctx, run.ExperimentID)
+	require.NoError(t, err)
+
+	deleteResp, err := api.DeleteRuns(ctx, &apiv1.DeleteRunsRequest{
+		RunIds:    []int32{runIDs[0], -1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleteResp.Results, 2)
+	require.Equal(t, fmt.Sprintf("Run with id '-1' not found in project with id '%d'", projectID),
+		deleteResp.Results[0].Error)
+	require.Equal(t, "", deleteResp.Results[1].Error)
+	require.Equal(t, runIDs[0], deleteResp.Results[1].Id)
+
+	requireRunsDeleted(ctx, t, runIDs[0])
+	require.Equal(t, []int32{runIDs[1]}, searchRunsShowArchived(ctx, t, api, projectID, true))
+
+	// The experiment left without runs is deleted with its run, and so is its job.
+	_, err = api.GetExperiment(ctx, &apiv1.GetExperimentRequest{ExperimentId: int32(run.ExperimentID)})
+	require.Equal(t, apiPkg.NotFoundErrs("experiment", fmt.Sprint(run.ExperimentID), true), err)
+	mockRM.AssertCalled(t, "DeleteJob", sproto.DeleteJob{JobID: exp.JobID})
+}
+
+func TestDeleteRunsFilter(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	projectID, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 2)
+
+	// If provided with a filter, DeleteRuns ignores the run ids.
+	deleteResp, err := api.DeleteRuns(ctx, &apiv1.DeleteRunsRequest{
+		RunIds:    []int32{runIDs[0]},
+		ProjectId: projectID,
+		Filter:    allRunsFilter(false),
+	})
+	require.NoError(t, err)
+	require.Len(t, deleteResp.Results, 2)
+	for _, res := range deleteResp.Results {
+		require.Equal(t, "", res.Error)
+	}
+
+	requireRunsDeleted(ctx, t, runIDs...)
+	require.Empty(t, searchRunsShowArchived(ctx, t, api, projectID, true))
+}
+
+func TestDeleteRunsMultiTrial(t *testing.T) {
+	mockRM := MockRM()
+	api, curUser, ctx := setupAPITest(t, nil, mockRM)
+	projectID, _, runID1, runID2, expID := setUpMultiTrialExperiments(ctx, t, api, curUser)
+
+	// Runs that are not in a terminal state may not be deleted.
+	deleteResp, err := api.DeleteRuns(ctx, &apiv1.DeleteRunsRequest{
+		RunIds:    []int32{runID1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleteResp.Results, 1)
+	require.Equal(t, "Run is not in terminal state.", deleteResp.Results[0].Error)
+
+	_, err = db.Bun().NewUpdate().Table("runs").
+		Set("state = ?", model.CompletedState).
+		Where("experiment_id = ?", expID).
+		Exec(ctx)
+	require.NoError(t, err)
+
+	deleteResp, err = api.DeleteRuns(ctx, &apiv1.DeleteRunsRequest{
+		RunIds:         []int32{runID1},
+		ProjectId:      projectID,
+		SkipMultitrial: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleteResp.Results, 1)
+	require.Equal(t, fmt.Sprintf("Skipping run '%d' (part of multi-trial).", runID1),
+		deleteResp.Results[0].Error)
+
+	// Without skipping, only the run is deleted and the experiment keeps its other run.
+	deleteResp, err = api.DeleteRuns(ctx, &apiv1.DeleteRunsRequest{
+		RunIds:    []int32{runID1},
+		ProjectId: projectID,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleteResp.Results, 1)
+	require.Equal(t, "", deleteResp.Results[0].Error)
+
+	requireRunsDeleted(ctx, t, runID1)
+	require.Equal(t, []int32{runID2}, searchRunsShowArchived(ctx, t, api, projectID, false))
+	_, err = api.getExperiment(ctx, curUser, int(expID))
+	require.NoError(t, err)
+	mockRM.AssertNotCalled(t, "DeleteJob", mock.Anything)
+}
+
+func TestSearchRunsUpdatedSince(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, projectIDInt := createProjectAndWorkspace(ctx, t, api)
+	projectID := int32(projectIDInt)
+
+	var runIDs []int
+	for i := 0; i < 2; i++ {
+		exp := createTestExpWithProjectID(t, api, curUser, projectIDInt)
+		task := &model.Task{TaskType: model.TaskTypeTrial, TaskID: model.NewTaskID()}
+		require.NoError(t, db.AddTask(ctx, task))
+		trial := &model.Trial{
+			State:        model.PausedState,
+			ExperimentID: exp.ID,
+			StartTime:    time.Now(),
+		}
+		require.NoError(t, db.AddTrial(ctx, trial, task.TaskID))
+		runIDs = append(runIDs, trial.ID)
+	}
+
+	resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{ProjectId: &projectID})
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 2)
+	require.NotNil(t, resp.NextUpdatedSince)
+
+	// Nothing changed since the first search.
+	resp, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId:    &projectID,
+		UpdatedSince: resp.NextUpdatedSince,
+	})
+	require.NoError(t, err)
+	require.Empty(t, resp.Runs)
+
+	_, err = db.Bun().NewUpdate().Table("runs").
+		Set("state = ?", model.ActiveState).
+		Where("id = ?", runIDs[0]).
+		Exec(ctx)
+	require.NoError(t, err)
+
+	// Only the changed run is returned from the watermark of the previous search.
+	resp, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId:    &projectID,
+		UpdatedSince: resp.NextUpdatedSince,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 1)
+	require.Equal(t, int32(runIDs[0]), resp.Runs[0].Id)
+}
+
+func TestRunTags(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	_, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 1)
+	runID := runIDs[0]
+
+	getResp, err := api.GetRunTags(ctx, &apiv1.GetRunTagsRequest{RunId: runID})
+	require.NoError(t, err)
+	require.Empty(t, getResp.Tags)
+
+	// Tags are returned sorted and adding a tag the run already has is a no-op.
+	addResp, err := api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{
+		RunId: runID,
+		Tags:  []string{"best", "baseline"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"baseline", "best"}, addResp.Tags)
+	addResp, err = api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{
+		RunId: runID,
+		Tags:  []string{"best", "lr-sweep"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"baseline", "best", "lr-sweep"}, addResp.Tags)
+
+	// Removing a tag the run does not have is a no-op.
+	removeResp, err := api.RemoveRunTags(ctx, &apiv1.RemoveRunTagsRequest{
+		RunId: runID,
+		Tags:  []string{"best", "missing"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"baseline", "lr-sweep"}, removeResp.Tags)
+
+	getResp, err = api.GetRunTags(ctx, &apiv1.GetRunTagsRequest{RunId: runID})
+	require.NoError(t, err)
+	require.Equal(t, []string{"baseline", "lr-sweep"}, getResp.Tags)
+
+	_, err = api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{RunId: runID})
+	require.Error(t, err)
+	_, err = api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{RunId: runID, Tags: []string{" "}})
+	require.Error(t, err)
+}
+
+func TestSearchRunsFilterTags(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	projectID, runIDs := setUpCompletedRuns(ctx, t, api, curUser, 3)
+
+	_, err := api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{
+		RunId: runIDs[0],
+		Tags:  []string{"baseline", "lr-sweep"},
+	})
+	require.NoError(t, err)
+	_, err = api.AddRunTags(ctx, &apiv1.AddRunTagsRequest{
+		RunId: runIDs[1],
+		Tags:  []string{"best"},
+	})
+	require.NoError(t, err)
+
+	tagsFilter := func(operator, value string) *string {
+		return ptrs.Ptr(`{"filterGroup":{"children":[{"columnName":"tags","kind":"field",` +
+			`"location":"LOCATION_TYPE_RUN_TAGS","operator":"` + operator + `",` +
+			`"type":"COLUMN_TYPE_TEXT","value":` + value + `}],` +
+			`"conjunction":"and","kind":"group"},"showArchived":false}`)
+	}
+
+	tests := map[string]struct {
+		expectedRunIDs []int32
+		filter         *string
+	}{
+		"TagsContains": {
+			expectedRunIDs: []int32{runIDs[0], runIDs[1]},
+			filter:         tagsFilter("contains", `"b"`),
+		},
+		"TagsHasKey": {
+			expectedRunIDs: []int32{runIDs[1]},
+			filter:         tagsFilter("hasKey", `"best"`),
+		},
+		"TagsIn": {
+			expectedRunIDs: []int32{runIDs[0], runIDs[1]},
+			filter:         tagsFilter("in", `["lr-sweep","best"]`),
+		},
+		"TagsEmpty": {
+			expectedRunIDs: []int32{runIDs[2]},
+			filter:         tagsFilter("isEmpty", `null`),
+		},
+		"TagsNotEmpty": {
+			expectedRunIDs: []int32{runIDs[0], runIDs[1]},
+			filter:         tagsFilter("notEmpty", `null`),
+		},
+	}
+
+	for testCase, testVars := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			resp, err := api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+				ProjectId: &projectID,
+				Filter:    testVars.filter,
+				Sort:      ptrs.Ptr("id=asc"),
+			})
+			require.NoError(t, err)
+			var gotRunIDs []int32
+			for _, run := range resp.Runs {
+				gotRunIDs = append(gotRunIDs, run.Id)
+			}
+			require.Equal(t, testVars.expectedRunIDs, gotRunIDs)
+		})
+	}
+
+	// The in operator requires a list of tags.
+	_, err = api.SearchRuns(ctx, &apiv1.SearchRunsRequest{
+		ProjectId: &projectID,
+		Filter:    tagsFilter("in", `"best"`),
+	})
+	require.Error(t, err)
+}
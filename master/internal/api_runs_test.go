@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/determined-ai/determined/proto/pkg/runv1"
+)
+
+func TestExportRunFieldsHyperparameters(t *testing.T) {
+	hps, err := structpb.NewStruct(map[string]any{
+		"optimizer": map[string]any{"lr": 0.5},
+		"data.path": "/data",
+	})
+	require.NoError(t, err)
+	run := &runv1.FlatRun{Id: 1, Hyperparameters: hps}
+
+	fields := exportRunFields(run, []string{"hp.optimizer.lr", "hp.data.path", "hp.optimizer", "hp.nope"})
+	require.Equal(t, []exportRunField{
+		{"hp.optimizer.lr", 0.5},
+		{"hp.data.path", "/data"},
+		{"hp.optimizer", map[string]any{"lr": 0.5}},
+		{"hp.nope", nil},
+	}, fields)
+}
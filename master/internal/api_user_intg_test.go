@@ -5,12 +5,17 @@ package internal
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
 
 	"github.com/determined-ai/determined/master/internal/job/jobservice"
@@ -151,6 +156,107 @@ func fetchUserIds(ctx context.Context, t *testing.T, api *apiServer, req *apiv1.
 	return ids
 }
 
+func TestImpersonateUserHeader(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+
+	targetName := uuid.New().String()
+	targetID, err := user.Add(context.TODO(), &model.User{
+		Username:     targetName,
+		PasswordHash: null.NewString("", false),
+		Active:       true,
+	}, nil)
+	require.NoError(t, err)
+
+	withHeader := func(ctx context.Context, username string) context.Context {
+		md, _ := metadata.FromIncomingContext(ctx)
+		md = md.Copy()
+		md.Set(grpcutil.ImpersonateUserHeader, username)
+		return metadata.NewIncomingContext(ctx, md)
+	}
+
+	// Admins may make requests as another user.
+	u, session, err := grpcutil.GetUser(withHeader(ctx, targetName))
+	require.NoError(t, err)
+	require.Equal(t, targetID, u.ID)
+	require.Equal(t, curUser.ID, session.UserID)
+
+	_, _, err = grpcutil.GetUser(withHeader(ctx, uuid.New().String()))
+	require.ErrorContains(t, err, "not found")
+
+	// Other users may not.
+	resp, err := api.Login(context.TODO(), &apiv1.LoginRequest{Username: targetName})
+	require.NoError(t, err)
+	targetCtx := metadata.NewIncomingContext(context.TODO(),
+		metadata.Pairs("x-user-token", fmt.Sprintf("Bearer %s", resp.Token)))
+	_, _, err = grpcutil.GetUser(withHeader(targetCtx, curUser.Username))
+	require.Equal(t, grpcutil.ErrPermissionDenied, err)
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	api, _, ctx := setupAPITest(t, nil)
+
+	_, _, err := grpcutil.GetUser(ctx)
+	require.NoError(t, err)
+
+	_, err = api.Logout(ctx, &apiv1.LogoutRequest{})
+	require.NoError(t, err)
+
+	// Replaying the logged out token is rejected.
+	_, _, err = grpcutil.GetUser(ctx)
+	require.Equal(t, grpcutil.ErrInvalidCredentials, err)
+}
+
+func TestLogoutRevokesExternalToken(t *testing.T) {
+	api, _, _ := setupAPITest(t, nil)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwtKey, err := json.Marshal(&key.PublicKey)
+	require.NoError(t, err)
+
+	ext := &config.GetMasterConfig().InternalConfig.ExternalSessions
+	defer func(orig model.ExternalSessions) { *ext = orig }(*ext)
+	ext.JwtKey = string(jwtKey)
+	ext.OrgID = "test-org"
+
+	userID := uuid.NewString()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, &model.JWT{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		UserID: userID,
+		Email:  userID + "@example.com",
+		Name:   userID,
+		OrgRoles: map[model.OrgID]model.OrgRoleClaims{
+			ext.OrgID: {Role: model.UserRole},
+		},
+	}).SignedString(key)
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("x-user-token", "Bearer "+token))
+
+	// External sessions have no user_sessions row for logout to delete.
+	_, _, err = grpcutil.GetUser(ctx)
+	require.NoError(t, err)
+
+	_, err = api.Logout(ctx, &apiv1.LogoutRequest{})
+	require.NoError(t, err)
+
+	_, _, err = grpcutil.GetUser(ctx)
+	require.Equal(t, grpcutil.ErrInvalidCredentials, err)
+
+	// The revocation is persisted, so it holds for other masters and across restarts.
+	hash := sha256.Sum256([]byte(token))
+	revoked, err := db.Bun().NewSelect().
+		Table("revoked_tokens").
+		Where("token_hash = ?", hash[:]).
+		Where("expiry > now()").
+		Exists(ctx)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
 func TestProcessAuth(t *testing.T) {
 	api, _, _ := setupAPITest(t, nil)
 	extConfig := model.ExternalSessions{}
@@ -462,6 +462,7 @@ type InternalConfig struct {
 	ExternalSessions    model.ExternalSessions `json:"external_sessions"`
 	ProxiedServers      []ProxiedServerConfig  `json:"proxied_servers"`
 	PreemptionTimeout   *model.Duration        `json:"preemption_timeout"`
+	AuthLookupTimeout   *model.Duration        `json:"auth_lookup_timeout"`
 }
 
 // Validate implements the check.Validatable interface.
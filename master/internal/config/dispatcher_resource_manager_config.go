@@ -3,10 +3,14 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
 )
 
 const (
@@ -15,6 +19,13 @@ const (
 	enroot      = "enroot"
 )
 
+// Image pull policies of partitions and launcher-provided pools, which take precedence over the
+// force_pull_image of jobs.
+const (
+	ImagePullPolicyAlways       = "always"
+	ImagePullPolicyIfNotPresent = "if_not_present"
+)
+
 // job labeling modes.
 const (
 	Project     = "project"
@@ -23,6 +34,25 @@ const (
 	LabelPrefix = "label:"
 )
 
+// CPU slot display modes for CPU-only nodes.
+const (
+	CPUSlotDisplayAggregate = "aggregate"
+	CPUSlotDisplayPerCore   = "per_core"
+)
+
+// Modes of selection of the default compute pool.
+const (
+	DefaultComputePoolSelectionFixed       = "fixed"
+	DefaultComputePoolSelectionLeastLoaded = "least_loaded"
+)
+
+// Task fields that may be included in the job comment.
+const (
+	JobCommentExperimentID = "experiment_id"
+	JobCommentUser         = "user"
+	JobCommentWorkspace    = "workspace"
+)
+
 // DispatcherResourceManagerConfig is the object that stores the values of
 // the "resource_manager" section of "tools/devcluster.yaml".
 type DispatcherResourceManagerConfig struct {
@@ -48,11 +78,91 @@ type DispatcherResourceManagerConfig struct {
 	LauncherJvmArgs      string `json:"launcher_jvm_args"`
 	SudoAuthorized       string `json:"sudo_authorized"`
 	// Configuration parameters handled by DispatchRM within master
-	TresSupported              bool    `json:"tres_supported"`
-	GresSupported              bool    `json:"gres_supported"`
-	DefaultAuxResourcePool     *string `json:"default_aux_resource_pool"`
-	DefaultComputeResourcePool *string `json:"default_compute_resource_pool"`
-	JobProjectSource           *string `json:"job_project_source"`
+	TresSupported              bool     `json:"tres_supported"`
+	GresSupported              bool     `json:"gres_supported"`
+	DefaultAuxResourcePool     *string  `json:"default_aux_resource_pool"`
+	DefaultComputeResourcePool *string  `json:"default_compute_resource_pool"`
+	CheckpointGCResourcePool   *string  `json:"checkpoint_gc_resource_pool"`
+	JobProjectSource           *string  `json:"job_project_source"`
+	JobNamePrefix              *string  `json:"job_name_prefix"`
+	CPUSlotDisplay             string   `json:"cpu_slot_display"`
+	JobCommentFields           []string `json:"job_comment_fields"`
+	// DispatchPayloadNamePrefix scopes the garbage collection of orphaned launcher dispatches
+	// to those whose payload names start with it, so that the launcher may be shared with
+	// other masters or workloads. It must be a prefix of JobNamePrefix. Unset means every
+	// dispatch launched by a Determined master is in scope.
+	DispatchPayloadNamePrefix *string `json:"dispatch_payload_name_prefix"`
+	// RequireMinimumLauncherVersion makes startup fail, rather than only warn, when the
+	// launcher is older than the minimum supported version.
+	RequireMinimumLauncherVersion bool `json:"require_minimum_launcher_version"`
+	// MaxQueuedAllocations bounds the number of allocation requests held by the resource
+	// manager, beyond which new allocations are rejected. Unset means unbounded.
+	MaxQueuedAllocations *int `json:"max_queued_allocations"`
+	// ReportJobUsage reports the resource usage of each completed job, as recorded by the
+	// accounting of the workload manager, in the logs of its task.
+	ReportJobUsage bool `json:"report_job_usage"`
+	// ReportFairShare reports the fair-share factors of the Slurm accounts, as reported by
+	// sshare, in the details of the resource pools. It has no effect with PBS.
+	ReportFairShare bool `json:"report_fair_share"`
+	// MinResourceSampleRatio is the fraction of the nodes and partitions of the last sample of
+	// HPC resources below which a new sample is considered truncated and discarded. Zero
+	// disables the check.
+	MinResourceSampleRatio float64 `json:"min_resource_sample_ratio"`
+	// ResourceQueryLogRetries is how many more times the log of the HPC resources query is
+	// read when it is found empty or incomplete. Unset means DefaultResourceQueryLogRetries.
+	ResourceQueryLogRetries *int `json:"resource_query_log_retries"`
+	// ResourceQueryLogRetryInterval is how long to wait before reading the log of the HPC
+	// resources query again. Unset means DefaultResourceQueryLogRetryInterval.
+	ResourceQueryLogRetryInterval *model.Duration `json:"resource_query_log_retry_interval"`
+	// ResourceQueryLogSpoolBytes is the size in bytes above which the log of the HPC
+	// resources query is spooled to a temporary file as it is read, rather than buffered in
+	// memory. Unset means the log is never spooled.
+	ResourceQueryLogSpoolBytes *int `json:"resource_query_log_spool_bytes"`
+	// AccountingLabelKeys are the keys of the "key=value" task labels that are attributed to
+	// jobs in the accounting of the workload manager, e.g. for chargeback.
+	AccountingLabelKeys []string `json:"accounting_label_keys"`
+	// JobWatcherPollInterval is how often the job watcher polls the launcher for the status of
+	// the jobs. Unset means DefaultJobWatcherPollInterval.
+	JobWatcherPollInterval *model.Duration `json:"job_watcher_poll_interval"`
+	// JobWatcherPendingPollInterval is how often the status of jobs last seen pending is
+	// polled. Unset means JobWatcherPollInterval.
+	JobWatcherPendingPollInterval *model.Duration `json:"job_watcher_pending_poll_interval"`
+	// RestoreDispatchGracePeriod is how long the restore of an allocation waits for its
+	// dispatch to be found before the allocation is failed, for the dispatch may be recorded
+	// shortly after the master restarts. Unset means DefaultRestoreDispatchGracePeriod.
+	RestoreDispatchGracePeriod *model.Duration `json:"restore_dispatch_grace_period"`
+	// LauncherRequestTimeout is how long a single request to the launcher may take before it
+	// is abandoned. Unset means DefaultLauncherRequestTimeout.
+	LauncherRequestTimeout *model.Duration `json:"launcher_request_timeout"`
+	// DispatchCleanupRetryInterval is how long to wait before retrying the cleanup of a
+	// dispatch whose launcher environment could not be deleted. The wait doubles after each
+	// failed attempt. Zero disables the retries. Unset means DefaultDispatchCleanupRetryInterval.
+	DispatchCleanupRetryInterval *model.Duration `json:"dispatch_cleanup_retry_interval"`
+	// DispatchCleanupAlertAttempts is after how many failed attempts to clean up a dispatch an
+	// error is reported. Unset means DefaultDispatchCleanupAlertAttempts.
+	DispatchCleanupAlertAttempts *int `json:"dispatch_cleanup_alert_attempts"`
+	// AllowedSbatchArgs are the names of the options, e.g. --mem or -l, that users may
+	// specify in slurm.sbatch_args or pbs.pbsbatch_args. Unset means any option that is
+	// not denied.
+	AllowedSbatchArgs []string `json:"allowed_sbatch_args"`
+	// DeniedSbatchArgs are the names of the options that users may not specify in
+	// slurm.sbatch_args or pbs.pbsbatch_args. They take precedence over AllowedSbatchArgs.
+	DeniedSbatchArgs []string `json:"denied_sbatch_args"`
+	// WorkspaceResourcePools restricts the workspaces whose IDs are its keys to the resource
+	// pools it lists for them. Workspaces that are not listed may use any resource pool.
+	WorkspaceResourcePools map[int][]string `json:"workspace_resource_pools"`
+	// DefaultComputePoolSelection is how the default compute pool is selected when it is not
+	// configured: the same partition each time ("fixed", the default), or the least loaded of
+	// the GPU partitions of the same size as that partition ("least_loaded").
+	DefaultComputePoolSelection string `json:"default_compute_pool_selection"`
+	// DefaultGpuSlotType is the slot type of partitions with GPUs whose slot type is neither
+	// configured nor overridden. Unset means DefaultGpuSlotType.
+	DefaultGpuSlotType *device.Type `json:"default_gpu_slot_type"`
+	// ShowHPCJobID adds the ID of the job in the workload manager to the logs of its task
+	// when the job is first seen. The ID is recorded internally regardless.
+	ShowHPCJobID bool `json:"show_hpc_job_id"`
+
+	LDAPImpersonation *DispatcherLDAPImpersonationConfig `json:"ldap_impersonation"`
 
 	Name     string            `json:"name"`
 	Metadata map[string]string `json:"metadata"`
@@ -61,19 +171,238 @@ type DispatcherResourceManagerConfig struct {
 	PartitionOverrides map[string]DispatcherPartitionOverrideConfigs `json:"partition_overrides"`
 }
 
-// DispatcherSecurityConfig configures security-related options for the elastic logging backend.
+// DispatcherSecurityConfig configures security-related options for the connection to the launcher.
 type DispatcherSecurityConfig struct {
 	TLS model.TLSClientConfig `json:"tls"`
+	// ClientCert and ClientKey are the files of the certificate that is presented to a launcher
+	// that requires client authentication. They are reloaded whenever they change.
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+}
+
+// Validate performs validation.
+func (c DispatcherSecurityConfig) Validate() []error {
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return []error{fmt.Errorf("security.client_cert and security.client_key must be set together")}
+	}
+	return nil
+}
+
+// DispatcherLDAPImpersonationConfig configures looking up the HPC user that jobs of a
+// Determined user are launched as in an LDAP directory, rather than requiring each
+// Determined user to be linked to an agent user.
+type DispatcherLDAPImpersonationConfig struct {
+	URL              string         `json:"url"`
+	BindDN           string         `json:"bind_dn"`
+	BindPasswordFile string         `json:"bind_password_file"`
+	BaseDN           string         `json:"base_dn"`
+	UserFilter       string         `json:"user_filter"`
+	UserAttribute    string         `json:"user_attribute"`
+	CacheTTL         model.Duration `json:"cache_ttl"`
+}
+
+// DefaultJobWatcherPollInterval is how often the job watcher polls the launcher for the
+// status of the jobs by default.
+const DefaultJobWatcherPollInterval = 10 * time.Second
+
+// ResolveJobWatcherPollInterval returns how often the job watcher polls the launcher for
+// the status of the jobs.
+func (c DispatcherResourceManagerConfig) ResolveJobWatcherPollInterval() time.Duration {
+	if c.JobWatcherPollInterval != nil {
+		return time.Duration(*c.JobWatcherPollInterval)
+	}
+	return DefaultJobWatcherPollInterval
+}
+
+// ResolveJobWatcherPendingPollInterval returns how often the status of jobs last seen
+// pending is polled.
+func (c DispatcherResourceManagerConfig) ResolveJobWatcherPendingPollInterval() time.Duration {
+	if c.JobWatcherPendingPollInterval != nil {
+		return time.Duration(*c.JobWatcherPendingPollInterval)
+	}
+	return c.ResolveJobWatcherPollInterval()
+}
+
+// ResolveTresSupported returns whether GPUs are requested with the Slurm TRES options, which
+// is only possible when generic resources are supported. tres_supported is ignored otherwise.
+func (c DispatcherResourceManagerConfig) ResolveTresSupported() bool {
+	return c.TresSupported && c.GresSupported
+}
+
+// DefaultRestoreDispatchGracePeriod is how long the restore of an allocation waits for its
+// dispatch to be found, unless configured otherwise.
+const DefaultRestoreDispatchGracePeriod = 10 * time.Second
+
+// ResolveRestoreDispatchGracePeriod returns how long the restore of an allocation waits
+// for its dispatch to be found before the allocation is failed.
+func (c DispatcherResourceManagerConfig) ResolveRestoreDispatchGracePeriod() time.Duration {
+	if c.RestoreDispatchGracePeriod != nil {
+		return time.Duration(*c.RestoreDispatchGracePeriod)
+	}
+	return DefaultRestoreDispatchGracePeriod
+}
+
+// DefaultLauncherRequestTimeout is how long a single request to the launcher may take,
+// unless configured otherwise. It allows for a launch, which waits for the job to be
+// submitted to the workload manager.
+const DefaultLauncherRequestTimeout = 5 * time.Minute
+
+// ResolveLauncherRequestTimeout returns how long a single request to the launcher may take
+// before it is abandoned.
+func (c DispatcherResourceManagerConfig) ResolveLauncherRequestTimeout() time.Duration {
+	if c.LauncherRequestTimeout != nil {
+		return time.Duration(*c.LauncherRequestTimeout)
+	}
+	return DefaultLauncherRequestTimeout
+}
+
+// DefaultDispatchCleanupRetryInterval is how long to wait before retrying the cleanup of a
+// dispatch whose launcher environment could not be deleted, unless configured otherwise.
+const DefaultDispatchCleanupRetryInterval = time.Minute
+
+// DefaultDispatchCleanupAlertAttempts is after how many failed attempts to clean up a
+// dispatch an error is reported, unless configured otherwise.
+const DefaultDispatchCleanupAlertAttempts = 10
+
+// ResolveDispatchCleanupRetryInterval returns how long to wait before retrying the cleanup of
+// a dispatch whose launcher environment could not be deleted, or zero if it is not retried.
+func (c DispatcherResourceManagerConfig) ResolveDispatchCleanupRetryInterval() time.Duration {
+	if c.DispatchCleanupRetryInterval != nil {
+		return time.Duration(*c.DispatchCleanupRetryInterval)
+	}
+	return DefaultDispatchCleanupRetryInterval
+}
+
+// ResolveDispatchCleanupAlertAttempts returns after how many failed attempts to clean up a
+// dispatch an error is reported.
+func (c DispatcherResourceManagerConfig) ResolveDispatchCleanupAlertAttempts() int {
+	if c.DispatchCleanupAlertAttempts != nil {
+		return *c.DispatchCleanupAlertAttempts
+	}
+	return DefaultDispatchCleanupAlertAttempts
+}
+
+// DefaultGpuSlotType is the slot type of partitions with GPUs, unless configured otherwise.
+const DefaultGpuSlotType = device.CUDA
+
+// ResolveDefaultGpuSlotType returns the slot type of partitions with GPUs whose slot type is
+// not configured.
+func (c DispatcherResourceManagerConfig) ResolveDefaultGpuSlotType() device.Type {
+	if c.DefaultGpuSlotType != nil {
+		return *c.DefaultGpuSlotType
+	}
+	return DefaultGpuSlotType
+}
+
+// DefaultResourceQueryLogRetries is how many more times the log of the HPC resources query
+// is read when it is found empty or incomplete, unless configured otherwise.
+const DefaultResourceQueryLogRetries = 3
+
+// DefaultResourceQueryLogRetryInterval is how long to wait before reading the log of the HPC
+// resources query again, unless configured otherwise.
+const DefaultResourceQueryLogRetryInterval = 2 * time.Second
+
+// ResolveResourceQueryLogRetries returns how many more times the log of the HPC resources
+// query is read when it is found empty or incomplete.
+func (c DispatcherResourceManagerConfig) ResolveResourceQueryLogRetries() int {
+	if c.ResourceQueryLogRetries != nil {
+		return *c.ResourceQueryLogRetries
+	}
+	return DefaultResourceQueryLogRetries
+}
+
+// ResolveResourceQueryLogRetryInterval returns how long to wait before reading the log of
+// the HPC resources query again.
+func (c DispatcherResourceManagerConfig) ResolveResourceQueryLogRetryInterval() time.Duration {
+	if c.ResourceQueryLogRetryInterval != nil {
+		return time.Duration(*c.ResourceQueryLogRetryInterval)
+	}
+	return DefaultResourceQueryLogRetryInterval
+}
+
+// LDAPUsernamePlaceholder is replaced by the (escaped) Determined username in the
+// LDAP user filter.
+const LDAPUsernamePlaceholder = "{username}"
+
+var defaultDispatcherLDAPImpersonationConfig = DispatcherLDAPImpersonationConfig{
+	UserFilter:    "(uid=" + LDAPUsernamePlaceholder + ")",
+	UserAttribute: "uid",
+	CacheTTL:      model.Duration(5 * time.Minute),
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (c *DispatcherLDAPImpersonationConfig) UnmarshalJSON(data []byte) error {
+	*c = defaultDispatcherLDAPImpersonationConfig
+	type DefaultParser *DispatcherLDAPImpersonationConfig
+	return json.Unmarshal(data, DefaultParser(c))
+}
+
+// Validate performs validation.
+func (c DispatcherLDAPImpersonationConfig) Validate() []error {
+	var errs []error
+	if c.URL == "" {
+		errs = append(errs, fmt.Errorf("ldap_impersonation.url must be specified"))
+	}
+	if c.BaseDN == "" {
+		errs = append(errs, fmt.Errorf("ldap_impersonation.base_dn must be specified"))
+	}
+	if !strings.Contains(c.UserFilter, LDAPUsernamePlaceholder) {
+		errs = append(errs, fmt.Errorf(
+			"ldap_impersonation.user_filter '%s' must contain %s",
+			c.UserFilter, LDAPUsernamePlaceholder))
+	}
+	if c.UserAttribute == "" {
+		errs = append(errs, fmt.Errorf("ldap_impersonation.user_attribute must be specified"))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("ldap_impersonation.cache_ttl must not be negative"))
+	}
+	return errs
 }
 
 // Validate performs validation.
 func (c DispatcherResourceManagerConfig) Validate() []error {
-	// Allowed values for the container run type are either 'singularity', 'podman' or 'enroot'
-	if !(c.LauncherContainerRunType == singularity ||
-		c.LauncherContainerRunType == podman ||
-		c.LauncherContainerRunType == enroot) {
+	if !isValidContainerRunType(c.LauncherContainerRunType) {
 		return []error{fmt.Errorf("invalid launch container run type: '%s'", c.LauncherContainerRunType)}
 	}
+	for name, overrides := range c.PartitionOverrides {
+		if overrides.ContainerRunType != nil && !isValidContainerRunType(*overrides.ContainerRunType) {
+			return []error{fmt.Errorf(
+				"invalid launch container run type for partition '%s': '%s'",
+				name, *overrides.ContainerRunType)}
+		}
+		if overrides.MaxSlotsPerJob != nil && *overrides.MaxSlotsPerJob <= 0 {
+			return []error{fmt.Errorf(
+				"invalid max_slots_per_job for partition '%s': %d.  Specify a positive value",
+				name, *overrides.MaxSlotsPerJob)}
+		}
+		if p := overrides.ImagePullPolicy; p != nil &&
+			*p != ImagePullPolicyAlways && *p != ImagePullPolicyIfNotPresent {
+			return []error{fmt.Errorf(
+				"invalid image_pull_policy for partition '%s': '%s'.  Specify one of %s or %s",
+				name, *p, ImagePullPolicyAlways, ImagePullPolicyIfNotPresent)}
+		}
+		if overrides.MaxPendingDuration != nil && *overrides.MaxPendingDuration <= 0 {
+			return []error{fmt.Errorf(
+				"invalid max_pending_duration for partition '%s': %s.  Specify a positive value",
+				name, time.Duration(*overrides.MaxPendingDuration))}
+		}
+		if overrides.MasterHost != nil && strings.TrimSpace(*overrides.MasterHost) == "" {
+			return []error{fmt.Errorf(
+				"invalid master_host for partition '%s'.  Specify a host name or IP address",
+				name)}
+		}
+		if p := overrides.MasterPort; p != nil && (*p < 1 || *p > maxPort) {
+			return []error{fmt.Errorf(
+				"invalid master_port for partition '%s': %d.  Specify a value from 1 to %d",
+				name, *p, maxPort)}
+		}
+		if !validPoolLabels(overrides.Labels) {
+			return []error{fmt.Errorf(
+				"invalid labels for partition '%s'.  Specify non-empty keys without '=' or ',' "+
+					"and values without '=' or ','", name)}
+		}
+	}
 	if c.ApptainerImageRoot != "" && c.SingularityImageRoot != "" {
 		return []error{fmt.Errorf("apptainer_image_root and singularity_image_root cannot be both set")}
 	}
@@ -86,10 +415,195 @@ func (c DispatcherResourceManagerConfig) Validate() []error {
 				"invalid slot_type '%s'.  Specify one of cuda, rocm, or cpu", *c.SlotType)}
 		}
 	}
+	if c.DefaultGpuSlotType != nil {
+		switch *c.DefaultGpuSlotType {
+		case device.CUDA, device.ROCM:
+		default:
+			return []error{fmt.Errorf(
+				"invalid default_gpu_slot_type '%s'.  Specify one of cuda or rocm",
+				*c.DefaultGpuSlotType)}
+		}
+	}
+
+	switch c.DefaultComputePoolSelection {
+	case "", DefaultComputePoolSelectionFixed, DefaultComputePoolSelectionLeastLoaded:
+	default:
+		return []error{fmt.Errorf(
+			"invalid default_compute_pool_selection '%s'.  Specify one of %s or %s",
+			c.DefaultComputePoolSelection, DefaultComputePoolSelectionFixed,
+			DefaultComputePoolSelectionLeastLoaded)}
+	}
+	switch c.CPUSlotDisplay {
+	case "", CPUSlotDisplayAggregate, CPUSlotDisplayPerCore:
+	default:
+		return []error{fmt.Errorf(
+			"invalid cpu_slot_display '%s'.  Specify one of %s or %s",
+			c.CPUSlotDisplay, CPUSlotDisplayAggregate, CPUSlotDisplayPerCore)}
+	}
+	if c.MaxQueuedAllocations != nil && *c.MaxQueuedAllocations <= 0 {
+		return []error{fmt.Errorf(
+			"invalid max_queued_allocations %d.  Specify a positive value", *c.MaxQueuedAllocations)}
+	}
+	if c.MinResourceSampleRatio < 0 || c.MinResourceSampleRatio >= 1 {
+		return []error{fmt.Errorf(
+			"invalid min_resource_sample_ratio %v.  Specify a value in [0, 1)",
+			c.MinResourceSampleRatio)}
+	}
+	if c.ResourceQueryLogRetries != nil && *c.ResourceQueryLogRetries < 0 {
+		return []error{fmt.Errorf(
+			"invalid resource_query_log_retries %d.  Specify a non-negative value",
+			*c.ResourceQueryLogRetries)}
+	}
+	if c.ResourceQueryLogRetryInterval != nil && *c.ResourceQueryLogRetryInterval < 0 {
+		return []error{fmt.Errorf(
+			"invalid resource_query_log_retry_interval %s.  Specify a non-negative value",
+			time.Duration(*c.ResourceQueryLogRetryInterval))}
+	}
+	if c.ResourceQueryLogSpoolBytes != nil && *c.ResourceQueryLogSpoolBytes <= 0 {
+		return []error{fmt.Errorf(
+			"invalid resource_query_log_spool_bytes %d.  Specify a positive value",
+			*c.ResourceQueryLogSpoolBytes)}
+	}
+	if c.JobWatcherPollInterval != nil && time.Duration(*c.JobWatcherPollInterval) < time.Second {
+		return []error{fmt.Errorf(
+			"invalid job_watcher_poll_interval %s.  Specify at least 1s",
+			time.Duration(*c.JobWatcherPollInterval))}
+	}
+	if c.JobWatcherPendingPollInterval != nil &&
+		time.Duration(*c.JobWatcherPendingPollInterval) < c.ResolveJobWatcherPollInterval() {
+		return []error{fmt.Errorf(
+			"invalid job_watcher_pending_poll_interval %s.  Specify at least the "+
+				"job_watcher_poll_interval", time.Duration(*c.JobWatcherPendingPollInterval))}
+	}
+	if c.RestoreDispatchGracePeriod != nil && *c.RestoreDispatchGracePeriod < 0 {
+		return []error{fmt.Errorf(
+			"invalid restore_dispatch_grace_period %s.  Specify a non-negative value",
+			time.Duration(*c.RestoreDispatchGracePeriod))}
+	}
+	if c.LauncherRequestTimeout != nil && *c.LauncherRequestTimeout <= 0 {
+		return []error{fmt.Errorf(
+			"invalid launcher_request_timeout %s.  Specify a positive value",
+			time.Duration(*c.LauncherRequestTimeout))}
+	}
+	if c.DispatchCleanupRetryInterval != nil && *c.DispatchCleanupRetryInterval < 0 {
+		return []error{fmt.Errorf(
+			"invalid dispatch_cleanup_retry_interval %s.  Specify a non-negative value",
+			time.Duration(*c.DispatchCleanupRetryInterval))}
+	}
+	if c.DispatchCleanupAlertAttempts != nil && *c.DispatchCleanupAlertAttempts <= 0 {
+		return []error{fmt.Errorf(
+			"invalid dispatch_cleanup_alert_attempts %d.  Specify a positive value",
+			*c.DispatchCleanupAlertAttempts)}
+	}
+	for workspace, pools := range c.WorkspaceResourcePools {
+		if workspace <= 0 {
+			return []error{fmt.Errorf(
+				"invalid workspace_resource_pools workspace id %d.  "+
+					"Specify the positive ID of a workspace", workspace)}
+		}
+		if len(pools) == 0 {
+			return []error{fmt.Errorf(
+				"invalid workspace_resource_pools for workspace id %d.  "+
+					"Specify at least one resource pool", workspace)}
+		}
+	}
+	for _, key := range c.AccountingLabelKeys {
+		if !accountingLabelKeyRegEx.MatchString(key) {
+			return []error{fmt.Errorf(
+				"invalid accounting_label_keys value '%s'. "+
+					"Only alpha-numeric characters and underscores are allowed", key)}
+		}
+	}
+	for _, arg := range slices.Concat(c.AllowedSbatchArgs, c.DeniedSbatchArgs) {
+		if !sbatchArgNameRegEx.MatchString(arg) {
+			return []error{fmt.Errorf(
+				"invalid sbatch argument name '%s'.  Specify an option name starting with '-', "+
+					"without a value, e.g. --mem or -n", arg)}
+		}
+	}
+	if errs := c.validateJobNamePrefix(); len(errs) > 0 {
+		return errs
+	}
+	if errs := c.validateDispatchPayloadNamePrefix(); len(errs) > 0 {
+		return errs
+	}
+	for _, field := range c.JobCommentFields {
+		switch field {
+		case JobCommentExperimentID, JobCommentUser, JobCommentWorkspace:
+		default:
+			return []error{fmt.Errorf(
+				"invalid job_comment_fields value '%s'.  Specify any of %s, %s or %s",
+				field, JobCommentExperimentID, JobCommentUser, JobCommentWorkspace)}
+		}
+	}
 
 	return c.validateJobProjectSource()
 }
 
+// maxPort is the largest valid TCP port.
+const maxPort = 65535
+
+// maxJobNamePrefixLength leaves room for the task description in the job name
+// that is submitted to the workload manager.
+const maxJobNamePrefixLength = 32
+
+// sbatchArgNameRegEx matches the name of a long option, or of a single-letter short option.
+var sbatchArgNameRegEx = regexp.MustCompile(`^(--[a-zA-Z0-9][a-zA-Z0-9\-_]*|-[a-zA-Z0-9])$`)
+
+var jobNamePrefixRegEx = regexp.MustCompile(`^[a-zA-Z0-9\-_]+$`)
+
+// accountingLabelKeyRegEx limits accounting label keys to characters that are valid in the
+// names of environment variables.
+var accountingLabelKeyRegEx = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+func (c DispatcherResourceManagerConfig) validateJobNamePrefix() []error {
+	if c.JobNamePrefix == nil {
+		return nil
+	}
+	if !jobNamePrefixRegEx.MatchString(*c.JobNamePrefix) {
+		return []error{fmt.Errorf(
+			"invalid job_name_prefix value: '%s'. "+
+				"Only alpha-numeric characters, dashes and underscores are allowed",
+			*c.JobNamePrefix)}
+	}
+	if len(*c.JobNamePrefix) > maxJobNamePrefixLength {
+		return []error{fmt.Errorf(
+			"job_name_prefix '%s' exceeds the maximum length of %d characters",
+			*c.JobNamePrefix, maxJobNamePrefixLength)}
+	}
+	return nil
+}
+
+// validateDispatchPayloadNamePrefix checks that the jobs of this master are in the scope of
+// the dispatch_payload_name_prefix, for their dispatches would otherwise be left to the other
+// masters sharing the launcher.
+func (c DispatcherResourceManagerConfig) validateDispatchPayloadNamePrefix() []error {
+	if c.DispatchPayloadNamePrefix == nil {
+		return nil
+	}
+	if !jobNamePrefixRegEx.MatchString(*c.DispatchPayloadNamePrefix) {
+		return []error{fmt.Errorf(
+			"invalid dispatch_payload_name_prefix value: '%s'. "+
+				"Only alpha-numeric characters, dashes and underscores are allowed",
+			*c.DispatchPayloadNamePrefix)}
+	}
+	if c.JobNamePrefix == nil || !strings.HasPrefix(*c.JobNamePrefix, *c.DispatchPayloadNamePrefix) {
+		return []error{fmt.Errorf(
+			"invalid dispatch_payload_name_prefix '%s'.  Specify a job_name_prefix that starts "+
+				"with it, so that the jobs of this master are in its scope",
+			*c.DispatchPayloadNamePrefix)}
+	}
+	return nil
+}
+
+// isValidContainerRunType returns true if the container run type is either 'singularity',
+// 'podman' or 'enroot'.
+func isValidContainerRunType(containerRunType string) bool {
+	return containerRunType == singularity ||
+		containerRunType == podman ||
+		containerRunType == enroot
+}
+
 func (c DispatcherResourceManagerConfig) validateJobProjectSource() []error {
 	switch {
 	case c.JobProjectSource == nil:
@@ -112,6 +626,9 @@ var defaultDispatcherResourceManagerConfig = DispatcherResourceManagerConfig{
 	TresSupported:            true,
 	GresSupported:            true,
 	LauncherContainerRunType: singularity,
+	CPUSlotDisplay:           CPUSlotDisplayAggregate,
+	MinResourceSampleRatio:   0.5,
+	ShowHPCJobID:             true,
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -127,9 +644,32 @@ func (c *DispatcherResourceManagerConfig) UnmarshalJSON(data []byte) error {
 	if c.LauncherContainerRunType == "apptainer" {
 		c.LauncherContainerRunType = "singularity"
 	}
+	for name, overrides := range c.PartitionOverrides {
+		if overrides.ContainerRunType != nil && *overrides.ContainerRunType == "apptainer" {
+			overrides.ContainerRunType = ptrs.Ptr(singularity)
+			c.PartitionOverrides[name] = overrides
+		}
+	}
 	return nil
 }
 
+// PartitionOverride returns the overrides of the partition. Due to viper.MergeConfigMap,
+// map keys in configurations lose case, so partition names are matched case-insensitively,
+// preferring an exact match.
+func (c DispatcherResourceManagerConfig) PartitionOverride(
+	partition string,
+) (DispatcherPartitionOverrideConfigs, bool) {
+	if overrides, ok := c.PartitionOverrides[partition]; ok {
+		return overrides, true
+	}
+	for name, overrides := range c.PartitionOverrides {
+		if strings.EqualFold(name, partition) {
+			return overrides, true
+		}
+	}
+	return DispatcherPartitionOverrideConfigs{}, false
+}
+
 // ResolveSlotType resolves the slot type by first looking for a partition-specific setting,
 // then falling back to the master config, and finally falling back to what we can infer.
 func (c DispatcherResourceManagerConfig) ResolveSlotType(partition string) *device.Type {
@@ -147,13 +687,7 @@ func (c DispatcherResourceManagerConfig) ResolveSlotTypeFromOverrides(
 func (c DispatcherResourceManagerConfig) resolveSlotTypeWithDefault(
 	partition string, defaultResult *device.Type,
 ) *device.Type {
-	for name, overrides := range c.PartitionOverrides {
-		if name != partition {
-			continue
-		}
-		if overrides.SlotType == nil {
-			break
-		}
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.SlotType != nil {
 		return overrides.SlotType
 	}
 	return defaultResult
@@ -164,13 +698,7 @@ func (c DispatcherResourceManagerConfig) resolveSlotTypeWithDefault(
 func (c DispatcherResourceManagerConfig) ResolveRendezvousNetworkInterface(
 	partition string,
 ) string {
-	for name, overrides := range c.PartitionOverrides {
-		if name != partition {
-			continue
-		}
-		if overrides.RendezvousNetworkInterface == nil {
-			break
-		}
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.RendezvousNetworkInterface != nil {
 		return *overrides.RendezvousNetworkInterface
 	}
 	return c.RendezvousNetworkInterface
@@ -179,30 +707,36 @@ func (c DispatcherResourceManagerConfig) ResolveRendezvousNetworkInterface(
 // ResolveProxyNetworkInterface resolves the proxy network interface by first looking for a
 // partition-specific setting and then falling back to the master config.
 func (c DispatcherResourceManagerConfig) ResolveProxyNetworkInterface(partition string) string {
-	for name, overrides := range c.PartitionOverrides {
-		if name != partition {
-			continue
-		}
-		if overrides.ProxyNetworkInterface == nil {
-			break
-		}
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.ProxyNetworkInterface != nil {
 		return *overrides.ProxyNetworkInterface
 	}
 	return c.ProxyNetworkInterface
 }
 
+// ResolveContainerRunType resolves the container run type by first looking for a
+// partition-specific setting and then falling back to the master config.
+func (c DispatcherResourceManagerConfig) ResolveContainerRunType(partition string) string {
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.ContainerRunType != nil {
+		return *overrides.ContainerRunType
+	}
+	return c.LauncherContainerRunType
+}
+
+// ResolveImagePullPolicy resolves the image pull policy of the partition, which is empty if
+// the force_pull_image of each job decides.
+func (c DispatcherResourceManagerConfig) ResolveImagePullPolicy(partition string) string {
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.ImagePullPolicy != nil {
+		return *overrides.ImagePullPolicy
+	}
+	return ""
+}
+
 // ResolveTaskContainerDefaults resolves the task container defaults by first looking for
 // a partition-specific setting and then falling back to the master config.
 func (c DispatcherResourceManagerConfig) ResolveTaskContainerDefaults(
 	partition string,
 ) *model.TaskContainerDefaultsConfig {
-	for name, overrides := range c.PartitionOverrides {
-		if !strings.EqualFold(name, partition) {
-			continue
-		}
-		if overrides.TaskContainerDefaultsConfig == nil {
-			break
-		}
+	if overrides, ok := c.PartitionOverride(partition); ok && overrides.TaskContainerDefaultsConfig != nil {
 		return overrides.TaskContainerDefaultsConfig
 	}
 	return nil
@@ -214,6 +748,28 @@ type DispatcherPartitionOverrideConfigs struct {
 	RendezvousNetworkInterface  *string                            `json:"rendezvous_network_interface"`
 	ProxyNetworkInterface       *string                            `json:"proxy_network_interface"`
 	SlotType                    *device.Type                       `json:"slot_type"`
+	ContainerRunType            *string                            `json:"container_run_type"`
 	TaskContainerDefaultsConfig *model.TaskContainerDefaultsConfig `json:"task_container_defaults"`
 	Description                 string                             `json:"description"`
+	// Location is the site or datacenter of the partition, e.g. to help users pick pools
+	// near their data.
+	Location string `json:"location"`
+	// MaxSlotsPerJob is the maximum number of slots that a single job may request in the
+	// partition, to protect shared capacity. Unset means unbounded.
+	MaxSlotsPerJob *int `json:"max_slots_per_job"`
+	// MaxPendingDuration is how long a job may stay pending in the partition, e.g. because its
+	// resource request cannot be satisfied, before it is canceled. Unset means indefinitely.
+	MaxPendingDuration *model.Duration `json:"max_pending_duration"`
+	// ImagePullPolicy is whether the container images of jobs in the partition are pulled on
+	// every launch ("always") or only when they are not cached ("if_not_present"), whatever the
+	// force_pull_image of the job. Unset means as the force_pull_image of the job.
+	ImagePullPolicy *string `json:"image_pull_policy"`
+	// MasterHost and MasterPort are the address at which jobs in the partition reach the
+	// master, e.g. when the partition is on a network of its own. Unset means the master_host
+	// and master_port of the resource manager.
+	MasterHost *string `json:"master_host"`
+	MasterPort *int    `json:"master_port"`
+	// Labels describe the partition, e.g. gpu: a100, so that configurations may select its
+	// resource pool by label rather than by name.
+	Labels map[string]string `json:"labels"`
 }
@@ -1,11 +1,17 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 
 	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 )
 
@@ -13,7 +19,29 @@ func TestDispatcherResourceManagerConfig_Validate(t *testing.T) {
 	type fields struct {
 		LauncherContainerRunType string
 		JobProjectSource         *string
+		JobNamePrefix            *string
+		PayloadNamePrefix        *string
+		CPUSlotDisplay           string
+		ComputePoolSelection     string
+		JobCommentFields         []string
 		SlotType                 *string
+		DefaultGpuSlotType       *string
+		MaxQueuedAllocations     *int
+		MinResourceSampleRatio   float64
+		ResourceQueryLogRetries  *int
+		ResourceQueryLogInterval *model.Duration
+		ResourceQueryLogSpool    *int
+		AccountingLabelKeys      []string
+		AllowedSbatchArgs        []string
+		DeniedSbatchArgs         []string
+		JobWatcherPollInterval   *model.Duration
+		JobWatcherPendingPoll    *model.Duration
+		RestoreGracePeriod       *model.Duration
+		LauncherRequestTimeout   *model.Duration
+		CleanupRetryInterval     *model.Duration
+		CleanupAlertAttempts     *int
+		WorkspaceResourcePools   map[int][]string
+		PartitionOverrides       map[string]DispatcherPartitionOverrideConfigs
 	}
 	tests := []struct {
 		name   string
@@ -104,13 +132,490 @@ func TestDispatcherResourceManagerConfig_Validate(t *testing.T) {
 				"invalid job_project_source value: 'something-bad'. " +
 					"Specify one of project, workspace or label[:value]")},
 		},
+		{
+			name: "job_name_prefix case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr("det-"),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid job_name_prefix characters",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr("det job:"),
+			},
+			want: []error{fmt.Errorf(
+				"invalid job_name_prefix value: 'det job:'. " +
+					"Only alpha-numeric characters, dashes and underscores are allowed")},
+		},
+		{
+			name: "job_name_prefix too long",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr(strings.Repeat("d", 33)),
+			},
+			want: []error{fmt.Errorf(
+				"job_name_prefix '%s' exceeds the maximum length of 32 characters",
+				strings.Repeat("d", 33))},
+		},
+		{
+			name: "per_core cpu_slot_display",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				CPUSlotDisplay:           "per_core",
+			},
+			want: nil,
+		},
+		{
+			name: "invalid cpu_slot_display",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				CPUSlotDisplay:           "per_socket",
+			},
+			want: []error{fmt.Errorf(
+				"invalid cpu_slot_display 'per_socket'.  Specify one of aggregate or per_core")},
+		},
+		{
+			name: "least_loaded default_compute_pool_selection",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				ComputePoolSelection:     "least_loaded",
+			},
+			want: nil,
+		},
+		{
+			name: "invalid default_compute_pool_selection",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				ComputePoolSelection:     "round_robin",
+			},
+			want: []error{fmt.Errorf(
+				"invalid default_compute_pool_selection 'round_robin'.  " +
+					"Specify one of fixed or least_loaded")},
+		},
+		{
+			name: "job_comment_fields case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobCommentFields:         []string{"experiment_id", "user", "workspace"},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid job_comment_fields",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobCommentFields:         []string{"user", "project"},
+			},
+			want: []error{fmt.Errorf(
+				"invalid job_comment_fields value 'project'.  " +
+					"Specify any of experiment_id, user or workspace")},
+		},
+		{
+			name: "max_queued_allocations case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				MaxQueuedAllocations:     ptrs.Ptr(1000),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid max_queued_allocations",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				MaxQueuedAllocations:     ptrs.Ptr(0),
+			},
+			want: []error{fmt.Errorf(
+				"invalid max_queued_allocations 0.  Specify a positive value")},
+		},
+		{
+			name: "invalid min_resource_sample_ratio",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				MinResourceSampleRatio:   1,
+			},
+			want: []error{fmt.Errorf(
+				"invalid min_resource_sample_ratio 1.  Specify a value in [0, 1)")},
+		},
+		{
+			name: "accounting_label_keys case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				AccountingLabelKeys:      []string{"cost_center", "GRANT_ID"},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid accounting_label_keys",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				AccountingLabelKeys:      []string{"cost-center"},
+			},
+			want: []error{fmt.Errorf(
+				"invalid accounting_label_keys value 'cost-center'. " +
+					"Only alpha-numeric characters and underscores are allowed")},
+		},
+		{
+			name: "sbatch args policy case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				AllowedSbatchArgs:        []string{"--mem", "-J", "-l"},
+				DeniedSbatchArgs:         []string{"--exclusive"},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid allowed_sbatch_args",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				AllowedSbatchArgs:        []string{"--mem=4G"},
+			},
+			want: []error{fmt.Errorf(
+				"invalid sbatch argument name '--mem=4G'.  Specify an option name starting " +
+					"with '-', without a value, e.g. --mem or -n")},
+		},
+		{
+			name: "invalid short sbatch argument name",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				DeniedSbatchArgs:         []string{"-n4"},
+			},
+			want: []error{fmt.Errorf(
+				"invalid sbatch argument name '-n4'.  Specify an option name starting " +
+					"with '-', without a value, e.g. --mem or -n")},
+		},
+		{
+			name: "invalid denied_sbatch_args",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				DeniedSbatchArgs:         []string{"exclusive"},
+			},
+			want: []error{fmt.Errorf(
+				"invalid sbatch argument name 'exclusive'.  Specify an option name starting " +
+					"with '-', without a value, e.g. --mem or -n")},
+		},
+		{
+			name: "job watcher poll intervals case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobWatcherPollInterval:   ptrs.Ptr(model.Duration(5 * time.Second)),
+				JobWatcherPendingPoll:    ptrs.Ptr(model.Duration(time.Minute)),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid job_watcher_poll_interval",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobWatcherPollInterval:   ptrs.Ptr(model.Duration(100 * time.Millisecond)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid job_watcher_poll_interval 100ms.  Specify at least 1s")},
+		},
+		{
+			name: "invalid job_watcher_pending_poll_interval",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobWatcherPendingPoll:    ptrs.Ptr(model.Duration(5 * time.Second)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid job_watcher_pending_poll_interval 5s.  " +
+					"Specify at least the job_watcher_poll_interval")},
+		},
+		{
+			name: "invalid resource_query_log_retries",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				ResourceQueryLogRetries:  ptrs.Ptr(-1),
+			},
+			want: []error{fmt.Errorf(
+				"invalid resource_query_log_retries -1.  Specify a non-negative value")},
+		},
+		{
+			name: "default_gpu_slot_type case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				DefaultGpuSlotType:       ptrs.Ptr("rocm"),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid default_gpu_slot_type",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				DefaultGpuSlotType:       ptrs.Ptr("cpu"),
+			},
+			want: []error{fmt.Errorf(
+				"invalid default_gpu_slot_type 'cpu'.  Specify one of cuda or rocm")},
+		},
+		{
+			name: "invalid resource_query_log_retry_interval",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				ResourceQueryLogInterval: ptrs.Ptr(model.Duration(-time.Second)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid resource_query_log_retry_interval -1s.  Specify a non-negative value")},
+		},
+		{
+			name: "invalid resource_query_log_spool_bytes",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				ResourceQueryLogSpool:    ptrs.Ptr(0),
+			},
+			want: []error{fmt.Errorf(
+				"invalid resource_query_log_spool_bytes 0.  Specify a positive value")},
+		},
+		{
+			name: "invalid restore_dispatch_grace_period",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				RestoreGracePeriod:       ptrs.Ptr(model.Duration(-time.Second)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid restore_dispatch_grace_period -1s.  Specify a non-negative value")},
+		},
+		{
+			name: "invalid launcher_request_timeout",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				LauncherRequestTimeout:   ptrs.Ptr(model.Duration(0)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid launcher_request_timeout 0s.  Specify a positive value")},
+		},
+		{
+			name: "dispatch cleanup retries disabled",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				CleanupRetryInterval:     ptrs.Ptr(model.Duration(0)),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid dispatch_cleanup_retry_interval",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				CleanupRetryInterval:     ptrs.Ptr(model.Duration(-time.Minute)),
+			},
+			want: []error{fmt.Errorf(
+				"invalid dispatch_cleanup_retry_interval -1m0s.  Specify a non-negative value")},
+		},
+		{
+			name: "invalid dispatch_cleanup_alert_attempts",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				CleanupAlertAttempts:     ptrs.Ptr(0),
+			},
+			want: []error{fmt.Errorf(
+				"invalid dispatch_cleanup_alert_attempts 0.  Specify a positive value")},
+		},
+		{
+			name: "workspace_resource_pools case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				WorkspaceResourcePools:   map[int][]string{2: {"gpus"}},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid workspace_resource_pools",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				WorkspaceResourcePools:   map[int][]string{2: {}},
+			},
+			want: []error{fmt.Errorf(
+				"invalid workspace_resource_pools for workspace id 2.  " +
+					"Specify at least one resource pool")},
+		},
+		{
+			name: "invalid workspace_resource_pools workspace id",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				WorkspaceResourcePools:   map[int][]string{0: {"gpus"}},
+			},
+			want: []error{fmt.Errorf(
+				"invalid workspace_resource_pools workspace id 0.  " +
+					"Specify the positive ID of a workspace")},
+		},
+		{
+			name: "dispatch_payload_name_prefix case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr("site-a-prod"),
+				PayloadNamePrefix:        ptrs.Ptr("site-a"),
+			},
+			want: nil,
+		},
+		{
+			name: "invalid dispatch_payload_name_prefix characters",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr("site-a-prod"),
+				PayloadNamePrefix:        ptrs.Ptr("site a"),
+			},
+			want: []error{fmt.Errorf(
+				"invalid dispatch_payload_name_prefix value: 'site a'. " +
+					"Only alpha-numeric characters, dashes and underscores are allowed")},
+		},
+		{
+			name: "dispatch_payload_name_prefix without job_name_prefix",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PayloadNamePrefix:        ptrs.Ptr("site-a"),
+			},
+			want: []error{fmt.Errorf(
+				"invalid dispatch_payload_name_prefix 'site-a'.  Specify a job_name_prefix that " +
+					"starts with it, so that the jobs of this master are in its scope")},
+		},
+		{
+			name: "dispatch_payload_name_prefix not a prefix of job_name_prefix",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				JobNamePrefix:            ptrs.Ptr("site-b"),
+				PayloadNamePrefix:        ptrs.Ptr("site-a"),
+			},
+			want: []error{fmt.Errorf(
+				"invalid dispatch_payload_name_prefix 'site-a'.  Specify a job_name_prefix that " +
+					"starts with it, so that the jobs of this master are in its scope")},
+		},
+		{
+			name: "max_slots_per_job case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MaxSlotsPerJob: ptrs.Ptr(8)},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid max_slots_per_job",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MaxSlotsPerJob: ptrs.Ptr(0)},
+				},
+			},
+			want: []error{fmt.Errorf(
+				"invalid max_slots_per_job for partition 'gpus': 0.  Specify a positive value")},
+		},
+		{
+			name: "image_pull_policy case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"fast": {ImagePullPolicy: ptrs.Ptr(ImagePullPolicyIfNotPresent)},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid image_pull_policy",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"fast": {ImagePullPolicy: ptrs.Ptr("never")},
+				},
+			},
+			want: []error{fmt.Errorf("invalid image_pull_policy for partition 'fast': " +
+				"'never'.  Specify one of always or if_not_present")},
+		},
+		{
+			name: "invalid max_pending_duration",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MaxPendingDuration: ptrs.Ptr(model.Duration(-time.Minute))},
+				},
+			},
+			want: []error{fmt.Errorf(
+				"invalid max_pending_duration for partition 'gpus': -1m0s.  Specify a positive value")},
+		},
+		{
+			name: "master address override case",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MasterHost: ptrs.Ptr("10.1.0.1"), MasterPort: ptrs.Ptr(8443)},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "invalid master_host",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MasterHost: ptrs.Ptr(" ")},
+				},
+			},
+			want: []error{fmt.Errorf(
+				"invalid master_host for partition 'gpus'.  Specify a host name or IP address")},
+		},
+		{
+			name: "invalid master_port",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {MasterPort: ptrs.Ptr(70000)},
+				},
+			},
+			want: []error{fmt.Errorf(
+				"invalid master_port for partition 'gpus': 70000.  Specify a value from 1 to 65535")},
+		},
+		{
+			name: "valid labels",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {Labels: map[string]string{"gpu": "a100", "network": "infiniband"}},
+				},
+			},
+		},
+		{
+			name: "invalid labels",
+			fields: fields{
+				LauncherContainerRunType: "singularity",
+				PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+					"gpus": {Labels: map[string]string{"gpu": "a100,h100"}},
+				},
+			},
+			want: []error{fmt.Errorf(
+				"invalid labels for partition 'gpus'.  Specify non-empty keys without '=' or ',' " +
+					"and values without '=' or ','")},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := DispatcherResourceManagerConfig{
-				LauncherContainerRunType: tt.fields.LauncherContainerRunType,
-				JobProjectSource:         tt.fields.JobProjectSource,
-				SlotType:                 (*device.Type)(tt.fields.SlotType),
+				LauncherContainerRunType:      tt.fields.LauncherContainerRunType,
+				JobProjectSource:              tt.fields.JobProjectSource,
+				JobNamePrefix:                 tt.fields.JobNamePrefix,
+				DispatchPayloadNamePrefix:     tt.fields.PayloadNamePrefix,
+				CPUSlotDisplay:                tt.fields.CPUSlotDisplay,
+				DefaultComputePoolSelection:   tt.fields.ComputePoolSelection,
+				JobCommentFields:              tt.fields.JobCommentFields,
+				SlotType:                      (*device.Type)(tt.fields.SlotType),
+				DefaultGpuSlotType:            (*device.Type)(tt.fields.DefaultGpuSlotType),
+				MaxQueuedAllocations:          tt.fields.MaxQueuedAllocations,
+				MinResourceSampleRatio:        tt.fields.MinResourceSampleRatio,
+				ResourceQueryLogRetries:       tt.fields.ResourceQueryLogRetries,
+				ResourceQueryLogRetryInterval: tt.fields.ResourceQueryLogInterval,
+				ResourceQueryLogSpoolBytes:    tt.fields.ResourceQueryLogSpool,
+				AccountingLabelKeys:           tt.fields.AccountingLabelKeys,
+				AllowedSbatchArgs:             tt.fields.AllowedSbatchArgs,
+				DeniedSbatchArgs:              tt.fields.DeniedSbatchArgs,
+				JobWatcherPollInterval:        tt.fields.JobWatcherPollInterval,
+				JobWatcherPendingPollInterval: tt.fields.JobWatcherPendingPoll,
+				RestoreDispatchGracePeriod:    tt.fields.RestoreGracePeriod,
+				LauncherRequestTimeout:        tt.fields.LauncherRequestTimeout,
+				DispatchCleanupRetryInterval:  tt.fields.CleanupRetryInterval,
+				DispatchCleanupAlertAttempts:  tt.fields.CleanupAlertAttempts,
+				WorkspaceResourcePools:        tt.fields.WorkspaceResourcePools,
+				PartitionOverrides:            tt.fields.PartitionOverrides,
 			}
 			if got := c.Validate(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("DispatcherResourceManagerConfig.Validate(%s) = %v, want %v", tt.name, got, tt.want)
@@ -118,3 +623,88 @@ func TestDispatcherResourceManagerConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestDispatcherLDAPImpersonationConfig(t *testing.T) {
+	var c DispatcherLDAPImpersonationConfig
+	require.NoError(t, json.Unmarshal(
+		[]byte(`{"url": "ldaps://ldap.example.com", "base_dn": "ou=people,dc=example,dc=com"}`), &c))
+	require.Equal(t, "(uid={username})", c.UserFilter)
+	require.Equal(t, "uid", c.UserAttribute)
+	require.Equal(t, model.Duration(5*time.Minute), c.CacheTTL)
+	require.Empty(t, c.Validate())
+
+	c = DispatcherLDAPImpersonationConfig{UserFilter: "(uid=bob)"}
+	require.Equal(t, []error{
+		fmt.Errorf("ldap_impersonation.url must be specified"),
+		fmt.Errorf("ldap_impersonation.base_dn must be specified"),
+		fmt.Errorf("ldap_impersonation.user_filter '(uid=bob)' must contain {username}"),
+		fmt.Errorf("ldap_impersonation.user_attribute must be specified"),
+	}, c.Validate())
+}
+
+func TestDispatcherSecurityConfig(t *testing.T) {
+	require.Empty(t, DispatcherSecurityConfig{}.Validate())
+	require.Empty(t, DispatcherSecurityConfig{ClientCert: "c.crt", ClientKey: "c.key"}.Validate())
+	require.Equal(t, []error{
+		fmt.Errorf("security.client_cert and security.client_key must be set together"),
+	}, DispatcherSecurityConfig{ClientCert: "c.crt"}.Validate())
+}
+
+func TestDispatcherResourceManagerConfig_ResolveTresSupported(t *testing.T) {
+	require.True(t, defaultDispatcherResourceManagerConfig.ResolveTresSupported())
+	require.False(t, DispatcherResourceManagerConfig{GresSupported: true}.ResolveTresSupported())
+	// TRES cannot be used without GRES.
+	require.False(t, DispatcherResourceManagerConfig{TresSupported: true}.ResolveTresSupported())
+}
+
+func TestDispatcherResourceManagerConfig_ContainerRunTypeOverride(t *testing.T) {
+	var c DispatcherResourceManagerConfig
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"container_run_type": "podman",
+		"partition_overrides": {
+			"gpus": {"container_run_type": "apptainer"},
+			"cpus": {"container_run_type": "enroot"},
+			"other": {"slot_type": "cpu"}
+		}
+	}`), &c))
+	require.Empty(t, c.Validate())
+
+	require.Equal(t, "singularity", c.ResolveContainerRunType("gpus"))
+	require.Equal(t, "singularity", c.ResolveContainerRunType("GPUs"))
+	require.Equal(t, "enroot", c.ResolveContainerRunType("cpus"))
+	require.Equal(t, "podman", c.ResolveContainerRunType("other"))
+	require.Equal(t, "podman", c.ResolveContainerRunType("unknown"))
+
+	c.PartitionOverrides["cpus"] = DispatcherPartitionOverrideConfigs{
+		ContainerRunType: ptrs.Ptr("docker"),
+	}
+	require.Equal(t, []error{fmt.Errorf(
+		"invalid launch container run type for partition 'cpus': 'docker'")}, c.Validate())
+}
+
+func TestDispatcherResourceManagerConfig_PartitionOverride(t *testing.T) {
+	c := DispatcherResourceManagerConfig{
+		ProxyNetworkInterface: "eth0",
+		PartitionOverrides: map[string]DispatcherPartitionOverrideConfigs{
+			"gpu": {ProxyNetworkInterface: ptrs.Ptr("ib0"), Location: "lower"},
+			"GPU": {Location: "upper"},
+		},
+	}
+
+	// An exact match is preferred, otherwise names are matched case-insensitively.
+	overrides, ok := c.PartitionOverride("GPU")
+	require.True(t, ok)
+	require.Equal(t, "upper", overrides.Location)
+	overrides, ok = c.PartitionOverride("gpu")
+	require.True(t, ok)
+	require.Equal(t, "lower", overrides.Location)
+	_, ok = c.PartitionOverride("Gpu")
+	require.True(t, ok)
+	_, ok = c.PartitionOverride("cpu")
+	require.False(t, ok)
+
+	require.Equal(t, "ib0", c.ResolveProxyNetworkInterface("gpu"))
+	require.Equal(t, "eth0", c.ResolveProxyNetworkInterface("cpu"))
+	delete(c.PartitionOverrides, "GPU")
+	require.Equal(t, "ib0", c.ResolveProxyNetworkInterface("GPU"))
+}
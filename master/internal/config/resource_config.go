@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 )
 
 // DefaultRMName is the default resource manager name when a user does not provide one.
@@ -141,6 +143,32 @@ func (r ResourceConfig) Validate() []error {
 
 			rmPoolNames[rp.PoolName] = true
 			poolNames[rp.PoolName] = true
+
+			// Only the Slurm and PBS resource managers select pools by label.
+			if len(rp.Labels) > 0 &&
+				r.ResourceManager.DispatcherRM == nil && r.ResourceManager.PbsRM == nil {
+				errs = append(errs, fmt.Errorf(
+					"resource pool %s has labels, which only Slurm and PBS resource pools support",
+					rp.PoolName))
+			}
+		}
+		errs = append(errs, validateProvidedPools(r.ResourcePools)...)
+	}
+
+	aliasPools := make(map[string]string)
+	for _, r := range r.ResourceManagers() {
+		for _, rp := range r.ResourcePools {
+			for _, alias := range rp.Aliases {
+				if poolNames[alias] {
+					errs = append(errs, fmt.Errorf(
+						"resource pool %s has an alias that is the name of a resource pool: %s",
+						rp.PoolName, alias))
+				} else if other, ok := aliasPools[alias]; ok && other != rp.PoolName {
+					errs = append(errs, fmt.Errorf(
+						"resource pools %s and %s have a duplicate alias: %s", other, rp.PoolName, alias))
+				}
+				aliasPools[alias] = rp.PoolName
+			}
 		}
 	}
 
@@ -153,3 +181,44 @@ func (r ResourceConfig) Validate() []error {
 
 	return errs
 }
+
+// validateProvidedPools checks that each launcher-provided pool is provided by an HPC
+// partition rather than by another launcher-provided pool, by its name or an alias, which
+// would make an indirection or a cycle that the resource manager does not follow.
+func validateProvidedPools(pools []ResourcePoolConfig) []error {
+	providers := make(map[string]string)
+	for _, rp := range pools {
+		if rp.Provider == nil || rp.Provider.HPC == nil {
+			continue
+		}
+		providers[rp.PoolName] = rp.Provider.HPC.Partition
+		for _, alias := range rp.Aliases {
+			providers[alias] = rp.Provider.HPC.Partition
+		}
+	}
+
+	var errs []error
+	for _, rp := range pools {
+		if rp.Provider == nil || rp.Provider.HPC == nil {
+			continue
+		}
+		partition := rp.Provider.HPC.Partition
+		if _, ok := providers[partition]; !ok {
+			continue
+		}
+		// Follow the references to describe the chain, up to the first name seen twice.
+		chain := []string{rp.PoolName}
+		for name, ok := partition, true; ok; name, ok = providers[name] {
+			seen := slices.Contains(chain, name)
+			chain = append(chain, name)
+			if seen {
+				break
+			}
+		}
+		errs = append(errs, fmt.Errorf(
+			"resource pool %s must be provided by an HPC partition, "+
+				"not by the launcher-provided resource pool %s: %s",
+			rp.PoolName, partition, strings.Join(chain, " -> ")))
+	}
+	return errs
+}
@@ -2,11 +2,13 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/require"
 
+	"github.com/determined-ai/determined/master/internal/config/provconfig"
 	"github.com/determined-ai/determined/master/pkg/aproto"
 	"github.com/determined-ai/determined/master/pkg/check"
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -102,6 +104,48 @@ func TestGetAgentRMConfig(t *testing.T) {
 	})
 }
 
+func TestValidateProvidedPools(t *testing.T) {
+	provided := func(name, partition string, aliases ...string) ResourcePoolConfig {
+		return ResourcePoolConfig{
+			PoolName: name,
+			Aliases:  aliases,
+			Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: partition}},
+		}
+	}
+	validate := func(pools ...ResourcePoolConfig) []error {
+		return ResourceConfig{
+			RootManagerInternal: &ResourceManagerConfig{
+				DispatcherRM: &DispatcherResourceManagerConfig{},
+			},
+			RootPoolsInternal: pools,
+		}.Validate()
+	}
+
+	// Pools provided by partitions, including one that is also configured as a pool.
+	require.Empty(t, validate(
+		ResourcePoolConfig{PoolName: "gpus"},
+		provided("gpus-low", "gpus"),
+		provided("gpus-high", "gpus"),
+	))
+
+	require.Equal(t, []error{fmt.Errorf(
+		"resource pool gpus must be provided by an HPC partition, " +
+			"not by the launcher-provided resource pool gpus: gpus -> gpus")},
+		validate(provided("gpus", "gpus")))
+
+	require.Equal(t, []error{fmt.Errorf(
+		"resource pool a must be provided by an HPC partition, " +
+			"not by the launcher-provided resource pool old-b: a -> old-b -> gpus")},
+		validate(provided("a", "old-b"), provided("b", "gpus", "old-b")))
+
+	require.Equal(t, []error{
+		fmt.Errorf("resource pool a must be provided by an HPC partition, " +
+			"not by the launcher-provided resource pool b: a -> b -> a"),
+		fmt.Errorf("resource pool b must be provided by an HPC partition, " +
+			"not by the launcher-provided resource pool a: b -> a -> b"),
+	}, validate(provided("a", "b"), provided("b", "a")))
+}
+
 func TestResolveConfigErrors(t *testing.T) {
 	cases := []struct {
 		name                  string
@@ -142,6 +186,45 @@ additional_resource_managers:
 				"They must be unique across even different resource managers",
 		},
 
+		{
+			"dupe pool aliases", `
+resource_manager:
+  type: agent
+  name: a
+resource_pools:
+  - pool_name: a
+    aliases: [old]
+  - pool_name: b
+    aliases: [old]`, nil, "Check Failed! 2 errors found:\n\terror found at root.ResourceConfig: " +
+				"resource pools a and b have a duplicate alias: old\n\terror found at root: " +
+				"resource pools a and b have a duplicate alias: old",
+		},
+
+		{
+			"pool alias is pool name", `
+resource_manager:
+  type: agent
+  name: a
+resource_pools:
+  - pool_name: a
+  - pool_name: b
+    aliases: [a]`, nil, "Check Failed! 2 errors found:\n\terror found at root.ResourceConfig: " +
+				"resource pool b has an alias that is the name of a resource pool: a\n\terror " +
+				"found at root: resource pool b has an alias that is the name of a resource pool: a",
+		},
+
+		{
+			"labels of an agent pool", `
+resource_manager:
+  type: agent
+  name: a
+resource_pools:
+  - pool_name: a
+    labels: {gpu: a100}`, nil, "Check Failed! 2 errors found:\n\terror found at root.ResourceConfig: " +
+				"resource pool a has labels, which only Slurm and PBS resource pools support\n\terror " +
+				"found at root: resource pool a has labels, which only Slurm and PBS resource pools support",
+		},
+
 		{"dupe rm names", `
 resource_manager:
   type: agent
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/determined-ai/determined/master/internal/config/provconfig"
 	"github.com/determined-ai/determined/master/pkg/aproto"
@@ -22,6 +23,7 @@ func defaultRPConfig() ResourcePoolConfig {
 type ResourcePoolConfig struct {
 	PoolName                 string                             `json:"pool_name"`
 	Description              string                             `json:"description"`
+	Location                 string                             `json:"location,omitempty"`
 	Provider                 *provconfig.Config                 `json:"provider"`
 	Scheduler                *SchedulerConfig                   `json:"scheduler,omitempty"`
 	MaxAuxContainersPerAgent int                                `json:"max_aux_containers_per_agent"`
@@ -37,6 +39,35 @@ type ResourcePoolConfig struct {
 	// which in most cases will be the namespace the helm deployment is in.
 	KubernetesNamespace string `json:"kubernetes_namespace"`
 
+	// MaxSlotsPerJob is the maximum number of slots that a single job may request in a
+	// launcher-provided pool, overriding that of its partition. Unset means that of its
+	// partition, if any.
+	MaxSlotsPerJob *int `json:"max_slots_per_job,omitempty"`
+
+	// MaxPendingDuration is how long a job may stay pending in a launcher-provided pool before
+	// it is canceled, overriding that of its partition. Unset means that of its partition, if any.
+	MaxPendingDuration *model.Duration `json:"max_pending_duration,omitempty"`
+
+	// ImagePullPolicy is whether the container images of jobs in a launcher-provided pool are
+	// pulled on every launch or only when they are not cached, overriding that of its
+	// partition. Unset means that of its partition, if any.
+	ImagePullPolicy *string `json:"image_pull_policy,omitempty"`
+
+	// MasterHost and MasterPort are the address at which jobs in a launcher-provided pool reach
+	// the master, overriding those of its partition. Unset means those of its partition, if any.
+	MasterHost *string `json:"master_host,omitempty"`
+	MasterPort *int    `json:"master_port,omitempty"`
+
+	// Aliases are previous names of the pool, e.g. from before its partition was renamed,
+	// that configurations may continue to refer to it by.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Labels describe a launcher-provided pool, e.g. gpu: a100, so that configurations may
+	// select it by label rather than by name. They are added to those of its partition. Only
+	// the Slurm and PBS resource managers support them, and label selectors are not routed
+	// among multiple resource managers.
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// Deprecated: Use MaxAuxContainersPerAgent instead.
 	MaxCPUContainersPerAgent int `json:"max_cpu_containers_per_agent,omitempty"`
 }
@@ -64,7 +95,32 @@ func (r ResourcePoolConfig) Validate() []error {
 		check.True(len(r.PoolName) != 0, "resource pool name cannot be empty"),
 		check.True(r.MaxAuxContainersPerAgent >= 0,
 			"resource pool max cpu containers per agent should be >= 0"),
+		check.True(r.MaxSlotsPerJob == nil || *r.MaxSlotsPerJob > 0,
+			"resource pool max slots per job should be > 0"),
+		check.True(r.MaxPendingDuration == nil || *r.MaxPendingDuration > 0,
+			"resource pool max pending duration should be > 0"),
+		check.True(r.ImagePullPolicy == nil || *r.ImagePullPolicy == ImagePullPolicyAlways ||
+			*r.ImagePullPolicy == ImagePullPolicyIfNotPresent,
+			"resource pool image pull policy should be "+ImagePullPolicyAlways+" or "+
+				ImagePullPolicyIfNotPresent),
+		check.True(r.MasterHost == nil || strings.TrimSpace(*r.MasterHost) != "",
+			"resource pool master host cannot be empty"),
+		check.True(r.MasterPort == nil || (*r.MasterPort >= 1 && *r.MasterPort <= maxPort),
+			"resource pool master port should be between 1 and 65535"),
+		check.True(validPoolLabels(r.Labels),
+			"resource pool labels should have non-empty keys and no '=' or ','"),
+	}
+}
+
+// validPoolLabels returns whether the labels of a resource pool may be matched by a label
+// selector: keys are not empty, and neither keys nor values contain '=' or ','.
+func validPoolLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, "=,") || strings.ContainsAny(v, "=,") {
+			return false
+		}
 	}
+	return true
 }
 
 // Printable returns a printable object.
@@ -1028,6 +1028,12 @@ func (m *Master) checkIfRMDefaultsAreUnbound(rmConfig *config.ResourceManagerCon
 				return err
 			}
 		}
+		if rmConfig.DispatcherRM.CheckpointGCResourcePool != nil {
+			err := db.CheckIfRPUnbound(*rmConfig.DispatcherRM.CheckpointGCResourcePool)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 	if rmConfig.PbsRM != nil {
@@ -1043,6 +1049,12 @@ func (m *Master) checkIfRMDefaultsAreUnbound(rmConfig *config.ResourceManagerCon
 				return err
 			}
 		}
+		if rmConfig.PbsRM.CheckpointGCResourcePool != nil {
+			err := db.CheckIfRPUnbound(*rmConfig.PbsRM.CheckpointGCResourcePool)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 	return fmt.Errorf("no Resource Manager found")
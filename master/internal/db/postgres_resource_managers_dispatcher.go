@@ -18,6 +18,7 @@ type Dispatch struct {
 	ResourceID       sproto.ResourcesID `bun:"resource_id"`
 	AllocationID     model.AllocationID `bun:"allocation_id"`
 	ImpersonatedUser string             `bun:"impersonated_user"`
+	Nodes            []string           `bun:"nodes,array"`
 }
 
 // InsertDispatch persists the existence for a dispatch.
@@ -29,6 +30,18 @@ func InsertDispatch(ctx context.Context, r *Dispatch) error {
 	return nil
 }
 
+// UpdateDispatchNodes records the nodes that the dispatch is running on.
+func UpdateDispatchNodes(ctx context.Context, id string, nodes []string) error {
+	_, err := Bun().NewUpdate().Model(&Dispatch{Nodes: nodes}).
+		Column("nodes").
+		Where("dispatch_id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("updating nodes of dispatch (%s): %w", id, err)
+	}
+	return nil
+}
+
 // DispatchByID retrieves a dispatch by its ID.
 func DispatchByID(
 	ctx context.Context,
@@ -64,6 +77,49 @@ func ListAllDispatches(ctx context.Context) ([]*Dispatch, error) {
 	})
 }
 
+// ListDispatchOwners returns the username of the owner of the job of each dispatch, by
+// dispatch ID. Dispatches whose job has no owner are omitted.
+func ListDispatchOwners(ctx context.Context) (map[string]string, error) {
+	var rows []struct {
+		DispatchID string `bun:"dispatch_id"`
+		Username   string `bun:"username"`
+	}
+	err := Bun().NewSelect().Model((*Dispatch)(nil)).
+		Column("dispatch.dispatch_id").
+		ColumnExpr("users.username").
+		Join("join allocations on allocations.allocation_id = dispatch.allocation_id").
+		Join("join tasks on tasks.task_id = allocations.task_id").
+		Join("join jobs on jobs.job_id = tasks.job_id").
+		Join("join users on users.id = jobs.owner_id").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanning dispatch owners: %w", err)
+	}
+
+	owners := make(map[string]string, len(rows))
+	for _, r := range rows {
+		owners[r.DispatchID] = r.Username
+	}
+	return owners, nil
+}
+
+// AllocationOwnerID returns the ID of the user that owns the job of an allocation, or nil
+// if the job has no owner.
+func AllocationOwnerID(ctx context.Context, id model.AllocationID) (*model.UserID, error) {
+	var ownerID *model.UserID
+	err := Bun().NewSelect().
+		ColumnExpr("jobs.owner_id").
+		TableExpr("allocations").
+		Join("join tasks on tasks.task_id = allocations.task_id").
+		Join("left join jobs on jobs.job_id = tasks.job_id").
+		Where("allocations.allocation_id = ?", id).
+		Scan(ctx, &ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("scanning owner of allocation %s: %w", id, err)
+	}
+	return ownerID, nil
+}
+
 // ListDispatchesByAllocationID lists all dispatches for an allocation ID.
 func ListDispatchesByAllocationID(
 	ctx context.Context,
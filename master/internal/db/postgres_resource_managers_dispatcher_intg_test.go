@@ -54,6 +54,19 @@ VALUES ($1, $2)
 	require.NoError(t, err)
 	require.Equal(t, &d, byID)
 
+	owners, err := ListDispatchOwners(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{d.DispatchID: u.Username}, owners)
+
+	nodes := []string{"gpu-001", "gpu-002"}
+	err = UpdateDispatchNodes(context.TODO(), d.DispatchID, nodes)
+	require.NoError(t, err)
+
+	byID, err = DispatchByID(context.TODO(), d.DispatchID)
+	require.NoError(t, err)
+	require.Equal(t, nodes, byID.Nodes)
+	d.Nodes = nodes
+
 	count, err := DeleteDispatch(context.TODO(), d.DispatchID)
 	require.NoError(t, err)
 	require.Equal(t, int64(1), count)
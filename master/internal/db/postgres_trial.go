@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"math"
 	"regexp"
@@ -923,3 +924,154 @@ RETURNING true`, bun.In(uniqueExpIDs)).Scan(ctx, &res)
 
 	return nil
 }
+
+// DeleteRuns deletes the runs and their checkpoints in a single transaction, along with
+// the experiments that are left without runs. It returns the IDs of the deleted runs and
+// of their tasks, whose logs are left to the caller to delete.
+func DeleteRuns(ctx context.Context, ids []int32) ([]int32, []model.TaskID, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	var deletedIDs []int32
+	var taskIDs []model.TaskID
+	err := Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var runTaskIDs []model.RunTaskID
+		if err := tx.NewSelect().Model(&runTaskIDs).
+			Where("run_id IN (?)", bun.In(ids)).
+			Scan(ctx); err != nil {
+			return fmt.Errorf("querying for task IDs of runs: %w", err)
+		}
+		for _, r := range runTaskIDs {
+			taskIDs = append(taskIDs, r.TaskID)
+		}
+
+		if _, err := tx.NewDelete().Model(&model.CheckpointV2{}).
+			Where(`task_id IN (SELECT task_id FROM run_id_task_id WHERE run_id IN (?))`, bun.In(ids)).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("deleting checkpoints (v2): %w", err)
+		}
+
+		var expIDs []int32
+		if err := tx.NewSelect().Table("runs").
+			Column("experiment_id").
+			Where("id IN (?)", bun.In(ids)).
+			Where("experiment_id IS NOT NULL").
+			Scan(ctx, &expIDs); err != nil {
+			return fmt.Errorf("querying for experiment IDs of runs: %w", err)
+		}
+
+		if _, err := tx.NewDelete().Table("runs").
+			Where("id IN (?)", bun.In(ids)).
+			Returning("id").
+			Exec(ctx, &deletedIDs); err != nil {
+			return fmt.Errorf("deleting from runs table: %w", err)
+		}
+
+		if len(expIDs) > 0 {
+			if _, err := tx.NewDelete().Model(&model.Experiment{}).
+				Where("id IN (?)", bun.In(expIDs)).
+				Where("NOT EXISTS (SELECT 1 FROM runs r WHERE r.experiment_id = experiment.id)").
+				Exec(ctx); err != nil {
+				return fmt.Errorf("deleting experiments without runs: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("deleting runs %v: %w", ids, err)
+	}
+	return deletedIDs, taskIDs, nil
+}
+
+// RunCheckpointsToGC returns the checkpoints of the runs whose files have not been deleted.
+func RunCheckpointsToGC(ctx context.Context, runIDs []int32) ([]uuid.UUID, error) {
+	var checkpointIDs []uuid.UUID
+	if err := Bun().NewSelect().Table("checkpoints_v2").
+		Column("uuid").
+		Where(`task_id IN (SELECT task_id FROM run_id_task_id WHERE run_id IN (?))`, bun.In(runIDs)).
+		Where("state != ?", model.DeletedState).
+		Scan(ctx, &checkpointIDs); err != nil {
+		return nil, fmt.Errorf("querying for checkpoints of runs %v: %w", runIDs, err)
+	}
+	return checkpointIDs, nil
+}
+
+type runTag struct {
+	bun.BaseModel `bun:"table:run_tags"`
+
+	RunID int32  `bun:"run_id,pk"`
+	Tag   string `bun:"tag,pk"`
+}
+
+// RunTags returns the sorted tags of the run.
+func RunTags(ctx context.Context, runID int32) ([]string, error) {
+	return runTags(ctx, Bun(), runID)
+}
+
+func runTags(ctx context.Context, idb bun.IDB, runID int32) ([]string, error) {
+	tags := []string{}
+	if err := idb.NewSelect().Table("run_tags").
+		Column("tag").
+		Where("run_id = ?", runID).
+		Order("tag").
+		Scan(ctx, &tags); err != nil {
+		return nil, fmt.Errorf("querying for tags of run %d: %w", runID, err)
+	}
+	return tags, nil
+}
+
+// AddRunTags adds the tags that the run does not have yet and returns its sorted tags.
+func AddRunTags(ctx context.Context, runID int32, tags []string) ([]string, error) {
+	return updateRunTags(ctx, runID, func(tx bun.Tx) (sql.Result, error) {
+		rows := make([]runTag, 0, len(tags))
+		for _, tag := range tags {
+			rows = append(rows, runTag{RunID: runID, Tag: tag})
+		}
+		return tx.NewInsert().Model(&rows).
+			On("CONFLICT (run_id, tag) DO NOTHING").
+			Exec(ctx)
+	})
+}
+
+// RemoveRunTags removes the tags that the run has and returns its sorted tags.
+func RemoveRunTags(ctx context.Context, runID int32, tags []string) ([]string, error) {
+	return updateRunTags(ctx, runID, func(tx bun.Tx) (sql.Result, error) {
+		return tx.NewDelete().Table("run_tags").
+			Where("run_id = ?", runID).
+			Where("tag IN (?)", bun.In(tags)).
+			Exec(ctx)
+	})
+}
+
+// updateRunTags changes the tags of the run and, if any changed, its updated_at, so that
+// the change is seen by searches of the runs updated since a previous search.
+func updateRunTags(
+	ctx context.Context, runID int32, update func(tx bun.Tx) (sql.Result, error),
+) ([]string, error) {
+	var tags []string
+	err := Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := update(tx)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows > 0 {
+			if _, err := tx.NewUpdate().Table("runs").
+				Set("updated_at = now()").
+				Where("id = ?", runID).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		tags, err = runTags(ctx, tx, runID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating tags of run %d: %w", runID, err)
+	}
+	return tags, nil
+}
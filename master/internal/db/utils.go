@@ -1,7 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"net"
 
 	"github.com/jackc/pgconn"
 	"github.com/pkg/errors"
@@ -40,6 +43,20 @@ func MustHaveAffectedRows(result sql.Result, err error) error {
 	return err
 }
 
+// IsTransientConnError returns true if the error is from failing to connect to or
+// communicate with the database, rather than from executing the query, so that the
+// query may succeed if it is retried.
+func IsTransientConnError(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, driver.ErrBadConn), pgconn.SafeToRetry(err):
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 func pgErrCode(err error) string {
 	if e, ok := err.(*pgconn.PgError); ok {
 		return e.Code
@@ -25,6 +25,8 @@ const (
 	doesNotContain     operator          = "notContains"
 	empty              operator          = "isEmpty"
 	notEmpty           operator          = "notEmpty"
+	inList             operator          = "in"
+	hasKey             operator          = "hasKey"
 
 	metricGroupValidation string = "validation_metrics"
 	metricGroupTraining   string = "avg_metrics"
@@ -80,6 +82,8 @@ func (o *operator) toSQL() (string, error) {
 		return s, nil
 	case doesNotContain:
 		return s, nil
+	case inList, hasKey:
+		return s, nil
 	default:
 		return "", fmt.Errorf("invalid operator %v", *o)
 	}
@@ -132,6 +136,57 @@ func expColumnNameToSQL(columnName string) (string, error) {
 	return col, nil
 }
 
+// runExperimentColumnNameToSQL returns the SQL of an experiment column that runs may be
+// filtered by, through the join of the experiment of each run as e. Not every experiment
+// column is filterable: those describing the trials of an experiment are run columns.
+func runExperimentColumnNameToSQL(columnName string) (string, error) {
+	// To prevent SQL injection this function should never
+	// return a user generated field name
+
+	runExperimentColMap := map[string]string{
+		"id":                   "e.id",
+		"description":          "e.config->>'description'",
+		"name":                 "e.config->>'name'",
+		"tags":                 "e.config->>'labels'",
+		"searcherType":         "e.config->'searcher'->>'name'",
+		"searcherMetric":       "e.config->'searcher'->>'metric'",
+		"startTime":            "e.start_time",
+		"endTime":              "e.end_time",
+		"duration":             "extract(epoch FROM coalesce(e.end_time, now()) - e.start_time)",
+		"state":                "e.state",
+		"numTrials":            "(SELECT COUNT(*) FROM runs er WHERE er.experiment_id = e.id)",
+		"progress":             "ROUND(COALESCE(e.progress, 0) * 100)::INTEGER", // multiply by 100 for percent
+		"user":                 "e.owner_id",
+		"forkedFrom":           "e.parent_id",
+		"resourcePool":         "e.config->'resources'->>'resource_pool'",
+		"projectId":            "e.project_id",
+		"checkpointSize":       "e.checkpoint_size",
+		"checkpointCount":      "e.checkpoint_count",
+		"externalExperimentId": "e.external_experiment_id",
+	}
+	col, exists := runExperimentColMap[columnName]
+	if !exists {
+		return "", fmt.Errorf("invalid experiment column %s for runs", columnName)
+	}
+	return col, nil
+}
+
+// runColumnAliases maps the former names of run filter and sort columns to their current
+// names, so that saved filters and sorts keep working after a column is renamed.
+var runColumnAliases = map[string]string{
+	"externalTrialId":    "externalRunId",
+	"searcherMetricsVal": "searcherMetricValue",
+	"tags":               "labels",
+}
+
+// resolveRunColumnName returns the current name of a run filter or sort column.
+func resolveRunColumnName(columnName string) string {
+	if name, ok := runColumnAliases[columnName]; ok {
+		return name
+	}
+	return columnName
+}
+
 func runColumnNameToSQL(columnName string) (string, error) {
 	// To prevent SQL injection this function should never
 	// return a user generated field name
@@ -140,7 +195,7 @@ func runColumnNameToSQL(columnName string) (string, error) {
 		"id":                    "r.id",
 		"experimentDescription": "e.config->>'description'",
 		"experimentName":        "e.config->>'name'",
-		"tags":                  "e.config->>'labels'",
+		"labels":                "e.config->>'labels'",
 		"searcherType":          "e.config->'searcher'->>'name'",
 		"searcherMetric":        "e.config->'searcher'->>'metric'",
 		"startTime":             "r.start_time",
@@ -154,13 +209,13 @@ func runColumnNameToSQL(columnName string) (string, error) {
 		"projectId":             "r.project_id",
 		"checkpointSize":        "e.checkpoint_size",
 		"checkpointCount":       "e.checkpoint_count",
-		"searcherMetricsVal":    "r.searcher_metric_value",
+		"searcherMetricValue":   "r.searcher_metric_value",
 		"externalExperimentId":  "e.external_experiment_id",
-		"externalTrialId":       "r.external_run_id",
+		"externalRunId":         "r.external_run_id",
 		"experimentId":          "e.id",
 	}
 	var exists bool
-	col, exists := filterExperimentColMap[columnName]
+	col, exists := filterExperimentColMap[resolveRunColumnName(columnName)]
 	if !exists {
 		return "", fmt.Errorf("invalid run column %s", columnName)
 	}
@@ -235,6 +290,110 @@ func runHpToSQL(c string, filterColumnType *string, filterValue *interface{},
 	return q.Where(queryString, queryArgs...), nil
 }
 
+// runMetricsToSQL filters runs by a summary metric, named metrics.<group>.<metric>.<qualifier>,
+// with the same operators as runHpToSQL.
+func runMetricsToSQL(c string, filterColumnType *string, filterValue *interface{},
+	op *operator, q *bun.SelectQuery,
+	fc *filterConjunction,
+) (*bun.SelectQuery, error) {
+	queryColumnType := projectv1.ColumnType_COLUMN_TYPE_UNSPECIFIED.String()
+	var o operator
+	var queryValue interface{}
+	if op == nil {
+		return nil, fmt.Errorf("metric field defined without an operator")
+	}
+	if filterValue == nil && *op != empty && *op != notEmpty {
+		return nil, fmt.Errorf("metric field defined without value and without a valid operator")
+	}
+	o = *op
+	if o != empty && o != notEmpty {
+		queryValue = *filterValue
+	}
+	if filterColumnType != nil {
+		queryColumnType = *filterColumnType
+	}
+	metricGroup, metricName, metricQualifier, err := parseMetricsName(strings.TrimPrefix(c, "metrics."))
+	if err != nil {
+		return nil, err
+	}
+	queryArgs := []interface{}{metricGroup, metricName, metricQualifier}
+	col := `r.summary_metrics->?->?->>?`
+	oSQL, err := o.toSQL()
+	if err != nil {
+		return nil, err
+	}
+	var queryString string
+	switch o {
+	case empty:
+		queryString = fmt.Sprintf(`%s IS NULL`, col)
+	case notEmpty:
+		queryString = fmt.Sprintf(`%s IS NOT NULL`, col)
+	case contains:
+		queryArgs = append(queryArgs, queryValue)
+		if queryColumnType == projectv1.ColumnType_COLUMN_TYPE_NUMBER.String() {
+			queryString = fmt.Sprintf(`(%s)::float8 = ?`, col)
+		} else {
+			queryString = fmt.Sprintf(`%s LIKE ?`, col)
+		}
+	case doesNotContain:
+		queryArgs = append(queryArgs, queryValue)
+		if queryColumnType == projectv1.ColumnType_COLUMN_TYPE_NUMBER.String() {
+			queryString = fmt.Sprintf(`(%s)::float8 != ?`, col)
+		} else {
+			queryString = fmt.Sprintf(`%s NOT LIKE ?`, col)
+		}
+	default:
+		queryArgs = append(queryArgs, bun.Safe(oSQL), queryValue)
+		if queryColumnType == projectv1.ColumnType_COLUMN_TYPE_NUMBER.String() {
+			queryString = fmt.Sprintf(`(%s)::float8 ? ?`, col)
+		} else {
+			queryString = fmt.Sprintf(`%s ? ?`, col)
+		}
+	}
+
+	if fc != nil && *fc == or {
+		return q.WhereOr(queryString, queryArgs...), nil
+	}
+	return q.Where(queryString, queryArgs...), nil
+}
+
+func runTagsToSQL(filterValue *interface{}, op *operator, q *bun.SelectQuery,
+	fc *filterConjunction,
+) (*bun.SelectQuery, error) {
+	var queryArgs []interface{}
+	var queryString string
+	switch *op {
+	case empty:
+		queryString = "NOT EXISTS (SELECT 1 FROM run_tags rt WHERE rt.run_id = r.id)"
+	case notEmpty:
+		queryString = "EXISTS (SELECT 1 FROM run_tags rt WHERE rt.run_id = r.id)"
+	case contains:
+		queryString = "EXISTS (SELECT 1 FROM run_tags rt WHERE rt.run_id = r.id AND rt.tag ILIKE ?)"
+		queryArgs = append(queryArgs, fmt.Sprintf("%%%s%%", *filterValue))
+	case hasKey:
+		queryString = "EXISTS (SELECT 1 FROM run_tags rt WHERE rt.run_id = r.id AND rt.tag = ?)"
+		queryArgs = append(queryArgs, fmt.Sprintf("%v", *filterValue))
+	case inList:
+		values, ok := (*filterValue).([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tags filter with operator %v requires a list value", *op)
+		}
+		tags := make([]string, 0, len(values))
+		for _, v := range values {
+			tags = append(tags, fmt.Sprintf("%v", v))
+		}
+		queryString = "EXISTS (SELECT 1 FROM run_tags rt WHERE rt.run_id = r.id AND rt.tag IN (?))"
+		queryArgs = append(queryArgs, bun.In(tags))
+	default:
+		return nil, fmt.Errorf("invalid operator %v for tags filter", *op)
+	}
+
+	if fc != nil && *fc == or {
+		return q.WhereOr(queryString, queryArgs...), nil
+	}
+	return q.Where(queryString, queryArgs...), nil
+}
+
 // nolint: lll
 func hpToSQL(c string, filterColumnType *string, filterValue *interface{},
 	op *operator, q *bun.SelectQuery,
@@ -447,7 +606,17 @@ func expRunOperatorQuery(o operator, col string, oSQL string, val *interface{})
 }
 
 func (e experimentFilterRoot) toSQL(q *bun.SelectQuery) (*bun.SelectQuery, error) {
-	q, err := e.FilterGroup.toSQL(q, nil)
+	q, err := e.FilterGroup.toSQL(q, nil, expColumnNameToSQL)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// toRunSQL applies the filter to a query of runs joined with their experiments as e, where
+// experiment columns are limited to those of runExperimentColumnNameToSQL.
+func (e experimentFilterRoot) toRunSQL(q *bun.SelectQuery) (*bun.SelectQuery, error) {
+	q, err := e.FilterGroup.toSQL(q, nil, runExperimentColumnNameToSQL)
 	if err != nil {
 		return nil, err
 	}
@@ -455,7 +624,7 @@ func (e experimentFilterRoot) toSQL(q *bun.SelectQuery) (*bun.SelectQuery, error
 }
 
 func (e experimentFilter) toSQL(q *bun.SelectQuery,
-	c *filterConjunction,
+	c *filterConjunction, expColumnToSQL func(string) (string, error),
 ) (*bun.SelectQuery, error) {
 	switch e.Kind {
 	case field:
@@ -476,7 +645,7 @@ func (e experimentFilter) toSQL(q *bun.SelectQuery,
 		switch location {
 		case projectv1.LocationType_LOCATION_TYPE_EXPERIMENT.String():
 			var col string
-			col, err = expColumnNameToSQL(e.ColumnName)
+			col, err = expColumnToSQL(e.ColumnName)
 			if err != nil {
 				return nil, err
 			}
@@ -546,6 +715,10 @@ func (e experimentFilter) toSQL(q *bun.SelectQuery,
 			return hpToSQL(e.ColumnName, e.Type, e.Value, e.Operator, q, c)
 		case projectv1.LocationType_LOCATION_TYPE_RUN_HYPERPARAMETERS.String():
 			return runHpToSQL(e.ColumnName, e.Type, e.Value, e.Operator, q, c)
+		case projectv1.LocationType_LOCATION_TYPE_RUN_METRICS.String():
+			return runMetricsToSQL(e.ColumnName, e.Type, e.Value, e.Operator, q, c)
+		case projectv1.LocationType_LOCATION_TYPE_RUN_TAGS.String():
+			return runTagsToSQL(e.Value, e.Operator, q, c)
 		}
 	case group:
 		var co string
@@ -566,7 +739,7 @@ func (e experimentFilter) toSQL(q *bun.SelectQuery,
 		}
 		for _, c := range e.Children {
 			q = q.WhereGroup(co, func(q *bun.SelectQuery) *bun.SelectQuery {
-				_, err = c.toSQL(q, e.Conjunction)
+				_, err = c.toSQL(q, e.Conjunction, expColumnToSQL)
 				if err != nil {
 					return q
 				}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunColumnAliases(t *testing.T) {
+	for alias, name := range runColumnAliases {
+		col, err := runColumnNameToSQL(name)
+		require.NoError(t, err, name)
+		aliasCol, err := runColumnNameToSQL(alias)
+		require.NoError(t, err, alias)
+		require.Equal(t, col, aliasCol, "filter column %s", alias)
+
+		col, sortErr := runSortColumnToSQL(name)
+		aliasCol, aliasSortErr := runSortColumnToSQL(alias)
+		require.Equal(t, sortErr == nil, aliasSortErr == nil, "sort column %s", alias)
+		require.Equal(t, col, aliasCol, "sort column %s", alias)
+	}
+
+	for _, c := range []struct {
+		alias string
+		name  string
+		sql   string
+	}{
+		{"externalTrialId", "externalRunId", "r.external_run_id"},
+		{"searcherMetricsVal", "searcherMetricValue", "r.searcher_metric_value"},
+		{"tags", "labels", "e.config->>'labels'"},
+	} {
+		require.Equal(t, c.name, resolveRunColumnName(c.alias))
+		col, err := runColumnNameToSQL(c.alias)
+		require.NoError(t, err)
+		require.Equal(t, c.sql, col)
+	}
+	require.Equal(t, "id", resolveRunColumnName("id"))
+
+	col, err := runSortColumnToSQL("searcherMetricsVal")
+	require.NoError(t, err)
+	require.Equal(t, "r.searcher_metric_value", col)
+
+	_, err = runColumnNameToSQL("notValid")
+	require.ErrorContains(t, err, "invalid run column notValid")
+	_, err = runSortColumnToSQL("notValid")
+	require.ErrorContains(t, err, "invalid sort col: notValid")
+}
+
+func TestRunExperimentColumnNameToSQL(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		sql  string
+	}{
+		{"id", "e.id"},
+		{"description", "e.config->>'description'"},
+		{"tags", "e.config->>'labels'"},
+		{"projectId", "e.project_id"},
+	} {
+		col, err := runExperimentColumnNameToSQL(c.name)
+		require.NoError(t, err)
+		require.Equal(t, c.sql, col)
+	}
+
+	for _, name := range []string{"externalTrialId", "searcherMetricsVal", "notValid"} {
+		_, err := runExperimentColumnNameToSQL(name)
+		require.ErrorContains(t, err, "invalid experiment column "+name+" for runs")
+	}
+}
+
+func TestRunMetricsToSQLWithoutOperator(t *testing.T) {
+	_, err := runMetricsToSQL("metrics.validation.loss.last", nil, nil, nil, nil, nil)
+	require.ErrorContains(t, err, "without an operator")
+}
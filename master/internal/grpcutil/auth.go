@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -36,18 +37,49 @@ const (
 	// AllocationTokenHeader is the header used to pass the allocation token.
 	AllocationTokenHeader = "x-allocation-token"
 	userTokenHeader       = "x-user-token"
+	// ImpersonateUserHeader is the header an admin may set to the username of another
+	// user to make a request as that user, e.g. to reproduce an issue they reported.
+	ImpersonateUserHeader = "x-impersonate-user"
 	cookieName            = "auth"
 )
 
+const (
+	// defaultAuthLookupTimeout is how long the database lookups to authenticate a
+	// request may take, unless configured otherwise.
+	defaultAuthLookupTimeout = 10 * time.Second
+	// authLookupRetries is how many times a lookup that fails with a transient
+	// connection error is retried.
+	authLookupRetries      = 2
+	authLookupRetryBackoff = 100 * time.Millisecond
+)
+
 type (
 	userContextKey        struct{}
 	userSessionContextKey struct{}
 )
 
-var unauthenticatedMethods = map[string]bool{
-	"/determined.api.v1.Determined/Login":        true,
-	"/determined.api.v1.Determined/GetMaster":    true,
-	"/determined.api.v1.Determined/GetTelemetry": true,
+// userByToken looks up the user of a token; it is replaced in tests.
+var userByToken = user.ByToken
+
+var (
+	unauthenticatedMethodsMu sync.RWMutex
+	unauthenticatedMethods   = map[string]bool{}
+)
+
+// RegisterUnauthenticatedMethod exempts a gRPC method, given by its full name (e.g.
+// "/determined.api.v1.Determined/Login"), from authentication. Endpoints that must be
+// reachable without credentials, such as health and info endpoints, register
+// themselves before the server starts.
+func RegisterUnauthenticatedMethod(fullMethod string) {
+	unauthenticatedMethodsMu.Lock()
+	defer unauthenticatedMethodsMu.Unlock()
+	unauthenticatedMethods[fullMethod] = true
+}
+
+func isUnauthenticatedMethod(fullMethod string) bool {
+	unauthenticatedMethodsMu.RLock()
+	defer unauthenticatedMethodsMu.RUnlock()
+	return unauthenticatedMethods[fullMethod]
 }
 
 var (
@@ -59,9 +91,42 @@ var (
 	ErrNotActive = status.Error(codes.PermissionDenied, "user is not active")
 	// ErrPermissionDenied notifies that the user does not have permission to access the method.
 	ErrPermissionDenied = status.Error(codes.PermissionDenied, "user does not have permission")
+	// ErrAuthLookupTimeout notifies that the credentials could not be looked up in time.
+	ErrAuthLookupTimeout = status.Error(codes.Unavailable, "timed out looking up credentials")
 )
 
-func allocationSessionByTokenBun(token string) (*model.AllocationSession, error) {
+// authLookup runs a database lookup needed to authenticate a request, failing if it
+// does not complete within the configured timeout. Lookups that fail with transient
+// connection errors are retried; other errors, such as db.ErrNotFound, are not.
+func authLookup(ctx context.Context, lookup func(ctx context.Context) error) error {
+	timeout := defaultAuthLookupTimeout
+	if t := config.GetMasterConfig().InternalConfig.AuthLookupTimeout; t != nil {
+		timeout = time.Duration(*t)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		err := lookup(ctx)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrAuthLookupTimeout
+		}
+		if attempt > authLookupRetries || !db.IsTransientConnError(err) {
+			return err
+		}
+		log.WithError(err).Debugf("retrying auth lookup (attempt %d)", attempt)
+
+		select {
+		case <-ctx.Done():
+			return ErrAuthLookupTimeout
+		case <-time.After(time.Duration(attempt) * authLookupRetryBackoff):
+		}
+	}
+}
+
+func allocationSessionByTokenBun(
+	ctx context.Context, token string,
+) (*model.AllocationSession, error) {
 	v2 := paseto.NewV2()
 
 	var session model.AllocationSession
@@ -71,7 +136,9 @@ func allocationSessionByTokenBun(token string) (*model.AllocationSession, error)
 		return nil, db.ErrNotFound
 	}
 
-	err = db.Bun().NewSelect().Model(&session).Where("id = ?", session.ID).Scan(context.Background())
+	err = authLookup(ctx, func(ctx context.Context) error {
+		return db.Bun().NewSelect().Model(&session).Where("id = ?", session.ID).Scan(ctx)
+	})
 	if errors.Cause(err) == sql.ErrNoRows {
 		log.WithField("allocation_sessions.id", session.ID).Debug("allocation_session not found")
 		return nil, db.ErrNotFound
@@ -100,7 +167,7 @@ func getAllocationSessionBun(ctx context.Context) (*model.AllocationSession, err
 	}
 	token = strings.TrimPrefix(token, "Bearer ")
 
-	switch session, err := allocationSessionByTokenBun(token); err {
+	switch session, err := allocationSessionByTokenBun(ctx, token); err {
 	case nil:
 		return session, nil
 	case db.ErrNotFound:
@@ -113,10 +180,15 @@ func getAllocationSessionBun(ctx context.Context) (*model.AllocationSession, err
 // GetUser returns the currently logged in user.
 func GetUser(ctx context.Context) (*model.User, *model.UserSession, error) {
 	if user, ok := ctx.Value(userContextKey{}).(*model.User); ok {
-		if session, ok := ctx.Value(userSessionContextKey{}).(*model.UserSession); ok {
+		session, ok := ctx.Value(userSessionContextKey{}).(*model.UserSession)
+		switch {
+		case !ok:
+			return user, nil, nil // Allocation token cache hit.
+		case session.Expiry.After(time.Now()):
 			return user, session, nil // User token cache hit.
 		}
-		return user, nil, nil // Allocation token cache hit.
+		// The session expired after it was cached, as can happen over the life of a
+		// stream, so authenticate the token again to have it rejected.
 	}
 
 	extConfig := config.GetMasterConfig().InternalConfig.ExternalSessions
@@ -130,6 +202,10 @@ func GetUser(ctx context.Context) (*model.User, *model.UserSession, error) {
 		tokens = md[gatewayTokenHeader]
 	}
 	if len(tokens) == 0 {
+		// Impersonation is only allowed for users authenticated by their own token.
+		if len(md[ImpersonateUserHeader]) > 0 {
+			return nil, nil, ErrPermissionDenied
+		}
 		allocationSession, err := getAllocationSessionBun(ctx)
 		if err != nil {
 			return nil, nil, err
@@ -138,7 +214,11 @@ func GetUser(ctx context.Context) (*model.User, *model.UserSession, error) {
 			return nil, nil, status.Error(codes.InvalidArgument,
 				"allocation session has no associated user")
 		}
-		u, err := user.ByID(ctx, *allocationSession.OwnerID)
+		var u *model.FullUser
+		err = authLookup(ctx, func(ctx context.Context) (err error) {
+			u, err = user.ByID(ctx, *allocationSession.OwnerID)
+			return err
+		})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -154,7 +234,10 @@ func GetUser(ctx context.Context) (*model.User, *model.UserSession, error) {
 	var userModel *model.User
 	var session *model.UserSession
 	var err error
-	userModel, session, err = user.ByToken(ctx, token, &extConfig)
+	err = authLookup(ctx, func(ctx context.Context) (err error) {
+		userModel, session, err = userByToken(ctx, token, &extConfig)
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) ||
 			errors.Is(err, db.ErrNotFound) ||
@@ -167,9 +250,70 @@ func GetUser(ctx context.Context) (*model.User, *model.UserSession, error) {
 	if !userModel.Active {
 		return nil, nil, ErrPermissionDenied
 	}
+	if names := md[ImpersonateUserHeader]; len(names) > 0 {
+		userModel, err = impersonateUser(ctx, userModel, names[0])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	return userModel, session, nil
 }
 
+// impersonateUser returns the user with the given username for an admin caller to
+// make the request as, recording the impersonation in the audit log. The session
+// remains that of the caller.
+func impersonateUser(
+	ctx context.Context, caller *model.User, username string,
+) (*model.User, error) {
+	if !caller.Admin {
+		return nil, ErrPermissionDenied
+	}
+
+	var target *model.User
+	err := authLookup(ctx, func(ctx context.Context) (err error) {
+		target, err = user.ByUsername(ctx, username)
+		return err
+	})
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return nil, status.Errorf(codes.InvalidArgument, "user to impersonate %q not found", username)
+	case err != nil:
+		return nil, err
+	case !target.Active:
+		return nil, ErrNotActive
+	}
+
+	method, _ := grpc.Method(ctx)
+	audit.Log(log.Fields{
+		"type":               "user_impersonation",
+		"endpoint":           method,
+		"userID":             caller.ID,
+		"impersonatedUserID": target.ID,
+	})
+	return target, nil
+}
+
+// RevokeUserToken revokes the token the current request was authenticated with, so that
+// GetUser rejects it from then on even if it is replayed before the session expires.
+func RevokeUserToken(ctx context.Context, session *model.UserSession) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ErrTokenMissing
+	}
+	tokens := md[userTokenHeader]
+	if len(tokens) == 0 {
+		tokens = md[gatewayTokenHeader]
+	}
+	if len(tokens) == 0 {
+		return ErrTokenMissing
+	}
+	token := tokens[0]
+	if !strings.HasPrefix(token, "Bearer ") {
+		return ErrInvalidCredentials
+	}
+	return user.RevokeToken(ctx, strings.TrimPrefix(token, "Bearer "), session.Expiry)
+}
+
 // GetUserExternalToken returns the external token for the currently logged in user.
 func GetUserExternalToken(ctx context.Context) (string, error) {
 	if config.GetMasterConfig().InternalConfig.ExternalSessions.JwtKey == "" {
@@ -194,30 +338,67 @@ func GetUserExternalToken(ctx context.Context) (string, error) {
 func auth(ctx context.Context, db *db.PgDB, fullMethod string,
 	extConfig *model.ExternalSessions,
 ) (*model.User, *model.UserSession, error) {
-	if unauthenticatedMethods[fullMethod] {
+	if isUnauthenticatedMethod(fullMethod) {
 		return nil, nil, nil
 	}
 
 	return GetUser(ctx)
 }
 
+// auditAuth records the outcome of authenticating a call to an endpoint, if audit
+// logging is enabled. Calls to unauthenticated methods are recorded as such, and
+// denied calls include the reason.
+func auditAuth(fullMethod string, user *model.User, err error) {
+	if !config.GetMasterConfig().InternalConfig.AuditLoggingEnabled {
+		return
+	}
+
+	fields := log.Fields{
+		"type":              "grpc_auth_audit",
+		"endpoint":          fullMethod,
+		"timestamp":         time.Now().UTC().Format(time.RFC3339Nano),
+		"permissionGranted": err == nil,
+	}
+	switch {
+	case err != nil:
+		fields["outcome"] = "denied"
+		fields["reason"] = err.Error()
+	case isUnauthenticatedMethod(fullMethod):
+		fields["outcome"] = "unauthenticated"
+	default:
+		fields["outcome"] = "allowed"
+	}
+	if user != nil {
+		fields["userID"] = user.ID
+	}
+	audit.Log(fields)
+}
+
 func streamAuthInterceptor(db *db.PgDB,
 	extConfig *model.ExternalSessions,
 ) grpc.StreamServerInterceptor {
 	return func(
 		srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
 	) error {
-		// Don't cache the result of the stream auth interceptor because
-		// we can't easily modify ss's context and
-		// we would have to worry about the user session expiring in the context.
-		_, _, err := auth(ss.Context(), db, info.FullMethod, extConfig)
+		// Streams may outlive the user's session; GetUser re-authenticates once the
+		// cached session has expired.
+		user, session, err := auth(ss.Context(), db, info.FullMethod, extConfig)
+		auditAuth(info.FullMethod, user, err)
+		if err != nil {
+			return err
+		}
+
 		fields := log.Fields{"endpoint": info.FullMethod}
+		ctx := context.WithValue(ss.Context(), audit.LogKey{}, fields)
+		if user != nil {
+			ctx = context.WithValue(ctx, userContextKey{}, user)
+		}
+		if session != nil {
+			ctx = context.WithValue(ctx, userSessionContextKey{}, session)
+		}
 		wrappedSS := grpc_middleware.WrappedServerStream{
 			ServerStream:   ss,
-			WrappedContext: context.WithValue(ss.Context(), audit.LogKey{}, fields),
-		}
-		if err != nil {
-			return err
+			WrappedContext: ctx,
 		}
 
 		return handler(srv, &wrappedSS)
@@ -231,6 +412,7 @@ func unaryAuthInterceptor(db *db.PgDB,
 		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
 	) (resp interface{}, err error) {
 		user, session, err := auth(ctx, db, info.FullMethod, extConfig)
+		auditAuth(info.FullMethod, user, err)
 		if err != nil {
 			return nil, err
 		}
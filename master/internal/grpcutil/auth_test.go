@@ -0,0 +1,175 @@
+package grpcutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func TestAuthLookup(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	err := authLookup(ctx, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = authLookup(ctx, func(context.Context) error {
+		calls++
+		return driver.ErrBadConn
+	})
+	require.ErrorIs(t, err, driver.ErrBadConn)
+	require.Equal(t, 1+authLookupRetries, calls)
+
+	calls = 0
+	err = authLookup(ctx, func(context.Context) error {
+		calls++
+		return db.ErrNotFound
+	})
+	require.ErrorIs(t, err, db.ErrNotFound)
+	require.Equal(t, 1, calls)
+
+	internalConfig := &config.GetMasterConfig().InternalConfig
+	internalConfig.AuthLookupTimeout = ptrs.Ptr(model.Duration(10 * time.Millisecond))
+	defer func() { internalConfig.AuthLookupTimeout = nil }()
+	err = authLookup(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.Equal(t, ErrAuthLookupTimeout, err)
+}
+
+func TestUnauthenticatedMethodSkipsGetUser(t *testing.T) {
+	const method = "/determined.api.v1.Determined/TestUnauthenticatedHealth"
+	interceptor := unaryAuthInterceptor(nil, nil)
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	// Without credentials, GetUser fails for methods that are not exempt.
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	require.Equal(t, ErrTokenMissing, err)
+
+	RegisterUnauthenticatedMethod(method)
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+}
+
+func TestAuditAuth(t *testing.T) {
+	const (
+		method                = "/determined.api.v1.Determined/TestAuditAuth"
+		unauthenticatedMethod = "/determined.api.v1.Determined/TestAuditAuthHealth"
+	)
+	RegisterUnauthenticatedMethod(unauthenticatedMethod)
+	hook := logrustest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	// Nothing is recorded unless audit logging is enabled.
+	auditAuth(method, nil, ErrTokenMissing)
+	require.Empty(t, hook.AllEntries())
+
+	internalConfig := &config.GetMasterConfig().InternalConfig
+	internalConfig.AuditLoggingEnabled = true
+	defer func() { internalConfig.AuditLoggingEnabled = false }()
+
+	auditAuth(method, &model.User{ID: 7}, nil)
+	entry := hook.LastEntry()
+	require.Equal(t, method, entry.Data["endpoint"])
+	require.Equal(t, "allowed", entry.Data["outcome"])
+	require.Equal(t, model.UserID(7), entry.Data["userID"])
+	require.Equal(t, true, entry.Data["permissionGranted"])
+	require.NotEmpty(t, entry.Data["timestamp"])
+
+	auditAuth(method, nil, ErrTokenMissing)
+	entry = hook.LastEntry()
+	require.Equal(t, "denied", entry.Data["outcome"])
+	require.Equal(t, ErrTokenMissing.Error(), entry.Data["reason"])
+	require.Equal(t, false, entry.Data["permissionGranted"])
+	require.NotContains(t, entry.Data, "userID")
+
+	auditAuth(unauthenticatedMethod, nil, nil)
+	entry = hook.LastEntry()
+	require.Equal(t, "unauthenticated", entry.Data["outcome"])
+	require.Equal(t, unauthenticatedMethod, entry.Data["endpoint"])
+}
+
+func TestImpersonateUserRequiresAdmin(t *testing.T) {
+	_, err := impersonateUser(context.Background(), &model.User{ID: 7}, "someone")
+	require.Equal(t, ErrPermissionDenied, err)
+
+	// The header may not be combined with an allocation token.
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		AllocationTokenHeader, "Bearer token",
+		ImpersonateUserHeader, "someone",
+	))
+	_, _, err = GetUser(ctx)
+	require.Equal(t, ErrPermissionDenied, err)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestStreamAuthInterceptorCachesUser(t *testing.T) {
+	lookups := 0
+	expiry := time.Now().Add(time.Hour)
+	userByToken = func(context.Context, string, *model.ExternalSessions) (
+		*model.User, *model.UserSession, error,
+	) {
+		lookups++
+		if expiry.Before(time.Now()) {
+			return nil, nil, db.ErrNotFound
+		}
+		return &model.User{ID: 7, Active: true}, &model.UserSession{UserID: 7, Expiry: expiry}, nil
+	}
+	defer func() { userByToken = user.ByToken }()
+
+	ss := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(userTokenHeader, "Bearer token"))}
+	info := &grpc.StreamServerInfo{FullMethod: "/determined.api.v1.Determined/TestStream"}
+	interceptor := streamAuthInterceptor(nil, nil)
+
+	// The handler sees the user authenticated by the interceptor without another lookup.
+	err := interceptor(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		u, session, err := GetUser(stream.Context())
+		require.NoError(t, err)
+		require.Equal(t, model.UserID(7), u.ID)
+		require.Equal(t, model.UserID(7), session.UserID)
+		require.Equal(t, 1, lookups)
+
+		// Once the session expires, the token is checked again.
+		expiry = time.Now().Add(-time.Second)
+		session.Expiry = expiry
+		_, _, err = GetUser(stream.Context())
+		require.Equal(t, ErrInvalidCredentials, err)
+		require.Equal(t, 2, lookups)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -156,6 +156,21 @@ func (a *ResourceManager) HealthCheck() []model.ResourceManagerHealth {
 	}
 }
 
+// Capabilities implements rm.ResourceManager.
+func (a *ResourceManager) Capabilities() []*apiv1.ResourceManagerCapabilities {
+	return []*apiv1.ResourceManagerCapabilities{
+		{
+			ResourceManagerName:  a.config.Name,
+			Type:                 "agent",
+			SupportsPriority:     true,
+			SupportsWeight:       true,
+			SupportsMoveJob:      true,
+			SupportsDisableAgent: true,
+			SupportsDisableSlot:  true,
+		},
+	}
+}
+
 // DisableSlot implements rm.ResourceManager.
 func (a *ResourceManager) DisableSlot(req *apiv1.DisableSlotRequest) (*apiv1.DisableSlotResponse, error) {
 	deviceIDStr, err := strconv.Atoi(req.SlotId)
@@ -566,6 +581,38 @@ func (a *ResourceManager) ValidateResourcePool(name rm.ResourcePoolName) error {
 	return nil
 }
 
+// GetClusterUtilization is unsupported.
+func (a *ResourceManager) GetClusterUtilization() (*apiv1.GetClusterUtilizationResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// CleanupOrphanedDispatches is unsupported.
+func (a *ResourceManager) CleanupOrphanedDispatches() (*apiv1.CleanupOrphanedDispatchesResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// GetDispatchUsage is unsupported.
+func (a *ResourceManager) GetDispatchUsage() (*apiv1.GetDispatchUsageResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// PauseResourcePool is unsupported.
+func (a *ResourceManager) PauseResourcePool(rm.ResourcePoolName) error {
+	return rmerrors.UnsupportedError("pausing resource pools is unsupported in the agent RM")
+}
+
+// GetResourcePoolPartition is unsupported.
+func (a *ResourceManager) GetResourcePoolPartition(
+	rm.ResourcePoolName,
+) (*apiv1.GetResourcePoolPartitionResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// ResumeResourcePool is unsupported.
+func (a *ResourceManager) ResumeResourcePool(rm.ResourcePoolName) error {
+	return rmerrors.UnsupportedError("pausing resource pools is unsupported in the agent RM")
+}
+
 func (a *ResourceManager) createResourcePool(
 	db db.DB, config config.ResourcePoolConfig, cert *tls.Certificate,
 ) (*resourcePool, error) {
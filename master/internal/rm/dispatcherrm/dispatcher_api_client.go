@@ -2,10 +2,12 @@ package dispatcherrm
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
@@ -24,6 +26,8 @@ const (
 	blankImpersonatedUser = ""
 	resourceQueryName     = "DAI-HPC-Resources"
 	queueQueryName        = "DAI-HPC-Queues"
+	usageQueryName        = "DAI-HPC-Job-Usage"
+	fairShareQueryName    = "DAI-HPC-Fair-Share"
 )
 
 // One time activity to create a manifest using SlurmResources carrier.
@@ -36,6 +40,58 @@ var hpcResourcesManifest = createSlurmResourcesManifest()
 // pending/running HPC jobs.
 var hpcQueueManifest = createHpcQueueManifest()
 
+// One time activity to create a manifest using SlurmFairShare carrier.
+// This manifest is used on demand to retrieve the fair-share standing of
+// the Slurm accounts.
+var hpcFairShareManifest = createHpcFairShareManifest()
+
+// launcherClient is the subset of the launcher API used by the dispatcher RM and its
+// HPC resource details cache. It is implemented by launcherAPIClient, and exists so that
+// tests may provide a fake in place of a live launcher.
+type launcherClient interface {
+	getVersion(ctx context.Context, launcherAPILogger *logrus.Entry) (*semver.Version, error)
+	launchDispatcherJob(
+		manifest *launcher.Manifest,
+		impersonatedUser string,
+		allocationID string,
+		launcherAPILogger *logrus.Entry,
+	) (launcher.DispatchInfo, *http.Response, error)
+	launchHPCResourcesJob(launcherAPILogger *logrus.Entry) (
+		launcher.DispatchInfo, *http.Response, error,
+	)
+	launchHPCJobUsageJob(hpcJobID string, launcherAPILogger *logrus.Entry) (
+		launcher.DispatchInfo, *http.Response, error,
+	)
+	launchHPCFairShareJob(launcherAPILogger *logrus.Entry) (
+		launcher.DispatchInfo, *http.Response, error,
+	)
+	listAllRunning(launcherAPILogger *logrus.Entry) (
+		map[string][]launcher.DispatchInfo, *http.Response, error,
+	)
+	getManagementStatus(owner string, dispatchID string, launcherAPILogger *logrus.Entry) (
+		launcher.DispatchManagementStatus, *http.Response, error,
+	)
+	terminateDispatch(owner string, dispatchID string, launcherAPILogger *logrus.Entry) (
+		launcher.DispatchInfo, *http.Response, error,
+	)
+	terminateDispatches(owner string, dispatchIDs []string, launcherAPILogger *logrus.Entry) map[string]error
+	deleteDispatch(owner, dispatchID string, launcherAPILogger *logrus.Entry) (*http.Response, error)
+	loadEnvironmentLog(
+		owner string,
+		dispatchID string,
+		logFileName string,
+		launcherAPILogger *logrus.Entry,
+	) (string, *http.Response, error)
+	loadEnvironmentLogSpooled(
+		owner string,
+		dispatchID string,
+		logFileName string,
+		spoolThreshold int,
+		launcherAPILogger *logrus.Entry,
+	) (io.ReadCloser, *http.Response, error)
+	handleLauncherError(r *http.Response, errPrefix string, err error) string
+}
+
 type launcherAPIClient struct {
 	*launcher.APIClient
 
@@ -43,6 +99,32 @@ type launcherAPIClient struct {
 	mu       sync.RWMutex
 	auth     string
 	authFile string
+	// requestTimeout bounds each request to the launcher. Zero applies no deadline.
+	requestTimeout time.Duration
+}
+
+// launcherRequestTimeoutError is the error of a launcher request that was abandoned after the
+// request timeout, so that callers may tell a launcher that does not answer from one that
+// fails the request.
+type launcherRequestTimeoutError struct {
+	apiName string
+	timeout time.Duration
+	err     error
+}
+
+func (e launcherRequestTimeoutError) Error() string {
+	return fmt.Sprintf("launcher request %s timed out after %s: %v", e.apiName, e.timeout, e.err)
+}
+
+func (e launcherRequestTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// isLauncherRequestTimeout returns whether the error is that of a launcher request that
+// timed out.
+func isLauncherRequestTimeout(err error) bool {
+	var timeoutErr launcherRequestTimeoutError
+	return errors.As(err, &timeoutErr)
 }
 
 func newLauncherAPIClient(cfg *config.DispatcherResourceManagerConfig) (*launcherAPIClient, error) {
@@ -52,10 +134,12 @@ func newLauncherAPIClient(cfg *config.DispatcherResourceManagerConfig) (*launche
 	lcfg.Host = fmt.Sprintf("%s:%d", cfg.LauncherHost, cfg.LauncherPort)
 	lcfg.Scheme = cfg.LauncherProtocol // "http" or "https"
 	if cfg.Security != nil {
-		transport := cleanhttp.DefaultTransport()
-		transport.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: cfg.Security.TLS.SkipVerify, //nolint:gosec
+		tlsConfig, err := newLauncherTLSConfig(cfg.Security)
+		if err != nil {
+			return nil, err
 		}
+		transport := cleanhttp.DefaultTransport()
+		transport.TLSClientConfig = tlsConfig
 
 		client := cleanhttp.DefaultClient()
 		client.Transport = transport
@@ -67,6 +151,8 @@ func newLauncherAPIClient(cfg *config.DispatcherResourceManagerConfig) (*launche
 		log:       log,
 		APIClient: launcher.NewAPIClient(lcfg),
 		authFile:  cfg.LauncherAuthFile,
+
+		requestTimeout: cfg.ResolveLauncherRequestTimeout(),
 	}
 
 	err := c.loadAuthToken()
@@ -84,6 +170,26 @@ func (c *launcherAPIClient) withAuth(ctx context.Context) context.Context {
 	return context.WithValue(ctx, launcher.ContextAccessToken, c.auth)
 }
 
+// withRequestTimeout returns a context with launcher API auth added for a single request to
+// the launcher, which is canceled after the request timeout, and a function to defer until
+// the request returns. The function releases the context and replaces the error of a request
+// that timed out with a launcherRequestTimeoutError.
+func (c *launcherAPIClient) withRequestTimeout(
+	ctx context.Context, apiName string, err *error,
+) (context.Context, func()) {
+	cancel := func() {}
+	if c.requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+	}
+	ctx = c.withAuth(ctx)
+	return ctx, func() {
+		if *err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			*err = launcherRequestTimeoutError{apiName: apiName, timeout: c.requestTimeout, err: *err}
+		}
+		cancel()
+	}
+}
+
 func (c *launcherAPIClient) loadAuthToken() error {
 	if len(c.authFile) > 0 {
 		auth, err := os.ReadFile(c.authFile)
@@ -117,10 +223,13 @@ func (c *launcherAPIClient) getVersion(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("get_version")()
-	defer recordAPIErr("get_version")(err)
+	defer recordAPIErr("get_version")(&err)
+
+	ctx, done := c.withRequestTimeout(ctx, "getVersion", &err)
+	defer done()
 
 	resp, _, err := c.InfoApi.
-		GetServerVersion(c.withAuth(ctx)).
+		GetServerVersion(ctx).
 		Execute() //nolint:bodyclose
 	if err != nil {
 		return nil, fmt.Errorf("getting launcher version: %w", err)
@@ -144,7 +253,10 @@ func (c *launcherAPIClient) launchDispatcherJob(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("launch_dispatcher_job")()
-	defer recordAPIErr("launch_dispatcher_job")(err)
+	defer recordAPIErr("launch_dispatcher_job")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "launchDispatcherJob", &err)
+	defer done()
 
 	/*
 	 * "Launch()" waits until the job has been submitted to the Workload manager
@@ -160,7 +272,7 @@ func (c *launcherAPIClient) launchDispatcherJob(
 	 * (e.g. "/etc/passwd"), LDAP, or some other authentication mechanism.
 	 */
 	return c.LaunchApi.
-		Launch(c.withAuth(context.TODO())).
+		Launch(ctx).
 		Manifest(*manifest).
 		Impersonate(impersonatedUser).
 		DispatchId(allocationID).
@@ -177,14 +289,37 @@ func (c *launcherAPIClient) getEnvironmentStatus(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("get_environment_status")()
-	defer recordAPIErr("get_environment_status")(err)
+	defer recordAPIErr("get_environment_status")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "getEnvironmentStatus", &err)
+	defer done()
 
 	return c.MonitoringApi.
-		GetEnvironmentStatus(c.withAuth(context.TODO()), owner, dispatchID).
+		GetEnvironmentStatus(ctx, owner, dispatchID).
 		Refresh(true).
 		Execute() //nolint:bodyclose
 }
 
+func (c *launcherAPIClient) getManagementStatus(
+	owner string,
+	dispatchID string,
+	launcherAPILogger *logrus.Entry,
+) (status launcher.DispatchManagementStatus, response *http.Response, err error) {
+	launcherAPILogger = launcherAPILogger.WithField("dispatch-id", dispatchID).
+		WithField("api-name", "getManagementStatus")
+
+	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
+	defer recordAPITiming("get_management_status")()
+	defer recordAPIErr("get_management_status")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "getManagementStatus", &err)
+	defer done()
+
+	return c.MonitoringApi.
+		CanManageEnvironment(ctx, owner, dispatchID).
+		Execute() //nolint:bodyclose
+}
+
 func (c *launcherAPIClient) getEnvironmentDetails(
 	owner string,
 	dispatchID string,
@@ -195,10 +330,13 @@ func (c *launcherAPIClient) getEnvironmentDetails(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("get_environment_details")()
-	defer recordAPIErr("get_environment_details")(err)
+	defer recordAPIErr("get_environment_details")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "getEnvironmentDetails", &err)
+	defer done()
 
 	return c.MonitoringApi.
-		GetEnvironmentDetails(c.withAuth(context.TODO()), owner, dispatchID).
+		GetEnvironmentDetails(ctx, owner, dispatchID).
 		Execute() //nolint:bodyclose
 }
 
@@ -211,12 +349,15 @@ func (c *launcherAPIClient) launchHPCResourcesJob(launcherAPILogger *logrus.Entr
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("launch_hpc_resources_job")()
-	defer recordAPIErr("launch_hpc_resources_job")(err)
+	defer recordAPIErr("launch_hpc_resources_job")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "launchHPCResourcesJob", &err)
+	defer done()
 
 	// Launch the HPC Resources manifest. Launch() method will ensure
 	// the manifest is in the RUNNING state on successful completion.
 	return c.LaunchApi.
-		Launch(c.withAuth(context.TODO())).
+		Launch(ctx).
 		Manifest(hpcResourcesManifest).
 		Impersonate(blankImpersonatedUser).
 		Execute() //nolint:bodyclose
@@ -231,17 +372,66 @@ func (c *launcherAPIClient) launchHPCQueueJob(launcherAPILogger *logrus.Entry) (
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("launch_hpc_queue_job")()
-	defer recordAPIErr("launch_hpc_queue_job")(err)
+	defer recordAPIErr("launch_hpc_queue_job")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "launchHPCQueueJob", &err)
+	defer done()
 
 	// Launch the HPC Resources manifest. Launch() method will ensure
 	// the manifest is in the RUNNING state on successful completion.
 	return c.LaunchApi.
-		Launch(c.withAuth(context.TODO())).
+		Launch(ctx).
 		Manifest(hpcQueueManifest).
 		Impersonate(blankImpersonatedUser).
 		Execute() //nolint:bodyclose
 }
 
+// launchHPCJobUsageJob queries the accounting of the workload manager, e.g. sacct, for the
+// resource usage of the given HPC job.
+func (c *launcherAPIClient) launchHPCJobUsageJob(hpcJobID string, launcherAPILogger *logrus.Entry) (
+	info launcher.DispatchInfo,
+	resp *http.Response,
+	err error,
+) {
+	launcherAPILogger = launcherAPILogger.WithField("api-name", "launchHPCJobUsageJob")
+
+	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
+	defer recordAPITiming("launch_hpc_job_usage_job")()
+	defer recordAPIErr("launch_hpc_job_usage_job")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "launchHPCJobUsageJob", &err)
+	defer done()
+
+	return c.LaunchApi.
+		Launch(ctx).
+		Manifest(createHpcJobUsageManifest(hpcJobID)).
+		Impersonate(blankImpersonatedUser).
+		Execute() //nolint:bodyclose
+}
+
+// launchHPCFairShareJob queries the fair-share standing of the accounts of the workload
+// manager, e.g. sshare.
+func (c *launcherAPIClient) launchHPCFairShareJob(launcherAPILogger *logrus.Entry) (
+	info launcher.DispatchInfo,
+	resp *http.Response,
+	err error,
+) {
+	launcherAPILogger = launcherAPILogger.WithField("api-name", "launchHPCFairShareJob")
+
+	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
+	defer recordAPITiming("launch_hpc_fair_share_job")()
+	defer recordAPIErr("launch_hpc_fair_share_job")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "launchHPCFairShareJob", &err)
+	defer done()
+
+	return c.LaunchApi.
+		Launch(ctx).
+		Manifest(hpcFairShareManifest).
+		Impersonate(blankImpersonatedUser).
+		Execute() //nolint:bodyclose
+}
+
 func (c *launcherAPIClient) listAllTerminated(
 	launcherAPILogger *logrus.Entry,
 ) (dispatchInfo map[string][]launcher.DispatchInfo, response *http.Response, err error) {
@@ -249,10 +439,13 @@ func (c *launcherAPIClient) listAllTerminated(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("list_all_terminated")()
-	defer recordAPIErr("list_all_terminated")(err)
+	defer recordAPIErr("list_all_terminated")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "listAllTerminated", &err)
+	defer done()
 
 	return c.TerminatedApi.
-		ListAllTerminated(c.withAuth(context.TODO())).
+		ListAllTerminated(ctx).
 		EventLimit(0).
 		Execute() //nolint:bodyclose
 }
@@ -264,10 +457,13 @@ func (c *launcherAPIClient) listAllRunning(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("list_all_running")()
-	defer recordAPIErr("list_all_running")(err)
+	defer recordAPIErr("list_all_running")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "listAllRunning", &err)
+	defer done()
 
 	return c.RunningApi.
-		ListAllRunning(c.withAuth(context.TODO())).
+		ListAllRunning(ctx).
 		EventLimit(0).
 		Execute() //nolint:bodyclose
 }
@@ -280,16 +476,66 @@ func (c *launcherAPIClient) terminateDispatch(
 	info launcher.DispatchInfo,
 	resp *http.Response,
 	err error,
+) {
+	return c.terminate(context.TODO(), owner, dispatchID, launcherAPILogger)
+}
+
+// maxConcurrentTerminations bounds the termination requests that terminateDispatches has
+// outstanding with the launcher at once.
+const maxConcurrentTerminations = 8
+
+// terminateDispatches terminates the dispatches of the given owner concurrently, each
+// within its own request timeout, and returns the error of each dispatch that failed to be
+// terminated, keyed by its dispatch ID.
+func (c *launcherAPIClient) terminateDispatches(
+	owner string,
+	dispatchIDs []string,
+	launcherAPILogger *logrus.Entry,
+) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	sem := make(chan struct{}, maxConcurrentTerminations)
+	for _, dispatchID := range dispatchIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(dispatchID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, _, err := c.terminate(context.TODO(), owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+			if err != nil {
+				mu.Lock()
+				errs[dispatchID] = err
+				mu.Unlock()
+			}
+		}(dispatchID)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (c *launcherAPIClient) terminate(
+	ctx context.Context,
+	owner string,
+	dispatchID string,
+	launcherAPILogger *logrus.Entry,
+) (
+	info launcher.DispatchInfo,
+	resp *http.Response,
+	err error,
 ) {
 	launcherAPILogger = launcherAPILogger.WithField("dispatch-id", dispatchID).
 		WithField("api-name", "terminateDispatch")
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("terminate")()
-	defer recordAPIErr("terminate")(err)
+	defer recordAPIErr("terminate")(&err)
+
+	ctx, done := c.withRequestTimeout(ctx, "terminateDispatch", &err)
+	defer done()
 
 	info, resp, err = c.RunningApi.
-		TerminateRunning(c.withAuth(context.TODO()), owner, dispatchID).
+		TerminateRunning(ctx, owner, dispatchID).
 		Force(true).Execute() //nolint:bodyclose
 	switch {
 	case err != nil && resp != nil && resp.StatusCode == 404:
@@ -312,12 +558,15 @@ func (c *launcherAPIClient) deleteDispatch(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("delete_env")()
-	defer recordAPIErr("delete_env")(err)
+	defer recordAPIErr("delete_env")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "deleteDispatch", &err)
+	defer done()
 
 	launcherAPILogger.Debug("deleting environment")
 
 	resp, err = c.MonitoringApi.
-		DeleteEnvironment(c.withAuth(context.TODO()), owner, dispatchID).
+		DeleteEnvironment(ctx, owner, dispatchID).
 		Execute() //nolint:bodyclose
 	switch {
 	case err != nil && resp != nil && resp.StatusCode == 404:
@@ -341,10 +590,13 @@ func (c *launcherAPIClient) loadEnvironmentLog(
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
 	defer recordAPITiming("load_environment_log")()
-	defer recordAPIErr("load_environment_log")(err)
+	defer recordAPIErr("load_environment_log")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "loadEnvironmentLog", &err)
+	defer done()
 
 	data, resp, err = c.MonitoringApi.
-		LoadEnvironmentLog(c.withAuth(context.TODO()), owner, dispatchID, logFileName).
+		LoadEnvironmentLog(ctx, owner, dispatchID, logFileName).
 		Execute() //nolint:bodyclose
 	if err != nil {
 		return data, nil, fmt.Errorf(c.handleLauncherError(
@@ -353,6 +605,87 @@ func (c *launcherAPIClient) loadEnvironmentLog(
 	return data, resp, nil
 }
 
+// loadEnvironmentLogSpooled is loadEnvironmentLog for logs that may be large. The log is
+// streamed from the launcher, rather than buffered and copied by the generated client, and
+// spooled to a temporary file if it exceeds spoolThreshold bytes, so that it can be decoded
+// as it is read rather than held in memory. The caller must close the log.
+func (c *launcherAPIClient) loadEnvironmentLogSpooled(
+	owner string,
+	dispatchID string,
+	logFileName string,
+	spoolThreshold int,
+	launcherAPILogger *logrus.Entry,
+) (log io.ReadCloser, resp *http.Response, err error,
+) {
+	launcherAPILogger = launcherAPILogger.WithField("dispatch-id", dispatchID).
+		WithField("api-name", "loadEnvironmentLogSpooled")
+
+	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
+	defer recordAPITiming("load_environment_log")()
+	defer recordAPIErr("load_environment_log")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "loadEnvironmentLogSpooled", &err)
+	defer done()
+
+	req, err := c.newEnvironmentLogRequest(ctx, owner, dispatchID, logFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err = c.GetConfig().HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf(c.handleLauncherError(
+			resp, "Failed to retrieve HPC Resource details", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, nil, fmt.Errorf(c.handleLauncherError(
+			resp, "Failed to retrieve HPC Resource details", errors.New(resp.Status)))
+	}
+
+	log, err = readLauncherLog(resp.Body, spoolThreshold)
+	if err != nil {
+		return nil, resp, fmt.Errorf("reading log %s of dispatch %s: %w", logFileName, dispatchID, err)
+	}
+	return log, resp, nil
+}
+
+// newEnvironmentLogRequest builds the request for an environment log that the generated
+// client would send, with the auth of the given context.
+func (c *launcherAPIClient) newEnvironmentLogRequest(
+	ctx context.Context, owner string, dispatchID string, logFileName string,
+) (*http.Request, error) {
+	cfg := c.GetConfig()
+	basePath, err := cfg.ServerURLWithContext(ctx, "MonitoringApiService.LoadEnvironmentLog")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(basePath + "/monitoring/" + url.PathEscape(owner) +
+		"/environments/" + url.PathEscape(dispatchID) + "/logs/" + url.PathEscape(logFileName))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Host != "" {
+		u.Host = cfg.Host
+	}
+	if cfg.Scheme != "" {
+		u.Scheme = cfg.Scheme
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	if auth, ok := ctx.Value(launcher.ContextAccessToken).(string); ok {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+	for header, value := range cfg.DefaultHeader {
+		req.Header.Add(header, value)
+	}
+	return req, nil
+}
+
 func (c *launcherAPIClient) loadEnvironmentLogWithRange(
 	owner string,
 	dispatchID string,
@@ -365,11 +698,14 @@ func (c *launcherAPIClient) loadEnvironmentLogWithRange(
 		WithField("api-name", "loadEnvironmentLogWithRange")
 
 	defer c.logExcessiveAPIResponseTimes(launcherAPILogger)()
-	defer recordAPITiming("launch_environment_log_with_range")()
-	defer recordAPIErr("launch_environment_log_with_range")(err)
+	defer recordAPITiming("load_environment_log_with_range")()
+	defer recordAPIErr("load_environment_log_with_range")(&err)
+
+	ctx, done := c.withRequestTimeout(context.TODO(), "loadEnvironmentLogWithRange", &err)
+	defer done()
 
 	return c.MonitoringApi.
-		LoadEnvironmentLog(c.withAuth(context.TODO()), owner, dispatchID, logFileName).
+		LoadEnvironmentLog(ctx, owner, dispatchID, logFileName).
 		Range_(logRange).
 		Execute() //nolint:bodyclose
 }
@@ -447,6 +783,56 @@ func createHpcQueueManifest() launcher.Manifest {
 	return manifest
 }
 
+// createHpcJobUsageManifest creates a Manifest for the Slurm/PBSJobUsage Carrier.
+// This Manifest is used to retrieve the resource usage of a completed HPC job.
+func createHpcJobUsageManifest(hpcJobID string) launcher.Manifest {
+	payload := launcher.NewPayloadWithDefaults()
+	payload.SetName(usageQueryName)
+	payload.SetId("com.cray.analytics.capsules.hpc.usage")
+	payload.SetVersion("latest")
+	payload.SetCarriers([]string{
+		"com.cray.analytics.capsules.carriers.hpc.slurm.SlurmJobUsage",
+		"com.cray.analytics.capsules.carriers.hpc.pbs.PbsJobUsage",
+	})
+
+	launchParameters := launcher.NewLaunchParameters()
+	launchParameters.SetMode("interactive")
+	launchParameters.SetArguments([]string{hpcJobID})
+	payload.SetLaunchParameters(*launchParameters)
+
+	clientMetadata := launcher.NewClientMetadataWithDefaults()
+	clientMetadata.SetName(usageQueryName)
+
+	manifest := *launcher.NewManifest("v1", *clientMetadata)
+	manifest.SetPayloads([]launcher.Payload{*payload})
+
+	return manifest
+}
+
+// createHpcFairShareManifest creates a Manifest for the SlurmFairShare Carrier.
+// This Manifest is used to retrieve the fair-share standing of the Slurm accounts.
+func createHpcFairShareManifest() launcher.Manifest {
+	payload := launcher.NewPayloadWithDefaults()
+	payload.SetName(fairShareQueryName)
+	payload.SetId("com.cray.analytics.capsules.hpc.fairshare")
+	payload.SetVersion("latest")
+	payload.SetCarriers([]string{
+		"com.cray.analytics.capsules.carriers.hpc.slurm.SlurmFairShare",
+	})
+
+	launchParameters := launcher.NewLaunchParameters()
+	launchParameters.SetMode("interactive")
+	payload.SetLaunchParameters(*launchParameters)
+
+	clientMetadata := launcher.NewClientMetadataWithDefaults()
+	clientMetadata.SetName(fairShareQueryName)
+
+	manifest := *launcher.NewManifest("v1", *clientMetadata)
+	manifest.SetPayloads([]launcher.Payload{*payload})
+
+	return manifest
+}
+
 // If we have a BadRequest/InternalServerError with a details
 // message in the response body, return it after appling our
 // filterOutSuperfluousMessages cleanup method; otherwise return an
@@ -0,0 +1,117 @@
+package dispatcherrm
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func TestLauncherRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the monitoring API answers.
+		if strings.Contains(r.URL.Path, "/monitoring/") {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host, portStr, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	c, err := newLauncherAPIClient(&config.DispatcherResourceManagerConfig{
+		LauncherHost:           host,
+		LauncherPort:           port,
+		LauncherProtocol:       "http",
+		LauncherRequestTimeout: ptrs.Ptr(model.Duration(100 * time.Millisecond)),
+	})
+	require.NoError(t, err)
+	log := logrus.WithField("component", "dispatcher-test")
+
+	// A launcher that does not answer fails the request with a timeout, rather than hanging.
+	start := time.Now()
+	_, _, err = c.launchHPCResourcesJob(log) //nolint:bodyclose
+	require.True(t, isLauncherRequestTimeout(err), err)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	_, _, err = c.terminateDispatch("alice", "dispatch-1", log) //nolint:bodyclose
+	require.True(t, isLauncherRequestTimeout(err), err)
+
+	// A request answered in time is not affected.
+	_, err = c.deleteDispatch("alice", "dispatch-1", log) //nolint:bodyclose
+	require.NoError(t, err)
+	require.False(t, isLauncherRequestTimeout(err))
+}
+
+func TestLoadEnvironmentLogSpooled(t *testing.T) {
+	log := strings.Repeat("partitions:\n- partitionName: gpus\n", 100)
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if !strings.HasSuffix(r.URL.Path, "/slurm-resources-info") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(log))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host, portStr, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	c, err := newLauncherAPIClient(&config.DispatcherResourceManagerConfig{
+		LauncherHost:     host,
+		LauncherPort:     port,
+		LauncherProtocol: "http",
+	})
+	require.NoError(t, err)
+	logger := logrus.WithField("component", "dispatcher-test")
+	t.Setenv("TMPDIR", t.TempDir())
+
+	// The log is the same whether it is buffered by the generated client, or spooled.
+	data, _, err := c.loadEnvironmentLog( //nolint:bodyclose
+		"alice", "dispatch-1", "slurm-resources-info", logger)
+	require.NoError(t, err)
+	require.Equal(t, log, data)
+	for _, threshold := range []int{16, len(log), 4 * len(log)} {
+		spooled, _, err := c.loadEnvironmentLogSpooled( //nolint:bodyclose
+			"alice", "dispatch-1", "slurm-resources-info", threshold, logger)
+		require.NoError(t, err)
+		data, err := io.ReadAll(spooled)
+		require.NoError(t, err)
+		require.NoError(t, spooled.Close())
+		require.Equal(t, log, string(data))
+	}
+	require.Len(t, paths, 4)
+	for _, path := range paths[1:] {
+		require.Equal(t, paths[0], path)
+	}
+
+	_, _, err = c.loadEnvironmentLogSpooled( //nolint:bodyclose
+		"alice", "dispatch-1", "missing", 16, logger)
+	require.ErrorContains(t, err, "Failed to retrieve HPC Resource details")
+}
@@ -2,10 +2,12 @@ package dispatcherrm
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
+	"golang.org/x/exp/maps"
 
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/tasks"
@@ -17,7 +19,7 @@ const (
 )
 
 // Possible names of dispatcher jobs generated by dispatcher RM.
-var ourJobNames = []string{tasks.ManifestName, resourceQueryName, queueQueryName}
+var ourJobNames = []string{tasks.ManifestName, resourceQueryName, queueQueryName, usageQueryName}
 
 // isForeignJob returns true if the HPC job has name but is not one of the names
 // employed by the dispatcher RM.
@@ -35,6 +37,26 @@ func isForeignJob(v launcher.DispatchInfo) bool {
 	return true
 }
 
+// isOwnedDispatch returns true if the payload names of the dispatch start with the
+// dispatch_payload_name_prefix that scopes the dispatches of this master, or if no
+// prefix is configured. When scoped, a dispatch without payloads is not owned, since
+// it cannot be attributed to this master.
+func isOwnedDispatch(v launcher.DispatchInfo, payloadNamePrefix *string) bool {
+	if payloadNamePrefix == nil {
+		return true
+	}
+	payloads := v.GetPayloadStates()
+	if len(payloads) == 0 {
+		return false
+	}
+	for name := range payloads {
+		if !strings.HasPrefix(name, *payloadNamePrefix) {
+			return false
+		}
+	}
+	return true
+}
+
 // gcOrphanedDispatches is a background method to do
 // a single garbage collection of orhaned dispatches.  Such
 // orphaned dispatches should be rare in normal circumstances, but
@@ -47,15 +69,18 @@ func isForeignJob(v launcher.DispatchInfo) bool {
 // on all orphaned running dispatches (orphaned PENDING/RUNNING need an
 // explicit termination to get to a terminated state -- in case there is no
 // job ID to check).   It then deletes all orphaned terminated dispatches.
-// Once gc completes, the  routine terminates.
+// Once gc completes, the  routine terminates. If payloadNamePrefix is set, only
+// the dispatches whose payload names start with it are considered, so that the
+// dispatches of other masters sharing the launcher are left alone.
 func gcOrphanedDispatches(
 	ctx context.Context,
 	log *logrus.Entry,
 	cl *launcherAPIClient,
+	payloadNamePrefix *string,
 ) {
 	time.Sleep(gcDelay)
-	gcTerminateRunningOrphans(ctx, log, cl)
-	gcTerminatedOrphans(ctx, log, cl)
+	gcTerminateRunningOrphans(ctx, log, cl, payloadNamePrefix)
+	gcTerminatedOrphans(ctx, log, cl, payloadNamePrefix)
 }
 
 // refreshRunningOrphans requests the launcher to terminate and update the state of
@@ -65,6 +90,7 @@ func gcTerminateRunningOrphans(
 	ctx context.Context,
 	log *logrus.Entry,
 	cl *launcherAPIClient,
+	payloadNamePrefix *string,
 ) {
 	// The logger we will pass to the API client, so that when the API client
 	// logs a message, we know who called it.
@@ -86,6 +112,12 @@ func gcTerminateRunningOrphans(
 				Debug("skipping foreign dispatch")
 			continue
 		}
+		if !isOwnedDispatch(v, payloadNamePrefix) {
+			log.WithField("dispatch-id", dispatchID).
+				WithField("payloads", maps.Keys(v.GetPayloadStates())).
+				Debug("skipping dispatch outside of dispatch_payload_name_prefix")
+			continue
+		}
 		_, err := db.DispatchByID(ctx, dispatchID)
 		if err == nil {
 			log.WithField("dispatch-id", dispatchID).
@@ -128,6 +160,7 @@ func gcTerminatedOrphans(
 	ctx context.Context,
 	log *logrus.Entry,
 	cl *launcherAPIClient,
+	payloadNamePrefix *string,
 ) {
 	// The logger we will pass to the API client, so that when the API client
 	// logs a message, we know who called it.
@@ -150,6 +183,12 @@ func gcTerminatedOrphans(
 				Debug("skipping foreign dispatch")
 			continue
 		}
+		if !isOwnedDispatch(v, payloadNamePrefix) {
+			log.WithField("dispatch-id", dispatchID).
+				WithField("payloads", maps.Keys(v.GetPayloadStates())).
+				Debug("skipping dispatch outside of dispatch_payload_name_prefix")
+			continue
+		}
 		_, err := db.DispatchByID(ctx, dispatchID)
 		if err == nil {
 			log.WithField("dispatch-id", dispatchID).
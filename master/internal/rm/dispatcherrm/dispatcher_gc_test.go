@@ -90,3 +90,56 @@ func Test_isForeignJob(t *testing.T) {
 		})
 	}
 }
+
+func Test_isOwnedDispatch(t *testing.T) {
+	payloads := func(names ...string) launcher.DispatchInfo {
+		states := map[string]launcher.DispatchState{}
+		for _, name := range names {
+			states[name] = launcher.RUNNING
+		}
+		return launcher.DispatchInfo{PayloadStates: &states}
+	}
+	tests := []struct {
+		name   string
+		v      launcher.DispatchInfo
+		prefix *string
+		want   bool
+	}{
+		{
+			name: "not scoped",
+			v:    payloads("other_exp-1-trial-1"),
+			want: true,
+		},
+		{
+			name:   "payload in scope",
+			v:      payloads("site-a-prodexp-1-trial-1"),
+			prefix: launcher.PtrString("site-a"),
+			want:   true,
+		},
+		{
+			name:   "payload of another master",
+			v:      payloads("site-bexp-1-trial-1"),
+			prefix: launcher.PtrString("site-a"),
+			want:   false,
+		},
+		{
+			name:   "one payload out of scope",
+			v:      payloads("site-aexp-1-trial-1", "job"),
+			prefix: launcher.PtrString("site-a"),
+			want:   false,
+		},
+		{
+			name:   "no payloads",
+			v:      launcher.DispatchInfo{},
+			prefix: launcher.PtrString("site-a"),
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOwnedDispatch(tt.v, tt.prefix); got != tt.want {
+				t.Errorf("isOwnedDispatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,7 +26,6 @@ import (
 
 //nolint:lll
 const (
-	pollLoopInterval         = time.Duration(10) * time.Second
 	ignoredReporter          = "com.cray.analytics.capsules.dispatcher.shasta.ShastaDispatcher"
 	errorLinesToRetrieve     = 500
 	errorLinesToDisplay      = 15
@@ -80,6 +80,7 @@ type launcherJob struct {
 	hpcJobID                      string
 	payloadName                   string
 	jobPendingReasonCode          string
+	jobPendingReasonDesc          string
 	lastJobStatusCheckTime        time.Time
 	lastJobTerminationRequestTime time.Time
 	totalContainers               int
@@ -87,6 +88,7 @@ type launcherJob struct {
 	jobWasTerminated              bool
 	launchInProgress              bool // Launch proceeding concurrent with monitoring
 	position                      atomic.Int32
+	lastJobState                  launcher.DispatchState
 }
 
 // launcherMonitorEvent is a union of all events emitted by the launcherMonitor.
@@ -97,6 +99,7 @@ type launcherMonitorEvent interface {
 func (dispatchExpLogMessage) launcherMonitorEvent() {}
 func (DispatchExited) launcherMonitorEvent()        {}
 func (DispatchStateChange) launcherMonitorEvent()   {}
+func (dispatchNodesAssigned) launcherMonitorEvent() {}
 
 // launcherMonitor describes the monitoring of jobs created by the launcher.
 type launcherMonitor struct {
@@ -108,7 +111,9 @@ type launcherMonitor struct {
 	outbox chan<- launcherMonitorEvent
 
 	// immutable state.
-	schedulerTick *time.Ticker
+	schedulerTick       *time.Ticker
+	pendingPollInterval time.Duration
+	pollInterval        time.Duration
 
 	// mutable internal state. requires a lock if not threadsafe already.
 	monitoredJobs         mapx.Map[string, *launcherJob]
@@ -129,10 +134,15 @@ type dispatchLastJobStatusCheckTime struct {
 	lastJobStatusCheckTime time.Time
 }
 
+// newDispatchWatcher creates a job watcher that polls the status of the jobs every
+// pollInterval, except for the jobs last seen pending, which are polled every
+// pendingPollInterval.
 func newDispatchWatcher(
 	apiClient *launcherAPIClient,
 	dispatchIDToHPCJobID *mapx.Map[string, string],
 	outbox chan<- launcherMonitorEvent,
+	pollInterval time.Duration,
+	pendingPollInterval time.Duration,
 ) *launcherMonitor {
 	return &launcherMonitor{
 		syslog: logrus.WithField("component", "dispatchwatcher"),
@@ -146,7 +156,9 @@ func newDispatchWatcher(
 		removeLauncherJob: make(chan *launcherJob),
 		checkLauncherJob:  make(chan *launcherJob),
 		// Poll job status this often
-		schedulerTick:        time.NewTicker(pollLoopInterval),
+		schedulerTick:        time.NewTicker(pollInterval),
+		pollInterval:         pollInterval,
+		pendingPollInterval:  pendingPollInterval,
 		dispatchIDToHPCJobID: dispatchIDToHPCJobID,
 	}
 }
@@ -247,11 +259,10 @@ func (m *launcherMonitor) watch() {
 		case <-m.schedulerTick.C:
 			// Protect against running another "processWatchedJobs()" goroutine
 			// while the previous one is still running. The "schedulerTick"
-			// message is received every 10 seconds, as per the value we set
-			// "pollLoopIntervalSecs". If we have a lot of jobs to monitor, it
-			// is possible that "processWatchedJobs()" will still be querying
-			// the launcher for job status when the next "schedulerTick" message
-			// arrives 10 seconds later.
+			// message is received every "pollInterval" (10 seconds by
+			// default). If we have a lot of jobs to monitor, it is possible
+			// that "processWatchedJobs()" will still be querying the launcher
+			// for job status when the next "schedulerTick" message arrives.
 			//
 			// We really don't need a mutex for testing and setting the
 			// "processingWatchedJobs" boolean, because the "watch()" method
@@ -323,6 +334,55 @@ func filterOutSuperfluousMessages(allMessages []string) []string {
 	return messagesMatchingPattern
 }
 
+// findContainerDispatch returns which of the given dispatches of an allocation owns the
+// container that reported that it is running with the given rank on the given node, so
+// that the notification is counted by exactly one dispatch. A dispatch that already
+// recorded the rank on that node owns it. Otherwise, it is a dispatch that has not recorded
+// the rank and expects the same number of containers, preferring the one whose ID is the
+// allocation ID, as is the case for all dispatches launched since 0.22.2. The second result
+// is false if none of the dispatches is monitored.
+func (m *launcherMonitor) findContainerDispatch(
+	allocationID string,
+	dispatchIDs []string,
+	rank int32,
+	numPeers int32,
+	nodeName string,
+) (string, bool) {
+	var candidates []string
+	var conflicting string
+	monitored := false
+	for _, dispatchID := range dispatchIDs {
+		job, ok := m.monitoredJobs.Load(dispatchID)
+		if !ok {
+			continue
+		}
+		monitored = true
+
+		switch existingEntry, ok := job.runningContainers.Load(int(rank)); {
+		case ok && existingEntry.nodeName == nodeName:
+			return dispatchID, true
+		case ok:
+			// The rank is owned by a container on another node.
+			if conflicting == "" {
+				conflicting = dispatchID
+			}
+		case job.totalContainers == 0 || job.totalContainers == int(numPeers):
+			candidates = append(candidates, dispatchID)
+		}
+	}
+
+	for _, dispatchID := range candidates {
+		if dispatchID == allocationID {
+			return dispatchID, true
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], true
+	}
+	// Let the dispatch that recorded the rank on another node report the conflict.
+	return conflicting, monitored
+}
+
 // Receive notification that one of the ranks of the job has started
 // execution.  It generates experiment log messages as the ranks
 // start, and updates job info so we know when all have started.
@@ -374,6 +434,15 @@ func (m *launcherMonitor) notifyContainerRunning(
 		m.syslog.WithField("dispatch-id", dispatchID).
 			Info(startedMsg)
 
+		// Once every container has reported in, the placement of the job is
+		// complete, so report the nodes that it landed on.
+		if m.allContainersRunning(job) {
+			m.outbox <- dispatchNodesAssigned{
+				DispatchID: dispatchID,
+				Nodes:      getNodesRunningContainers(job),
+			}
+		}
+
 	// Rank already existed in the map. This is not expected, as each container
 	// should only send the notification that it's running only once.
 	case existingEntry.nodeName != nodeName:
@@ -420,6 +489,23 @@ func getNodesThatAreRunningContainer(job *launcherJob) string {
 	return sb.String()
 }
 
+// Returns the sorted, distinct names of the nodes that have notified the
+// Determined master that they are running the container.
+func getNodesRunningContainers(job *launcherJob) []string {
+	var nodes []string
+
+	job.runningContainers.WithLock(func(inmap map[int]containerInfo) {
+		for _, v := range inmap {
+			if !slices.Contains(nodes, v.nodeName) {
+				nodes = append(nodes, v.nodeName)
+			}
+		}
+	})
+
+	slices.Sort(nodes)
+	return nodes
+}
+
 // Returns true if all the containers have notified the Determined Master that
 // they are running; false otherwise.
 func (m *launcherMonitor) allContainersRunning(job *launcherJob) bool {
@@ -519,6 +605,11 @@ func (m *launcherMonitor) processWatchedJobs() {
 			continue
 		}
 
+		if m.isPendingJobPollDeferred(dispatchID, job) {
+			delete(qStats, job.hpcJobID)
+			continue
+		}
+
 		if m.obtainJobStateFromWlmQueueDetails(dispatchID, qStats, job) {
 			// The 'qStats' data is obtained once before the start of the loop.
 			// If the job is canceled while we're processing the jobs, then
@@ -555,6 +646,18 @@ func (m *launcherMonitor) processWatchedJobs() {
 	}
 }
 
+// isPendingJobPollDeferred returns whether polling the status of the specified job can be
+// deferred to a later pass, because it was last seen pending and its status was checked
+// less than "pendingPollInterval" ago. Pending jobs rarely change state, so this lessens
+// the load on the launcher and the master on clusters with many queued jobs.
+func (m *launcherMonitor) isPendingJobPollDeferred(dispatchID string, job *launcherJob) bool {
+	if m.pendingPollInterval <= m.pollInterval || job.lastJobState != launcher.PENDING ||
+		m.isJobMarkedAsTerminated(dispatchID) {
+		return false
+	}
+	return time.Since(m.getLastJobStatusCheckTime(dispatchID)) < m.pendingPollInterval
+}
+
 // obtainJobStateFromWlmQueueDetails gets the state of the specified dispatch from
 // the supplied WLM queue details. If found the associated job state will be published.
 func (m *launcherMonitor) obtainJobStateFromWlmQueueDetails(
@@ -577,6 +680,7 @@ func (m *launcherMonitor) obtainJobStateFromWlmQueueDetails(
 
 	switch {
 	case nativeState == "PD" || strings.ToLower(nativeState) == "pending":
+		job.jobPendingReasonDesc = reasonDesc
 		m.publishJobState(launcher.PENDING, job, dispatchID, hpcJobID)
 
 		m.processReasonCodeForPendingJobs(dispatchID, reasonCode, reasonDesc, job)
@@ -594,6 +698,7 @@ func (m *launcherMonitor) obtainJobStateFromWlmQueueDetails(
 	}
 
 	job.jobPendingReasonCode = ""
+	job.jobPendingReasonDesc = ""
 
 	return false
 }
@@ -833,6 +938,21 @@ func (m *launcherMonitor) getLastJobTerminationRequestTime(dispatchID string) ti
 	return val
 }
 
+// getLastJobState returns the last state reported for the job, and whether the job is
+// monitored. The state is empty if none has been reported yet.
+func (m *launcherMonitor) getLastJobState(dispatchID string) (launcher.DispatchState, bool) {
+	var state launcher.DispatchState
+	found := false
+
+	m.monitoredJobs.WithLock(func(inmap map[string]*launcherJob) {
+		if job, ok := inmap[dispatchID]; ok {
+			state, found = job.lastJobState, true
+		}
+	})
+
+	return state, found
+}
+
 func (m *launcherMonitor) clearJobsToRemoveMap() {
 	m.jobsToRemove.WithLock(func(inmap map[string]struct{}) {
 		for k := range inmap {
@@ -947,9 +1067,13 @@ func (m *launcherMonitor) updateJobStatus(job *launcherJob) bool {
 			m.syslog.WithField("dispatch-id", dispatchID).Error(missingDispatchMsg)
 		}
 
+		cause := dispatchLost
+		if job.jobWasTerminated {
+			cause = dispatchCanceled
+		}
 		m.outbox <- DispatchExited{
 			DispatchID: dispatchID,
-			ExitCode:   -1,
+			Cause:      cause,
 			Message:    missingDispatchMsg,
 		}
 
@@ -960,7 +1084,7 @@ func (m *launcherMonitor) updateJobStatus(job *launcherJob) bool {
 		return false
 	}
 
-	if exitStatus, exitMessages, ok := calculateJobExitStatus(resp); ok {
+	if exitStatus, cause, exitMessages, ok := calculateJobExitStatus(resp); ok {
 		// Try to filter out messages that offer no value to the user, leaving only the
 		// message that identifies the root cause of the error.
 		filteredMessages := filterOutSuperfluousMessages(exitMessages)
@@ -974,7 +1098,7 @@ func (m *launcherMonitor) updateJobStatus(job *launcherJob) bool {
 		}
 
 		// Insert the last few lines of the error log into the failure message.
-		if exitStatus != 0 {
+		if exitStatus != 0 || cause != dispatchExitedWithCode {
 			var errMessages []string
 			errMessages, _ = m.getTaskLogsFromDispatcher(job, "error.log", errorLinesToRetrieve)
 			if m.allContainersRunning(job) {
@@ -987,13 +1111,15 @@ func (m *launcherMonitor) updateJobStatus(job *launcherJob) bool {
 		}
 
 		m.syslog.WithField("dispatch-id", dispatchID).
-			Debugf("sending job termination status to DAI: exitCode=%d, messages=%s",
+			Debugf("sending job termination status to DAI: exitCode=%d, cause=%s, messages=%s",
 				exitStatus,
+				cause,
 				exitMessages)
 
 		m.outbox <- DispatchExited{
 			DispatchID: dispatchID,
 			ExitCode:   exitStatus,
+			Cause:      cause,
 			Message:    strings.Join(exitMessages, "\n") + "\n",
 		}
 
@@ -1034,6 +1160,9 @@ func (m *launcherMonitor) publishJobState(
 	hpcJobID string,
 ) {
 	isPullingImage := notifyState == launcher.RUNNING && !m.allContainersRunning(job)
+	m.monitoredJobs.WithLock(func(map[string]*launcherJob) {
+		job.lastJobState = notifyState
+	})
 
 	m.syslog.WithField("dispatch-id", dispatchID).
 		WithField("hpc-job-id", job.hpcJobID).
@@ -1041,11 +1170,16 @@ func (m *launcherMonitor) publishJobState(
 		WithField("pulling", isPullingImage).
 		Debug("sending DAI a job state")
 
+	pendingReason := ""
+	if notifyState == launcher.PENDING {
+		pendingReason = job.jobPendingReasonDesc
+	}
 	m.outbox <- DispatchStateChange{
 		DispatchID:     dispatchID,
 		State:          notifyState,
 		IsPullingImage: isPullingImage,
 		HPCJobID:       job.hpcJobID,
+		PendingReason:  pendingReason,
 	}
 }
 
@@ -1078,7 +1212,11 @@ func (m *launcherMonitor) getDispatchStatus(
 			return launcher.DispatchInfo{}, ignoreNotFound
 		}
 
-		if r != nil && (r.StatusCode == http.StatusUnauthorized ||
+		if isLauncherRequestTimeout(err) {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				WithError(err).
+				Warn("the job status could not be obtained because the launcher did not respond in time")
+		} else if r != nil && (r.StatusCode == http.StatusUnauthorized ||
 			r.StatusCode == http.StatusForbidden) {
 			//nolint:lll
 			m.syslog.WithField("dispatch-id", dispatchID).
@@ -1106,32 +1244,62 @@ func (m *launcherMonitor) getDispatchStatus(
 
 type exitCode int
 
+// dispatchExitCause is why a dispatch exited, which determines whether its exit code is
+// meaningful.
+type dispatchExitCause int
+
+const (
+	// dispatchExitedWithCode is a job that reached a terminal state with an exit code.
+	dispatchExitedWithCode dispatchExitCause = iota
+	// dispatchFailed is a job that failed without an exit code.
+	dispatchFailed
+	// dispatchLost is a job that the launcher no longer knows of, although it was not canceled.
+	dispatchLost
+	// dispatchCanceled is a job that was canceled.
+	dispatchCanceled
+)
+
+func (c dispatchExitCause) String() string {
+	switch c {
+	case dispatchExitedWithCode:
+		return "exited"
+	case dispatchFailed:
+		return "failed"
+	case dispatchLost:
+		return "lost"
+	case dispatchCanceled:
+		return "canceled"
+	default:
+		return fmt.Sprintf("dispatchExitCause(%d)", int(c))
+	}
+}
+
 // calculateJobExitStatus determines  an exit status for the specified job. If the job is not
 // in a terminal state, there is no exit status (and monitoring continues).
-// If in a terminal state, also return the job messages.
+// If in a terminal state, also return the cause of the exit and the job messages.
 func calculateJobExitStatus(
 	resp launcher.DispatchInfo,
-) (exitCode, []string, bool) {
+) (exitCode, dispatchExitCause, []string, bool) {
 	state, ok := resp.GetStateOk()
 	if ok {
 		// TODO(HAL-2813): Track and send more of these state changes with sendStatusToDetermined.
 		switch *state {
 		case "TERMINATED": // User-initiated termination complete
-			return 1, getJobExitMessages(resp), true
+			return 1, dispatchExitedWithCode, getJobExitMessages(resp), true
 		case "FAILED":
-			// exit status TBD -- use -1 to skip printing incorrect (exit code 1)
-			return -1, getJobExitMessages(resp), true
+			// The exit status is unknown, rather than the incorrect (exit code 1).
+			return 0, dispatchFailed, getJobExitMessages(resp), true
 		case "MISSING": // Unexpected job state, assuming job is terminated
-			return -1,
+			return 0, dispatchLost,
 				append(getJobExitMessages(resp), "HPC launcher job lost. Assuming job terminated."),
 				true
 		case "COMPLETED": // Normal completion
-			return 0, getJobExitMessages(resp), true
+			return 0, dispatchExitedWithCode, getJobExitMessages(resp), true
 		default:
-			return 0, nil, false
+			return 0, dispatchExitedWithCode, nil, false
 		}
 	}
-	return 0, nil, false
+	return 0, dispatchExitedWithCode, nil, false
 }
 
 // getJobExitMessages returns the job messages from the event array (if any).
@@ -1231,7 +1399,7 @@ func (m *launcherMonitor) isDispatchInProgress(owner string, dispatchID string)
 		// We know it does not exist so not in progress
 		return false
 	}
-	_, _, exited := calculateJobExitStatus(resp)
+	_, _, _, exited := calculateJobExitStatus(resp)
 	return !exited
 }
 
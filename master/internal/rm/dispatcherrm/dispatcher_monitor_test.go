@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
+	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/pkg/syncx/mapx"
 	"github.com/determined-ai/determined/proto/pkg/jobv1"
 )
@@ -133,6 +134,27 @@ func Test_getJobExitMessagesAndFiltering(t *testing.T) {
 	}
 }
 
+func Test_calculateJobExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		state    launcher.DispatchState
+		code     exitCode
+		cause    dispatchExitCause
+		terminal bool
+	}{
+		{state: launcher.COMPLETED, code: 0, cause: dispatchExitedWithCode, terminal: true},
+		{state: launcher.TERMINATED, code: 1, cause: dispatchExitedWithCode, terminal: true},
+		{state: launcher.FAILED, code: 0, cause: dispatchFailed, terminal: true},
+		{state: launcher.MISSING, code: 0, cause: dispatchLost, terminal: true},
+		{state: launcher.RUNNING, code: 0, cause: dispatchExitedWithCode, terminal: false},
+	} {
+		code, cause, _, terminal := calculateJobExitStatus(
+			launcher.DispatchInfo{State: tt.state.Ptr()})
+		assert.Equal(t, code, tt.code, tt.state)
+		assert.Equal(t, cause, tt.cause, tt.state)
+		assert.Equal(t, terminal, tt.terminal, tt.state)
+	}
+}
+
 func Test_getJobID(t *testing.T) {
 	var jobID string
 
@@ -227,6 +249,43 @@ func Test_allContainersRunning(t *testing.T) {
 	}, "numTimesWriteExperimentLogCalled != 3")
 }
 
+// Verifies that the nodes of the job are reported once, only after all of its
+// containers have notified the job watcher that they are running.
+func Test_notifyContainerRunningReportsNodes(t *testing.T) {
+	jobWatcher, events := getJobWatcher()
+
+	job := getJob("11ae54526b544bcd-8607d5744a7b1439", time.Now())
+	jobWatcher.monitoredJobs.Store(job.dispatcherID, job)
+
+	nodesAssigned := func() []dispatchNodesAssigned {
+		var assigned []dispatchNodesAssigned
+		for {
+			select {
+			case e := <-events:
+				if e, ok := e.(dispatchNodesAssigned); ok {
+					assigned = append(assigned, e)
+				}
+			default:
+				return assigned
+			}
+		}
+	}
+
+	jobWatcher.notifyContainerRunning(job.dispatcherID, 0, 3, "node002")
+	jobWatcher.notifyContainerRunning(job.dispatcherID, 1, 3, "node001")
+	require.Empty(t, nodesAssigned())
+
+	jobWatcher.notifyContainerRunning(job.dispatcherID, 2, 3, "node002")
+	require.Equal(t, []dispatchNodesAssigned{{
+		DispatchID: job.dispatcherID,
+		Nodes:      []string{"node001", "node002"},
+	}}, nodesAssigned())
+
+	// A repeated notification does not report the nodes again.
+	jobWatcher.notifyContainerRunning(job.dispatcherID, 2, 3, "node002")
+	require.Empty(t, nodesAssigned())
+}
+
 // Verifies that "isJobBeingMonitored()" returns true when the job is being
 // monitored; false otherwise.
 func Test_isJobBeingMonitored(t *testing.T) {
@@ -249,7 +308,8 @@ func getJobWatcher() (*launcherMonitor, <-chan launcherMonitorEvent) {
 		log:       logrus.WithField("component", "dispatcher-test"),
 		APIClient: launcher.NewAPIClient(launcher.NewConfiguration()),
 		auth:      "dummyToken",
-	}, &dispatchIDToHPCJobID, events)
+	}, &dispatchIDToHPCJobID, events,
+		config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval)
 	return jobWatcher, events
 }
 
@@ -345,6 +405,73 @@ func Test_getDispatchIDsSortedByLastJobStatusCheckTime(t *testing.T) {
 	assert.Equal(t, sortedDispatchIDs[4], DispatchID5)
 }
 
+func Test_isPendingJobPollDeferred(t *testing.T) {
+	jobWatcher, _ := getJobWatcher()
+	jobWatcher.pendingPollInterval = time.Minute
+
+	pending := getJob(DispatchID1, time.Now())
+	pending.lastJobState = launcher.PENDING
+	running := getJob(DispatchID2, time.Now())
+	running.lastJobState = launcher.RUNNING
+	stale := getJob(DispatchID3, time.Now().Add(-2*time.Minute))
+	stale.lastJobState = launcher.PENDING
+	terminated := getJob(DispatchID4, time.Now())
+	terminated.lastJobState = launcher.PENDING
+	terminated.jobWasTerminated = true
+	for _, job := range []*launcherJob{pending, running, stale, terminated} {
+		jobWatcher.monitoredJobs.Store(job.dispatcherID, job)
+	}
+
+	// Only pending jobs checked recently are deferred.
+	assert.Equal(t, jobWatcher.isPendingJobPollDeferred(DispatchID1, pending), true)
+	assert.Equal(t, jobWatcher.isPendingJobPollDeferred(DispatchID2, running), false)
+	assert.Equal(t, jobWatcher.isPendingJobPollDeferred(DispatchID3, stale), false)
+	assert.Equal(t, jobWatcher.isPendingJobPollDeferred(DispatchID4, terminated), false)
+
+	// Nothing is deferred when pending jobs are polled as often as the others.
+	jobWatcher.pendingPollInterval = jobWatcher.pollInterval
+	assert.Equal(t, jobWatcher.isPendingJobPollDeferred(DispatchID1, pending), false)
+}
+
+func Test_findContainerDispatch(t *testing.T) {
+	jobWatcher, _ := getJobWatcher()
+	allocationID := "allocation-1"
+
+	// A dispatch from before dispatch IDs were allocation IDs, and the current one.
+	legacy := getJob(DispatchID1, time.Now())
+	current := getJob(allocationID, time.Now())
+	jobWatcher.monitoredJobs.Store(legacy.dispatcherID, legacy)
+	jobWatcher.monitoredJobs.Store(current.dispatcherID, current)
+	dispatchIDs := []string{DispatchID1, allocationID, DispatchID2}
+
+	// The dispatch whose ID is the allocation ID is preferred.
+	dispatchID, monitored := jobWatcher.findContainerDispatch(
+		allocationID, dispatchIDs, 0, 2, "node1")
+	assert.Equal(t, dispatchID, allocationID)
+	assert.Equal(t, monitored, true)
+
+	// A repeated notification goes to the dispatch that recorded the rank on that node.
+	legacy.totalContainers = 2
+	legacy.runningContainers.Store(1, containerInfo{nodeName: "node2"})
+	dispatchID, _ = jobWatcher.findContainerDispatch(allocationID, dispatchIDs, 1, 2, "node2")
+	assert.Equal(t, dispatchID, DispatchID1)
+
+	// Dispatches expecting a different number of containers do not own the container.
+	current.totalContainers = 4
+	dispatchID, _ = jobWatcher.findContainerDispatch(allocationID, dispatchIDs, 0, 2, "node1")
+	assert.Equal(t, dispatchID, DispatchID1)
+	dispatchID, monitored = jobWatcher.findContainerDispatch(
+		allocationID, dispatchIDs, 0, 8, "node1")
+	assert.Equal(t, dispatchID, "")
+	assert.Equal(t, monitored, true)
+
+	// None of the dispatches is monitored.
+	dispatchID, monitored = jobWatcher.findContainerDispatch(
+		allocationID, []string{DispatchID2}, 0, 2, "node1")
+	assert.Equal(t, dispatchID, "")
+	assert.Equal(t, monitored, false)
+}
+
 // Verifies the following behavior for "obtainJobStateFromWlmQueueDetails()":
 //
 //  1. Returns true if the job state is "PD" (pending) or "R" (running); false
@@ -464,6 +591,41 @@ func Test_obtainJobStateFromWlmQueueDetails(t *testing.T) {
 	assert.Equal(t, retValue, true)
 }
 
+// Verifies that the state changes of pending jobs carry the reason that the job is pending.
+func Test_obtainJobStateFromWlmQueueDetailsPendingReason(t *testing.T) {
+	qStats := map[string]map[string]string{
+		"hpcJobID1": {
+			"state":      "PD",
+			"reasonCode": "Resources",
+			"reasonDesc": "The QOS resource limit has been reached.",
+		},
+	}
+	jobWatcher, events := getJobWatcher()
+	jobWatcher.dispatchIDToHPCJobID.Store(DispatchID1, HpcJobID1)
+	job := getJob(DispatchID1, time.Now())
+
+	nextStateChange := func() DispatchStateChange {
+		for e := range events {
+			if change, ok := e.(DispatchStateChange); ok {
+				return change
+			}
+		}
+		t.Fatal("events closed")
+		return DispatchStateChange{}
+	}
+
+	jobWatcher.obtainJobStateFromWlmQueueDetails(DispatchID1, qStats, job)
+	change := nextStateChange()
+	assert.Equal(t, change.State, launcher.PENDING)
+	assert.Equal(t, change.PendingReason, "The QOS resource limit has been reached.")
+
+	qStats[HpcJobID1]["state"] = "R"
+	jobWatcher.obtainJobStateFromWlmQueueDetails(DispatchID1, qStats, job)
+	change = nextStateChange()
+	assert.Equal(t, change.State, launcher.RUNNING)
+	assert.Equal(t, change.PendingReason, "")
+}
+
 // Verifies that when a job is in the "Running" state with a reason code of
 // "Prolog", a message is displayed only once in the experiment log that
 // provides the description for the "Prolog" reason code.  And, when the
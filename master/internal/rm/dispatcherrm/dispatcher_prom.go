@@ -28,11 +28,39 @@ var (
 		Name:      "errors",
 		Help:      "errors from dispatcher API calls",
 	}, dispatcherLabels)
+	queuedAllocations = prom.NewGauge(prom.GaugeOpts{
+		Namespace: promNamespace,
+		Subsystem: promSubsystem,
+		Name:      "queued_allocations",
+		Help:      "number of allocation requests held by the resource manager",
+	})
+	stateStoreErrors = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: promSubsystem,
+		Name:      "state_store_errors",
+		Help:      "errors reading or writing the persisted dispatcher state",
+	}, []string{"operation"})
+	stateStoreHealthy = prom.NewGauge(prom.GaugeOpts{
+		Namespace: promNamespace,
+		Subsystem: promSubsystem,
+		Name:      "state_store_healthy",
+		Help:      "whether the last read or write of the persisted dispatcher state succeeded",
+	})
+	stuckDispatchCleanups = prom.NewGauge(prom.GaugeOpts{
+		Namespace: promNamespace,
+		Subsystem: promSubsystem,
+		Name:      "stuck_dispatch_cleanups",
+		Help:      "number of dispatches whose cleanup failed at least dispatch_cleanup_alert_attempts times",
+	})
 )
 
 func init() {
 	prom.MustRegister(dispatcherHistogram)
 	prom.MustRegister(dispatcherErrors)
+	prom.MustRegister(queuedAllocations)
+	prom.MustRegister(stateStoreErrors)
+	prom.MustRegister(stateStoreHealthy)
+	prom.MustRegister(stuckDispatchCleanups)
 }
 
 func recordAPITiming(labels ...string) (end func()) {
@@ -46,15 +74,49 @@ func recordAPITiming(labels ...string) (end func()) {
 	}
 }
 
-func recordAPIErr(labels ...string) func(error) {
+// recordAPIErr returns a function that increments the error counter if the
+// error it is given points to a non-nil error. It takes a pointer so that it
+// may be deferred against a named error return value, which is only populated
+// once the calling function returns.
+func recordAPIErr(labels ...string) func(*error) {
 	if !config.GetMasterConfig().Observability.EnablePrometheus {
-		return func(error) {}
+		return func(*error) {}
 	}
 
-	return func(err error) {
-		if err == nil {
+	return func(err *error) {
+		if err == nil || *err == nil {
 			return
 		}
 		dispatcherErrors.WithLabelValues(labels...).Inc()
 	}
 }
+
+// recordQueueDepth records the number of allocation requests held by the resource manager.
+func recordQueueDepth(n int) {
+	if !config.GetMasterConfig().Observability.EnablePrometheus {
+		return
+	}
+	queuedAllocations.Set(float64(n))
+}
+
+// recordStatePersistence records the outcome of reading or writing the persisted dispatcher
+// state.
+func recordStatePersistence(operation string, err error) {
+	if !config.GetMasterConfig().Observability.EnablePrometheus {
+		return
+	}
+	if err != nil {
+		stateStoreErrors.WithLabelValues(operation).Inc()
+		stateStoreHealthy.Set(0)
+		return
+	}
+	stateStoreHealthy.Set(1)
+}
+
+// recordStuckDispatchCleanups records the number of dispatches whose cleanup keeps failing.
+func recordStuckDispatchCleanups(n int) {
+	if !config.GetMasterConfig().Observability.EnablePrometheus {
+		return
+	}
+	stuckDispatchCleanups.Set(float64(n))
+}
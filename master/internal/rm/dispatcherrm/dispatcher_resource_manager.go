@@ -3,10 +3,13 @@ package dispatcherrm
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
 	"fmt"
-	"log"
+	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +21,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/determined-ai/determined/master/internal/api/apiutils"
+	"github.com/determined-ai/determined/master/internal/authz"
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/rm"
@@ -52,6 +56,9 @@ const (
 	root                          = "root"
 	// How frequently to cleanup terminated dispatches when in debug mode.
 	terminatedDispatchCleanupInterval = 18 * time.Hour
+	// The longest wait between the attempts to clean up a dispatch whose launcher
+	// environment could not be deleted, unless dispatch_cleanup_retry_interval is longer.
+	maxDispatchCleanupRetryInterval = time.Hour
 )
 
 // The launcher can only run up to 8 concurrent async launch threads. It will
@@ -72,11 +79,31 @@ var numTimesScheduledPendingTasksCalled uint64
 
 var errNotSupportedOnHpcCluster = fmt.Errorf("%w on HPC clusters", rmerrors.ErrNotSupported)
 
+// errQueueFull is returned when an allocation is requested while max_queued_allocations
+// requests are already held by the resource manager.
+var errQueueFull = errors.New("resource manager queue full")
+
+// errMaxSlotsPerJobExceeded is returned when a job requests more slots than the
+// max_slots_per_job of its resource pool.
+var errMaxSlotsPerJobExceeded = errors.New("slots requested exceed the maximum slots per job")
+
 type wlmType string
 
+// noAvailableNodesSuffix is appended to the description of a launcher-provided resource
+// pool whose partition is absent from the HPC resource sample.
+const noAvailableNodesSuffix = " (no available nodes)"
+
+// accountingLabelEnvVarPrefix prefixes the upper-cased key of each accounting label of a
+// task in the name of the environment variable that holds its value.
+const accountingLabelEnvVarPrefix = "DET_ACCOUNTING_LABEL_"
+
 // actionCoolDown is the rate limit for queue submission.
 const actionCoolDown = 500 * time.Millisecond
 
+// dependencyPollInterval is how often a task that depends on dispatches that are not
+// submitted yet checks them again.
+const dependencyPollInterval = 5 * time.Second
+
 // DispatcherResourceManager manages the lifecycle of dispatcher resources.
 //
 // "jobCancelQueue" is a FIFO queue where job cancelation requests are placed
@@ -92,7 +119,19 @@ type DispatcherResourceManager struct {
 	// system dependencies
 	syslog    *logrus.Entry
 	db        *db.PgDB
-	apiClient *launcherAPIClient
+	apiClient launcherClient
+	// listDispatchesByAllocationID lists the dispatches of an allocation, i.e.,
+	// db.ListDispatchesByAllocationID.
+	listDispatchesByAllocationID func(context.Context, model.AllocationID) ([]*db.Dispatch, error)
+	// allocationByID retrieves an allocation, i.e., db.AllocationByID.
+	allocationByID func(context.Context, model.AllocationID) (*model.Allocation, error)
+	// allocationOwnerID retrieves the owner of the job of an allocation, i.e.,
+	// db.AllocationOwnerID.
+	allocationOwnerID func(context.Context, model.AllocationID) (*model.UserID, error)
+	// insertDispatch persists a dispatch to the DB, i.e., db.InsertDispatch.
+	insertDispatch func(context.Context, *db.Dispatch) error
+	// deleteDispatch deletes a dispatch from the DB, i.e., db.DeleteDispatch.
+	deleteDispatch func(context.Context, string) (int64, error)
 
 	// static configuration.
 	wlmType         wlmType
@@ -102,6 +141,10 @@ type DispatcherResourceManager struct {
 	masterTLSConfig model.TLSClientConfig
 	loggingConfig   model.LoggingConfig
 
+	// impersonationResolver, if configured, resolves the HPC user that jobs are
+	// launched as.
+	impersonationResolver tasks.ImpersonationResolver
+
 	// mutable state. access must occur under lock or it must be thread-safe already (and then
 	// thinking about critical sections for logical consistency is still... critical).
 	mu                   sync.Mutex
@@ -111,6 +154,22 @@ type DispatcherResourceManager struct {
 	scheduledLaunches    mapx.Map[model.AllocationID, struct{}]
 	inflightCancelations mapx.Map[model.AllocationID, struct{}]
 	jobCancelQueue       *orderedmapx.Map[string, KillDispatcherResources]
+	// restoreAttempts records when the restore of an allocation whose dispatch is not
+	// found yet was first attempted, see waitForRestoredDispatch.
+	restoreAttempts mapx.Map[model.AllocationID, time.Time]
+	// pendingSince records when the dispatch of an allocation in a resource pool with a
+	// max_pending_duration was first seen pending, see checkPendingDuration.
+	pendingSince mapx.Map[model.AllocationID, time.Time]
+	// dependencyWaits records the dependencies of the allocations waiting for them to be
+	// submitted, so that dependency cycles can be detected, see resolveHPCJobDependencies.
+	dependencyWaits mapx.Map[model.AllocationID, []string]
+
+	// dispatchCleanupMu serializes the passes releasing the dispatches of inactive
+	// allocations, so that a dispatch isn't released twice.
+	dispatchCleanupMu sync.Mutex
+	// cleanupFailures records the dispatches whose launcher environment could not be
+	// deleted, by dispatch ID, see retryDispatchCleanups.
+	cleanupFailures mapx.Map[string, *dispatchCleanupFailure]
 
 	// caches.
 	hpcDetailsCache *hpcResourceDetailsCache
@@ -124,7 +183,7 @@ type DispatcherResourceManager struct {
 
 // New returns a new dispatcher resource manager.
 func New(
-	db *db.PgDB,
+	pgDB *db.PgDB,
 	echo *echoV4.Echo,
 	cfg *config.ResourceManagerWithPoolsConfig,
 	opts *aproto.MasterSetAgentOptions,
@@ -150,19 +209,41 @@ func New(
 		return nil, fmt.Errorf("building dispatcherrm: %w", err)
 	}
 
+	// Check the launcher before starting any of the subsystems, so that a misconfigured or
+	// incompatible launcher fails startup cleanly.
+	syslog := logrus.WithField("component", "dispatcherrm")
+	syslog.Info("starting dispatcher resource manager")
+	if rmCfg.TresSupported && !rmCfg.GresSupported {
+		syslog.Warn("tres_supported: true cannot be used when gres_supported: false is " +
+			"specified, so tres_supported is ignored. Specify tres_supported: false in the " +
+			"resource_manager configuration to remove this warning.")
+	}
+	if err := checkVersionNow(
+		context.TODO(), syslog, apiClient, rmCfg.RequireMinimumLauncherVersion,
+	); err != nil {
+		return nil, fmt.Errorf("building dispatcherrm: %w", err)
+	}
+
 	dispatchIDtoHPCJobID := mapx.New[string, string]()
 	monitorEvents := make(chan launcherMonitorEvent, 64)
-	watcher := newDispatchWatcher(apiClient, &dispatchIDtoHPCJobID, monitorEvents)
+	watcher := newDispatchWatcher(apiClient, &dispatchIDtoHPCJobID, monitorEvents,
+		rmCfg.ResolveJobWatcherPollInterval(), rmCfg.ResolveJobWatcherPendingPollInterval())
 
 	dbState, err := getDispatcherState(context.TODO())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create state for dispatcher resource manager: %w", err)
 	}
 	m := &DispatcherResourceManager{
-		syslog:    logrus.WithField("component", "dispatcherrm"),
-		db:        db,
+		syslog:    syslog,
+		db:        pgDB,
 		apiClient: apiClient,
 
+		listDispatchesByAllocationID: db.ListDispatchesByAllocationID,
+		allocationByID:               db.AllocationByID,
+		allocationOwnerID:            db.AllocationOwnerID,
+		insertDispatch:               db.InsertDispatch,
+		deleteDispatch:               db.DeleteDispatch,
+
 		wlmType:         wlm,
 		rmConfig:        rmCfg,
 		poolConfig:      cfg.ResourcePools,
@@ -176,21 +257,24 @@ func New(
 		scheduledLaunches:    mapx.New[model.AllocationID, struct{}](),
 		inflightCancelations: mapx.New[model.AllocationID, struct{}](),
 		jobCancelQueue:       orderedmapx.New[string, KillDispatcherResources](),
+		restoreAttempts:      mapx.New[model.AllocationID, time.Time](),
+		pendingSince:         mapx.New[model.AllocationID, time.Time](),
+		dependencyWaits:      mapx.New[model.AllocationID, []string](),
+		cleanupFailures:      mapx.New[string, *dispatchCleanupFailure](),
 
-		hpcDetailsCache: newHpcResourceDetailsCache(rmCfg, apiClient),
+		hpcDetailsCache: newHpcResourceDetailsCache(rmCfg, cfg.ResourcePools, apiClient, wlm),
 
 		dbState: *dbState,
 
 		jobWatcher: watcher,
 	}
 
-	m.syslog.Info("starting dispatcher resource manager")
-	if err := checkVersionNow(context.TODO(), m.syslog, m.apiClient); err != nil {
-		log.Fatal(err)
+	if rmCfg.LDAPImpersonation != nil {
+		m.impersonationResolver = newLDAPImpersonationResolver(*rmCfg.LDAPImpersonation)
 	}
 
 	go m.killAllInactiveDispatches()
-	go gcOrphanedDispatches(context.TODO(), m.syslog, m.apiClient)
+	go gcOrphanedDispatches(context.TODO(), m.syslog, apiClient, rmCfg.DispatchPayloadNamePrefix)
 	go m.jobWatcher.watch()
 	go m.handleLauncherMonitorEvents(monitorEvents)
 
@@ -199,6 +283,7 @@ func New(
 	m.hpcDetailsCache.wait()
 
 	go m.periodicallySchedulePendingTasks()
+	go m.retryDispatchCleanups()
 
 	return m, nil
 }
@@ -208,9 +293,10 @@ func (m *DispatcherResourceManager) Allocate(msg sproto.AllocateRequest) (*sprot
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	sub := rmevents.Subscribe(msg.AllocationID)
-	m.addTask(msg)
-	return sub, nil
+	if err := m.addTask(msg); err != nil {
+		return nil, err
+	}
+	return rmevents.Subscribe(msg.AllocationID), nil
 }
 
 // DeleteJob delete resources associated with a job from the launcher.
@@ -260,13 +346,18 @@ func (m *DispatcherResourceManager) ExternalPreemptionPending(msg sproto.Pending
 	return nil
 }
 
-// HealthCheck tries to call launcher and check if it is reachable.
+// HealthCheck tries to call launcher and check if it is reachable, and checks that the
+// dispatcher state can be persisted.
 func (m *DispatcherResourceManager) HealthCheck() []model.ResourceManagerHealth {
 	status := model.Healthy
 	_, err := m.apiClient.getVersion(context.TODO(), m.syslog.WithField("caller", "HealthCheck"))
 	if err != nil {
 		status = model.Unhealthy
 	}
+	if err := m.dbState.checkPersistence(context.TODO()); err != nil {
+		m.syslog.WithError(err).Warn("unable to persist the dispatcher state")
+		status = model.Unhealthy
+	}
 
 	return []model.ResourceManagerHealth{
 		{
@@ -405,8 +496,33 @@ func (m *DispatcherResourceManager) GetResourcePools() (
 		return nil, err
 	}
 
+	// Summarizing the pools resolves the slot type of each partition from the nodes of the
+	// sample, so the summary is reused until a new sample is installed. The pause state and
+	// fair share change independently of the sample, so they are filled in on each call.
+	summary := m.hpcDetailsCache.poolsSummary.Load()
+	if summary == nil || summary.sample != hpcDetails {
+		summary = m.summarizeResourcePools(hpcDetails)
+		m.hpcDetailsCache.poolsSummary.Store(summary)
+	}
+
+	result := make([]*resourcepoolv1.ResourcePool, 0, len(summary.pools))
+	for i, p := range summary.pools {
+		pool := duplicateResourcePool(p)
+		pool.Details = m.poolDetails(hpcDetails, summary.partitions[i])
+		pool.Paused = m.dbState.isPoolPaused(summary.partitions[i])
+		result = append(result, pool)
+	}
+	return &apiv1.GetResourcePoolsResponse{ResourcePools: result}, nil
+}
+
+// summarizeResourcePools builds the resource pools of the partitions of the given sample,
+// and of the launcher-provided pools, without their pause state and details.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) summarizeResourcePools(
+	hpcDetails *hpcResources,
+) *resourcePoolsSummary {
 	wlmName, schedulerType, fittingPolicy := m.getWlmResources()
-	var result []*resourcepoolv1.ResourcePool
+	summary := &resourcePoolsSummary{sample: hpcDetails}
 	poolNameMap := make(map[string]*resourcepoolv1.ResourcePool)
 
 	for _, v := range hpcDetails.Partitions {
@@ -416,6 +532,9 @@ func (m *DispatcherResourceManager) GetResourcePools() (
 		if slotType == device.CPU {
 			slotsAvailable = int32(v.TotalCPUSlots)
 			slotsUsed = int32(v.TotalCPUSlots - v.TotalAvailableCPUSlots)
+		} else {
+			slotsUsed = min(slotsUsed+int32(exclusivelyHeldGpus(hpcDetails.Nodes, v.PartitionName)),
+				slotsAvailable)
 		}
 		slotsPerAgent := 0
 
@@ -435,11 +554,10 @@ func (m *DispatcherResourceManager) GetResourcePools() (
 		}
 
 		description := wlmName + "-managed pool of resources"
-		// Due to viper.MergeConfigMap, map keys in configurations lose case. We match case
-		// insensitive here to handle partitions with upper case characters, at the cost of
-		// incorrectly matching when names are only equal when comparing case-insensitive.
-		if overrides, ok := m.rmConfig.PartitionOverrides[strings.ToLower(v.PartitionName)]; ok {
+		var location string
+		if overrides, ok := m.rmConfig.PartitionOverride(v.PartitionName); ok {
 			description = overrides.Description
+			location = overrides.Location
 		}
 
 		pool := resourcepoolv1.ResourcePool{
@@ -452,8 +570,8 @@ func (m *DispatcherResourceManager) GetResourcePools() (
 			SlotsUsed:                    slotsUsed,
 			AuxContainerCapacity:         int32(v.TotalCPUSlots),
 			AuxContainersRunning:         int32(v.TotalCPUSlots - v.TotalAvailableCPUSlots),
-			DefaultComputePool:           v.PartitionName == m.getDefaultPoolName(hpcDetails, false),
-			DefaultAuxPool:               v.PartitionName == m.getDefaultPoolName(hpcDetails, true),
+			DefaultComputePool:           strings.EqualFold(v.PartitionName, m.getDefaultPoolName(hpcDetails, false)),
+			DefaultAuxPool:               strings.EqualFold(v.PartitionName, m.getDefaultPoolName(hpcDetails, true)),
 			Preemptible:                  true,
 			MinAgents:                    int32(v.TotalNodes),
 			MaxAgents:                    int32(v.TotalNodes),
@@ -461,62 +579,176 @@ func (m *DispatcherResourceManager) GetResourcePools() (
 			AuxContainerCapacityPerAgent: 0,
 			SchedulerType:                schedulerType,
 			SchedulerFittingPolicy:       fittingPolicy,
-			Location:                     "",
+			Location:                     location,
 			ImageId:                      "",
 			InstanceType:                 "",
-			Details:                      &resourcepoolv1.ResourcePoolDetail{},
 			Accelerator:                  v.Accelerator,
 			ResourceManagerName:          m.rmConfig.Name,
 			ResourceManagerMetadata:      m.rmConfig.Metadata,
 		}
-		poolNameMap[pool.Name] = &pool
-		result = append(result, &pool)
+		poolNameMap[strings.ToLower(pool.Name)] = &pool
+		summary.pools = append(summary.pools, &pool)
+		summary.partitions = append(summary.partitions, v.PartitionName)
+	}
+	pools, partitions := m.getLauncherProvidedPools(hpcDetails, poolNameMap)
+	summary.pools = append(summary.pools, pools...)
+	summary.partitions = append(summary.partitions, partitions...)
+
+	return summary
+}
+
+// exclusivelyHeldGpus returns the number of GPUs of the nodes of the partition that the
+// workload manager reports as available, but that no job can be given because their node
+// is held exclusively by a job, such as one requesting exclusive nodes.
+func exclusivelyHeldGpus(nodes []hpcNodeDetails, partition string) int {
+	held := 0
+	for _, node := range nodes {
+		if slices.ContainsFunc(node.Partitions, func(p string) bool {
+			return strings.EqualFold(p, partition)
+		}) {
+			held += node.gpusInUse() - node.GpuInUseCount
+		}
 	}
-	result = append(result, m.getLauncherProvidedPools(hpcDetails, poolNameMap)...)
-
-	return &apiv1.GetResourcePoolsResponse{ResourcePools: result}, nil
+	return held
 }
 
 // getLauncherProvidedPools provides data for any launcher-provided resource pools
-// from the master configuration.
+// from the master configuration, along with the partition of each.
 // Note to the developer: this must not acquire a lock. Possibly changing this from a method to a
 // function makes this more obvious.
 func (m *DispatcherResourceManager) getLauncherProvidedPools(
 	hpcDetails *hpcResources,
 	poolNameMap map[string]*resourcepoolv1.ResourcePool,
-) []*resourcepoolv1.ResourcePool {
+) ([]*resourcepoolv1.ResourcePool, []string) {
 	var result []*resourcepoolv1.ResourcePool
+	var partitions []string
 	for _, pool := range m.poolConfig {
 		if isValidProvider(pool) {
 			basePoolName := pool.Provider.HPC.Partition
-			basePool, found := poolNameMap[basePoolName]
-			if !found {
-				m.syslog.Errorf("resource pool %s specifies provider.partition '%s' that does not exist",
-					pool.PoolName, basePoolName)
-				continue
+			basePool, found := poolNameMap[strings.ToLower(basePoolName)]
+			if found {
+				basePoolName = basePool.Name
+			} else {
+				// A partition that was in an earlier sample currently reports no nodes
+				// (e.g., all drained), so the pool is kept visible with zeroed capacity
+				// rather than dropped. A partition that was never in any sample is most
+				// likely misspelled in the configuration.
+				if m.hpcDetailsCache.partitionSampled(basePoolName) {
+					m.syslog.Debugf("resource pool %s specifies provider.partition '%s' that has no available nodes",
+						pool.PoolName, basePoolName)
+				} else {
+					m.syslog.Errorf("resource pool %s specifies provider.partition '%s' that does not exist",
+						pool.PoolName, basePoolName)
+				}
+				basePool = m.emptyResourcePool(hpcDetails, basePoolName)
 			}
-			// If the base resource pool was located in the map provided, make
-			// a copy, update the name to the launcher-provided pool name, and
-			// include it in the result.
+			// Make a copy of the base resource pool, update the name to the
+			// launcher-provided pool name, and include it in the result.
 			launcherPoolResult := duplicateResourcePool(basePool)
 			launcherPoolResult.Name = pool.PoolName
 			if pool.Description != "" {
 				launcherPoolResult.Description = pool.Description
 			}
+			if pool.Location != "" {
+				launcherPoolResult.Location = pool.Location
+			}
+			if !found {
+				launcherPoolResult.Description += noAvailableNodesSuffix
+			}
 			launcherPoolResult.DefaultComputePool = pool.PoolName == m.getDefaultPoolName(hpcDetails, false)
 			launcherPoolResult.DefaultAuxPool = pool.PoolName == m.getDefaultPoolName(hpcDetails, true)
 			result = append(result, launcherPoolResult)
+			partitions = append(partitions, basePoolName)
 		}
 	}
-	return result
+	return result, partitions
+}
+
+// emptyResourcePool returns a resource pool with zeroed capacity for a partition
+// that is absent from the HPC resource sample, without its pause state and details.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) emptyResourcePool(
+	hpcDetails *hpcResources, partition string,
+) *resourcepoolv1.ResourcePool {
+	wlmName, schedulerType, fittingPolicy := m.getWlmResources()
+	description := wlmName + "-managed pool of resources"
+	var location string
+	if overrides, ok := m.rmConfig.PartitionOverride(partition); ok {
+		description = overrides.Description
+		location = overrides.Location
+	}
+	return &resourcepoolv1.ResourcePool{
+		Name:                    partition,
+		Description:             description,
+		Location:                location,
+		Type:                    resourcepoolv1.ResourcePoolType_RESOURCE_POOL_TYPE_STATIC,
+		SlotType:                m.resolveSlotType(hpcDetails, partition).Proto(),
+		Preemptible:             true,
+		SchedulerType:           schedulerType,
+		SchedulerFittingPolicy:  fittingPolicy,
+		ResourceManagerName:     m.rmConfig.Name,
+		ResourceManagerMetadata: m.rmConfig.Metadata,
+	}
+}
+
+// poolDetails returns the details of the resource pools of the given partition. The HPC
+// details are nil if neither the fair share nor the accelerators are reported.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) poolDetails(
+	hpcDetails *hpcResources, partition string,
+) *resourcepoolv1.ResourcePoolDetail {
+	hpc := m.hpcDetailsCache.hpcPoolDetail(partition)
+	if accelerator := partitionAccelerator(hpcDetails, partition); accelerator != nil {
+		if hpc == nil {
+			hpc = &resourcepoolv1.ResourcePoolHpcDetail{FairShares: []*resourcepoolv1.HpcFairShare{}}
+		}
+		hpc.Accelerator = accelerator
+	}
+	return &resourcepoolv1.ResourcePoolDetail{Hpc: hpc}
+}
+
+// MoveJob implements rm.ResourceManager. The order of jobs is determined by the
+// workload manager, so a job may not be moved to another partition. A move that
+// resolves to the partition the job is already in is accepted as a no-op.
+func (m *DispatcherResourceManager) MoveJob(req sproto.MoveJob) error {
+	if req.Anchor == "" || req.ID == "" || req.Anchor == req.ID {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pool, ok := m.jobResourcePool(req.ID)
+	if !ok {
+		return sproto.ErrJobNotFound(req.ID)
+	}
+	current := m.getProvidingPartition(pool)
+
+	// The destination is the partition of the job being moved ahead of or behind,
+	// if we know of it, otherwise the requested resource pool.
+	destinationPool := req.ResourcePool
+	if anchorPool, ok := m.jobResourcePool(req.Anchor); ok {
+		destinationPool = anchorPool
+	}
+	destination := m.getProvidingPartition(destinationPool)
+	if destination == "" || destination == current {
+		return nil
+	}
+
+	return rmerrors.UnsupportedError(fmt.Sprintf(
+		"cannot move job %s from partition %s to partition %s: the dispatcher RM does not "+
+			"support moving jobs between partitions, since jobs are queued by the workload manager",
+		req.ID, current, destination))
 }
 
-// MoveJob implements rm.ResourceManager.
-func (*DispatcherResourceManager) MoveJob(req sproto.MoveJob) error {
-	// TODO(HAL-2863): We may not be able to support these specific actions, but how we
-	// let people interact with the job queue in dispatcher/slurm world.
-	// ctx.Respond(fmt.Errorf("modifying job positions is not yet supported in slurm"))
-	return rmerrors.UnsupportedError("move job unsupported in the dispatcher RM")
+// jobResourcePool returns the resource pool of the allocations of the specified job.
+func (m *DispatcherResourceManager) jobResourcePool(jobID model.JobID) (string, bool) {
+	for it := m.reqList.Iterator(); it.Next(); {
+		if req := it.Value(); req.JobID == jobID {
+			return req.ResourcePool, true
+		}
+	}
+	return "", false
 }
 
 // RecoverJobPosition implements rm.ResourceManager.
@@ -547,8 +779,11 @@ func (m *DispatcherResourceManager) Release(msg sproto.ResourcesReleased) {
 	// times, but there's no harm in calling "deleteScheduledLaunch()"
 	// more than once.
 	m.scheduledLaunches.Delete(msg.AllocationID)
+	m.restoreAttempts.Delete(msg.AllocationID)
+	m.pendingSince.Delete(msg.AllocationID)
 
 	req := m.reqList.RemoveTaskByID(msg.AllocationID)
+	recordQueueDepth(m.reqList.Len())
 	if req == nil {
 		m.syslog.
 			WithField("allocation-id", msg.AllocationID).
@@ -573,6 +808,32 @@ func (m *DispatcherResourceManager) SetGroupMaxSlots(
 	m.getOrCreateGroup(msg.JobID).MaxSlots = msg.MaxSlots
 }
 
+// Capabilities implements rm.ResourceManager. It must be kept consistent with the
+// operations below that return rmerrors.UnsupportedError or errNotSupportedOnHpcCluster.
+// Only disabling agents depends on the cluster. The other operations are unsupported
+// whatever the sample of the cluster reports: the priority, weight and order of jobs
+// are decided by the workload manager once the jobs are queued, and the launcher
+// schedules whole nodes or slot counts, never individual slots.
+func (m *DispatcherResourceManager) Capabilities() []*apiv1.ResourceManagerCapabilities {
+	return []*apiv1.ResourceManagerCapabilities{
+		{
+			ResourceManagerName:  m.rmConfig.Name,
+			Type:                 string(m.wlmType),
+			SupportsPriority:     false,
+			SupportsWeight:       false,
+			SupportsMoveJob:      false,
+			SupportsDisableAgent: m.supportsDisableAgent(),
+			SupportsDisableSlot:  false,
+		},
+	}
+}
+
+// supportsDisableAgent returns whether agents can be disabled. Disabled agents are
+// excluded from the jobs that are launched, which only Slurm supports.
+func (m *DispatcherResourceManager) supportsDisableAgent() bool {
+	return m.wlmType != pbsSchedulerType
+}
+
 // SetGroupPriority implements rm.ResourceManager.
 func (*DispatcherResourceManager) SetGroupPriority(sproto.SetGroupPriority) error {
 	// TODO(HAL-2863)
@@ -586,14 +847,107 @@ func (*DispatcherResourceManager) SetGroupWeight(sproto.SetGroupWeight) error {
 }
 
 // ValidateResources implements rm.ResourceManager.
-func (*DispatcherResourceManager) ValidateResources(
+func (m *DispatcherResourceManager) ValidateResources(
 	req sproto.ValidateResourcesRequest,
 ) ([]command.LaunchWarning, error) {
 	// TODO(HAL-2862): Use inferred value here if possible.
 	// fulfillable := m.config.MaxSlotsPerContainer >= msg.Slots
+	if err := m.checkMaxSlotsPerJob(req.ResourcePool, req.Slots); err != nil {
+		return nil, err
+	}
+	if err := m.validateSbatchArgs(req.SlurmArgs, req.PbsArgs); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
+// maxSlotsPerJob returns the maximum number of slots that a single job may request in the
+// resource pool, or nil if it is unbounded. A launcher-provided pool without its own limit
+// has the limit of its partition.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) maxSlotsPerJob(name string) *int {
+	name = m.resolvePoolAlias(name)
+	for _, pool := range m.poolConfig {
+		if isValidProvider(pool) && pool.PoolName == name && pool.MaxSlotsPerJob != nil {
+			return pool.MaxSlotsPerJob
+		}
+	}
+	if overrides, ok := m.rmConfig.PartitionOverride(m.getProvidingPartition(name)); ok {
+		return overrides.MaxSlotsPerJob
+	}
+	return nil
+}
+
+// maxPendingDuration returns how long a job may stay pending in the resource pool before it
+// is canceled, or 0 if it may stay pending indefinitely.
+func (m *DispatcherResourceManager) maxPendingDuration(name string) time.Duration {
+	name = m.resolvePoolAlias(name)
+	for _, pool := range m.poolConfig {
+		if isValidProvider(pool) && pool.PoolName == name && pool.MaxPendingDuration != nil {
+			return time.Duration(*pool.MaxPendingDuration)
+		}
+	}
+	if overrides, ok := m.rmConfig.PartitionOverride(m.getProvidingPartition(name)); ok &&
+		overrides.MaxPendingDuration != nil {
+		return time.Duration(*overrides.MaxPendingDuration)
+	}
+	return 0
+}
+
+// masterAddress returns the host and port at which the jobs in the resource pool, launched on
+// the partition, reach the master. The master_host and master_port of a launcher-provided
+// pool take precedence over those of its partition, which take precedence over those of the
+// resource manager.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) masterAddress(pool, partition string) (string, int) {
+	host, port := m.rmConfig.MasterHost, m.rmConfig.MasterPort
+	if overrides, ok := m.rmConfig.PartitionOverride(partition); ok {
+		if overrides.MasterHost != nil {
+			host = *overrides.MasterHost
+		}
+		if overrides.MasterPort != nil {
+			port = *overrides.MasterPort
+		}
+	}
+	pool = m.resolvePoolAlias(pool)
+	for _, p := range m.poolConfig {
+		if isValidProvider(p) && p.PoolName == pool {
+			if p.MasterHost != nil {
+				host = *p.MasterHost
+			}
+			if p.MasterPort != nil {
+				port = *p.MasterPort
+			}
+		}
+	}
+	return host, port
+}
+
+// imagePullPolicy returns the image pull policy of jobs in the resource pool: that of the
+// launcher-provided pool, if any, else that of the partition, which is empty if the
+// force_pull_image of each job decides.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) imagePullPolicy(pool, partition string) string {
+	pool = m.resolvePoolAlias(pool)
+	for _, p := range m.poolConfig {
+		if isValidProvider(p) && p.PoolName == pool && p.ImagePullPolicy != nil {
+			return *p.ImagePullPolicy
+		}
+	}
+	return m.rmConfig.ResolveImagePullPolicy(partition)
+}
+
+// checkMaxSlotsPerJob returns an error if a job requesting the given number of slots in the
+// resource pool exceeds the max_slots_per_job of the pool.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) checkMaxSlotsPerJob(pool string, slots int) error {
+	if limit := m.maxSlotsPerJob(pool); limit != nil && slots > *limit {
+		return fmt.Errorf("%w: %d slots requested, but resource pool %s allows at most %d",
+			errMaxSlotsPerJobExceeded, slots, pool, *limit)
+	}
+	return nil
+}
+
 // DisableAgent adds an agent to the exclude list when launching jobs.
 // Note to developers: this function doesn't acquire a lock and, ideally, we won't make it.
 func (m *DispatcherResourceManager) DisableAgent(msg *apiv1.DisableAgentRequest,
@@ -601,7 +955,7 @@ func (m *DispatcherResourceManager) DisableAgent(msg *apiv1.DisableAgentRequest,
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.wlmType == pbsSchedulerType {
+	if !m.supportsDisableAgent() {
 		return nil, errors.New("disable agent is not supported for PBS")
 	}
 
@@ -622,7 +976,7 @@ func (m *DispatcherResourceManager) DisableAgent(msg *apiv1.DisableAgentRequest,
 func (m *DispatcherResourceManager) EnableAgent(
 	msg *apiv1.EnableAgentRequest,
 ) (*apiv1.EnableAgentResponse, error) {
-	if m.wlmType == pbsSchedulerType {
+	if !m.supportsDisableAgent() {
 		return nil, errors.New("enable agent is not supported for PBS")
 	}
 
@@ -639,6 +993,31 @@ func (m *DispatcherResourceManager) EnableAgent(
 	return &apiv1.EnableAgentResponse{Agent: agent}, nil
 }
 
+// GetClusterUtilization reports how much of the HPC cluster is in use, from the last sample of
+// the HPC resource details.
+// Note to developers: this function must not acquire locks, since it is polled by dashboards.
+func (m *DispatcherResourceManager) GetClusterUtilization() (
+	*apiv1.GetClusterUtilizationResponse, error,
+) {
+	hpcDetails, err := m.hpcDetailsCache.load()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := summarizeHpcNodes(hpcDetails.Nodes)
+	resp := &apiv1.GetClusterUtilizationResponse{
+		Nodes:          int32(summary.nodes),
+		NodesAllocated: int32(summary.nodesAllocated),
+		Gpus:           int32(summary.gpus),
+		GpusAllocated:  int32(summary.gpusAllocated),
+		Cpus:           int32(summary.cpus),
+		CpusAllocated:  int32(summary.cpusAllocated),
+	}
+	resp.GpuUtilization = rmutils.UtilizationPercent(resp.GpusAllocated, resp.Gpus)
+	resp.CpuUtilization = rmutils.UtilizationPercent(resp.CpusAllocated, resp.Cpus)
+	return resp, nil
+}
+
 // GetAgent implements rm.ResourceManager.
 // Note to developers: this function must not acquire locks, since it is called to saturate UIs.
 func (m *DispatcherResourceManager) GetAgent(
@@ -702,6 +1081,9 @@ func (m *DispatcherResourceManager) ResolveResourcePool(name rm.ResourcePoolName
 		}
 	}
 
+	// Experiments configured before a pool was renamed keep referring to it by an alias.
+	name = rm.ResourcePoolName(m.resolvePoolAlias(name.String()))
+
 	resp, err := m.GetResourcePools()
 	if err != nil {
 		return "", err
@@ -712,6 +1094,18 @@ func (m *DispatcherResourceManager) ResolveResourcePool(name rm.ResourcePoolName
 	if err != nil {
 		return "", err
 	}
+	selector, isSelector, err := parsePoolLabelSelector(name.String())
+	if err != nil {
+		return "", err
+	}
+	if isSelector {
+		pool, err := m.selectPoolByLabels(name.String(), selector, poolNames)
+		if err != nil {
+			return "", err
+		}
+		name = rm.ResourcePoolName(pool)
+	}
+
 	found := false
 	for _, poolName := range poolNames {
 		if name.String() == poolName {
@@ -725,6 +1119,10 @@ func (m *DispatcherResourceManager) ResolveResourcePool(name rm.ResourcePoolName
 			name, workspace)
 	}
 
+	if err := m.checkWorkspacePoolAccess(workspace, name.String()); err != nil {
+		return "", err
+	}
+
 	_, err = m.validateResourcePool(hpcDetails, name.String())
 	if err != nil {
 		return "", fmt.Errorf("validating resource pool: %w", err)
@@ -732,7 +1130,33 @@ func (m *DispatcherResourceManager) ResolveResourcePool(name rm.ResourcePoolName
 	return name, nil
 }
 
-// ValidateResourcePool validates that the given resource pool exists.
+// checkWorkspacePoolAccess returns a permission error if workspace_resource_pools restricts
+// the workspace to other resource pools. Requests without a workspace, e.g. checkpoint GC,
+// are not restricted.
+func (m *DispatcherResourceManager) checkWorkspacePoolAccess(workspace int, poolName string) error {
+	if workspace <= 0 || m.workspacePoolAllowed(workspace, poolName) {
+		return nil
+	}
+	return authz.PermissionDeniedError{}.WithPrefix(fmt.Sprintf(
+		"resource pool %s is not available to workspace id %d:", poolName, workspace))
+}
+
+// workspacePoolAllowed returns whether the workspace may use the resource pool, which is
+// always the case for a workspace that workspace_resource_pools does not list. The
+// workspaces are listed by ID, so that renaming a workspace does not lift its restriction.
+func (m *DispatcherResourceManager) workspacePoolAllowed(workspace int, poolName string) bool {
+	pools, ok := m.rmConfig.WorkspaceResourcePools[workspace]
+	if !ok {
+		return true
+	}
+	return slices.ContainsFunc(pools, func(pool string) bool {
+		return m.resolvePoolAlias(pool) == poolName
+	})
+}
+
+// ValidateResourcePool validates that the given resource pool exists. It is not given a
+// workspace, so the workspace_resource_pools restrictions are checked by ResolveResourcePool,
+// which every task submission goes through.
 // Note to developers: this function doesn't acquire a lock and, ideally, we won't make it, since
 // it is called a lot.
 func (m *DispatcherResourceManager) ValidateResourcePool(name rm.ResourcePoolName) error {
@@ -745,11 +1169,149 @@ func (m *DispatcherResourceManager) ValidateResourcePool(name rm.ResourcePoolNam
 	return err
 }
 
+// PauseResourcePool stops new jobs from being launched into the partition that provides the
+// resource pool, e.g. ahead of maintenance, while jobs already launched keep running. Pausing
+// a launcher-provided pool therefore pauses all pools of its partition.
+// Note to developers: this function doesn't acquire a lock and, ideally, we won't make it.
+func (m *DispatcherResourceManager) PauseResourcePool(name rm.ResourcePoolName) error {
+	partition, err := m.resourcePoolPartition(name)
+	if err != nil {
+		return err
+	}
+	return m.dbState.pausePool(partition)
+}
+
+// ResumeResourcePool lets requests queued for a paused resource pool be launched again.
+// Note to developers: this function doesn't acquire a lock and, ideally, we won't make it.
+func (m *DispatcherResourceManager) ResumeResourcePool(name rm.ResourcePoolName) error {
+	partition, err := m.resourcePoolPartition(name)
+	if err != nil {
+		return err
+	}
+	return m.dbState.resumePool(partition)
+}
+
+// GetResourcePoolPartition returns the partition that provides the resource pool, and whether
+// the pool is a launcher-provided pool rather than the partition itself.
+// Note to developers: this function doesn't acquire a lock and, ideally, we won't make it.
+func (m *DispatcherResourceManager) GetResourcePoolPartition(
+	name rm.ResourcePoolName,
+) (*apiv1.GetResourcePoolPartitionResponse, error) {
+	partition, err := m.resourcePoolPartition(name)
+	if err != nil {
+		return nil, err
+	}
+	poolName := m.resolvePoolAlias(name.String())
+	launcherProvided := slices.ContainsFunc(m.poolConfig, func(pool config.ResourcePoolConfig) bool {
+		return isValidProvider(pool) && pool.PoolName == poolName
+	})
+	return &apiv1.GetResourcePoolPartitionResponse{
+		Partition:        partition,
+		LauncherProvided: launcherProvided,
+	}, nil
+}
+
+// resourcePoolPartition returns the partition that provides the given resource pool.
+func (m *DispatcherResourceManager) resourcePoolPartition(name rm.ResourcePoolName) (string, error) {
+	hpcDetails, err := m.hpcDetailsCache.load()
+	if err != nil {
+		return "", err
+	}
+	if !m.hasSlurmPartition(hpcDetails, m.resolvePoolAlias(name.String())).HasResourcePool {
+		return "", fmt.Errorf("resource pool not found: %s", name)
+	}
+	return m.getProvidingPartition(name.String()), nil
+}
+
+// resolvePoolAlias returns the name of the configured resource pool that has the given name as
+// one of its aliases, or the name itself if no pool claims it.
+func (m *DispatcherResourceManager) resolvePoolAlias(name string) string {
+	for _, pool := range m.poolConfig {
+		if slices.Contains(pool.Aliases, name) {
+			return pool.PoolName
+		}
+	}
+	return name
+}
+
+// parsePoolLabelSelector parses a resource pool label selector, e.g. gpu=a100,network=infiniband,
+// which selects the resource pool with all of the given labels. It returns false if the name is
+// not a selector but the name of a resource pool.
+func parsePoolLabelSelector(name string) (map[string]string, bool, error) {
+	if !strings.Contains(name, "=") {
+		return nil, false, nil
+	}
+
+	selector := make(map[string]string)
+	for _, term := range strings.Split(name, ",") {
+		key, value, ok := strings.Cut(term, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, true, fmt.Errorf(
+				"invalid resource pool label selector %s: specify key=value labels separated by commas",
+				name)
+		}
+		selector[key] = value
+	}
+	return selector, true, nil
+}
+
+// selectPoolByLabels returns the one of the given resource pools that has all the labels of
+// the selector. No match or more than one match is an error, rather than a pick that would
+// depend on the order of the pools.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) selectPoolByLabels(
+	name string, selector map[string]string, pools []string,
+) (string, error) {
+	var matches []string
+	for _, pool := range pools {
+		labels := m.poolLabels(pool)
+		matched := true
+		for key, value := range selector {
+			if v, ok := labels[key]; !ok || v != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, pool)
+		}
+	}
+	slices.Sort(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no resource pool available to the workspace has the labels %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("resource pools %s all have the labels %s, specify more labels to select one",
+			strings.Join(matches, ", "), name)
+	}
+}
+
+// poolLabels returns the labels of the resource pool: those of its partition, to which those of
+// a launcher-provided pool are added.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) poolLabels(pool string) map[string]string {
+	labels := make(map[string]string)
+	if overrides, ok := m.rmConfig.PartitionOverride(m.getProvidingPartition(pool)); ok {
+		maps.Copy(labels, overrides.Labels)
+	}
+	pool = m.resolvePoolAlias(pool)
+	for _, p := range m.poolConfig {
+		if isValidProvider(p) && p.PoolName == pool {
+			maps.Copy(labels, p.Labels)
+		}
+	}
+	return labels
+}
+
 func (m *DispatcherResourceManager) validateResourcePool(
 	hpcDetails *hpcResources,
 	name string,
 ) (string, error) {
-	switch resp := m.hasSlurmPartition(hpcDetails, name); {
+	switch resp := m.hasSlurmPartition(hpcDetails, m.resolvePoolAlias(name)); {
 	case !resp.HasResourcePool && resp.ProvidingPartition != "":
 		return "", fmt.Errorf(
 			"resource pool %s is configured to use partition '%s' that does not exist "+
@@ -790,6 +1352,8 @@ func (m *DispatcherResourceManager) handleLauncherMonitorEvents(evs <-chan launc
 			m.handleDispatchExited(msg)
 		case dispatchExpLogMessage:
 			m.DispatchExpLogMessage(msg)
+		case dispatchNodesAssigned:
+			m.handleDispatchNodesAssigned(msg)
 		}
 	}
 	m.syslog.Error("dispatcher monitor stopped unexpectedly")
@@ -819,8 +1383,8 @@ func (m *DispatcherResourceManager) handleDispatchExited(msg DispatchExited) {
 	}
 
 	alloc := m.reqList.Allocation(task.AllocationID)
-	if len(alloc.Resources) != 1 {
-		log.Warnf("allocation has malformed resources: %v", alloc)
+	if alloc == nil || len(alloc.Resources) != 1 {
+		m.failMalformedAllocation(log, task, alloc)
 		return
 	}
 
@@ -828,15 +1392,16 @@ func (m *DispatcherResourceManager) handleDispatchExited(msg DispatchExited) {
 	go m.dispatchExited(msg, task, alloc)
 }
 
-// makeProvidedPoolsMap returns a map where the key is the providing partition
-// and the values are the launcher-provided pools provided by the partition.
+// makeProvidedPoolsMap returns a map where the key is the lower case name of the
+// providing partition and the values are the launcher-provided pools provided by the
+// partition, since partition names are matched case-insensitively.
 // This is all static configuration data, so we can make this map just once
 // in the lifetime of this RM.
 func makeProvidedPoolsMap(poolConfig []config.ResourcePoolConfig) map[string][]string {
 	poolProviderMap := make(map[string][]string)
 	for _, pool := range poolConfig {
 		if isValidProvider(pool) {
-			partitionName := pool.Provider.HPC.Partition
+			partitionName := strings.ToLower(pool.Provider.HPC.Partition)
 			poolProviderMap[partitionName] = append(poolProviderMap[partitionName], pool.PoolName)
 		}
 	}
@@ -844,6 +1409,7 @@ func makeProvidedPoolsMap(poolConfig []config.ResourcePoolConfig) map[string][]s
 }
 
 func (m *DispatcherResourceManager) getProvidingPartition(name string) string {
+	name = m.resolvePoolAlias(name)
 	for _, pool := range m.poolConfig {
 		if isValidProvider(pool) && pool.PoolName == name {
 			return pool.Provider.HPC.Partition
@@ -852,6 +1418,21 @@ func (m *DispatcherResourceManager) getProvidingPartition(name string) string {
 	return name
 }
 
+// getLaunchPartition returns the partition that the request is to be launched in, which is
+// the checkpoint GC resource pool, if configured and known to the sample, for checkpoint GC
+// tasks. On some clusters the default aux partition has no access to the checkpoint storage,
+// so GC would never run.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) getLaunchPartition(
+	hpcDetails *hpcResources, taskType model.TaskType, req *sproto.AllocateRequest,
+) string {
+	if req.SlotsNeeded == 0 && taskType == model.TaskTypeCheckpointGC &&
+		hpcDetails.CheckpointGCPool != "" {
+		return m.getProvidingPartition(hpcDetails.CheckpointGCPool)
+	}
+	return m.getProvidingPartition(req.ResourcePool)
+}
+
 // jobCancelQueueWorker waits to be notified that a job cancelation request is
 // in the queue, then calls "stopLauncherJob()" to cancel the job.
 func (m *DispatcherResourceManager) jobCancelQueueWorker(workerID int) {
@@ -936,15 +1517,28 @@ func performValidation(pool config.ResourcePoolConfig) []error {
 }
 
 // partitionExists return true if the specified partition exists on the HPC cluster.
+// Partition names are matched case-insensitively, as for the partition overrides.
 func partitionExists(targetPartition string, knowPartitions []hpcPartitionDetails) bool {
 	for _, p := range knowPartitions {
-		if p.PartitionName == targetPartition {
+		if strings.EqualFold(p.PartitionName, targetPartition) {
 			return true
 		}
 	}
 	return false
 }
 
+// sampledPartitionName returns the name of the partition that matches the target partition
+// case-insensitively, as the workload manager spells it, or the target partition if none
+// matches.
+func sampledPartitionName(targetPartition string, knowPartitions []hpcPartitionDetails) string {
+	for _, p := range knowPartitions {
+		if strings.EqualFold(p.PartitionName, targetPartition) {
+			return p.PartitionName
+		}
+	}
+	return targetPartition
+}
+
 // hpcNodeToAgent converts a hpcNodeDetails to an agentv1.Agent.
 func (m *DispatcherResourceManager) hpcNodeToAgent(node hpcNodeDetails) *agentv1.Agent {
 	agent := &agentv1.Agent{
@@ -955,22 +1549,33 @@ func (m *DispatcherResourceManager) hpcNodeToAgent(node hpcNodeDetails) *agentv1
 		Addresses:      node.Addresses,
 		Enabled:        m.dbState.isAgentEnabled(node.Name),
 		Draining:       node.Draining,
+		State:          node.State,
+		StateReason:    node.Reason,
 	}
 	m.updateAgentWithAnyProvidedResourcePools(agent)
-	if node.GpuCount == 0 {
+	switch {
+	case node.GpuCount == 0 && m.rmConfig.CPUSlotDisplay == config.CPUSlotDisplayPerCore:
 		// Adds a slot ID (e.g., 0, 1, 2, ..., N) to the agent for every
-		// CPU being used on the node. This is needed so that the
-		// "Resource Pools" page on the Determined AI User Interface
-		// correctly shows the "N/M CPU Slots Allocated".
+		// CPU being used on the node, so that the agent shows how many
+		// of its CPUs are allocated.
 		for i := 0; i < node.CPUCount; i++ {
 			addSlotToAgent(
 				agent, devicev1.Type_TYPE_CPU, node, i, i < node.CPUInUseCount)
 		}
-	} else {
+	case node.GpuCount == 0:
+		// Nodes may have a large number of cores, so by default represent the
+		// CPUs of the node as a single slot that is in use whenever any CPU is.
+		// The pool level CPU slot counts come from the partition details, so
+		// they are unaffected by this.
+		if node.CPUCount > 0 {
+			addSlotToAgent(
+				agent, devicev1.Type_TYPE_CPU, node, 0, node.CPUInUseCount > 0)
+		}
+	default:
 		for i := 0; i < node.GpuCount; i++ {
 			slotType := computeSlotType(node, m)
 			addSlotToAgent(
-				agent, slotType, node, i, i < node.GpuInUseCount) // [1:N] CUDA slots
+				agent, slotType, node, i, i < node.gpusInUse()) // [1:N] CUDA slots
 		}
 	}
 	agent.SlotStats = model.SummarizeSlots(agent.Slots)
@@ -981,13 +1586,14 @@ func (m *DispatcherResourceManager) updateAgentWithAnyProvidedResourcePools(
 	agent *agentv1.Agent,
 ) {
 	for _, poolName := range agent.ResourcePools {
-		agent.ResourcePools = append(agent.ResourcePools, m.poolProviderMap[poolName]...)
+		agent.ResourcePools = append(agent.ResourcePools,
+			m.poolProviderMap[strings.ToLower(poolName)]...)
 	}
 }
 
 // computeSlotType computes an agent GPU slot type from the configuration data available.
 // For nodes that are members of multiple partitions, take the first configured slot type found,
-// falling back to CUDA if nothing found.
+// falling back to the configured default GPU slot type (CUDA unless set) if nothing found.
 func computeSlotType(node hpcNodeDetails, m *DispatcherResourceManager) devicev1.Type {
 	for _, partition := range node.Partitions {
 		slotType := m.rmConfig.ResolveSlotTypeFromOverrides(partition)
@@ -995,7 +1601,7 @@ func computeSlotType(node hpcNodeDetails, m *DispatcherResourceManager) devicev1
 			return slotType.Proto()
 		}
 	}
-	return devicev1.Type_TYPE_CUDA
+	return m.rmConfig.ResolveDefaultGpuSlotType().Proto()
 }
 
 // addSlotToAgent adds to the specifies agent a slot populated with a device of the specified type.
@@ -1096,6 +1702,39 @@ func (m *DispatcherResourceManager) DispatchExpLogMessage(msg dispatchExpLogMess
 	rmevents.Publish(task.AllocationID, &sproto.ContainerLog{AuxMessage: &msg.Message})
 }
 
+// handleDispatchNodesAssigned records the nodes that the dispatch is running on, so that they
+// are reported in the allocation summary, and shows them in the log of the task.
+func (m *DispatcherResourceManager) handleDispatchNodesAssigned(msg dispatchNodesAssigned) {
+	log := m.syslog.WithField("dispatch-id", msg.DispatchID)
+
+	// Persist the nodes before taking the lock, so that they are still known
+	// if the allocation is restored after a restart of the master.
+	if err := db.UpdateDispatchNodes(context.TODO(), msg.DispatchID, msg.Nodes); err != nil {
+		log.WithError(err).Error("failed to record the nodes of the dispatch")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task := m.getAssociatedTask(log, msg.DispatchID)
+	if task == nil {
+		return
+	}
+
+	alloc := m.reqList.Allocation(task.AllocationID)
+	if alloc == nil {
+		return
+	}
+	for _, r := range alloc.Resources {
+		if r, ok := r.(*DispatcherResources); ok && r.nodes != nil {
+			r.nodes.Store(&msg.Nodes)
+		}
+	}
+
+	nodesMsg := "Running on nodes: " + strings.Join(msg.Nodes, ", ")
+	rmevents.Publish(task.AllocationID, &sproto.ContainerLog{AuxMessage: &nodesMsg})
+}
+
 // DispatchStateChange records state changes and propagates them to allocations. It is called
 // by the launcher monitor event handler.
 // Note to developers: this function locks so don't make API or DB calls without optimization.
@@ -1110,15 +1749,17 @@ func (m *DispatcherResourceManager) DispatchStateChange(msg DispatchStateChange)
 	}
 
 	alloc := m.reqList.Allocation(task.AllocationID)
-	if len(alloc.Resources) != 1 {
-		log.Warnf("allocation has malformed resources: %v", alloc)
+	if alloc == nil || len(alloc.Resources) != 1 {
+		m.failMalformedAllocation(log, task, alloc)
 		return
 	}
 
 	_, exist := m.dispatchIDToHPCJobID.Load(msg.DispatchID)
 	if !exist && msg.HPCJobID != "" {
-		hpcJobIDMsg := "HPC Job ID: " + msg.HPCJobID
-		rmevents.Publish(task.AllocationID, &sproto.ContainerLog{AuxMessage: &hpcJobIDMsg})
+		if m.rmConfig.ShowHPCJobID {
+			hpcJobIDMsg := "HPC Job ID: " + msg.HPCJobID
+			rmevents.Publish(task.AllocationID, &sproto.ContainerLog{AuxMessage: &hpcJobIDMsg})
+		}
 		m.dispatchIDToHPCJobID.Store(msg.DispatchID, msg.HPCJobID)
 
 		log.WithField("hpc-job-id", msg.HPCJobID).
@@ -1134,6 +1775,92 @@ func (m *DispatcherResourceManager) DispatchStateChange(msg DispatchStateChange)
 		ResourcesState:   resourcesStateFromDispatchState(msg.IsPullingImage, msg.State),
 		ResourcesStarted: &sproto.ResourcesStarted{},
 	})
+	m.checkPendingDuration(log, task, rID, msg)
+}
+
+// checkPendingDuration cancels the dispatch of a task that has been pending for longer than the
+// max_pending_duration of its resource pool, e.g. because its resource request cannot be
+// satisfied, so that it does not stay queued indefinitely. The task is told why in its log.
+// Note to developers: the caller must hold the lock.
+func (m *DispatcherResourceManager) checkPendingDuration(
+	log *logrus.Entry, task *sproto.AllocateRequest, rID sproto.ResourcesID,
+	msg DispatchStateChange,
+) {
+	if msg.State != launcher.PENDING {
+		m.pendingSince.Delete(task.AllocationID)
+		return
+	}
+	limit := m.maxPendingDuration(task.ResourcePool)
+	if limit <= 0 {
+		return
+	}
+	since, ok := m.pendingSince.Load(task.AllocationID)
+	if !ok {
+		m.pendingSince.Store(task.AllocationID, time.Now())
+		return
+	}
+	pendingFor := time.Since(since)
+	if pendingFor < limit {
+		return
+	}
+	if _, ok := m.inflightCancelations.Load(task.AllocationID); ok {
+		return
+	}
+	// Start timing again, so that the cancelation is retried only if the job is still
+	// pending after another max_pending_duration.
+	m.pendingSince.Delete(task.AllocationID)
+	if _, ok := m.jobCancelQueue.PutIfAbsent(string(task.AllocationID), KillDispatcherResources{
+		ResourcesID:  rID,
+		AllocationID: task.AllocationID,
+	}); !ok {
+		return
+	}
+
+	message := fmt.Sprintf("HPC job canceled after pending for %s, longer than the "+
+		"max_pending_duration of %s of resource pool %s",
+		pendingFor.Round(time.Second), limit, task.ResourcePool)
+	if msg.PendingReason != "" {
+		message += ". The job was waiting to be scheduled: " + msg.PendingReason
+	}
+	log.WithField("allocation-id", task.AllocationID).
+		WithField("pending-duration", pendingFor).
+		Info("canceling job pending longer than max_pending_duration")
+	rmevents.Publish(task.AllocationID, &sproto.ContainerLog{AuxMessage: &message})
+}
+
+// failMalformedAllocation fails an allocation that does not have exactly one resources, as
+// every allocation of this resource manager has, rather than dropping its state changes and
+// leaving it stuck. Each of its resources is terminated with a failure, or, if it has none,
+// the allocation is failed as a whole.
+// Note to developers: the caller must hold the lock.
+func (m *DispatcherResourceManager) failMalformedAllocation(
+	log *logrus.Entry, task *sproto.AllocateRequest, alloc *sproto.ResourcesAllocated,
+) {
+	count := 0
+	if alloc != nil {
+		count = len(alloc.Resources)
+	}
+	reason := fmt.Sprintf("allocation has %d resources, expected 1", count)
+	log.WithField("allocation-id", task.AllocationID).
+		WithField("resources-count", count).
+		Errorf("allocation has malformed resources, failing it: %s", reason)
+
+	if count == 0 {
+		rmevents.Publish(task.AllocationID, &sproto.InvalidResourcesRequestError{
+			Cause: errors.New(reason),
+		})
+		return
+	}
+	for rID := range alloc.Resources {
+		stopped := sproto.ResourcesStopped{
+			Failure: sproto.NewResourcesFailure(sproto.ResourcesFailed, reason, nil),
+		}
+		rmevents.Publish(task.AllocationID, &sproto.ResourcesStateChanged{
+			ResourcesID:      rID,
+			ResourcesState:   sproto.Terminated,
+			ResourcesStopped: &stopped,
+		})
+	}
 }
 
 // Utility method to convert a dispatchID to an allocationID
@@ -1199,26 +1926,18 @@ func (m *DispatcherResourceManager) dispatchExited(
 		})
 	}
 
-	stopped := sproto.ResourcesStopped{}
-	if msg.ExitCode > 0 {
-		stopped.Failure = sproto.NewResourcesFailure(
-			sproto.ResourcesFailed,
-			"",
-			ptrs.Ptr(sproto.ExitCode(msg.ExitCode)),
-		)
+	stopped := msg.resourcesStopped()
+	if msg.Cause == dispatchExitedWithCode {
+		log.Infof("dispatch exited with exit code %d", msg.ExitCode)
+	} else {
+		log.Infof("dispatch %s", msg.Cause)
 	}
 
-	// Turn off printing the last line (exit code 1) from resources.go
-	if msg.ExitCode == -1 {
-		stopped.Failure = sproto.NewResourcesFailure(
-			sproto.ResourcesFailed,
-			"",
-			nil,
-		)
+	// Report the usage while the allocation still accepts logs.
+	if m.rmConfig.ReportJobUsage {
+		m.reportJobUsage(task.AllocationID, msg.DispatchID)
 	}
 
-	log.Infof("dispatch exited with exit code %d", msg.ExitCode)
-
 	rmevents.Publish(task.AllocationID, &sproto.ResourcesStateChanged{
 		ResourcesID:      rID,
 		ResourcesState:   sproto.Terminated,
@@ -1229,7 +1948,7 @@ func (m *DispatcherResourceManager) dispatchExited(
 
 	// Find the Dispatch IDs associated with the allocation ID. We'll need the
 	// Dispatch ID to clean up the dispatcher environments for the job.
-	dispatches, err := db.ListDispatchesByAllocationID(context.TODO(), allocationID)
+	dispatches, err := m.listDispatchesByAllocationID(context.TODO(), allocationID)
 	if err != nil {
 		log.WithError(err).
 			Error("failed to retrieve the dispatches")
@@ -1256,19 +1975,21 @@ func (m *DispatcherResourceManager) dispatchExited(
 }
 
 // Common method for sending a terminate request, and appropriately clean up a dispatch.
-// Called only from killAllInactiveDispatches which is always run via go routine.
+// Called only from releaseInactiveDispatches, and returns whether the dispatch was
+// terminated and whether it was deleted.
 // Note to developers: this function must not acquire locks, unless they careful avoid being
 // held over the API and DB calls.
 func (m *DispatcherResourceManager) terminateAndDeleteDispatch(
 	dispatchID string,
 	impersonatedUser string,
-) {
+) (terminated bool, deleted bool) {
 	log := m.syslog.WithField("dispatch-id", dispatchID)
 
 	log.WithField("impersonated-user", impersonatedUser).
 		Info("terminating dispatch job initiated by user")
 
-	if m.terminateDispatcherJob(dispatchID, impersonatedUser, false) {
+	terminated = m.terminateDispatcherJob(dispatchID, impersonatedUser, false)
+	if terminated {
 		// Do not remove the dispatch environment if the job is being
 		// monitored by the job watcher, as it is needed in order for
 		// the launcher to report the job status. If we remove the
@@ -1289,9 +2010,10 @@ func (m *DispatcherResourceManager) terminateAndDeleteDispatch(
 			// for the terminate request above to complete, before we can actually
 			// do the delete of the environment to avoid a 500 error response.
 			m.waitForDispatchTerminalState(impersonatedUser, dispatchID)
-			m.removeDispatchEnvironment(impersonatedUser, dispatchID)
+			deleted = m.removeDispatchEnvironment(impersonatedUser, dispatchID)
 		}
 	}
+	return terminated, deleted
 }
 
 // Wait up to 2mins for the dispatch to be in a terminal state.
@@ -1340,7 +2062,7 @@ func (m *DispatcherResourceManager) startLauncherJob(
 
 	// TODO: There is a 'which first?' issue with resolving slot type and partition that needs to be
 	// unwound before it causes a bug.
-	partition := m.getProvidingPartition(req.ResourcePool)
+	partition := m.getLaunchPartition(hpcDetails, msg.Spec.TaskType, req)
 
 	slotType := device.CPU
 	// Only resolve the slot type if the number of slots requested is non-zero.
@@ -1354,25 +2076,68 @@ func (m *DispatcherResourceManager) startLauncherJob(
 	if partition == "" {
 		partition = m.getDefaultPoolName(hpcDetails, slotType == device.CPU)
 	}
+	// The partition may be configured in a different case than the workload manager uses.
+	partition = sampledPartitionName(partition, hpcDetails.Partitions)
+
+	if err := m.validateExclusiveNodes(hpcDetails, partition, msg.Spec, req.SlotsNeeded); err != nil {
+		m.sendResourceStateChangedErrorResponse(err, msg, "unable to launch job")
+		return
+	}
+
+	if err := m.validateReservation(hpcDetails, partition, msg.Spec); err != nil {
+		m.sendResourceStateChangedErrorResponse(err, msg, "unable to launch job")
+		return
+	}
+
+	err = m.validateNodeCapacity(hpcDetails, partition, slotType, msg.Spec, req.SlotsNeeded)
+	if err != nil {
+		m.sendResourceStateChangedErrorResponse(err, msg, "unable to launch job")
+		return
+	}
+
+	if err := m.validateSbatchArgs(
+		userSbatchArgs(msg.Spec.SlurmConfig.SbatchArgs(), msg.Spec.TaskContainerDefaults.Slurm.SbatchArgs()),
+		userSbatchArgs(msg.Spec.PbsConfig.SbatchArgs(), msg.Spec.TaskContainerDefaults.Pbs.SbatchArgs()),
+	); err != nil {
+		m.sendResourceStateChangedErrorResponse(err, msg, "unable to launch job")
+		return
+	}
 
-	tresSupported := m.rmConfig.TresSupported
-	gresSupported := m.rmConfig.GresSupported
-	if m.rmConfig.TresSupported && !m.rmConfig.GresSupported {
-		m.syslog.Warn("tres_supported: true cannot be used when " +
-			"gres_supported: false is specified. Use tres_supported: false instead.")
-		tresSupported = false
+	hpcJobDependencies, active, err := m.resolveHPCJobDependencies(msg)
+	if err != nil {
+		m.sendResourceStateChangedErrorResponse(err, msg, "unable to launch job")
+		return
+	}
+	if !active {
+		return
 	}
 
 	disabledAgents := set.FromSlice(append(m.dbState.DisabledAgents, req.BlockedNodes...)).ToSlice()
 
+	containerRunType := m.rmConfig.ResolveContainerRunType(partition)
+	masterHost, masterPort := m.masterAddress(req.ResourcePool, partition)
+
 	// Create the manifest that will be ultimately sent to the launcher.
 	manifest, impersonatedUser, payloadName, err := msg.Spec.ToDispatcherManifest(
 		m.syslog, string(req.AllocationID),
-		m.masterTLSConfig.Enabled,
-		m.rmConfig.MasterHost, m.rmConfig.MasterPort, m.masterTLSConfig.CertificateName,
-		req.SlotsNeeded, slotType, partition, tresSupported, gresSupported,
-		m.rmConfig.LauncherContainerRunType, m.wlmType == pbsSchedulerType,
-		m.rmConfig.JobProjectSource, disabledAgents,
+		req.SlotsNeeded, slotType, partition,
+		tasks.DispatcherLaunchOptions{
+			TLSEnabled:            m.masterTLSConfig.Enabled,
+			MasterHost:            masterHost,
+			MasterPort:            masterPort,
+			CertificateName:       m.masterTLSConfig.CertificateName,
+			TresSupported:         m.rmConfig.ResolveTresSupported(),
+			GresSupported:         m.rmConfig.GresSupported,
+			ContainerRunType:      containerRunType,
+			IsPbsLauncher:         m.wlmType == pbsSchedulerType,
+			LabelMode:             m.rmConfig.JobProjectSource,
+			JobNamePrefix:         m.rmConfig.JobNamePrefix,
+			JobCommentFields:      m.rmConfig.JobCommentFields,
+			ImpersonationResolver: m.impersonationResolver,
+			DisabledNodes:         disabledAgents,
+			HPCJobDependencies:    hpcJobDependencies,
+			ImagePullPolicy:       m.imagePullPolicy(req.ResourcePool, partition),
+		},
 	)
 	if err != nil {
 		m.sendResourceStateChangedErrorResponse(err, msg,
@@ -1396,7 +2161,7 @@ func (m *DispatcherResourceManager) startLauncherJob(
 	}
 
 	warning := msg.Spec.WarnUnsupportedOptions(
-		msg.UserConfiguredPriority, m.rmConfig.LauncherContainerRunType)
+		msg.UserConfiguredPriority, containerRunType)
 
 	if len(warning) > 0 {
 		rmevents.Publish(msg.AllocationID, &sproto.ContainerLog{
@@ -1413,7 +2178,7 @@ func (m *DispatcherResourceManager) startLauncherJob(
 	// handle events from the launched job and insert the dispatch into
 	// the DB so that we ensure that it is later cleaned-up
 	// if the launch is successful.
-	if err := db.InsertDispatch(context.TODO(), &db.Dispatch{
+	if err := m.insertDispatch(context.TODO(), &db.Dispatch{
 		DispatchID:       dispatchID,
 		ResourceID:       msg.ResourcesID,
 		AllocationID:     req.AllocationID,
@@ -1437,7 +2202,7 @@ func (m *DispatcherResourceManager) startLauncherJob(
 			WithField("description", msg.Spec.Description).
 			Infof("remove dispatch from failed launch")
 
-		_, dberr := db.DeleteDispatch(context.TODO(), dispatchID)
+		_, dberr := m.deleteDispatch(context.TODO(), dispatchID)
 		if dberr != nil {
 			m.syslog.WithField("dispatch-id", dispatchID).
 				WithError(dberr).Errorf("failed to delete dispatch from DB")
@@ -1466,6 +2231,180 @@ func (m *DispatcherResourceManager) startLauncherJob(
 	}
 }
 
+// taskDependencies returns the dispatches that a task depends on, from the section of its
+// configuration for the workload manager.
+func (m *DispatcherResourceManager) taskDependencies(spec tasks.TaskSpec) []string {
+	if m.wlmType == pbsSchedulerType {
+		return spec.PbsConfig.DependsOn()
+	}
+	return spec.SlurmConfig.DependsOn()
+}
+
+// resolveHPCJobDependencies returns the HPC job IDs of the dispatches that a task depends
+// on. While some of them are not submitted yet, it waits without counting against the
+// limit of concurrent launches, so that they can be launched first, unless they in turn
+// wait for the task. It returns false if the task is released while waiting.
+func (m *DispatcherResourceManager) resolveHPCJobDependencies(
+	msg StartDispatcherResources,
+) ([]string, bool, error) {
+	log := m.syslog.WithField("allocation-id", msg.AllocationID)
+	dependencies := m.taskDependencies(msg.Spec)
+	waiting := false
+	defer m.dependencyWaits.Delete(msg.AllocationID)
+	for {
+		var hpcJobIDs, pending []string
+		for _, dispatchID := range dependencies {
+			if dispatchID == string(msg.AllocationID) {
+				return nil, true, fmt.Errorf("the job cannot depend on its own dispatch %s", dispatchID)
+			}
+			hpcJobID, isPending, err := m.lookupHPCJobDependency(msg.Spec.Owner, dispatchID)
+			switch {
+			case err != nil:
+				return nil, true, err
+			case isPending:
+				pending = append(pending, dispatchID)
+			case hpcJobID != "":
+				hpcJobIDs = append(hpcJobIDs, hpcJobID)
+			}
+		}
+		if len(pending) == 0 {
+			if waiting {
+				m.scheduledLaunches.Store(msg.AllocationID, struct{}{})
+			}
+			return hpcJobIDs, true, nil
+		}
+
+		if !waiting {
+			m.dependencyWaits.Store(msg.AllocationID, dependencies)
+			if cycle := m.dependencyCycle(msg.AllocationID); cycle != nil {
+				return nil, true, fmt.Errorf("the job depends on itself through %s",
+					strings.Join(cycle, " -> "))
+			}
+			waiting = true
+			m.scheduledLaunches.Delete(msg.AllocationID)
+			log.WithField("dependencies", pending).Info("waiting for dependencies to be submitted")
+			rmevents.Publish(msg.AllocationID, &sproto.ContainerLog{
+				AuxMessage: ptrs.Ptr(fmt.Sprintf(
+					"Waiting for %s to be submitted before launching the job",
+					strings.Join(pending, ", "))),
+			})
+		}
+		time.Sleep(dependencyPollInterval)
+
+		m.mu.Lock()
+		_, ok := m.reqList.TaskByID(msg.AllocationID)
+		m.mu.Unlock()
+		if !ok {
+			log.Info("job released while waiting for dependencies")
+			return nil, false, nil
+		}
+	}
+}
+
+// dependencyCycle returns the dispatches through which an allocation waiting for its
+// dependencies waits for itself, or nil if it does not.
+func (m *DispatcherResourceManager) dependencyCycle(allocationID model.AllocationID) []string {
+	visited := make(map[string]bool)
+	var visit func(dispatchID string, path []string) []string
+	visit = func(dispatchID string, path []string) []string {
+		path = append(path, dispatchID)
+		if dispatchID == string(allocationID) {
+			return path
+		}
+		if visited[dispatchID] {
+			return nil
+		}
+		visited[dispatchID] = true
+		dependencies, _ := m.dependencyWaits.Load(model.AllocationID(dispatchID))
+		for _, dependency := range dependencies {
+			if cycle := visit(dependency, slices.Clone(path)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	dependencies, _ := m.dependencyWaits.Load(allocationID)
+	for _, dependency := range dependencies {
+		if cycle := visit(dependency, nil); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// lookupHPCJobDependency returns the HPC job ID of a dispatch that a task of the given owner
+// depends on, or whether the dispatch is still to be submitted. A dispatch that already
+// completed successfully has nothing left to wait for, so it has no HPC job ID; one that
+// failed is an error, since the task would never start. Only admins may depend on the
+// dispatches of the jobs of other users; to others, those are not known dispatches, so that
+// their existence and outcome are not disclosed.
+func (m *DispatcherResourceManager) lookupHPCJobDependency(
+	owner *model.User, dispatchID string,
+) (hpcJobID string, pending bool, err error) {
+	if err := m.checkDependencyAccess(owner, dispatchID); err != nil {
+		return "", false, err
+	}
+
+	if hpcJobID, ok := m.dispatchIDToHPCJobID.Load(dispatchID); ok && hpcJobID != "" {
+		return hpcJobID, false, nil
+	}
+
+	// The dispatch ID is the allocation ID.
+	allocationID := model.AllocationID(dispatchID)
+	if _, ok := m.scheduledLaunches.Load(allocationID); ok ||
+		m.jobWatcher.isJobBeingMonitored(dispatchID) {
+		return "", true, nil
+	}
+	m.mu.Lock()
+	_, queued := m.reqList.TaskByID(allocationID)
+	m.mu.Unlock()
+	if queued {
+		return "", true, nil
+	}
+
+	alloc, err := m.allocationByID(context.TODO(), allocationID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "", false, fmt.Errorf("dependency %s is not a known dispatch", dispatchID)
+	case err != nil:
+		return "", false, fmt.Errorf("looking up dependency %s: %w", dispatchID, err)
+	case alloc.EndTime == nil:
+		// The allocation is being set up, or is exiting and its end isn't recorded yet.
+		return "", true, nil
+	case alloc.ExitErr != nil:
+		// The error may disclose details of the dependency, so it is only logged.
+		m.syslog.WithField("dispatch-id", dispatchID).
+			WithField("exit-error", *alloc.ExitErr).
+			Info("job dependency failed")
+		return "", false, fmt.Errorf("dependency %s failed", dispatchID)
+	default:
+		return "", false, nil
+	}
+}
+
+// checkDependencyAccess returns an error if a task of the given owner may not depend on the
+// dispatch, which is the same as that of a dispatch that does not exist.
+func (m *DispatcherResourceManager) checkDependencyAccess(owner *model.User, dispatchID string) error {
+	if owner != nil && owner.Admin {
+		return nil
+	}
+
+	ownerID, err := m.allocationOwnerID(context.TODO(), model.AllocationID(dispatchID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("dependency %s is not a known dispatch", dispatchID)
+	case err != nil:
+		return fmt.Errorf("looking up dependency %s: %w", dispatchID, err)
+	case owner == nil || ownerID == nil || *ownerID != owner.ID:
+		m.syslog.WithField("dispatch-id", dispatchID).
+			Info("denied a dependency on the dispatch of a job of another user")
+		return fmt.Errorf("dependency %s is not a known dispatch", dispatchID)
+	default:
+		return nil
+	}
+}
+
 // stopLauncherJob is called only via KillDispatcherResources and called via go routine.
 // Note to developers: this function must not acquire locks, unless they careful avoid being
 // held over the API and DB calls.
@@ -1483,7 +2422,7 @@ func (m *DispatcherResourceManager) stopLauncherJob(msg KillDispatcherResources)
 
 	// Find the Dispatch IDs associated with the allocation ID. We'll need the
 	// Dispatch ID to cancel the job on the launcher side.
-	dispatches, err := db.ListDispatchesByAllocationID(context.TODO(), msg.AllocationID)
+	dispatches, err := m.listDispatchesByAllocationID(context.TODO(), msg.AllocationID)
 	if err != nil {
 		m.syslog.WithField("allocation-id", msg.AllocationID).WithError(err).Errorf(
 			"failed to retrieve the dispatches")
@@ -1505,16 +2444,16 @@ func (m *DispatcherResourceManager) stopLauncherJob(msg KillDispatcherResources)
 		Debugf("job termination handler found %d jobs associated with allocation",
 			len(dispatches))
 
+	var toTerminate []*db.Dispatch
 	for _, dispatch := range dispatches {
 		dispatchID := dispatch.DispatchID
-		impersonatedUser := dispatch.ImpersonatedUser
 
 		// Get the HPC job ID, if it's available, to include in the log message.
 		hpcJobID, _ := m.dispatchIDToHPCJobID.Load(dispatchID)
 
 		logger := m.syslog.WithField("dispatch-id", dispatchID).
 			WithField("hpc-job-id", hpcJobID).
-			WithField("impersonated-user", impersonatedUser)
+			WithField("impersonated-user", dispatch.ImpersonatedUser)
 
 		// When the job monitor's queue is large, it may take a while for the
 		// job monitor to query the launcher for confirmation that the Workload
@@ -1532,47 +2471,51 @@ func (m *DispatcherResourceManager) stopLauncherJob(msg KillDispatcherResources)
 				WithField("current-job-position", m.jobWatcher.currentJobPosition.Load()).
 				WithField("last-update", m.jobWatcher.getLastJobStatusCheckTime(dispatchID).Format("2006-01-02 15:04:05")).
 				Info("termination request already sent, waiting for acknowledgement of termination")
-			return
+			continue
 		}
 
 		logger.Info("terminating job initiated by user")
+		toTerminate = append(toTerminate, dispatch)
+	}
 
-		// Terminate and cleanup, on failure leave Dispatch in DB for later retry
-		if m.terminateDispatcherJob(dispatchID, impersonatedUser, false) {
-			// Do not remove the dispatch environment if the job is being
-			// monitored by the job watcher, as it is needed in order for
-			// the launcher to report the job status. If we remove the
-			// dispatch environment, then the launcher will no longer be
-			// able to provide job information and will return an HTTP 404
-			// status when the job watcher asks it for status. As a result,
-			// the Detemined AI job status will never get updated from
-			// "Running" to "Canceled", for example.  When the job watcher
-			// gets a terminatal state from the launcher, it will take care
-			// of removing the dispatch environment at that time.
-			if m.jobWatcher.isJobBeingMonitored(dispatchID) {
-				m.syslog.WithField("dispatch-id", dispatchID).Debug(
-					"not removing dispatch environment because job is being monitored")
-			} else {
-				// If we are here, then we are likely being called from
-				// startup, as opposed to a user explicitly canceling
-				// a job. It's OK to remove the environment in this case
-				// because we aren't actively monitoring any jobs, but we need to wait
-				// for the terminate request above to complete, before we can actually
-				// do the delete of the environment to avoid a 500 error response.
-				m.waitForDispatchTerminalState(impersonatedUser, dispatchID)
-				m.removeDispatchEnvironment(impersonatedUser, dispatchID)
-
-				// The job monitor usually takes care of notifying Determined
-				// that the job terminated, but since the job is no longer
-				// being monitored, we have to send the notification ourselves,
-				// so that the job doesn't remain in the STOPPING_CANCELED
-				// state.
-				m.handleDispatchExited(DispatchExited{
-					DispatchID: dispatchID,
-					ExitCode:   -1,
-					Message:    "Job was canceled",
-				})
-			}
+	// Terminate and cleanup, on failure leave Dispatch in DB for later retry
+	for _, dispatch := range m.terminateDispatcherJobs(toTerminate) {
+		dispatchID := dispatch.DispatchID
+		impersonatedUser := dispatch.ImpersonatedUser
+
+		// Do not remove the dispatch environment if the job is being
+		// monitored by the job watcher, as it is needed in order for
+		// the launcher to report the job status. If we remove the
+		// dispatch environment, then the launcher will no longer be
+		// able to provide job information and will return an HTTP 404
+		// status when the job watcher asks it for status. As a result,
+		// the Detemined AI job status will never get updated from
+		// "Running" to "Canceled", for example.  When the job watcher
+		// gets a terminatal state from the launcher, it will take care
+		// of removing the dispatch environment at that time.
+		if m.jobWatcher.isJobBeingMonitored(dispatchID) {
+			m.syslog.WithField("dispatch-id", dispatchID).Debug(
+				"not removing dispatch environment because job is being monitored")
+		} else {
+			// If we are here, then we are likely being called from
+			// startup, as opposed to a user explicitly canceling
+			// a job. It's OK to remove the environment in this case
+			// because we aren't actively monitoring any jobs, but we need to wait
+			// for the terminate request above to complete, before we can actually
+			// do the delete of the environment to avoid a 500 error response.
+			m.waitForDispatchTerminalState(impersonatedUser, dispatchID)
+			m.removeDispatchEnvironment(impersonatedUser, dispatchID)
+
+			// The job monitor usually takes care of notifying Determined
+			// that the job terminated, but since the job is no longer
+			// being monitored, we have to send the notification ourselves,
+			// so that the job doesn't remain in the STOPPING_CANCELED
+			// state.
+			m.handleDispatchExited(DispatchExited{
+				DispatchID: dispatchID,
+				Cause:      dispatchCanceled,
+				Message:    "Job was canceled",
+			})
 		}
 	}
 }
@@ -1639,7 +2582,8 @@ func (m *DispatcherResourceManager) getWlmResources() (
 
 // resolveSlotType resolves the correct slot type for a job targeting the given partition. If the
 // slot type is specified in the master config, use that. Otherwise if the partition is specified
-// and known, and has no GPUs select CPU as the processor type, else default to CUDA.
+// and known, and has no GPUs select CPU as the processor type, else default to the configured
+// default GPU slot type (CUDA unless set).
 // Note to the developer: this must not acquire a lock.
 func (m *DispatcherResourceManager) resolveSlotType(
 	hpcDetails *hpcResources,
@@ -1650,11 +2594,143 @@ func (m *DispatcherResourceManager) resolveSlotType(
 	}
 
 	for _, v := range hpcDetails.Partitions {
-		if v.PartitionName == partition && v.TotalGpuSlots == 0 {
+		if strings.EqualFold(v.PartitionName, partition) && v.TotalGpuSlots == 0 {
 			return device.CPU
 		}
 	}
-	return device.CUDA
+	return m.rmConfig.ResolveDefaultGpuSlotType()
+}
+
+// validateExclusiveNodes checks that a job requesting whole nodes for exclusive use
+// does not ask for more nodes than the partition has, and that its slots can be
+// spread evenly across those nodes so that each node is accounted the same number of
+// slots.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) validateExclusiveNodes(
+	hpcDetails *hpcResources,
+	partition string,
+	spec tasks.TaskSpec,
+	numSlots int,
+) error {
+	numNodes := spec.ExclusiveNodes(numSlots, m.wlmType == pbsSchedulerType)
+	if numNodes == 0 {
+		return nil
+	}
+	if numSlots%numNodes != 0 {
+		return fmt.Errorf("the %d slots requested cannot be spread evenly across "+
+			"the %d exclusive nodes requested", numSlots, numNodes)
+	}
+	for _, v := range hpcDetails.Partitions {
+		if strings.EqualFold(v.PartitionName, partition) && numNodes > v.TotalNodes {
+			return fmt.Errorf("%d exclusive nodes requested, but resource pool '%s' "+
+				"has only %d nodes", numNodes, partition, v.TotalNodes)
+		}
+	}
+	return nil
+}
+
+// validateNodeCapacity checks that some node of the partition has as many slots as the job
+// requires on each node, so that a job that can never be scheduled fails now rather than
+// pending forever. The check is skipped if the sample has no nodes of the partition, e.g.
+// while they are all down.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) validateNodeCapacity(
+	hpcDetails *hpcResources,
+	partition string,
+	slotType device.Type,
+	spec tasks.TaskSpec,
+	numSlots int,
+) error {
+	slotsPerNode := spec.SlotsRequiredPerNode(numSlots, m.wlmType == pbsSchedulerType)
+	if slotsPerNode == 0 {
+		return nil
+	}
+	found := false
+	for _, node := range hpcDetails.Nodes {
+		if !slices.ContainsFunc(node.Partitions, func(p string) bool {
+			return strings.EqualFold(p, partition)
+		}) {
+			continue
+		}
+		found = true
+		capacity := node.GpuCount
+		if slotType == device.CPU {
+			capacity = node.CPUCount
+		}
+		if capacity >= slotsPerNode {
+			return nil
+		}
+	}
+	if !found {
+		return nil
+	}
+	return fmt.Errorf("unschedulable: no node in resource pool '%s' has %d %s slots",
+		partition, slotsPerNode, slotType)
+}
+
+// validateReservation checks that the Slurm reservation targeted by a job, if any, exists
+// and may be used in the partition, so that the job fails now rather than pending forever.
+// The check is skipped if the launcher does not report the reservations.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) validateReservation(
+	hpcDetails *hpcResources,
+	partition string,
+	spec tasks.TaskSpec,
+) error {
+	reservation := spec.SlurmConfig.Reservation()
+	if reservation == nil || m.wlmType == pbsSchedulerType || hpcDetails.Reservations == nil {
+		return nil
+	}
+	for _, v := range hpcDetails.Reservations {
+		if v.Name != *reservation {
+			continue
+		}
+		if v.Partition != "" && !strings.EqualFold(v.Partition, partition) {
+			return fmt.Errorf("reservation '%s' is for partition '%s' and cannot be used "+
+				"in resource pool '%s'", *reservation, v.Partition, partition)
+		}
+		return nil
+	}
+	return fmt.Errorf("reservation '%s' does not exist -- verify the name of the "+
+		"reservation in the slurm section of the configuration", *reservation)
+}
+
+// validateSbatchArgs checks that the sbatch or qsub arguments that a user specified for a job
+// are permitted by the allowed_sbatch_args and denied_sbatch_args of the resource manager.
+// Note to the developer: this must not acquire a lock.
+func (m *DispatcherResourceManager) validateSbatchArgs(slurmArgs, pbsArgs []string) error {
+	allowed, denied := m.rmConfig.AllowedSbatchArgs, m.rmConfig.DeniedSbatchArgs
+	var errs []error
+	if m.wlmType == pbsSchedulerType {
+		errs = tasks.ValidatePbsPolicy(pbsArgs, allowed, denied)
+	} else {
+		errs = tasks.ValidateSlurmPolicy(slurmArgs, allowed, denied)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// userSbatchArgs returns the batch arguments of a job without those of the task container
+// defaults, which are merged into the arguments of the task. The defaults are configured by
+// the administrator, so they are not subject to the allowed_sbatch_args and
+// denied_sbatch_args.
+func userSbatchArgs(args, defaults []string) []string {
+	var result []string
+	remaining := slices.Clone(defaults)
+	for _, arg := range args {
+		if i := slices.Index(remaining, arg); i >= 0 {
+			remaining = slices.Delete(remaining, i, i+1)
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
 }
 
 // ResourceQueryPostActions performs actions to clean up after any dispatch
@@ -1668,7 +2744,7 @@ func (m *DispatcherResourceManager) resolveSlotType(
 // with the DB, so we do not log an error if we fail to delete it.
 // On any REST failure where we cannot confirm the dispatch has been removed
 // by the launcher, we skip any attempt to delete the Dispatch from the DB.
-// The Dispatch is left in the DB, for a future cleanup attempt on startup.
+// The Dispatch is left in the DB, for a later cleanup attempt.
 // Called only from fetchHpcResourceDetails and always run via go routine
 // except the one time during startup to retrieve initial cluster cache.
 func (m *DispatcherResourceManager) ResourceQueryPostActions(
@@ -1718,19 +2794,70 @@ func (m *DispatcherResourceManager) terminateDispatcherJob(
 	return true
 }
 
+// terminateDispatcherJobs terminates the given dispatches, sending the termination
+// requests of each impersonated user to the launcher as one batch, and returns the
+// dispatches that were terminated, in their original order.
+// Note to developers: this function must not acquire locks.
+func (m *DispatcherResourceManager) terminateDispatcherJobs(dispatches []*db.Dispatch) []*db.Dispatch {
+	// The logger we will pass to the API client, so that when the API client
+	// logs a message, we know who called it.
+	launcherAPILogger := m.syslog.WithField("caller", "terminateDispatcherJobs")
+
+	var owners []string
+	dispatchIDsByOwner := make(map[string][]string)
+	for _, dispatch := range dispatches {
+		if dispatch.DispatchID == "" {
+			m.syslog.Warn("missing dispatchID, so no environment clean-up")
+			continue
+		}
+		if _, ok := dispatchIDsByOwner[dispatch.ImpersonatedUser]; !ok {
+			owners = append(owners, dispatch.ImpersonatedUser)
+		}
+		dispatchIDsByOwner[dispatch.ImpersonatedUser] = append(
+			dispatchIDsByOwner[dispatch.ImpersonatedUser], dispatch.DispatchID)
+	}
+
+	errs := make(map[string]error)
+	for _, owner := range owners {
+		maps.Copy(errs, m.apiClient.terminateDispatches(
+			owner, dispatchIDsByOwner[owner], launcherAPILogger))
+	}
+
+	var terminated []*db.Dispatch
+	for _, dispatch := range dispatches {
+		if dispatch.DispatchID == "" {
+			continue
+		}
+		if err := errs[dispatch.DispatchID]; err != nil {
+			m.syslog.WithField("dispatch-id", dispatch.DispatchID).
+				WithError(err).Errorf("failed to terminate dispatch job")
+			continue
+		}
+		m.syslog.WithField("dispatch-id", dispatch.DispatchID).Info("terminated dispatch job")
+
+		// Let the job monitor know that the job was terminated, otherwise it
+		// might get a 404 (Not Found) error from the launcher and not send
+		// Determined notification that the job was terminated.
+		m.jobWatcher.markJobAsTerminated(dispatch.DispatchID)
+		terminated = append(terminated, dispatch)
+	}
+	return terminated
+}
+
 // removeDispatchEnvironment uses the dispatcher REST API to remove
 // the environment created on the launcher node in support of the
 // job with the specified dispatch ID. This prevents stale information
 // from accumulating in the dispatcher.  Upon success, it additionally
 // attempts to remove the dispatchID association (if present) with the allocation
 // in the DB.  On failure, the attempt to remove the Dispatch
-// from the DB is skipped and left for a future cleanup attempt on startup.
+// from the DB is skipped and left for a later cleanup attempt, see retryDispatchCleanups,
+// or the cleanup on startup.
 // When querying Slurm resource information, the DispatchID is not registered
 // with the DB, so we do not log an error if we fail to remove it.
 // Note to developers: this function must not acquire locks.
 func (m *DispatcherResourceManager) removeDispatchEnvironment(
 	owner string, dispatchID string,
-) {
+) bool {
 	log := m.syslog.WithField("dispatch-id", dispatchID).WithField("owner", owner)
 
 	// The logger we will pass to the API client, so that when the API client
@@ -1740,16 +2867,208 @@ func (m *DispatcherResourceManager) removeDispatchEnvironment(
 	_, err := m.apiClient.deleteDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
 	if err != nil {
 		log.WithError(err).Error("failed to delete dispatch")
-		return
+		m.recordDispatchCleanupFailure(owner, dispatchID, time.Now())
+		return false
 	}
 
-	count, err := db.DeleteDispatch(context.TODO(), dispatchID)
+	count, err := m.deleteDispatch(context.TODO(), dispatchID)
 	if err != nil {
 		log.WithError(err).Error("failed to delete dispatch from DB")
-		return
+		m.recordDispatchCleanupFailure(owner, dispatchID, time.Now())
+		return false
 	}
 	// On Slurm resource query there may be no Dispatch in the DB, so only log as trace.
 	log.Tracef("Deleted dispatch from DB, count %d", count)
+	m.clearDispatchCleanupFailure(dispatchID)
+	return true
+}
+
+// dispatchCleanupFailure records the failed attempts to clean up a dispatch.
+type dispatchCleanupFailure struct {
+	owner       string
+	attempts    int
+	nextAttempt time.Time
+}
+
+// recordDispatchCleanupFailure records a failed attempt to clean up the dispatch and
+// schedules the next one, unless retries are disabled. An error is logged when the
+// cleanup has failed dispatch_cleanup_alert_attempts times.
+func (m *DispatcherResourceManager) recordDispatchCleanupFailure(
+	owner, dispatchID string, now time.Time,
+) {
+	interval := m.rmConfig.ResolveDispatchCleanupRetryInterval()
+	if interval == 0 {
+		return
+	}
+	alertAttempts := m.rmConfig.ResolveDispatchCleanupAlertAttempts()
+
+	var attempts, stuck int
+	m.cleanupFailures.WithLock(func(inmap map[string]*dispatchCleanupFailure) {
+		f, ok := inmap[dispatchID]
+		if !ok {
+			f = &dispatchCleanupFailure{owner: owner}
+			inmap[dispatchID] = f
+		}
+		f.attempts++
+		f.nextAttempt = now.Add(dispatchCleanupBackoff(interval, f.attempts))
+		attempts = f.attempts
+		stuck = countStuckDispatchCleanups(inmap, alertAttempts)
+	})
+	recordStuckDispatchCleanups(stuck)
+
+	if attempts == alertAttempts {
+		m.syslog.WithField("dispatch-id", dispatchID).WithField("owner", owner).
+			Errorf("failed to clean up dispatch after %d attempts; its launcher environment "+
+				"may need to be deleted manually, but cleanup is still retried", attempts)
+	}
+}
+
+// clearDispatchCleanupFailure forgets the failed attempts to clean up the dispatch, once
+// it is cleaned up.
+func (m *DispatcherResourceManager) clearDispatchCleanupFailure(dispatchID string) {
+	alertAttempts := m.rmConfig.ResolveDispatchCleanupAlertAttempts()
+
+	var stuck int
+	found := false
+	m.cleanupFailures.WithLock(func(inmap map[string]*dispatchCleanupFailure) {
+		if _, found = inmap[dispatchID]; found {
+			delete(inmap, dispatchID)
+			stuck = countStuckDispatchCleanups(inmap, alertAttempts)
+		}
+	})
+	if found {
+		recordStuckDispatchCleanups(stuck)
+		m.syslog.WithField("dispatch-id", dispatchID).Info("cleaned up dispatch after retrying")
+	}
+}
+
+// countStuckDispatchCleanups returns how many dispatches failed to be cleaned up at least
+// alertAttempts times.
+func countStuckDispatchCleanups(failures map[string]*dispatchCleanupFailure, alertAttempts int) int {
+	stuck := 0
+	for _, f := range failures {
+		if f.attempts >= alertAttempts {
+			stuck++
+		}
+	}
+	return stuck
+}
+
+// dispatchCleanupBackoff returns how long to wait before the next attempt to clean up a
+// dispatch that failed the given number of times: the interval, doubled after each failure
+// up to maxDispatchCleanupRetryInterval.
+func dispatchCleanupBackoff(interval time.Duration, attempts int) time.Duration {
+	limit := max(interval, maxDispatchCleanupRetryInterval)
+	backoff := interval
+	for i := 1; i < attempts && backoff < limit; i++ {
+		backoff *= 2
+	}
+	return min(backoff, limit)
+}
+
+// retryDispatchCleanups periodically retries the cleanup of the dispatches whose launcher
+// environment could not be deleted, rather than leaving them until the master restarts.
+func (m *DispatcherResourceManager) retryDispatchCleanups() {
+	interval := m.rmConfig.ResolveDispatchCleanupRetryInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		m.retryDueDispatchCleanups(now)
+	}
+}
+
+// retryDueDispatchCleanups retries the cleanup of the dispatches whose next attempt is due.
+func (m *DispatcherResourceManager) retryDueDispatchCleanups(now time.Time) {
+	due := map[string]string{}
+	m.cleanupFailures.WithLock(func(inmap map[string]*dispatchCleanupFailure) {
+		for dispatchID, f := range inmap {
+			if !now.Before(f.nextAttempt) {
+				due[dispatchID] = f.owner
+			}
+		}
+	})
+
+	for dispatchID, owner := range due {
+		m.syslog.WithField("dispatch-id", dispatchID).Info("retrying the cleanup of dispatch")
+		m.removeDispatchEnvironment(owner, dispatchID)
+	}
+}
+
+// dispatchStatus is the state of a dispatch as reported by the launcher, along with the
+// reason the launcher gives for it, if any.
+type dispatchStatus struct {
+	state  launcher.DispatchState
+	reason string
+}
+
+// getDispatchStatus asks the launcher for the current state of the dispatch, rather than
+// waiting for the job watcher to report it. A dispatch the launcher does not know of is
+// reported as MISSING, and one the launcher reports no state for as UNKNOWN.
+// Note to developers: this function makes an API call, so it must not be called while
+// holding m.mu.
+func (m *DispatcherResourceManager) getDispatchStatus(
+	owner string, dispatchID string,
+) (dispatchStatus, error) {
+	// The logger we will pass to the API client, so that when the API client
+	// logs a message, we know who called it.
+	launcherAPILogger := m.syslog.WithField("caller", "getDispatchStatus")
+
+	status, r, err := m.apiClient.getManagementStatus( //nolint:bodyclose
+		owner,
+		dispatchID,
+		launcherAPILogger)
+	if err != nil {
+		if r != nil && r.StatusCode == http.StatusNotFound {
+			return dispatchStatus{
+				state:  launcher.MISSING,
+				reason: "dispatch not found by the launcher",
+			}, nil
+		}
+		return dispatchStatus{}, errors.New(m.apiClient.handleLauncherError(
+			r, "failed to get the status of dispatch "+dispatchID, err))
+	}
+
+	result := dispatchStatus{state: launcher.UNKNOWN, reason: status.GetReason()}
+	if info, ok := status.GetInfoOk(); ok {
+		if state, ok := info.GetStateOk(); ok {
+			result.state = *state
+		}
+	}
+	return result, nil
+}
+
+// publishRestoredDispatchState reports the state of a reconnected dispatch as soon as it
+// is known, instead of at the job watcher's next poll. Exits are left to the job watcher,
+// which collects the exit status and messages of the job.
+func (m *DispatcherResourceManager) publishRestoredDispatchState(
+	owner string, dispatchID string,
+) {
+	log := m.syslog.WithField("dispatch-id", dispatchID)
+
+	status, err := m.getDispatchStatus(owner, dispatchID)
+	if err != nil {
+		log.WithError(err).Warn("unable to get the state of the reconnected dispatch")
+		return
+	}
+	log = log.WithField("state", status.state).WithField("reason", status.reason)
+
+	switch status.state {
+	case launcher.PENDING, launcher.RUNNING:
+		log.Info("reconnected dispatch state")
+		// Until the containers of a reconnected job report in, the job watcher also
+		// reports a running dispatch as pulling its image.
+		m.DispatchStateChange(DispatchStateChange{
+			DispatchID:     dispatchID,
+			State:          status.state,
+			IsPullingImage: status.state == launcher.RUNNING,
+		})
+	default:
+		log.Info("reconnected dispatch is not active")
+	}
 }
 
 // Sends the manifest to the launcher.
@@ -1777,6 +3096,10 @@ func (m *DispatcherResourceManager) sendManifestToDispatcher(
 			return "", errors.Wrapf(err, m.apiClient.handleLauncherError(
 				response, "Job launch failed", err))
 		}
+		if isLauncherRequestTimeout(err) {
+			return "", errors.Wrapf(err, "Job launch failed, because the launcher did not "+
+				"respond within the launcher_request_timeout.  The launcher may be overloaded.")
+		}
 		if strings.Contains(err.Error(), "EOF") {
 			return "", errors.Wrapf(err, "Launcher rejected the job due to "+
 				"excessive outstanding requests.  Normal operation will typically "+
@@ -1788,7 +3111,29 @@ func (m *DispatcherResourceManager) sendManifestToDispatcher(
 	return dispatchInfo.GetDispatchId(), nil
 }
 
-func (m *DispatcherResourceManager) addTask(msg sproto.AllocateRequest) {
+func (m *DispatcherResourceManager) addTask(msg sproto.AllocateRequest) error {
+	// Allocations being restored belong to jobs that are already running on the
+	// cluster, so they are accepted even when the queue is full.
+	if limit := m.rmConfig.MaxQueuedAllocations; limit != nil && !msg.Restore &&
+		m.reqList.Len() >= *limit {
+		m.syslog.WithField("name", msg.Name).
+			WithField("allocation-id", msg.AllocationID).
+			WithField("queued-allocations", m.reqList.Len()).
+			Warn("rejecting allocation request, the queue is full")
+		return fmt.Errorf("%w: %d allocation requests are queued, the limit is %d",
+			errQueueFull, m.reqList.Len(), *limit)
+	}
+	// Likewise, jobs that are already running are not held to the current limit.
+	if !msg.Restore {
+		if err := m.checkMaxSlotsPerJob(msg.ResourcePool, msg.SlotsNeeded); err != nil {
+			m.syslog.WithField("name", msg.Name).
+				WithField("allocation-id", msg.AllocationID).
+				WithError(err).
+				Warn("rejecting allocation request")
+			return err
+		}
+	}
+
 	m.getOrCreateGroup(msg.JobID)
 	if len(msg.Name) == 0 {
 		msg.Name = "Unnamed-Launcher-Job"
@@ -1798,17 +3143,20 @@ func (m *DispatcherResourceManager) addTask(msg sproto.AllocateRequest) {
 		WithField("allocation-id", msg.AllocationID).
 		Info("resources are requested")
 	m.reqList.AddTask(&msg)
+	recordQueueDepth(m.reqList.Len())
+	return nil
 }
 
 func (m *DispatcherResourceManager) assignResources(req *sproto.AllocateRequest) {
 	var dispatchID string
 	var impersonatedUser string
 	var rID sproto.ResourcesID
+	var nodes []string
 
 	if req.Restore {
 		// Find the Dispatch IDs associated with the allocation ID. We'll need the
 		// Dispatch ID to reconnect with the existing allocation.
-		dispatches, err := db.ListDispatchesByAllocationID(context.TODO(), req.AllocationID)
+		dispatches, err := m.listDispatchesByAllocationID(context.TODO(), req.AllocationID)
 		if err != nil {
 			m.syslog.WithField("allocation-id", req.AllocationID).
 				WithError(err).Errorf("failed to retrieve dispatches")
@@ -1823,13 +3171,28 @@ func (m *DispatcherResourceManager) assignResources(req *sproto.AllocateRequest)
 			dispatchID = dispatch.DispatchID
 			impersonatedUser = dispatch.ImpersonatedUser
 			rID = dispatch.ResourceID
+			nodes = dispatch.Nodes
 			break
 		}
+
+		// Leave the request unscheduled, so that the restore is attempted again on a
+		// later pass, in case the dispatch is recorded shortly after the restart.
+		if len(dispatchID) == 0 && m.waitForRestoredDispatch(req.AllocationID) {
+			return
+		}
+		m.restoreAttempts.Delete(req.AllocationID)
 	}
 
 	if len(rID) == 0 {
 		rID = sproto.ResourcesID(uuid.NewString())
 	}
+	var reattachStatus sproto.ReattachStatus
+	if req.Restore {
+		reattachStatus = sproto.Reattached
+		if len(dispatchID) == 0 {
+			reattachStatus = sproto.ReattachLost
+		}
+	}
 	allocations := sproto.ResourceList{
 		rID: &DispatcherResources{
 			id:                     rID,
@@ -1838,8 +3201,14 @@ func (m *DispatcherResourceManager) assignResources(req *sproto.AllocateRequest)
 			group:                  m.groups[req.JobID],
 			defaultRendezvousIface: m.rmConfig.ResolveRendezvousNetworkInterface(req.ResourcePool),
 			defaultProxyIface:      m.rmConfig.ResolveProxyNetworkInterface(req.ResourcePool),
+			accountingLabels:       &atomic.Pointer[map[string]string]{},
+			nodes:                  &atomic.Pointer[[]string]{},
+			reattachStatus:         reattachStatus,
 		},
 	}
+	if len(nodes) > 0 {
+		allocations[rID].(*DispatcherResources).nodes.Store(&nodes)
+	}
 
 	assigned := sproto.ResourcesAllocated{ID: req.AllocationID, Resources: allocations}
 	m.reqList.AddAllocationRaw(req.AllocationID, &assigned)
@@ -1864,6 +3233,7 @@ func (m *DispatcherResourceManager) assignResources(req *sproto.AllocateRequest)
 				WithField("impersonated-user", impersonatedUser).
 				Info("reconnecting")
 			m.jobWatcher.monitorJob(impersonatedUser, dispatchID, "", false)
+			go m.publishRestoredDispatchState(impersonatedUser, dispatchID)
 		}
 	} else {
 		m.syslog.
@@ -1873,6 +3243,24 @@ func (m *DispatcherResourceManager) assignResources(req *sproto.AllocateRequest)
 	}
 }
 
+// waitForRestoredDispatch reports whether the restore of the allocation, whose dispatch
+// was not found, should be attempted again rather than failed. The allocation is failed
+// once the restore_dispatch_grace_period has elapsed since its first attempt.
+func (m *DispatcherResourceManager) waitForRestoredDispatch(allocationID model.AllocationID) bool {
+	gracePeriod := m.rmConfig.ResolveRestoreDispatchGracePeriod()
+	firstAttempt, ok := m.restoreAttempts.Load(allocationID)
+	if !ok {
+		firstAttempt = time.Now()
+		if gracePeriod > 0 {
+			m.restoreAttempts.Store(allocationID, firstAttempt)
+			m.syslog.WithField("allocation-id", allocationID).
+				WithField("grace-period", gracePeriod).
+				Info("restore: no dispatch found yet, waiting for it to be recorded")
+		}
+	}
+	return time.Since(firstAttempt) < gracePeriod
+}
+
 // Perform a terminate and delete all dispatches in the DB
 // that are no-longer associated with an active experiment/task.
 // All active tasks will get reconnected via AllocationRequest{Restore:true}
@@ -1885,29 +3273,10 @@ func (m *DispatcherResourceManager) killAllInactiveDispatches() {
 	for ; true; <-ticker.C {
 		m.syslog.Info("releasing all dispatches for terminated allocations")
 
-		// Find the Dispatch IDs
-		dispatches, err := db.ListAllDispatches(context.TODO())
-		if err != nil {
+		if _, _, err := m.releaseInactiveDispatches(); err != nil {
 			m.syslog.WithError(err).Error("failed to retrieve all dispatches")
 			return
 		}
-		m.syslog.Debugf("found %d dispatches to check", len(dispatches))
-		for _, dispatch := range dispatches {
-			dispatchID := dispatch.DispatchID
-			impersonatedUser := dispatch.ImpersonatedUser
-			allocation, err := db.AllocationByID(context.TODO(), dispatch.AllocationID)
-			if err != nil {
-				m.syslog.WithField("dispatch-id", dispatchID).
-					WithError(err).Errorf("unexpected DB lookup error")
-				continue
-			} else if allocation != nil && allocation.EndTime == nil {
-				m.syslog.WithField("dispatch-id", dispatchID).
-					Debug("not removing dispatch environment for dispatch because allocation is still active.")
-				continue
-			}
-
-			m.terminateAndDeleteDispatch(dispatchID, impersonatedUser)
-		}
 
 		if m.syslog.Logger.Level < logrus.DebugLevel {
 			// Do only one cleanup unless in debug mode
@@ -1916,6 +3285,128 @@ func (m *DispatcherResourceManager) killAllInactiveDispatches() {
 	}
 }
 
+// CleanupOrphanedDispatches terminates and deletes, on demand, the dispatches in the DB
+// that are no longer associated with an active allocation, as is done at startup.
+func (m *DispatcherResourceManager) CleanupOrphanedDispatches() (
+	*apiv1.CleanupOrphanedDispatchesResponse, error,
+) {
+	m.syslog.Info("releasing all dispatches for terminated allocations on request")
+
+	terminated, deleted, err := m.releaseInactiveDispatches()
+	if err != nil {
+		return nil, fmt.Errorf("retrieving all dispatches: %w", err)
+	}
+	return &apiv1.CleanupOrphanedDispatchesResponse{
+		TerminatedCount: int32(terminated),
+		DeletedCount:    int32(deleted),
+	}, nil
+}
+
+// GetDispatchUsage returns the dispatches of active allocations by impersonated user and
+// owner, with how many are running or pending in the workload manager and the slots they
+// request.
+func (m *DispatcherResourceManager) GetDispatchUsage() (*apiv1.GetDispatchUsageResponse, error) {
+	dispatches, err := db.ListAllDispatches(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("retrieving all dispatches: %w", err)
+	}
+	owners, err := db.ListDispatchOwners(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.GetDispatchUsageResponse{Usages: m.dispatchUsages(dispatches, owners)}, nil
+}
+
+// dispatchUsages sums the dispatches of the allocations that the RM is tracking by
+// impersonated user and owner. Dispatches whose job is neither running nor pending, such
+// as those being terminated, are left out.
+func (m *DispatcherResourceManager) dispatchUsages(
+	dispatches []*db.Dispatch, owners map[string]string,
+) []*apiv1.DispatchUsage {
+	var usages []*apiv1.DispatchUsage
+	for _, dispatch := range dispatches {
+		m.mu.Lock()
+		req, ok := m.reqList.TaskByID(dispatch.AllocationID)
+		m.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		usage := &apiv1.DispatchUsage{
+			ImpersonatedUser: dispatch.ImpersonatedUser,
+			Owner:            owners[dispatch.DispatchID],
+			Slots:            int32(req.SlotsNeeded),
+		}
+		// A job without a reported state is still being launched, so it is pending too.
+		switch state, _ := m.jobWatcher.getLastJobState(dispatch.DispatchID); state {
+		case launcher.RUNNING:
+			usage.RunningCount = 1
+		case launcher.PENDING, "":
+			usage.PendingCount = 1
+		default:
+			continue
+		}
+		usages = append(usages, usage)
+	}
+	return rmutils.MergeDispatchUsages(usages)
+}
+
+// releaseInactiveDispatches terminates and deletes the dispatches in the DB whose
+// allocations are no longer active, and returns how many were terminated and deleted.
+// Dispatches of allocations the RM is still tracking, such as those being restored, are
+// left to their allocations.
+func (m *DispatcherResourceManager) releaseInactiveDispatches() (terminated, deleted int, err error) {
+	m.dispatchCleanupMu.Lock()
+	defer m.dispatchCleanupMu.Unlock()
+
+	// Find the Dispatch IDs
+	dispatches, err := db.ListAllDispatches(context.TODO())
+	if err != nil {
+		return 0, 0, err
+	}
+	m.syslog.Debugf("found %d dispatches to check", len(dispatches))
+	for _, dispatch := range dispatches {
+		dispatchID := dispatch.DispatchID
+		impersonatedUser := dispatch.ImpersonatedUser
+		allocation, err := db.AllocationByID(context.TODO(), dispatch.AllocationID)
+		if err != nil {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				WithError(err).Errorf("unexpected DB lookup error")
+			continue
+		} else if allocation != nil && allocation.EndTime == nil {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				Debug("not removing dispatch environment for dispatch because allocation is still active.")
+			continue
+		} else if m.isDispatchInUse(dispatch) {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				Debug("not removing dispatch environment for dispatch because allocation is still tracked.")
+			continue
+		}
+
+		t, d := m.terminateAndDeleteDispatch(dispatchID, impersonatedUser)
+		if t {
+			terminated++
+		}
+		if d {
+			deleted++
+		}
+	}
+	return terminated, deleted, nil
+}
+
+// isDispatchInUse returns true if the allocation of the dispatch is still tracked by the
+// RM, or its job is still monitored, in which case releasing the allocation cleans it up.
+func (m *DispatcherResourceManager) isDispatchInUse(dispatch *db.Dispatch) bool {
+	if m.jobWatcher.isJobBeingMonitored(dispatch.DispatchID) {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.reqList.TaskByID(dispatch.AllocationID)
+	return ok
+}
+
 func (m *DispatcherResourceManager) getOrCreateGroup(jobID model.JobID) *tasklist.Group {
 	if g, ok := m.groups[jobID]; ok {
 		return g
@@ -1953,6 +3444,12 @@ func (m *DispatcherResourceManager) SchedulePendingTasks() {
 	for it := m.reqList.Iterator(); it.Next(); {
 		req := it.Value()
 		if !m.reqList.IsScheduled(req.AllocationID) {
+			// Requests for paused pools stay queued until the pool is resumed, but
+			// restored jobs were launched before the pause and keep being monitored.
+			if !req.Restore && m.dbState.isPoolPaused(m.getProvidingPartition(req.ResourcePool)) {
+				continue
+			}
+
 			// A restore means that the Determined master was restarted and
 			// we're simply monitoring the jobs we previously launched. When
 			// it's not a restore, we want to limit the number of launch
@@ -2012,6 +3509,19 @@ type (
 
 		defaultRendezvousIface string
 		defaultProxyIface      string
+
+		// accountingLabels are the accounting labels of the task, which are only known
+		// once it is started. The pointer is shared by all copies of the resources.
+		accountingLabels *atomic.Pointer[map[string]string]
+
+		// nodes are the nodes that the task was placed on, which are only known once
+		// all of its containers are running. The pointer is shared by all copies of
+		// the resources.
+		nodes *atomic.Pointer[[]string]
+
+		// reattachStatus is the outcome of reattaching to the dispatch of the task, if it
+		// was restored after a restart of the master.
+		reattachStatus sproto.ReattachStatus
 	}
 
 	// StartDispatcherResources comment to keep "golint" from complaining.
@@ -2035,6 +3545,8 @@ type (
 		State          launcher.DispatchState
 		IsPullingImage bool
 		HPCJobID       string
+		// PendingReason is why the workload manager reports the dispatch as pending, if known.
+		PendingReason string
 	}
 
 	// dispatchExpLogMessage notifies the dispatcher of a message to be added to the exp log.
@@ -2043,23 +3555,61 @@ type (
 		Message    string
 	}
 
-	// DispatchExited notifies the dispatcher that the give dispatch exited.
+	// dispatchNodesAssigned notifies the dispatcher of the nodes that the dispatch is running on.
+	dispatchNodesAssigned struct {
+		DispatchID string
+		Nodes      []string
+	}
+
+	// DispatchExited notifies the dispatcher that the give dispatch exited. ExitCode is only
+	// meaningful when the Cause is dispatchExitedWithCode.
 	DispatchExited struct {
 		DispatchID string
 		ExitCode   exitCode
+		Cause      dispatchExitCause
 		Message    string
 	}
 )
 
+// resourcesStopped returns how the exit of the dispatch is reported to its allocation. A
+// dispatch that exited without an exit code fails without one, which also turns off printing
+// the misleading last line (exit code 1) from resources.go.
+func (e DispatchExited) resourcesStopped() sproto.ResourcesStopped {
+	stopped := sproto.ResourcesStopped{}
+	switch {
+	case e.Cause != dispatchExitedWithCode:
+		stopped.Failure = sproto.NewResourcesFailure(sproto.ResourcesFailed, "", nil)
+	case e.ExitCode != 0:
+		stopped.Failure = sproto.NewResourcesFailure(
+			sproto.ResourcesFailed,
+			"",
+			ptrs.Ptr(sproto.ExitCode(e.ExitCode)),
+		)
+	}
+	return stopped
+}
+
 // Summary summarizes a container allocation.
 func (r DispatcherResources) Summary() sproto.ResourcesSummary {
-	return sproto.ResourcesSummary{
+	summary := sproto.ResourcesSummary{
 		ResourcesID:   r.id,
 		ResourcesType: sproto.ResourcesTypeSlurmJob,
 		AllocationID:  r.req.AllocationID,
 		AgentDevices:  map[aproto.ID][]device.Device{},
 		ContainerID:   nil,
 	}
+	if r.accountingLabels != nil {
+		if labels := r.accountingLabels.Load(); labels != nil {
+			summary.AccountingLabels = *labels
+		}
+	}
+	if r.nodes != nil {
+		if nodes := r.nodes.Load(); nodes != nil {
+			summary.Nodes = *nodes
+		}
+	}
+	summary.ReattachStatus = r.reattachStatus
+	return summary
 }
 
 // Start notifies the pods actor that it should launch a pod for the provided task spec.
@@ -2090,6 +3640,13 @@ func (r DispatcherResources) Start(
 	spec.ExtraEnvVars[sproto.ResourcesTypeEnvVar] = string(sproto.ResourcesTypeSlurmJob)
 	spec.ExtraEnvVars[sproto.SlurmRendezvousIfaceEnvVar] = r.defaultRendezvousIface
 	spec.ExtraEnvVars[sproto.SlurmProxyIfaceEnvVar] = r.defaultProxyIface
+	spec.AccountingLabels = tasks.ParseAccountingLabels(spec.Labels, r.rm.rmConfig.AccountingLabelKeys)
+	for key, value := range spec.AccountingLabels {
+		spec.ExtraEnvVars[accountingLabelEnvVarPrefix+strings.ToUpper(key)] = value
+	}
+	if r.accountingLabels != nil {
+		r.accountingLabels.Store(&spec.AccountingLabels)
+	}
 	r.rm.StartDispatcherResources(StartDispatcherResources{
 		AllocationID:           r.req.AllocationID,
 		ResourcesID:            r.id,
@@ -2155,15 +3712,26 @@ func (m *DispatcherResourceManager) NotifyContainerRunning(msg sproto.NotifyCont
 		return nil
 	}
 
-	foundMonitoredDispatch := false
+	dispatchIDs := make([]string, 0, len(dispatches))
 	for _, dispatch := range dispatches {
-		dispatchID := dispatch.DispatchID
-		if m.jobWatcher.isJobBeingMonitored(dispatchID) {
-			foundMonitoredDispatch = true
-			m.jobWatcher.notifyContainerRunning(dispatchID, msg.Rank, msg.NumPeers, msg.NodeName)
-		}
+		dispatchIDs = append(dispatchIDs, dispatch.DispatchID)
 	}
-	if !foundMonitoredDispatch {
+
+	// Only notify the dispatch that owns the container, so that it is not counted
+	// more than once when several dispatches are monitored for the allocation.
+	dispatchID, foundMonitoredDispatch := m.jobWatcher.findContainerDispatch(
+		string(msg.AllocationID), dispatchIDs, msg.Rank, msg.NumPeers, msg.NodeName)
+	switch {
+	case dispatchID != "":
+		m.jobWatcher.notifyContainerRunning(dispatchID, msg.Rank, msg.NumPeers, msg.NodeName)
+	case foundMonitoredDispatch:
+		m.syslog.WithField("allocation-id", msg.AllocationID).
+			WithField("rank", msg.Rank).
+			WithField("num-peers", msg.NumPeers).
+			WithField("node", msg.NodeName).
+			Warn("NotifyContainerRunning did not match the container to any of the " +
+				"monitored dispatches")
+	default:
 		m.syslog.WithField("allocation-id", msg.AllocationID).Warnf(
 			"NotifyContainerRunning did not find an active, monitored dispatch")
 	}
@@ -2194,8 +3762,9 @@ func (m *DispatcherResourceManager) TaskContainerDefaults(
 	}
 
 	var poolConfigOverrides *model.TaskContainerDefaultsConfig
+	poolName := m.resolvePoolAlias(resourcePoolName.String())
 	for _, pool := range m.poolConfig {
-		if resourcePoolName.String() == pool.PoolName {
+		if poolName == pool.PoolName {
 			if pool.TaskContainerDefaults == nil {
 				break
 			}
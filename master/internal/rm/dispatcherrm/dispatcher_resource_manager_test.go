@@ -1,21 +1,43 @@
 package dispatcherrm
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 
 	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/require"
+	"github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
 
+	"github.com/determined-ai/determined/master/internal/authz"
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/config/provconfig"
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/rm/rmerrors"
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/rm/tasklist"
+	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/etc"
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/master/pkg/schemas"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/syncx/mapx"
+	"github.com/determined-ai/determined/master/pkg/syncx/orderedmapx"
+	"github.com/determined-ai/determined/master/pkg/tasks"
 	"github.com/determined-ai/determined/proto/pkg/agentv1"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
 	"github.com/determined-ai/determined/proto/pkg/containerv1"
 	"github.com/determined-ai/determined/proto/pkg/devicev1"
 	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
@@ -28,6 +50,8 @@ func Test_generateGetAgentsResponse(t *testing.T) {
 		Partitions:    []string{"Partition 1"},
 		Addresses:     []string{"address 1", "address 2"},
 		Draining:      true,
+		State:         "idle+drain",
+		Reason:        "disk failure",
 		Allocated:     true,
 		Name:          "Node 1",
 		GpuCount:      0,
@@ -78,11 +102,15 @@ func Test_generateGetAgentsResponse(t *testing.T) {
 	}
 
 	// State that "Partition 2" is the providing partition for "launcher-provided-pool"
-	poolProviderMap := map[string][]string{
-		"Partition 2": {"launcher-provided-pool"},
-	}
+	poolProviderMap := makeProvidedPoolsMap([]config.ResourcePoolConfig{{
+		PoolName: "launcher-provided-pool",
+		Provider: &provconfig.Config{
+			HPC: &provconfig.HpcClusterConfig{Partition: "Partition 2"},
+		},
+	}})
 	config := &config.DispatcherResourceManagerConfig{
 		PartitionOverrides: overrides,
+		CPUSlotDisplay:     config.CPUSlotDisplayPerCore,
 	}
 
 	// Expect each agent to participate in resource pools as follows:
@@ -148,90 +176,1708 @@ func Test_generateGetAgentsResponse(t *testing.T) {
 		},
 	}
 
-	want1 := map[string]*agentv1.Slot{
-		"/agents/Node 2/slots/0": {
-			Id:        "0",
-			Device:    &devicev1.Device{Type: devicev1.Type_TYPE_ROCM},
-			Enabled:   true,
-			Container: &containerv1.Container{State: containerv1.State_STATE_RUNNING},
+	want1 := map[string]*agentv1.Slot{
+		"/agents/Node 2/slots/0": {
+			Id:        "0",
+			Device:    &devicev1.Device{Type: devicev1.Type_TYPE_ROCM},
+			Enabled:   true,
+			Container: &containerv1.Container{State: containerv1.State_STATE_RUNNING},
+		},
+
+		"/agents/Node 2/slots/1": {
+			Id:      "1",
+			Device:  &devicev1.Device{Type: devicev1.Type_TYPE_ROCM},
+			Enabled: true,
+		},
+	}
+
+	want2 := map[string]*agentv1.Slot{
+		"/agents/Node 3/slots/0": {
+			Id:        "0",
+			Device:    &devicev1.Device{Type: devicev1.Type_TYPE_CUDA},
+			Enabled:   true,
+			Container: &containerv1.Container{State: containerv1.State_STATE_RUNNING},
+		},
+
+		"/agents/Node 3/slots/1": {
+			Id:      "1",
+			Device:  &devicev1.Device{Type: devicev1.Type_TYPE_CUDA},
+			Enabled: true,
+		},
+	}
+
+	wantSlots := []map[string]*agentv1.Slot{want0, want1, want2}
+
+	m.dbState.DisabledAgents = []string{"Node 2"}
+
+	resp, err := m.GetAgents()
+	require.NoError(t, err)
+	assert.Equal(t, len(resp.Agents), len(nodes))
+
+	for i, agent := range resp.Agents {
+		assert.Equal(t, agent.Id, nodes[i].Name)
+		assert.DeepEqual(t, agent.ResourcePools, expectedResourcePools[i])
+		assert.DeepEqual(t, agent.Addresses, nodes[i].Addresses)
+		assert.Equal(t, agent.Draining, nodes[i].Draining)
+		assert.Equal(t, agent.State, nodes[i].State)
+		assert.Equal(t, agent.StateReason, nodes[i].Reason)
+		assert.Equal(t, agent.Enabled, agent.Id != "Node 2")
+		assert.Equal(t, len(agent.Slots), len(wantSlots[i]))
+		for key, value := range agent.Slots {
+			wantValue := wantSlots[i][key]
+			assert.Equal(t, value.Id, wantValue.Id)
+			assert.Equal(t, value.Device.Type, wantValue.Device.Type)
+			assert.Equal(t, value.Enabled, wantValue.Enabled)
+
+			if wantValue.Container != nil {
+				assert.Equal(t, value.Container.State, wantValue.Container.State)
+			} else if value.Container != nil {
+				t.Errorf("agent.Slots %s Container value error", key)
+			}
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	m := &DispatcherResourceManager{
+		syslog: logrus.WithField("component", "dispatcherrm"),
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			Name: "testname",
+		},
+		dbState: *newDispatcherState(),
+	}
+
+	c, err := newLauncherAPIClient(m.rmConfig)
+	require.NoError(t, err)
+	m.apiClient = c
+
+	require.Equal(t, []model.ResourceManagerHealth{
+		{
+			Name:   "testname",
+			Status: model.Unhealthy, // Unhealthy since launcher API client isn't set up properly.
+		},
+	}, m.HealthCheck())
+}
+
+func Test_hpcNodeToAgent_aggregateCPUSlots(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			CPUSlotDisplay: config.CPUSlotDisplayAggregate,
+		},
+		dbState: *newDispatcherState(),
+	}
+
+	tests := []struct {
+		name      string
+		node      hpcNodeDetails
+		wantSlots int
+		wantInUse bool
+	}{
+		{
+			name:      "partially allocated node",
+			node:      hpcNodeDetails{Name: "Node 1", CPUCount: 128, CPUInUseCount: 6},
+			wantSlots: 1,
+			wantInUse: true,
+		},
+		{
+			name:      "idle node",
+			node:      hpcNodeDetails{Name: "Node 2", CPUCount: 128},
+			wantSlots: 1,
+		},
+		{
+			name: "node without CPUs reported",
+			node: hpcNodeDetails{Name: "Node 3"},
+		},
+		{
+			name:      "GPU node is unaffected",
+			node:      hpcNodeDetails{Name: "Node 4", GpuCount: 4, CPUCount: 128},
+			wantSlots: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := m.hpcNodeToAgent(tt.node)
+			assert.Equal(t, len(agent.Slots), tt.wantSlots)
+			if tt.node.GpuCount > 0 || tt.wantSlots == 0 {
+				return
+			}
+			slot := agent.Slots[fmt.Sprintf("/agents/%s/slots/0", tt.node.Name)]
+			assert.Equal(t, slot.Device.Type, devicev1.Type_TYPE_CPU)
+			assert.Equal(t, slot.Container != nil, tt.wantInUse)
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	for _, wlm := range []wlmType{slurmSchedulerType, pbsSchedulerType} {
+		m := &DispatcherResourceManager{
+			wlmType:  wlm,
+			rmConfig: &config.DispatcherResourceManagerConfig{Name: "testname"},
+		}
+
+		caps := m.Capabilities()
+		require.Len(t, caps, 1)
+		require.Equal(t, "testname", caps[0].ResourceManagerName)
+		require.Equal(t, string(wlm), caps[0].Type)
+		require.False(t, caps[0].SupportsPriority)
+		require.False(t, caps[0].SupportsWeight)
+		require.False(t, caps[0].SupportsMoveJob)
+		require.False(t, caps[0].SupportsDisableSlot)
+		require.Equal(t, wlm == slurmSchedulerType, caps[0].SupportsDisableAgent)
+		if !caps[0].SupportsDisableAgent {
+			_, err := m.DisableAgent(&apiv1.DisableAgentRequest{AgentId: "node-1"})
+			require.ErrorContains(t, err, "not supported")
+			_, err = m.EnableAgent(&apiv1.EnableAgentRequest{AgentId: "node-1"})
+			require.ErrorContains(t, err, "not supported")
+		}
+	}
+}
+
+func TestMoveJob(t *testing.T) {
+	m := &DispatcherResourceManager{
+		reqList: tasklist.New(),
+		poolConfig: []config.ResourcePoolConfig{{
+			PoolName: "gpu-pool",
+			Provider: &provconfig.Config{
+				HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+			},
+		}},
+	}
+	m.reqList.AddTask(&sproto.AllocateRequest{
+		AllocationID: "alloc-1", JobID: "job-1", ResourcePool: "gpus",
+	})
+	m.reqList.AddTask(&sproto.AllocateRequest{
+		AllocationID: "alloc-2", JobID: "job-2", ResourcePool: "gpu-pool",
+	})
+	m.reqList.AddTask(&sproto.AllocateRequest{
+		AllocationID: "alloc-3", JobID: "job-3", ResourcePool: "cpus",
+	})
+
+	// Moves within the same partition succeed, including via a launcher-provided pool.
+	require.NoError(t, m.MoveJob(sproto.MoveJob{ID: "job-1", Anchor: "job-2", Ahead: true}))
+	require.NoError(t, m.MoveJob(sproto.MoveJob{ID: "job-2", Anchor: "job-1"}))
+	require.NoError(t, m.MoveJob(sproto.MoveJob{
+		ID: "job-1", Anchor: "job-gone", ResourcePool: "gpu-pool",
+	}))
+
+	err := m.MoveJob(sproto.MoveJob{ID: "job-1", Anchor: "job-3", Ahead: true})
+	require.ErrorIs(t, err, rmerrors.ErrNotSupported)
+	require.ErrorContains(t, err, "from partition gpus to partition cpus")
+
+	err = m.MoveJob(sproto.MoveJob{ID: "job-gone", Anchor: "job-1"})
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestGetLaunchPartition(t *testing.T) {
+	gcPool := "gc-pool"
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		poolConfig: []config.ResourcePoolConfig{{
+			PoolName: gcPool,
+			Provider: &provconfig.Config{
+				HPC: &provconfig.HpcClusterConfig{Partition: "storage"},
+			},
+		}},
+	}
+	gcReq := &sproto.AllocateRequest{ResourcePool: "aux"}
+	hpcDetails := &hpcResources{}
+
+	// Without a checkpoint GC resource pool, GC tasks use the requested pool.
+	require.Equal(t, "aux", m.getLaunchPartition(hpcDetails, model.TaskTypeCheckpointGC, gcReq))
+
+	hpcDetails.CheckpointGCPool = gcPool
+	require.Equal(t, "storage", m.getLaunchPartition(hpcDetails, model.TaskTypeCheckpointGC, gcReq))
+	require.Equal(t, "aux", m.getLaunchPartition(hpcDetails, model.TaskTypeCommand, gcReq))
+	require.Equal(t, "gpus", m.getLaunchPartition(hpcDetails, model.TaskTypeCheckpointGC,
+		&sproto.AllocateRequest{ResourcePool: "gpus", SlotsNeeded: 1}))
+}
+
+func TestResourcePoolAliases(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		poolConfig: []config.ResourcePoolConfig{
+			{PoolName: "gpus", Aliases: []string{"old-gpus"}},
+			{
+				PoolName: "gc-pool",
+				Aliases:  []string{"old-gc-pool"},
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+				},
+			},
+		},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{{PartitionName: "gpus"}},
+		}),
+	}
+
+	require.Equal(t, "gpus", m.resolvePoolAlias("old-gpus"))
+	require.Equal(t, "gc-pool", m.resolvePoolAlias("old-gc-pool"))
+	require.Equal(t, "unknown", m.resolvePoolAlias("unknown"))
+
+	// A renamed partition is still found by its old name.
+	require.NoError(t, m.ValidateResourcePool("old-gpus"))
+	require.Equal(t, "gpus", m.getProvidingPartition("old-gpus"))
+
+	// As is a launcher-provided pool, which resolves to its providing partition.
+	hpcDetails, err := m.hpcDetailsCache.load()
+	require.NoError(t, err)
+	partition, err := m.validateResourcePool(hpcDetails, "old-gc-pool")
+	require.NoError(t, err)
+	require.Equal(t, "gpus", partition)
+	require.Equal(t, "gpus", m.getProvidingPartition("old-gc-pool"))
+
+	require.ErrorContains(t, m.ValidateResourcePool("unknown"), "resource pool not found: unknown")
+}
+
+func TestGetResourcePoolPartition(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		poolConfig: []config.ResourcePoolConfig{
+			{PoolName: "gpus", Aliases: []string{"old-gpus"}},
+			{
+				PoolName: "gc-pool",
+				Aliases:  []string{"old-gc-pool"},
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+				},
+			},
+		},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{{PartitionName: "gpus"}},
+		}),
+	}
+
+	for _, tt := range []struct {
+		pool string
+		want *apiv1.GetResourcePoolPartitionResponse
+	}{
+		{pool: "gpus", want: &apiv1.GetResourcePoolPartitionResponse{Partition: "gpus"}},
+		{pool: "old-gpus", want: &apiv1.GetResourcePoolPartitionResponse{Partition: "gpus"}},
+		{pool: "gc-pool", want: &apiv1.GetResourcePoolPartitionResponse{
+			Partition: "gpus", LauncherProvided: true,
+		}},
+		{pool: "old-gc-pool", want: &apiv1.GetResourcePoolPartitionResponse{
+			Partition: "gpus", LauncherProvided: true,
+		}},
+	} {
+		res, err := m.GetResourcePoolPartition(rm.ResourcePoolName(tt.pool))
+		require.NoError(t, err, tt.pool)
+		require.Equal(t, tt.want.Partition, res.Partition, tt.pool)
+		require.Equal(t, tt.want.LauncherProvided, res.LauncherProvided, tt.pool)
+	}
+
+	_, err := m.GetResourcePoolPartition("unknown")
+	require.ErrorContains(t, err, "resource pool not found: unknown")
+}
+
+func TestMasterAddress(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			MasterHost: "master.example.com",
+			MasterPort: 8080,
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"isolated": {MasterHost: ptrs.Ptr("10.1.0.1")},
+			},
+		},
+		poolConfig: []config.ResourcePoolConfig{
+			{
+				PoolName: "isolated-tls",
+				Aliases:  []string{"old-isolated-tls"},
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "isolated"},
+				},
+				MasterPort: ptrs.Ptr(8443),
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		pool      string
+		partition string
+		host      string
+		port      int
+	}{
+		{pool: "gpus", partition: "gpus", host: "master.example.com", port: 8080},
+		{pool: "isolated", partition: "isolated", host: "10.1.0.1", port: 8080},
+		{pool: "ISOLATED", partition: "ISOLATED", host: "10.1.0.1", port: 8080},
+		{pool: "isolated-tls", partition: "isolated", host: "10.1.0.1", port: 8443},
+		{pool: "old-isolated-tls", partition: "isolated", host: "10.1.0.1", port: 8443},
+	} {
+		host, port := m.masterAddress(tt.pool, tt.partition)
+		require.Equal(t, tt.host, host, tt.pool)
+		require.Equal(t, tt.port, port, tt.pool)
+	}
+}
+
+func TestImagePullPolicy(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"gpus": {ImagePullPolicy: ptrs.Ptr(config.ImagePullPolicyIfNotPresent)},
+			},
+		},
+		poolConfig: []config.ResourcePoolConfig{
+			{
+				PoolName: "gpus-fresh",
+				Aliases:  []string{"old-gpus-fresh"},
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+				},
+				ImagePullPolicy: ptrs.Ptr(config.ImagePullPolicyAlways),
+			},
+			{
+				PoolName: "gpus-shared",
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		pool      string
+		partition string
+		policy    string
+	}{
+		{pool: "cpus", partition: "cpus", policy: ""},
+		{pool: "gpus", partition: "gpus", policy: config.ImagePullPolicyIfNotPresent},
+		{pool: "gpus-fresh", partition: "gpus", policy: config.ImagePullPolicyAlways},
+		{pool: "old-gpus-fresh", partition: "gpus", policy: config.ImagePullPolicyAlways},
+		// A launcher-provided pool without a policy has that of its partition.
+		{pool: "gpus-shared", partition: "gpus", policy: config.ImagePullPolicyIfNotPresent},
+	} {
+		require.Equal(t, tt.policy, m.imagePullPolicy(tt.pool, tt.partition), tt.pool)
+	}
+}
+
+func TestSelectPoolByLabels(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"a100": {Labels: map[string]string{"gpu": "a100", "network": "ethernet"}},
+				"h100": {Labels: map[string]string{"gpu": "h100", "network": "infiniband"}},
+			},
+		},
+		poolConfig: []config.ResourcePoolConfig{
+			{
+				PoolName: "a100-ib",
+				Provider: &provconfig.Config{
+					HPC: &provconfig.HpcClusterConfig{Partition: "a100"},
+				},
+				Labels: map[string]string{"network": "infiniband"},
+			},
+		},
+	}
+	pools := []string{"a100", "h100", "cpus", "a100-ib"}
+
+	for _, tt := range []struct {
+		name    string
+		pool    string
+		wantErr string
+	}{
+		{name: "gpu=h100", pool: "h100"},
+		{name: "gpu=a100,network=ethernet", pool: "a100"},
+		// A launcher-provided pool has the labels of its partition, and its own.
+		{name: " gpu = a100 , network = infiniband ", pool: "a100-ib"},
+		{name: "gpu=a100", wantErr: "resource pools a100, a100-ib all have the labels gpu=a100"},
+		{name: "network=infiniband", wantErr: "resource pools a100-ib, h100 all have the labels"},
+		{name: "gpu=v100", wantErr: "no resource pool available to the workspace has the labels gpu=v100"},
+		{name: "gpu=", wantErr: "no resource pool available to the workspace has the labels gpu="},
+	} {
+		selector, isSelector, err := parsePoolLabelSelector(tt.name)
+		require.NoError(t, err, tt.name)
+		require.True(t, isSelector, tt.name)
+		pool, err := m.selectPoolByLabels(tt.name, selector, pools)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.pool, pool, tt.name)
+	}
+
+	// Only the given pools, e.g. those available to a workspace, are selected from.
+	pool, err := m.selectPoolByLabels("gpu=a100", map[string]string{"gpu": "a100"}, []string{"a100"})
+	require.NoError(t, err)
+	require.Equal(t, "a100", pool)
+
+	_, isSelector, err := parsePoolLabelSelector("a100")
+	require.NoError(t, err)
+	require.False(t, isSelector)
+	for _, name := range []string{"=a100", "gpu=a100,network"} {
+		_, _, err = parsePoolLabelSelector(name)
+		require.ErrorContains(t, err, "invalid resource pool label selector "+name, name)
+	}
+}
+
+func TestValidateExclusiveNodes(t *testing.T) {
+	m := &DispatcherResourceManager{wlmType: slurmSchedulerType}
+	hpcDetails := &hpcResources{
+		Partitions: []hpcPartitionDetails{{PartitionName: "gpus", TotalNodes: 4}},
+	}
+	exclusiveNodes := 4
+	spec := tasks.TaskSpec{
+		TaskType:    model.TaskTypeTrial,
+		SlurmConfig: expconf.SlurmConfig{RawExclusiveNodes: &exclusiveNodes},
+	}
+
+	require.NoError(t, m.validateExclusiveNodes(hpcDetails, "gpus", spec, 16))
+	require.ErrorContains(t, m.validateExclusiveNodes(hpcDetails, "gpus", spec, 10),
+		"cannot be spread evenly")
+
+	exclusiveNodes = 8
+	require.ErrorContains(t, m.validateExclusiveNodes(hpcDetails, "gpus", spec, 16),
+		"resource pool 'gpus' has only 4 nodes")
+
+	// Slot-based jobs and PBS are not affected.
+	require.NoError(t, m.validateExclusiveNodes(hpcDetails, "gpus", tasks.TaskSpec{}, 16))
+	m.wlmType = pbsSchedulerType
+	require.NoError(t, m.validateExclusiveNodes(hpcDetails, "gpus", spec, 16))
+}
+
+func TestValidateReservation(t *testing.T) {
+	m := &DispatcherResourceManager{wlmType: slurmSchedulerType}
+	reservation := "course101"
+	spec := tasks.TaskSpec{
+		SlurmConfig: expconf.SlurmConfig{RawReservation: &reservation},
+	}
+
+	// The reservation cannot be checked if the launcher does not report them.
+	require.NoError(t, m.validateReservation(&hpcResources{}, "gpus", spec))
+
+	hpcDetails, _, err := parseHpcResources(strings.NewReader(`
+reservations:
+- name: course101
+  partition: gpus
+- name: maintenance
+`))
+	require.NoError(t, err)
+	require.NoError(t, m.validateReservation(hpcDetails, "gpus", spec))
+	require.ErrorContains(t, m.validateReservation(hpcDetails, "cpus", spec),
+		"reservation 'course101' is for partition 'gpus'")
+
+	reservation = "maintenance"
+	require.NoError(t, m.validateReservation(hpcDetails, "cpus", spec))
+
+	reservation = "course102"
+	require.ErrorContains(t, m.validateReservation(hpcDetails, "gpus", spec),
+		"reservation 'course102' does not exist")
+
+	// Jobs not targeting a reservation are not affected.
+	require.NoError(t, m.validateReservation(hpcDetails, "gpus", tasks.TaskSpec{}))
+}
+
+func TestValidateSbatchArgs(t *testing.T) {
+	m := &DispatcherResourceManager{
+		wlmType: slurmSchedulerType,
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			AllowedSbatchArgs: []string{"--mem", "-l"},
+			DeniedSbatchArgs:  []string{"--exclusive"},
+		},
+	}
+	slurmArgs := []string{"--mem=4G"}
+	pbsArgs := []string{"-l walltime=1:00:00"}
+	require.NoError(t, m.validateSbatchArgs(slurmArgs, pbsArgs))
+
+	slurmArgs = []string{"--mem=4G", "--qos=high", "--exclusive"}
+	require.EqualError(t, m.validateSbatchArgs(slurmArgs, pbsArgs),
+		"slurm option --qos is not permitted on this cluster; "+
+			"slurm option --exclusive is not permitted on this cluster")
+
+	// Submissions are rejected before they are queued.
+	_, err := m.ValidateResources(sproto.ValidateResourcesRequest{SlurmArgs: slurmArgs})
+	require.ErrorContains(t, err, "slurm option --qos is not permitted on this cluster")
+
+	// The PBS arguments are checked with PBS.
+	m.wlmType = pbsSchedulerType
+	require.NoError(t, m.validateSbatchArgs(slurmArgs, pbsArgs))
+	pbsArgs = []string{"-m abe"}
+	require.EqualError(t, m.validateSbatchArgs(slurmArgs, pbsArgs),
+		"PBS option -m is not permitted on this cluster")
+
+	// Any argument is permitted without a policy.
+	m.rmConfig = &config.DispatcherResourceManagerConfig{}
+	require.NoError(t, m.validateSbatchArgs(slurmArgs, pbsArgs))
+}
+
+func TestUserSbatchArgs(t *testing.T) {
+	// The arguments of the task container defaults are exempt from the policy.
+	defaults := []string{"--qos=high", "--exclusive"}
+	require.Equal(t, []string{"--mem=4G"},
+		userSbatchArgs([]string{"--qos=high", "--exclusive", "--mem=4G"}, defaults))
+	require.Empty(t, userSbatchArgs(defaults, defaults))
+
+	// A user argument that repeats a default is still checked.
+	require.Equal(t, []string{"--exclusive"},
+		userSbatchArgs([]string{"--qos=high", "--exclusive", "--exclusive"}, defaults))
+	require.Equal(t, []string{"--exclusive"}, userSbatchArgs([]string{"--exclusive"}, nil))
+}
+
+func TestValidateNodeCapacity(t *testing.T) {
+	m := &DispatcherResourceManager{wlmType: slurmSchedulerType}
+	hpcDetails := &hpcResources{
+		Nodes: []hpcNodeDetails{
+			{Name: "node1", Partitions: []string{"gpus"}, GpuCount: 4, CPUCount: 64},
+			{Name: "node2", Partitions: []string{"gpus", "cpus"}, GpuCount: 8, CPUCount: 32},
+		},
+	}
+	trial := tasks.TaskSpec{TaskType: model.TaskTypeTrial}
+	notebook := tasks.TaskSpec{TaskType: model.TaskTypeNotebook}
+
+	// Slots that may be spread across nodes fit as long as a node has a slot.
+	require.NoError(t, m.validateNodeCapacity(hpcDetails, "gpus", device.CUDA, trial, 64))
+	require.NoError(t, m.validateNodeCapacity(hpcDetails, "GPUS", device.CUDA, notebook, 8))
+
+	// A request larger than any node of the partition is unschedulable.
+	require.EqualError(t,
+		m.validateNodeCapacity(hpcDetails, "gpus", device.CUDA, notebook, 16),
+		"unschedulable: no node in resource pool 'gpus' has 16 cuda slots")
+	slotsPerNode := 16
+	trial.SlurmConfig = expconf.SlurmConfig{RawSlotsPerNode: &slotsPerNode}
+	require.EqualError(t,
+		m.validateNodeCapacity(hpcDetails, "gpus", device.CUDA, trial, 32),
+		"unschedulable: no node in resource pool 'gpus' has 16 cuda slots")
+
+	// CPU slots are checked against the CPUs of the nodes of the partition.
+	require.NoError(t, m.validateNodeCapacity(hpcDetails, "cpus", device.CPU, notebook, 32))
+	require.EqualError(t,
+		m.validateNodeCapacity(hpcDetails, "cpus", device.CPU, notebook, 64),
+		"unschedulable: no node in resource pool 'cpus' has 64 cpu slots")
+
+	// The capacity is unknown without nodes of the partition, and irrelevant without slots.
+	require.NoError(t, m.validateNodeCapacity(hpcDetails, "drained", device.CUDA, notebook, 64))
+	require.NoError(t, m.validateNodeCapacity(hpcDetails, "gpus", device.CPU,
+		tasks.TaskSpec{TaskType: model.TaskTypeCheckpointGC}, 0))
+}
+
+func TestGetClusterUtilization(t *testing.T) {
+	m := &DispatcherResourceManager{
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Nodes: []hpcNodeDetails{
+				{Name: "Node 1", Allocated: true, GpuCount: 4, GpuInUseCount: 3, CPUCount: 32, CPUInUseCount: 8},
+				{Name: "Node 2", GpuCount: 4, CPUCount: 32},
+				{Name: "Node 3", Allocated: true, CPUCount: 64, CPUInUseCount: 24},
+			},
+		}),
+	}
+
+	res, err := m.GetClusterUtilization()
+	require.NoError(t, err)
+	require.Equal(t, &apiv1.GetClusterUtilizationResponse{
+		Nodes:          3,
+		NodesAllocated: 2,
+		Gpus:           8,
+		GpusAllocated:  3,
+		Cpus:           128,
+		CpusAllocated:  32,
+		GpuUtilization: 37.5,
+		CpuUtilization: 25,
+	}, res)
+
+	m.hpcDetailsCache = makeTestHpcDetailsCache(&hpcResources{})
+	res, err = m.GetClusterUtilization()
+	require.NoError(t, err)
+	require.Zero(t, res.GpuUtilization)
+}
+
+func TestExclusiveNodeSlotsInUse(t *testing.T) {
+	// Node 1 is held by an exclusive job, which was allocated all its CPUs but only
+	// requested 2 of its GPUs.
+	hpcDetails := &hpcResources{
+		Partitions: []hpcPartitionDetails{{
+			PartitionName: "gpus", TotalNodes: 2, TotalAvailableNodes: 1, TotalAllocatedNodes: 1,
+			TotalGpuSlots: 8, TotalAvailableGpuSlots: 5, TotalCPUSlots: 64, TotalAvailableCPUSlots: 32,
+		}},
+		Nodes: []hpcNodeDetails{
+			{
+				Name: "Node 1", Partitions: []string{"gpus"}, Allocated: true,
+				GpuCount: 4, GpuInUseCount: 2, CPUCount: 32, CPUInUseCount: 32,
+			},
+			{
+				Name: "Node 2", Partitions: []string{"gpus"}, Allocated: true,
+				GpuCount: 4, GpuInUseCount: 1, CPUCount: 32, CPUInUseCount: 1,
+			},
+		},
+	}
+	m := &DispatcherResourceManager{
+		wlmType:         slurmSchedulerType,
+		rmConfig:        &config.DispatcherResourceManagerConfig{},
+		hpcDetailsCache: makeTestHpcDetailsCache(hpcDetails),
+		dbState:         *newDispatcherState(),
+	}
+
+	for _, tt := range []struct {
+		node      hpcNodeDetails
+		wantInUse int
+	}{
+		{node: hpcDetails.Nodes[0], wantInUse: 4},
+		{node: hpcDetails.Nodes[1], wantInUse: 1},
+	} {
+		inUse := 0
+		for _, slot := range m.hpcNodeToAgent(tt.node).Slots {
+			if slot.Container != nil {
+				inUse++
+			}
+		}
+		require.Equal(t, tt.wantInUse, inUse, tt.node.Name)
+	}
+
+	res, err := m.GetClusterUtilization()
+	require.NoError(t, err)
+	require.Equal(t, int32(5), res.GpusAllocated)
+
+	summary := m.summarizeResourcePools(hpcDetails)
+	require.Len(t, summary.pools, 1)
+	require.Equal(t, int32(8), summary.pools[0].SlotsAvailable)
+	require.Equal(t, int32(5), summary.pools[0].SlotsUsed)
+}
+
+func TestSchedulePendingTasksPausedPool(t *testing.T) {
+	m := &DispatcherResourceManager{
+		syslog:            logrus.WithField("component", "dispatcherrm"),
+		rmConfig:          &config.DispatcherResourceManagerConfig{},
+		reqList:           tasklist.New(),
+		groups:            map[model.JobID]*tasklist.Group{},
+		scheduledLaunches: mapx.New[model.AllocationID, struct{}](),
+		dbState:           *newDispatcherState(),
+		poolConfig: []config.ResourcePoolConfig{{
+			PoolName: "gpu-pool",
+			Provider: &provconfig.Config{
+				HPC: &provconfig.HpcClusterConfig{Partition: "gpus"},
+			},
+		}},
+	}
+	for id, pool := range map[model.AllocationID]string{
+		"alloc-1": "gpus", "alloc-2": "gpu-pool", "alloc-3": "cpus",
+	} {
+		m.reqList.AddTask(&sproto.AllocateRequest{
+			AllocationID: id, JobID: model.JobID(id), ResourcePool: pool,
+		})
+	}
+
+	// Requests for a paused partition, including through launcher-provided pools, stay queued.
+	m.dbState.PausedPools = []string{"gpus"}
+	m.SchedulePendingTasks()
+	require.False(t, m.reqList.IsScheduled("alloc-1"))
+	require.False(t, m.reqList.IsScheduled("alloc-2"))
+	require.True(t, m.reqList.IsScheduled("alloc-3"))
+
+	m.dbState.PausedPools = nil
+	m.SchedulePendingTasks()
+	require.True(t, m.reqList.IsScheduled("alloc-1"))
+	require.True(t, m.reqList.IsScheduled("alloc-2"))
+}
+
+func TestAllocateQueueFull(t *testing.T) {
+	m := &DispatcherResourceManager{
+		syslog:            logrus.WithField("component", "dispatcherrm"),
+		rmConfig:          &config.DispatcherResourceManagerConfig{MaxQueuedAllocations: ptrs.Ptr(2)},
+		reqList:           tasklist.New(),
+		groups:            map[model.JobID]*tasklist.Group{},
+		scheduledLaunches: mapx.New[model.AllocationID, struct{}](),
+	}
+	for _, id := range []model.AllocationID{"alloc-1", "alloc-2"} {
+		sub, err := m.Allocate(sproto.AllocateRequest{AllocationID: id, JobID: model.JobID(id)})
+		require.NoError(t, err)
+		defer sub.Close()
+	}
+
+	_, err := m.Allocate(sproto.AllocateRequest{AllocationID: "alloc-3", JobID: "alloc-3"})
+	require.ErrorIs(t, err, errQueueFull)
+	require.Equal(t, 2, m.reqList.Len())
+
+	// Allocations of jobs that are already running are restored regardless.
+	sub, err := m.Allocate(sproto.AllocateRequest{
+		AllocationID: "alloc-4", JobID: "alloc-4", Restore: true,
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	m.Release(sproto.ResourcesReleased{AllocationID: "alloc-1"})
+	m.Release(sproto.ResourcesReleased{AllocationID: "alloc-2"})
+	sub, err = m.Allocate(sproto.AllocateRequest{AllocationID: "alloc-3", JobID: "alloc-3"})
+	require.NoError(t, err)
+	defer sub.Close()
+}
+
+func TestMaxSlotsPerJob(t *testing.T) {
+	m := &DispatcherResourceManager{
+		syslog: logrus.WithField("component", "dispatcherrm"),
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"gpus": {MaxSlotsPerJob: ptrs.Ptr(8)},
+			},
+		},
+		poolConfig: []config.ResourcePoolConfig{
+			{
+				PoolName: "gpus-shared",
+				Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "GPUs"}},
+			},
+			{
+				PoolName:       "gpus-small",
+				MaxSlotsPerJob: ptrs.Ptr(2),
+				Provider:       &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "GPUs"}},
+			},
+		},
+		reqList:           tasklist.New(),
+		groups:            map[model.JobID]*tasklist.Group{},
+		scheduledLaunches: mapx.New[model.AllocationID, struct{}](),
+	}
+
+	for _, tt := range []struct {
+		pool     string
+		slots    int
+		exceeded bool
+	}{
+		{pool: "gpus", slots: 8},
+		{pool: "gpus", slots: 9, exceeded: true},
+		{pool: "cpus", slots: 64},
+		{pool: "gpus-shared", slots: 8},
+		{pool: "gpus-shared", slots: 9, exceeded: true},
+		{pool: "gpus-small", slots: 2},
+		{pool: "gpus-small", slots: 3, exceeded: true},
+	} {
+		_, err := m.ValidateResources(sproto.ValidateResourcesRequest{
+			ResourcePool: tt.pool, Slots: tt.slots,
+		})
+		if tt.exceeded {
+			require.ErrorIs(t, err, errMaxSlotsPerJobExceeded, "%s: %d slots", tt.pool, tt.slots)
+		} else {
+			require.NoError(t, err, "%s: %d slots", tt.pool, tt.slots)
+		}
+	}
+
+	// Over-limit allocations are rejected, unless they are of jobs that are already running.
+	_, err := m.Allocate(sproto.AllocateRequest{
+		AllocationID: "alloc-1", JobID: "alloc-1", ResourcePool: "gpus", SlotsNeeded: 16,
+	})
+	require.ErrorIs(t, err, errMaxSlotsPerJobExceeded)
+	require.Equal(t, 0, m.reqList.Len())
+
+	sub, err := m.Allocate(sproto.AllocateRequest{
+		AllocationID: "alloc-2", JobID: "alloc-2", ResourcePool: "gpus", SlotsNeeded: 16,
+		Restore: true,
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Equal(t, 1, m.reqList.Len())
+}
+
+func TestDispatchExitedResourcesStopped(t *testing.T) {
+	// A job that ran to completion has no failure.
+	stopped := DispatchExited{Cause: dispatchExitedWithCode}.resourcesStopped()
+	require.Nil(t, stopped.Failure)
+
+	// A job that failed reports its exit code, including a negative one.
+	for _, code := range []exitCode{1, -1} {
+		stopped = DispatchExited{ExitCode: code, Cause: dispatchExitedWithCode}.resourcesStopped()
+		require.NotNil(t, stopped.Failure)
+		require.Equal(t, sproto.ResourcesFailed, stopped.Failure.FailureType)
+		require.Equal(t, ptrs.Ptr(sproto.ExitCode(code)), stopped.Failure.ExitCode)
+	}
+
+	// A canceled or lost job fails without an exit code.
+	for _, cause := range []dispatchExitCause{dispatchCanceled, dispatchLost, dispatchFailed} {
+		stopped = DispatchExited{Cause: cause}.resourcesStopped()
+		require.NotNil(t, stopped.Failure, cause)
+		require.Equal(t, sproto.ResourcesFailed, stopped.Failure.FailureType, cause)
+		require.Nil(t, stopped.Failure.ExitCode, cause)
+	}
+}
+
+func TestHealthCheckWithFakeLauncher(t *testing.T) {
+	cl := newFakeLauncherClient()
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		rmConfig:  &config.DispatcherResourceManagerConfig{Name: "testname"},
+		apiClient: cl,
+		dbState:   *newDispatcherState(),
+	}
+	require.Equal(t, model.Healthy, m.HealthCheck()[0].Status)
+
+	cl.versionErr = fmt.Errorf("launcher is down")
+	require.Equal(t, model.Unhealthy, m.HealthCheck()[0].Status)
+}
+
+func TestDispatcherStatePersistenceFailure(t *testing.T) {
+	cl := newFakeLauncherClient()
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		rmConfig:  &config.DispatcherResourceManagerConfig{Name: "testname"},
+		apiClient: cl,
+		dbState:   *newDispatcherState(),
+	}
+	var persistErr error
+	var persisted [][]string
+	m.dbState.persistFn = func(_ context.Context, s *dispatcherState) error {
+		if persistErr != nil {
+			return persistErr
+		}
+		persisted = append(persisted, slices.Clone(s.DisabledAgents))
+		return nil
+	}
+
+	require.NoError(t, m.dbState.disableAgent("node1"))
+	require.NoError(t, m.dbState.pausePool("partition1"))
+	require.Equal(t, model.Healthy, m.HealthCheck()[0].Status)
+
+	// Failed writes leave the in-memory state as it is in the database.
+	persistErr = fmt.Errorf("connection refused")
+	require.ErrorContains(t, m.dbState.disableAgent("node2"), "disabling agent node2: connection refused")
+	require.True(t, m.dbState.isAgentEnabled("node2"))
+	require.ErrorContains(t, m.dbState.enableAgent("node1"), "enabling agent node1")
+	require.False(t, m.dbState.isAgentEnabled("node1"))
+	require.ErrorContains(t, m.dbState.pausePool("partition2"), "pausing partition partition2")
+	require.False(t, m.dbState.isPoolPaused("partition2"))
+	require.ErrorContains(t, m.dbState.resumePool("partition1"), "resuming partition partition1")
+	require.True(t, m.dbState.isPoolPaused("partition1"))
+	require.Equal(t, []string{"node1"}, m.dbState.DisabledAgents)
+	require.False(t, m.hpcNodeToAgent(hpcNodeDetails{Name: "node1"}).Enabled)
+	require.True(t, m.hpcNodeToAgent(hpcNodeDetails{Name: "node2"}).Enabled)
+
+	// The state store is reported unhealthy until it can be written again.
+	require.Equal(t, model.Unhealthy, m.HealthCheck()[0].Status)
+	persistErr = nil
+	require.Equal(t, model.Healthy, m.HealthCheck()[0].Status)
+	require.Equal(t, [][]string{{"node1"}, {"node1"}, {"node1"}}, persisted)
+	require.Equal(t, model.Healthy, m.HealthCheck()[0].Status)
+	require.Len(t, persisted, 3)
+}
+
+func TestSendManifestToDispatcher(t *testing.T) {
+	cl := newFakeLauncherClient()
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+	}
+	manifest := launcher.NewManifest("v1", *launcher.NewClientMetadata("det"))
+
+	dispatchID, err := m.sendManifestToDispatcher(manifest, "alice", "alloc-1")
+	require.NoError(t, err)
+	require.Equal(t, "alloc-1", dispatchID)
+	require.Equal(t, []string{"alice"}, cl.launchedWith)
+
+	cl.launchErr = fmt.Errorf("unexpected EOF")
+	_, err = m.sendManifestToDispatcher(manifest, "alice", "alloc-2")
+	require.ErrorContains(t, err, "excessive outstanding requests")
+
+	cl.launchErr = fmt.Errorf("connection refused")
+	_, err = m.sendManifestToDispatcher(manifest, "alice", "alloc-3")
+	require.ErrorContains(t, err, "Verify that the launcher service is up and reachable")
+
+	// A launcher that does not answer in time is told apart from one that is unreachable.
+	cl.launchErr = launcherRequestTimeoutError{
+		apiName: "launchDispatcherJob", timeout: time.Minute, err: context.DeadlineExceeded,
+	}
+	_, err = m.sendManifestToDispatcher(manifest, "alice", "alloc-4")
+	require.ErrorContains(t, err, "did not respond within the launcher_request_timeout")
+}
+
+func TestTerminateDispatcherJob(t *testing.T) {
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+	m.jobWatcher.monitoredJobs.Store("dispatch-1", &launcherJob{dispatcherID: "dispatch-1"})
+
+	require.False(t, m.terminateDispatcherJob("", "alice", false))
+	require.Empty(t, cl.terminated)
+
+	require.True(t, m.terminateDispatcherJob("dispatch-1", "alice", false))
+	require.Equal(t, []string{"dispatch-1"}, cl.terminated)
+	require.True(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-1"))
+
+	cl.terminateErr = fmt.Errorf("launcher is down")
+	require.False(t, m.terminateDispatcherJob("dispatch-2", "alice", false))
+	require.False(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-2"))
+}
+
+func TestDispatchExitedWithFakeLauncher(t *testing.T) {
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	var deletedFromDB []string
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+		rmConfig:  &config.DispatcherResourceManagerConfig{},
+		listDispatchesByAllocationID: func(
+			context.Context, model.AllocationID,
+		) ([]*db.Dispatch, error) {
+			return []*db.Dispatch{{DispatchID: "alloc-1", ImpersonatedUser: "alice"}}, nil
+		},
+		deleteDispatch: func(_ context.Context, dispatchID string) (int64, error) {
+			deletedFromDB = append(deletedFromDB, dispatchID)
+			return 1, nil
+		},
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		cleanupFailures:      mapx.New[string, *dispatchCleanupFailure](),
+	}
+	req := &sproto.AllocateRequest{AllocationID: "alloc-1"}
+	m.reqList.AddTask(req)
+	alloc := &sproto.ResourcesAllocated{
+		ID:        req.AllocationID,
+		Resources: sproto.ResourceList{"resources-1": &DispatcherResources{id: "resources-1", req: req}},
+	}
+	m.reqList.AddAllocationRaw(req.AllocationID, alloc)
+	cl.launch("det", "alice", "alloc-1")
+	dispatchIDToHPCJobID.Store("alloc-1", "1234")
+	sub := rmevents.Subscribe(req.AllocationID)
+	defer sub.Close()
+
+	m.dispatchExited(DispatchExited{
+		DispatchID: "alloc-1", Cause: dispatchExitedWithCode, ExitCode: 2, Message: "out of memory",
+	}, req, alloc)
+
+	// The message of the dispatch is logged before the resources are terminated.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ev, err := sub.GetWithContext(ctx)
+	require.NoError(t, err)
+	containerLog, ok := ev.(*sproto.ContainerLog)
+	require.True(t, ok)
+	require.Equal(t, "out of memory", *containerLog.AuxMessage)
+	ev, err = sub.GetWithContext(ctx)
+	require.NoError(t, err)
+	changed, ok := ev.(*sproto.ResourcesStateChanged)
+	require.True(t, ok)
+	require.Equal(t, sproto.ResourcesID("resources-1"), changed.ResourcesID)
+	require.Equal(t, sproto.Terminated, changed.ResourcesState)
+	require.Equal(t, sproto.ExitCode(2), *changed.ResourcesStopped.Failure.ExitCode)
+
+	// The dispatcher environment of the job is removed.
+	require.Equal(t, []string{"alloc-1"}, cl.deleted)
+	require.Equal(t, []string{"alloc-1"}, deletedFromDB)
+	_, ok = dispatchIDToHPCJobID.Load("alloc-1")
+	require.False(t, ok)
+
+	// A failure to remove the environment is recorded for a later retry.
+	cl.deleteErr = fmt.Errorf("launcher is down")
+	m.dispatchExited(DispatchExited{DispatchID: "alloc-1", Cause: dispatchCanceled}, req, alloc)
+	require.Equal(t, []string{"alloc-1"}, deletedFromDB)
+	f, ok := m.cleanupFailures.Load("alloc-1")
+	require.True(t, ok)
+	require.Equal(t, "alice", f.owner)
+}
+
+func TestStopLauncherJobWithFakeLauncher(t *testing.T) {
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	var dispatches []*db.Dispatch
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+		listDispatchesByAllocationID: func(
+			context.Context, model.AllocationID,
+		) ([]*db.Dispatch, error) {
+			return dispatches, nil
+		},
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		inflightCancelations: mapx.New[model.AllocationID, struct{}](),
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+	for _, id := range []string{"dispatch-1", "dispatch-2", "dispatch-3"} {
+		m.jobWatcher.monitoredJobs.Store(id, &launcherJob{dispatcherID: id})
+	}
+
+	// Nothing is terminated before the launcher has created the dispatch.
+	m.stopLauncherJob(KillDispatcherResources{AllocationID: "alloc-1"})
+	require.Empty(t, cl.terminated)
+
+	// The dispatches are terminated in one batch per user. Their environments are left to
+	// the job watcher that monitors them.
+	dispatches = []*db.Dispatch{
+		{DispatchID: "dispatch-1", ImpersonatedUser: "alice"},
+		{DispatchID: "dispatch-2", ImpersonatedUser: "bob"},
+	}
+	m.stopLauncherJob(KillDispatcherResources{AllocationID: "alloc-1"})
+	require.Equal(t, map[string][]string{"alice": {"dispatch-1"}, "bob": {"dispatch-2"}}, cl.batches)
+	require.True(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-1"))
+	require.True(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-2"))
+	require.Empty(t, cl.deleted)
+	_, inflight := m.inflightCancelations.Load(model.AllocationID("alloc-1"))
+	require.False(t, inflight)
+
+	// A dispatch with a recent termination request is not terminated again, and a dispatch
+	// that fails to terminate is left for a later retry.
+	dispatches = append(dispatches, &db.Dispatch{DispatchID: "dispatch-3", ImpersonatedUser: "alice"})
+	cl.terminateErrs = map[string]error{"dispatch-3": fmt.Errorf("launcher is down")}
+	m.stopLauncherJob(KillDispatcherResources{AllocationID: "alloc-1"})
+	require.Equal(t, map[string][]string{
+		"alice": {"dispatch-1", "dispatch-3"}, "bob": {"dispatch-2"},
+	}, cl.batches)
+	require.False(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-3"))
+}
+
+func TestHasSlurmPartitionWithFakeLauncher(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.logs["slurm-resources-info"] = `
+partitions:
+- partitionName: gpus
+  default: true
+  totalNodes: 2
+  totalGpuSlots: 8
+`
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:       cl,
+	}
+	hpcDetails, ok := c.fetchHpcResourceDetails()
+	require.True(t, ok)
+
+	m := &DispatcherResourceManager{
+		poolConfig: []config.ResourcePoolConfig{{
+			PoolName: "gpus-shared",
+			Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "gpus"}},
+		}},
+	}
+	require.Equal(t, hasSlurmPartitionResponse{HasResourcePool: true},
+		m.hasSlurmPartition(hpcDetails, "gpus"))
+	resp := m.hasSlurmPartition(hpcDetails, "gpus-shared")
+	require.True(t, resp.HasResourcePool)
+	require.Equal(t, "gpus", resp.ProvidingPartition)
+	require.False(t, m.hasSlurmPartition(hpcDetails, "cpus").HasResourcePool)
+}
+
+func TestStartLauncherJobPartitionOverrides(t *testing.T) {
+	require.NoError(t, etc.SetRootPath("../../../static/srv/"))
+
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	var inserted []*db.Dispatch
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+		insertDispatch: func(_ context.Context, d *db.Dispatch) error {
+			inserted = append(inserted, d)
+			return nil
+		},
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			LauncherContainerRunType: "singularity",
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"gpus": {
+					ContainerRunType: ptrs.Ptr("podman"),
+					ImagePullPolicy:  ptrs.Ptr(config.ImagePullPolicyIfNotPresent),
+				},
+			},
+		},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{
+				{PartitionName: "gpus", TotalNodes: 2, TotalGpuSlots: 8, TotalCPUSlots: 8},
+				{PartitionName: "cpus", TotalNodes: 2, TotalCPUSlots: 8},
+			},
+		}),
+		dbState:              *newDispatcherState(),
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		scheduledLaunches:    mapx.New[model.AllocationID, struct{}](),
+		dependencyWaits:      mapx.New[model.AllocationID, []string](),
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+
+	image := "RawImage"
+	spec := tasks.TaskSpec{
+		Owner:          &model.User{Username: "admin"},
+		AgentUserGroup: &model.AgentUserGroup{User: "determined", Group: "determined"},
+		WorkDir:        "/run/determined/workdir",
+		Environment: expconf.EnvironmentConfigV0{
+			RawImage: &expconf.EnvironmentImageMapV0{
+				RawCPU: &image, RawCUDA: &image, RawROCM: &image,
+			},
+			RawEnvironmentVariables: &expconf.EnvironmentVariablesMap{},
+			RawProxyPorts:           &expconf.ProxyPortsConfigV0{},
+			RawForcePullImage:       ptrs.Ptr(true),
+			RawPodSpec:              &expconf.PodSpec{},
+		},
+		ResourcesConfig: schemas.WithDefaults(expconf.ResourcesConfig{}),
+	}
+
+	for _, tt := range []struct {
+		pool         string
+		wantCarrier  string
+		wantPullEach bool
+	}{
+		{
+			pool:         "gpus",
+			wantCarrier:  "com.cray.analytics.capsules.carriers.hpc.slurm.PodmanOverSlurm",
+			wantPullEach: false,
+		},
+		// A partition without overrides uses the resource manager's settings.
+		{
+			pool:         "cpus",
+			wantCarrier:  "com.cray.analytics.capsules.carriers.hpc.slurm.SingularityOverSlurm",
+			wantPullEach: true,
+		},
+	} {
+		t.Run(tt.pool, func(t *testing.T) {
+			allocationID := model.AllocationID("alloc-" + tt.pool)
+			req := &sproto.AllocateRequest{
+				AllocationID: allocationID, ResourcePool: tt.pool, SlotsNeeded: 1,
+			}
+			sub := rmevents.Subscribe(allocationID)
+			defer sub.Close()
+
+			monitored := make(chan *launcherJob, 1)
+			go func() { monitored <- <-m.jobWatcher.newLauncherJob }()
+			m.startLauncherJob(StartDispatcherResources{
+				AllocationID: allocationID, ResourcesID: "resources-1", Spec: spec,
+			}, req)
+			require.Zero(t, sub.Len())
+			require.Equal(t, string(allocationID), (<-monitored).dispatcherID)
+
+			require.NotEmpty(t, cl.manifests)
+			payload := (*cl.manifests[len(cl.manifests)-1].Payloads)[0]
+			require.Equal(t, []string{tt.wantCarrier}, payload.GetCarriers())
+			launchParameters := payload.GetLaunchParameters()
+			_, pullEach := launchParameters.GetConfiguration()["disableImageCache"]
+			require.Equal(t, tt.wantPullEach, pullEach)
+			require.Equal(t, tt.pool, launchParameters.GetConfiguration()["queue"])
+
+			require.Equal(t, string(allocationID), inserted[len(inserted)-1].DispatchID)
+		})
+	}
+}
+
+func TestIsDispatchInUse(t *testing.T) {
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:  logrus.WithField("component", "dispatcherrm"),
+		reqList: tasklist.New(),
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+	m.reqList.AddTask(&sproto.AllocateRequest{AllocationID: "alloc-1", Restore: true})
+	m.jobWatcher.monitoredJobs.Store("dispatch-2", &launcherJob{dispatcherID: "dispatch-2"})
+
+	// Dispatches of allocations being restored or monitored are left to their allocations.
+	require.True(t, m.isDispatchInUse(&db.Dispatch{DispatchID: "dispatch-1", AllocationID: "alloc-1"}))
+	require.True(t, m.isDispatchInUse(&db.Dispatch{DispatchID: "dispatch-2", AllocationID: "alloc-2"}))
+	require.False(t, m.isDispatchInUse(&db.Dispatch{DispatchID: "dispatch-3", AllocationID: "alloc-3"}))
+}
+
+func TestRestoreWaitsForDelayedDispatch(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		gracePeriod time.Duration
+		recorded    bool
+	}{
+		{name: "dispatch recorded late", gracePeriod: time.Minute, recorded: true},
+		{name: "no grace period", gracePeriod: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := newFakeLauncherClient()
+			dispatchIDToHPCJobID := mapx.New[string, string]()
+			var dispatches []*db.Dispatch
+			m := &DispatcherResourceManager{
+				syslog:    logrus.WithField("component", "dispatcherrm"),
+				apiClient: cl,
+				listDispatchesByAllocationID: func(
+					context.Context, model.AllocationID,
+				) ([]*db.Dispatch, error) {
+					return dispatches, nil
+				},
+				rmConfig: &config.DispatcherResourceManagerConfig{
+					RestoreDispatchGracePeriod: ptrs.Ptr(model.Duration(tt.gracePeriod)),
+				},
+				reqList:              tasklist.New(),
+				groups:               map[model.JobID]*tasklist.Group{},
+				dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+				scheduledLaunches:    mapx.New[model.AllocationID, struct{}](),
+				restoreAttempts:      mapx.New[model.AllocationID, time.Time](),
+				jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+					config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+			}
+			monitored := make(chan *launcherJob, 1)
+			if tt.recorded {
+				go func() { monitored <- <-m.jobWatcher.newLauncherJob }()
+			}
+			sub, err := m.Allocate(sproto.AllocateRequest{
+				AllocationID: "alloc-1", JobID: "job-1", Restore: true,
+			})
+			require.NoError(t, err)
+			defer sub.Close()
+
+			m.SchedulePendingTasks()
+			if tt.recorded {
+				// The dispatch isn't recorded yet, so the restore is attempted again later.
+				require.False(t, m.reqList.IsScheduled("alloc-1"))
+
+				dispatches = []*db.Dispatch{{
+					DispatchID: "alloc-1", ResourceID: "resources-1", ImpersonatedUser: "alice",
+				}}
+				m.SchedulePendingTasks()
+			}
+			require.True(t, m.reqList.IsScheduled("alloc-1"))
+			require.Equal(t, 0, m.restoreAttempts.Len())
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			ev, err := sub.GetWithContext(ctx)
+			require.NoError(t, err)
+			allocated, ok := ev.(*sproto.ResourcesAllocated)
+			require.True(t, ok)
+			require.Len(t, allocated.Resources, 1)
+			for _, r := range allocated.Resources {
+				summary := r.Summary()
+				if tt.recorded {
+					require.Equal(t, sproto.Reattached, summary.ReattachStatus)
+				} else {
+					require.Equal(t, sproto.ReattachLost, summary.ReattachStatus)
+				}
+				require.Equal(t, string(summary.ReattachStatus), summary.Proto().GetReattachStatus())
+			}
+			if tt.recorded {
+				require.Contains(t, allocated.Resources, sproto.ResourcesID("resources-1"))
+				require.Equal(t, "alloc-1", (<-monitored).dispatcherID)
+				return
+			}
+
+			ev, err = sub.GetWithContext(ctx)
+			require.NoError(t, err)
+			changed, ok := ev.(*sproto.ResourcesStateChanged)
+			require.True(t, ok)
+			require.Equal(t, sproto.Terminated, changed.ResourcesState)
+			require.Equal(t, "Unable to locate HPC job on restart.",
+				changed.ResourcesStopped.Failure.ErrMsg)
+		})
+	}
+}
+
+func TestDispatchUsages(t *testing.T) {
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:  logrus.WithField("component", "dispatcherrm"),
+		reqList: tasklist.New(),
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+	for i, state := range []launcher.DispatchState{
+		launcher.RUNNING, launcher.RUNNING, launcher.PENDING, launcher.TERMINATING, "",
+	} {
+		id := fmt.Sprintf("%d", i+1)
+		m.reqList.AddTask(&sproto.AllocateRequest{
+			AllocationID: model.AllocationID("alloc-" + id),
+			SlotsNeeded:  i + 1,
+		})
+		if state != "" {
+			m.jobWatcher.monitoredJobs.Store("dispatch-"+id, &launcherJob{
+				dispatcherID: "dispatch-" + id,
+				lastJobState: state,
+			})
+		}
+	}
+	dispatches := []*db.Dispatch{
+		{DispatchID: "dispatch-1", AllocationID: "alloc-1", ImpersonatedUser: "bob"},
+		{DispatchID: "dispatch-2", AllocationID: "alloc-2", ImpersonatedUser: "bob"},
+		{DispatchID: "dispatch-3", AllocationID: "alloc-3", ImpersonatedUser: "bob"},
+		{DispatchID: "dispatch-4", AllocationID: "alloc-4", ImpersonatedUser: "bob"},
+		{DispatchID: "dispatch-5", AllocationID: "alloc-5", ImpersonatedUser: "alice"},
+		{DispatchID: "dispatch-6", AllocationID: "alloc-6", ImpersonatedUser: "alice"},
+	}
+	owners := map[string]string{
+		"dispatch-1": "admin",
+		"dispatch-2": "admin",
+		"dispatch-3": "admin",
+		"dispatch-4": "admin",
+		"dispatch-5": "determined",
+		"dispatch-6": "determined",
+	}
+
+	// Dispatches of untracked allocations and terminating jobs are left out, and jobs that
+	// haven't reported a state yet are pending.
+	usages := m.dispatchUsages(dispatches, owners)
+	require.Len(t, usages, 2)
+	require.Equal(t, "alice", usages[0].ImpersonatedUser)
+	require.Equal(t, "determined", usages[0].Owner)
+	require.Equal(t, int32(0), usages[0].RunningCount)
+	require.Equal(t, int32(1), usages[0].PendingCount)
+	require.Equal(t, int32(5), usages[0].Slots)
+	require.Equal(t, "bob", usages[1].ImpersonatedUser)
+	require.Equal(t, "admin", usages[1].Owner)
+	require.Equal(t, int32(2), usages[1].RunningCount)
+	require.Equal(t, int32(1), usages[1].PendingCount)
+	require.Equal(t, int32(6), usages[1].Slots)
+}
+
+func TestTerminateDispatcherJobs(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.terminateErrs = map[string]error{"dispatch-3": fmt.Errorf("launcher is down")}
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+	}
+	for _, id := range []string{"dispatch-1", "dispatch-3"} {
+		m.jobWatcher.monitoredJobs.Store(id, &launcherJob{dispatcherID: id})
+	}
+
+	terminated := m.terminateDispatcherJobs([]*db.Dispatch{
+		{DispatchID: "dispatch-1", ImpersonatedUser: "alice"},
+		{DispatchID: "dispatch-2", ImpersonatedUser: "bob"},
+		{DispatchID: "dispatch-3", ImpersonatedUser: "alice"},
+		{DispatchID: "", ImpersonatedUser: "alice"},
+		{DispatchID: "dispatch-4", ImpersonatedUser: "alice"},
+	})
+
+	// The terminations of each user are sent as one batch.
+	require.Equal(t, map[string][]string{
+		"alice": {"dispatch-1", "dispatch-3", "dispatch-4"},
+		"bob":   {"dispatch-2"},
+	}, cl.batches)
+
+	// Failed terminations are left for a later retry.
+	var terminatedIDs []string
+	for _, d := range terminated {
+		terminatedIDs = append(terminatedIDs, d.DispatchID)
+	}
+	require.Equal(t, []string{"dispatch-1", "dispatch-2", "dispatch-4"}, terminatedIDs)
+	require.True(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-1"))
+	require.False(t, m.jobWatcher.isJobMarkedAsTerminated("dispatch-3"))
+}
+
+func TestGetDispatchStatus(t *testing.T) {
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:               logrus.WithField("component", "dispatcherrm"),
+		apiClient:            cl,
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+	}
+	cl.launch("job", "alice", "alloc-1")
+	cl.reasons["alloc-1"] = "running on node1"
+	cl.dispatches["alloc-2"] = launcher.DispatchInfo{DispatchId: launcher.PtrString("alloc-2")}
+
+	status, err := m.getDispatchStatus("alice", "alloc-1")
+	require.NoError(t, err)
+	require.Equal(t, dispatchStatus{state: launcher.RUNNING, reason: "running on node1"}, status)
+
+	status, err = m.getDispatchStatus("alice", "alloc-2")
+	require.NoError(t, err)
+	require.Equal(t, launcher.UNKNOWN, status.state)
+
+	status, err = m.getDispatchStatus("alice", "alloc-gone")
+	require.NoError(t, err)
+	require.Equal(t, launcher.MISSING, status.state)
+
+	// The state of a reconnected dispatch is published without waiting for the job watcher.
+	req := &sproto.AllocateRequest{AllocationID: "alloc-1", JobID: "job-1"}
+	m.reqList.AddTask(req)
+	m.reqList.AddAllocationRaw(req.AllocationID, &sproto.ResourcesAllocated{
+		ID: req.AllocationID,
+		Resources: sproto.ResourceList{
+			"resources-1": &DispatcherResources{id: "resources-1", req: req},
+		},
+	})
+	sub := rmevents.Subscribe(req.AllocationID)
+	defer sub.Close()
+
+	m.publishRestoredDispatchState("alice", "alloc-1")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ev, err := sub.GetWithContext(ctx)
+	require.NoError(t, err)
+	changed, ok := ev.(*sproto.ResourcesStateChanged)
+	require.True(t, ok)
+	require.Equal(t, sproto.ResourcesID("resources-1"), changed.ResourcesID)
+	require.Equal(t, sproto.Pulling, changed.ResourcesState)
+
+	// Exited dispatches are left to the job watcher.
+	cl.dispatches["alloc-1"] = launcher.DispatchInfo{State: launcher.COMPLETED.Ptr()}
+	m.publishRestoredDispatchState("alice", "alloc-1")
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = sub.GetWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDispatchStateChangeHPCJobID(t *testing.T) {
+	for _, show := range []bool{true, false} {
+		t.Run(fmt.Sprintf("show=%v", show), func(t *testing.T) {
+			dispatchIDToHPCJobID := mapx.New[string, string]()
+			m := &DispatcherResourceManager{
+				syslog:               logrus.WithField("component", "dispatcherrm"),
+				rmConfig:             &config.DispatcherResourceManagerConfig{ShowHPCJobID: show},
+				reqList:              tasklist.New(),
+				dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+			}
+			req := &sproto.AllocateRequest{AllocationID: model.AllocationID(fmt.Sprintf("alloc-%v", show))}
+			m.reqList.AddTask(req)
+			m.reqList.AddAllocationRaw(req.AllocationID, &sproto.ResourcesAllocated{
+				ID: req.AllocationID,
+				Resources: sproto.ResourceList{
+					"resources-1": &DispatcherResources{id: "resources-1", req: req},
+				},
+			})
+			sub := rmevents.Subscribe(req.AllocationID)
+			defer sub.Close()
+
+			m.DispatchStateChange(DispatchStateChange{
+				DispatchID: string(req.AllocationID),
+				State:      launcher.RUNNING,
+				HPCJobID:   "1234",
+			})
+
+			// The HPC job ID is recorded whether or not it is shown.
+			hpcJobID, ok := dispatchIDToHPCJobID.Load(string(req.AllocationID))
+			require.True(t, ok)
+			require.Equal(t, "1234", hpcJobID)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			ev, err := sub.GetWithContext(ctx)
+			require.NoError(t, err)
+			if show {
+				containerLog, ok := ev.(*sproto.ContainerLog)
+				require.True(t, ok)
+				require.Equal(t, "HPC Job ID: 1234", *containerLog.AuxMessage)
+				ev, err = sub.GetWithContext(ctx)
+				require.NoError(t, err)
+			}
+			_, ok = ev.(*sproto.ResourcesStateChanged)
+			require.True(t, ok)
+		})
+	}
+}
+
+func TestDispatchStateChangePendingTimeout(t *testing.T) {
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog: logrus.WithField("component", "dispatcherrm"),
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"gpu": {MaxPendingDuration: ptrs.Ptr(model.Duration(time.Minute))},
+			},
 		},
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		jobCancelQueue:       orderedmapx.New[string, KillDispatcherResources](),
+		pendingSince:         mapx.New[model.AllocationID, time.Time](),
+	}
+	addTask := func(id model.AllocationID, pool string) *sproto.ResourcesSubscription {
+		req := &sproto.AllocateRequest{AllocationID: id, ResourcePool: pool}
+		m.reqList.AddTask(req)
+		m.reqList.AddAllocationRaw(req.AllocationID, &sproto.ResourcesAllocated{
+			ID: req.AllocationID,
+			Resources: sproto.ResourceList{
+				"resources-1": &DispatcherResources{id: "resources-1", req: req},
+			},
+		})
+		return rmevents.Subscribe(req.AllocationID)
+	}
+	pending := func(id model.AllocationID) {
+		m.DispatchStateChange(DispatchStateChange{
+			DispatchID:    string(id),
+			State:         launcher.PENDING,
+			PendingReason: "Resources",
+		})
+	}
 
-		"/agents/Node 2/slots/1": {
-			Id:      "1",
-			Device:  &devicev1.Device{Type: devicev1.Type_TYPE_ROCM},
-			Enabled: true,
-		},
+	// Jobs in pools without a max_pending_duration may pend indefinitely.
+	sub := addTask("alloc-cpu", "cpu")
+	defer sub.Close()
+	pending("alloc-cpu")
+	_, ok := m.pendingSince.Load("alloc-cpu")
+	require.False(t, ok)
+
+	sub = addTask("alloc-gpu", "gpu")
+	defer sub.Close()
+	pending("alloc-gpu")
+	since, ok := m.pendingSince.Load("alloc-gpu")
+	require.True(t, ok)
+
+	// The job is not canceled before max_pending_duration, and leaving the pending state
+	// starts timing again.
+	pending("alloc-gpu")
+	_, ok = m.jobCancelQueue.Get("alloc-gpu")
+	require.False(t, ok)
+	m.DispatchStateChange(DispatchStateChange{DispatchID: "alloc-gpu", State: launcher.RUNNING})
+	_, ok = m.pendingSince.Load("alloc-gpu")
+	require.False(t, ok)
+
+	// A job pending longer than max_pending_duration is canceled, and its log says why.
+	m.pendingSince.Store("alloc-gpu", since.Add(-2*time.Minute))
+	pending("alloc-gpu")
+	kill, ok := m.jobCancelQueue.Get("alloc-gpu")
+	require.True(t, ok)
+	require.Equal(t, KillDispatcherResources{
+		ResourcesID:  "resources-1",
+		AllocationID: "alloc-gpu",
+	}, kill)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var containerLog *sproto.ContainerLog
+	for containerLog == nil {
+		ev, err := sub.GetWithContext(ctx)
+		require.NoError(t, err)
+		containerLog, _ = ev.(*sproto.ContainerLog)
+	}
+	require.Contains(t, *containerLog.AuxMessage, "HPC job canceled after pending for 2m")
+	require.Contains(t, *containerLog.AuxMessage,
+		"max_pending_duration of 1m0s of resource pool gpu")
+	require.Contains(t, *containerLog.AuxMessage, "waiting to be scheduled: Resources")
+}
+
+func TestDispatchStateChangeMalformedResources(t *testing.T) {
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	m := &DispatcherResourceManager{
+		syslog:               logrus.WithField("component", "dispatcherrm"),
+		rmConfig:             &config.DispatcherResourceManagerConfig{},
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		jobCancelQueue:       orderedmapx.New[string, KillDispatcherResources](),
 	}
 
-	want2 := map[string]*agentv1.Slot{
-		"/agents/Node 3/slots/0": {
-			Id:        "0",
-			Device:    &devicev1.Device{Type: devicev1.Type_TYPE_CUDA},
-			Enabled:   true,
-			Container: &containerv1.Container{State: containerv1.State_STATE_RUNNING},
+	// An allocation with more than one resources has each of them terminated with a failure.
+	req := &sproto.AllocateRequest{AllocationID: "alloc-two"}
+	m.reqList.AddTask(req)
+	m.reqList.AddAllocationRaw(req.AllocationID, &sproto.ResourcesAllocated{
+		ID: req.AllocationID,
+		Resources: sproto.ResourceList{
+			"resources-1": &DispatcherResources{id: "resources-1", req: req},
+			"resources-2": &DispatcherResources{id: "resources-2", req: req},
 		},
+	})
+	sub := rmevents.Subscribe(req.AllocationID)
+	defer sub.Close()
 
-		"/agents/Node 3/slots/1": {
-			Id:      "1",
-			Device:  &devicev1.Device{Type: devicev1.Type_TYPE_CUDA},
-			Enabled: true,
-		},
+	m.DispatchStateChange(DispatchStateChange{DispatchID: string(req.AllocationID), State: launcher.RUNNING})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var terminated []sproto.ResourcesID
+	for i := 0; i < 2; i++ {
+		ev, err := sub.GetWithContext(ctx)
+		require.NoError(t, err)
+		changed, ok := ev.(*sproto.ResourcesStateChanged)
+		require.True(t, ok)
+		require.Equal(t, sproto.Terminated, changed.ResourcesState)
+		require.NotNil(t, changed.ResourcesStopped.Failure)
+		require.Equal(t, "allocation has 2 resources, expected 1",
+			changed.ResourcesStopped.Failure.ErrMsg)
+		terminated = append(terminated, changed.ResourcesID)
 	}
+	require.ElementsMatch(t, []sproto.ResourcesID{"resources-1", "resources-2"}, terminated)
 
-	wantSlots := []map[string]*agentv1.Slot{want0, want1, want2}
-
-	m.dbState.DisabledAgents = []string{"Node 2"}
+	// An allocation without resources is failed as a whole, also when its dispatch exits.
+	req = &sproto.AllocateRequest{AllocationID: "alloc-none"}
+	m.reqList.AddTask(req)
+	m.reqList.AddAllocationRaw(req.AllocationID, &sproto.ResourcesAllocated{
+		ID:        req.AllocationID,
+		Resources: sproto.ResourceList{},
+	})
+	sub = rmevents.Subscribe(req.AllocationID)
+	defer sub.Close()
 
-	resp, err := m.GetAgents()
+	m.handleDispatchExited(DispatchExited{DispatchID: string(req.AllocationID), Cause: dispatchLost})
+	ev, err := sub.GetWithContext(ctx)
 	require.NoError(t, err)
-	assert.Equal(t, len(resp.Agents), len(nodes))
-
-	for i, agent := range resp.Agents {
-		assert.Equal(t, agent.Id, nodes[i].Name)
-		assert.DeepEqual(t, agent.ResourcePools, expectedResourcePools[i])
-		assert.DeepEqual(t, agent.Addresses, nodes[i].Addresses)
-		assert.Equal(t, agent.Draining, nodes[i].Draining)
-		assert.Equal(t, agent.Enabled, agent.Id != "Node 2")
-		assert.Equal(t, len(agent.Slots), len(wantSlots[i]))
-		for key, value := range agent.Slots {
-			wantValue := wantSlots[i][key]
-			assert.Equal(t, value.Id, wantValue.Id)
-			assert.Equal(t, value.Device.Type, wantValue.Device.Type)
-			assert.Equal(t, value.Enabled, wantValue.Enabled)
+	invalid, ok := ev.(*sproto.InvalidResourcesRequestError)
+	require.True(t, ok)
+	require.EqualError(t, invalid.Cause, "allocation has 0 resources, expected 1")
+}
 
-			if wantValue.Container != nil {
-				assert.Equal(t, value.Container.State, wantValue.Container.State)
-			} else if value.Container != nil {
-				t.Errorf("agent.Slots %s Container value error", key)
-			}
-		}
+func TestRemoveDispatchEnvironmentLauncherFailure(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.deleteErr = fmt.Errorf("launcher is down")
+	m := &DispatcherResourceManager{
+		syslog:          logrus.WithField("component", "dispatcherrm"),
+		apiClient:       cl,
+		rmConfig:        &config.DispatcherResourceManagerConfig{},
+		cleanupFailures: mapx.New[string, *dispatchCleanupFailure](),
 	}
+
+	// When the launcher fails to delete the environment, the dispatch must be left
+	// in the DB for a later retry, so this must return before touching the DB.
+	m.removeDispatchEnvironment("alice", "dispatch-1")
+	require.Empty(t, cl.deleted)
+	f, ok := m.cleanupFailures.Load("dispatch-1")
+	require.True(t, ok)
+	require.Equal(t, "alice", f.owner)
+	require.Equal(t, 1, f.attempts)
 }
 
-func TestHealthCheck(t *testing.T) {
+func TestRetryDispatchCleanups(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.deleteErr = fmt.Errorf("launcher is down")
+	var deletedFromDB []string
 	m := &DispatcherResourceManager{
-		syslog: logrus.WithField("component", "dispatcherrm"),
+		syslog:    logrus.WithField("component", "dispatcherrm"),
+		apiClient: cl,
 		rmConfig: &config.DispatcherResourceManagerConfig{
-			Name: "testname",
+			DispatchCleanupRetryInterval: ptrs.Ptr(model.Duration(time.Minute)),
+			DispatchCleanupAlertAttempts: ptrs.Ptr(3),
 		},
+		deleteDispatch: func(_ context.Context, id string) (int64, error) {
+			deletedFromDB = append(deletedFromDB, id)
+			return 1, nil
+		},
+		cleanupFailures: mapx.New[string, *dispatchCleanupFailure](),
 	}
 
-	c, err := newLauncherAPIClient(m.rmConfig)
-	require.NoError(t, err)
-	m.apiClient = c
+	now := time.Now()
+	m.recordDispatchCleanupFailure("alice", "dispatch-1", now)
+	f, _ := m.cleanupFailures.Load("dispatch-1")
+	require.Equal(t, now.Add(time.Minute), f.nextAttempt)
 
-	require.Equal(t, []model.ResourceManagerHealth{
-		{
-			Name:   "testname",
-			Status: model.Unhealthy, // Unhealthy since launcher API client isn't set up properly.
-		},
-	}, m.HealthCheck())
+	// Cleanups are only retried once due, and back off after each failure.
+	m.retryDueDispatchCleanups(now.Add(30 * time.Second))
+	require.Equal(t, 1, f.attempts)
+	m.retryDueDispatchCleanups(now.Add(time.Minute))
+	require.Equal(t, 2, f.attempts)
+	require.WithinDuration(t, time.Now().Add(2*time.Minute), f.nextAttempt, time.Second)
+
+	// Once the launcher recovers, the dispatch is deleted and forgotten.
+	cl.deleteErr = nil
+	m.retryDueDispatchCleanups(f.nextAttempt)
+	require.Equal(t, []string{"dispatch-1"}, cl.deleted)
+	require.Equal(t, []string{"dispatch-1"}, deletedFromDB)
+	require.Equal(t, 0, m.cleanupFailures.Len())
+
+	// Nothing is retried when the retries are disabled.
+	m.rmConfig.DispatchCleanupRetryInterval = ptrs.Ptr(model.Duration(0))
+	m.recordDispatchCleanupFailure("alice", "dispatch-2", now)
+	require.Equal(t, 0, m.cleanupFailures.Len())
+}
+
+func TestDispatchCleanupBackoff(t *testing.T) {
+	require.Equal(t, time.Minute, dispatchCleanupBackoff(time.Minute, 1))
+	require.Equal(t, 2*time.Minute, dispatchCleanupBackoff(time.Minute, 2))
+	require.Equal(t, 32*time.Minute, dispatchCleanupBackoff(time.Minute, 6))
+	require.Equal(t, time.Hour, dispatchCleanupBackoff(time.Minute, 7))
+	require.Equal(t, time.Hour, dispatchCleanupBackoff(time.Minute, 1000))
+	// A retry interval longer than the maximum backoff is kept.
+	require.Equal(t, 2*time.Hour, dispatchCleanupBackoff(2*time.Hour, 5))
+}
+
+func TestDispatcherResourcesSummaryAccountingLabels(t *testing.T) {
+	r := DispatcherResources{
+		id:               "resources-1",
+		req:              &sproto.AllocateRequest{AllocationID: "alloc-1"},
+		accountingLabels: &atomic.Pointer[map[string]string]{},
+	}
+	require.Nil(t, r.Summary().AccountingLabels)
+
+	// The labels stored when the resources are started are visible to all copies.
+	labels := map[string]string{"cost_center": "cc42"}
+	r.accountingLabels.Store(&labels)
+	require.Equal(t, labels, r.Summary().AccountingLabels)
 }
 
 func Test_summarizeResourcePool(t *testing.T) {
 	type args struct {
-		wlmType          wlmType
-		launcherPoolName string
+		wlmType           wlmType
+		launcherPoolName  string
+		launcherPartition string
 	}
 
 	type want struct {
@@ -356,6 +2002,34 @@ func Test_summarizeResourcePool(t *testing.T) {
 				fittingPolicy: resourcepoolv1.FittingPolicy_FITTING_POLICY_SLURM,
 			},
 		},
+		{
+			name:       "Launcher-provided resource pool whose partition has no nodes",
+			partitions: []hpcPartitionDetails{p1},
+			args: args{
+				wlmType:           slurmSchedulerType,
+				launcherPoolName:  "launcher-pool",
+				launcherPartition: "drained partition",
+			},
+			want: want{
+				pools: []resourcepoolv1.ResourcePool{
+					{
+						Name:           "partition 1",
+						SlotType:       devicev1.Type_TYPE_CUDA,
+						SlotsAvailable: 5,
+						SlotsUsed:      3,
+						NumAgents:      10,
+					},
+					{
+						Name:        "launcher-pool",
+						Description: launcherPoolDescription + noAvailableNodesSuffix,
+						SlotType:    devicev1.Type_TYPE_CUDA,
+					},
+				},
+				wlmName:       "Slurm",
+				schedulerType: resourcepoolv1.SchedulerType_SCHEDULER_TYPE_SLURM,
+				fittingPolicy: resourcepoolv1.FittingPolicy_FITTING_POLICY_SLURM,
+			},
+		},
 		{
 			name:       "Two resource pool test; WLM is PBS",
 			partitions: []hpcPartitionDetails{p1, p2},
@@ -506,8 +2180,12 @@ func Test_summarizeResourcePool(t *testing.T) {
 
 			dpPools := []config.ResourcePoolConfig{}
 			if tt.args.launcherPoolName != "" {
+				partition := tt.args.launcherPartition
+				if partition == "" {
+					partition = tt.partitions[0].PartitionName
+				}
 				hpcProvider := provconfig.HpcClusterConfig{
-					Partition: tt.partitions[0].PartitionName,
+					Partition: partition,
 				}
 
 				dpPool1Provider := provconfig.Config{
@@ -523,10 +2201,12 @@ func Test_summarizeResourcePool(t *testing.T) {
 			}
 
 			m := &DispatcherResourceManager{
+				syslog:          logrus.WithField("component", "dispatcherrm"),
 				wlmType:         tt.args.wlmType,
 				rmConfig:        rmConfig,
 				hpcDetailsCache: makeTestHpcDetailsCache(hpcResource),
 				poolConfig:      dpPools,
+				dbState:         *newDispatcherState(),
 			}
 
 			res, _ := m.GetResourcePools()
@@ -558,6 +2238,251 @@ func Test_summarizeResourcePool(t *testing.T) {
 	}
 }
 
+func Test_summarizeResourcePoolLocation(t *testing.T) {
+	provided := func(name, partition, location string) config.ResourcePoolConfig {
+		return config.ResourcePoolConfig{
+			PoolName: name,
+			Location: location,
+			Provider: &provconfig.Config{
+				HPC: &provconfig.HpcClusterConfig{Partition: partition},
+			},
+		}
+	}
+	m := &DispatcherResourceManager{
+		syslog:  logrus.WithField("component", "dispatcherrm"),
+		wlmType: slurmSchedulerType,
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"west":    {Location: "us-west"},
+				"drained": {Location: "us-central"},
+			},
+		},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{
+				{PartitionName: "West"},
+				{PartitionName: "east"},
+			},
+		}),
+		poolConfig: []config.ResourcePoolConfig{
+			provided("west-inherited", "West", ""),
+			provided("west-site-b", "West", "site-b"),
+			provided("drained-pool", "drained", ""),
+		},
+		dbState: *newDispatcherState(),
+	}
+
+	res, err := m.GetResourcePools()
+	require.NoError(t, err)
+	locations := map[string]string{}
+	for _, pool := range res.ResourcePools {
+		locations[pool.Name] = pool.Location
+	}
+	require.Equal(t, map[string]string{
+		"West":           "us-west",
+		"east":           "",
+		"west-inherited": "us-west",
+		"west-site-b":    "site-b",
+		"drained-pool":   "us-central",
+	}, locations)
+}
+
+func TestGetResourcePoolsSummaryReuse(t *testing.T) {
+	m := &DispatcherResourceManager{
+		syslog:   logrus.WithField("component", "dispatcherrm"),
+		wlmType:  slurmSchedulerType,
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{{PartitionName: "gpus", TotalNodes: 2}},
+		}),
+		poolConfig: []config.ResourcePoolConfig{{
+			PoolName: "gpus-provided",
+			Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "gpus"}},
+		}},
+		dbState: *newDispatcherState(),
+	}
+
+	res, err := m.GetResourcePools()
+	require.NoError(t, err)
+	summary := m.hpcDetailsCache.poolsSummary.Load()
+	require.NotNil(t, summary)
+
+	// Callers may modify the pools, so each call returns copies of the summary.
+	res.ResourcePools[0].Name = "modified"
+
+	// The pause state is not part of the summary, so it is up to date within a sample.
+	m.dbState.PausedPools = []string{"gpus"}
+	res, err = m.GetResourcePools()
+	require.NoError(t, err)
+	require.Same(t, summary, m.hpcDetailsCache.poolsSummary.Load())
+	require.Len(t, res.ResourcePools, 2)
+	for i, name := range []string{"gpus", "gpus-provided"} {
+		require.Equal(t, name, res.ResourcePools[i].Name)
+		require.True(t, res.ResourcePools[i].Paused, name)
+		require.Equal(t, int32(2), res.ResourcePools[i].MaxAgents, name)
+	}
+
+	// A new sample resets the summary.
+	m.hpcDetailsCache.storeSample(&hpcResources{
+		Partitions: []hpcPartitionDetails{{PartitionName: "gpus", TotalNodes: 4}},
+	})
+	require.Nil(t, m.hpcDetailsCache.poolsSummary.Load())
+	res, err = m.GetResourcePools()
+	require.NoError(t, err)
+	require.NotSame(t, summary, m.hpcDetailsCache.poolsSummary.Load())
+	require.Equal(t, int32(4), res.ResourcePools[0].MaxAgents)
+}
+
+func TestLauncherProvidedPoolsUnknownPartition(t *testing.T) {
+	logger, hook := logrustest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	provided := func(name, partition string) config.ResourcePoolConfig {
+		return config.ResourcePoolConfig{
+			PoolName: name,
+			Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: partition}},
+		}
+	}
+	m := &DispatcherResourceManager{
+		syslog:          logger.WithField("component", "dispatcherrm"),
+		wlmType:         slurmSchedulerType,
+		rmConfig:        &config.DispatcherResourceManagerConfig{},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{}),
+		poolConfig: []config.ResourcePoolConfig{
+			provided("drained-pool", "Drained"),
+			provided("typo-pool", "gpsu"),
+		},
+		dbState: *newDispatcherState(),
+	}
+	// The drained partition had nodes in an earlier sample.
+	m.hpcDetailsCache.storeSample(&hpcResources{
+		Partitions: []hpcPartitionDetails{{PartitionName: "drained"}},
+	})
+	m.hpcDetailsCache.storeSample(&hpcResources{})
+	require.True(t, m.hpcDetailsCache.partitionSampled("Drained"))
+	require.False(t, m.hpcDetailsCache.partitionSampled("gpsu"))
+
+	res, err := m.GetResourcePools()
+	require.NoError(t, err)
+	require.Len(t, res.ResourcePools, 2)
+	// Only the partition that was never sampled is reported as an error.
+	var errs []string
+	for _, e := range hook.AllEntries() {
+		if e.Level <= logrus.WarnLevel {
+			errs = append(errs, e.Message)
+		}
+	}
+	require.Equal(t, []string{
+		"resource pool typo-pool specifies provider.partition 'gpsu' that does not exist",
+	}, errs)
+}
+
+func TestPartitionNamesMatchCaseInsensitively(t *testing.T) {
+	poolConfig := []config.ResourcePoolConfig{{
+		PoolName: "gpu-provided",
+		Provider: &provconfig.Config{
+			HPC: &provconfig.HpcClusterConfig{Partition: "GPU"},
+		},
+	}}
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+				"gpu": {MaxSlotsPerJob: ptrs.Ptr(8)},
+			},
+		},
+		poolConfig:      poolConfig,
+		poolProviderMap: makeProvidedPoolsMap(poolConfig),
+	}
+	hpcDetails := &hpcResources{
+		Partitions: []hpcPartitionDetails{
+			{PartitionName: "Gpu", TotalGpuSlots: 4, TotalNodes: 1},
+			{PartitionName: "Cpu", TotalNodes: 1},
+		},
+	}
+
+	// The overrides of a partition apply whatever the case of its name.
+	require.Equal(t, ptrs.Ptr(8), m.maxSlotsPerJob("GPU"))
+	require.Equal(t, ptrs.Ptr(8), m.maxSlotsPerJob("gpu-provided"))
+
+	// The partition details are found whatever the case of its name.
+	require.True(t, partitionExists("gPU", hpcDetails.Partitions))
+	require.Equal(t, device.CPU, m.resolveSlotType(hpcDetails, "cpu"))
+	spec := tasks.TaskSpec{SlurmConfig: expconf.SlurmConfig{RawExclusiveNodes: ptrs.Ptr(2)}}
+	require.ErrorContains(t, m.validateExclusiveNodes(hpcDetails, "gpu", spec, 4),
+		"resource pool 'gpu' has only 1 nodes")
+
+	// The pools provided by a partition are on its agents whatever the case of its name.
+	agent := &agentv1.Agent{ResourcePools: []string{"Gpu"}}
+	m.updateAgentWithAnyProvidedResourcePools(agent)
+	require.Equal(t, []string{"Gpu", "gpu-provided"}, agent.ResourcePools)
+
+	// Jobs are launched in the partition as the workload manager spells it.
+	require.Equal(t, "Gpu", sampledPartitionName("GPU", hpcDetails.Partitions))
+	require.Equal(t, "nope", sampledPartitionName("nope", hpcDetails.Partitions))
+	require.Equal(t, "Gpu", selectLeastLoadedComputePool(hpcDetails.Partitions, "gpu"))
+
+	reservation := "course101"
+	spec = tasks.TaskSpec{SlurmConfig: expconf.SlurmConfig{RawReservation: &reservation}}
+	m.wlmType = slurmSchedulerType
+	require.NoError(t, m.validateReservation(&hpcResources{
+		Reservations: []hpcReservationDetails{{Name: "course101", Partition: "Gpu"}},
+	}, "gpu", spec))
+
+	// The provided pool reports the capacity of its partition, and the default partition is
+	// flagged, whatever the case of the names.
+	hpcDetails.DefaultComputePoolPartition = "GPU"
+	m.syslog = logrus.WithField("component", "dispatcherrm")
+	m.dbState = *newDispatcherState()
+	m.hpcDetailsCache = makeTestHpcDetailsCache(hpcDetails)
+	res, err := m.GetResourcePools()
+	require.NoError(t, err)
+	require.Len(t, res.ResourcePools, 3)
+	for _, pool := range res.ResourcePools {
+		if pool.Name == "Cpu" {
+			continue
+		}
+		require.Equal(t, int32(4), pool.SlotsAvailable, pool.Name)
+		require.NotContains(t, pool.Description, noAvailableNodesSuffix, pool.Name)
+		require.Equal(t, pool.Name == "Gpu", pool.DefaultComputePool, pool.Name)
+	}
+}
+
+func TestDefaultGpuSlotType(t *testing.T) {
+	hpcDetails := &hpcResources{
+		Partitions: []hpcPartitionDetails{
+			{PartitionName: "mi250", TotalGpuSlots: 8, TotalNodes: 2},
+			{PartitionName: "a100", TotalGpuSlots: 4, TotalNodes: 1},
+			{PartitionName: "cpu", TotalNodes: 4},
+		},
+	}
+	amdNode := hpcNodeDetails{Name: "amd-1", Partitions: []string{"mi250"}, GpuCount: 4}
+	nvidiaNode := hpcNodeDetails{Name: "nvidia-1", Partitions: []string{"a100"}, GpuCount: 4}
+
+	// Without a default, GPU partitions have CUDA slots.
+	m := &DispatcherResourceManager{rmConfig: &config.DispatcherResourceManagerConfig{}}
+	require.Equal(t, device.CUDA, m.resolveSlotType(hpcDetails, "mi250"))
+	require.Equal(t, devicev1.Type_TYPE_CUDA, computeSlotType(amdNode, m))
+
+	// On an AMD cluster, the default gives ROCM slots without per-partition configuration.
+	m = &DispatcherResourceManager{rmConfig: &config.DispatcherResourceManagerConfig{
+		DefaultGpuSlotType: ptrs.Ptr(device.ROCM),
+	}}
+	require.Equal(t, device.ROCM, m.resolveSlotType(hpcDetails, "mi250"))
+	require.Equal(t, device.ROCM, m.resolveSlotType(hpcDetails, "unknown"))
+	require.Equal(t, device.CPU, m.resolveSlotType(hpcDetails, "cpu"))
+	require.Equal(t, devicev1.Type_TYPE_ROCM, computeSlotType(amdNode, m))
+
+	// Per-partition overrides take precedence over the default.
+	m = &DispatcherResourceManager{rmConfig: &config.DispatcherResourceManagerConfig{
+		DefaultGpuSlotType: ptrs.Ptr(device.ROCM),
+		PartitionOverrides: map[string]config.DispatcherPartitionOverrideConfigs{
+			"a100": {SlotType: ptrs.Ptr(device.CUDA)},
+		},
+	}}
+	require.Equal(t, device.CUDA, m.resolveSlotType(hpcDetails, "a100"))
+	require.Equal(t, device.ROCM, m.resolveSlotType(hpcDetails, "mi250"))
+	require.Equal(t, devicev1.Type_TYPE_CUDA, computeSlotType(nvidiaNode, m))
+	require.Equal(t, devicev1.Type_TYPE_ROCM, computeSlotType(amdNode, m))
+}
+
 func Test_dispatcherResourceManager_getPartitionValidationResponse(t *testing.T) {
 	type fields struct {
 		poolConfig        []config.ResourcePoolConfig
@@ -602,6 +2527,21 @@ func Test_dispatcherResourceManager_getPartitionValidationResponse(t *testing.T)
 				HasResourcePool: true,
 			}},
 		},
+		{
+			name:   "resource pool is a discovered partition of another case",
+			fields: fields{},
+			args: args{
+				hpcDetails: hpcResources{
+					Partitions: []hpcPartitionDetails{{
+						PartitionName: "Target-Pool",
+					}},
+				},
+				targetPartitionName: "target-pool",
+			},
+			want: want{wantResp: hasSlurmPartitionResponse{
+				HasResourcePool: true,
+			}},
+		},
 		{
 			name: "launcher-provided pool, but partition not present",
 			fields: fields{
@@ -650,6 +2590,32 @@ func Test_dispatcherResourceManager_getPartitionValidationResponse(t *testing.T)
 				ProvidingPartition: "target-pool",
 			}},
 		},
+		{
+			name: "launcher-provided pool, and providing partition is present in another case",
+			fields: fields{
+				poolConfig: []config.ResourcePoolConfig{{
+					PoolName:    "partition-is-launcher-provided",
+					Description: launcherPoolDescription,
+					Provider: &provconfig.Config{
+						HPC: &provconfig.HpcClusterConfig{
+							Partition: "TARGET-POOL",
+						},
+					},
+				}},
+			},
+			args: args{
+				hpcDetails: hpcResources{
+					Partitions: []hpcPartitionDetails{{
+						PartitionName: "target-pool",
+					}},
+				},
+				targetPartitionName: "partition-is-launcher-provided",
+			},
+			want: want{wantResp: hasSlurmPartitionResponse{
+				HasResourcePool:    true,
+				ProvidingPartition: "TARGET-POOL",
+			}},
+		},
 		{
 			name: "launcher-provided pool, providing partition is present, BUT validation errors",
 			fields: fields{
@@ -843,3 +2809,150 @@ func Test_dispatcherResourceManager_getTaskContainerDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspacePoolAllowed(t *testing.T) {
+	m := &DispatcherResourceManager{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			WorkspaceResourcePools: map[int][]string{
+				2: {"gpus", "old-cpus"},
+			},
+		},
+		poolConfig: []config.ResourcePoolConfig{
+			{PoolName: "cpus", Aliases: []string{"old-cpus"}},
+		},
+	}
+
+	require.True(t, m.workspacePoolAllowed(2, "gpus"))
+	require.False(t, m.workspacePoolAllowed(2, "bigmem"))
+	// Allowed pools may be given by an alias.
+	require.True(t, m.workspacePoolAllowed(2, "cpus"))
+	// Workspaces that are not listed may use any pool.
+	require.True(t, m.workspacePoolAllowed(3, "bigmem"))
+
+	err := m.checkWorkspacePoolAccess(2, "bigmem")
+	require.True(t, authz.IsPermissionDenied(err))
+	require.ErrorContains(t, err, "resource pool bigmem is not available to workspace id 2")
+
+	// Without a mapping, or without a workspace, access is not checked at all.
+	require.NoError(t, m.checkWorkspacePoolAccess(-1, "bigmem"))
+	m.rmConfig.WorkspaceResourcePools = nil
+	require.NoError(t, m.checkWorkspacePoolAccess(2, "bigmem"))
+}
+
+func TestResolveHPCJobDependencies(t *testing.T) {
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	dispatchIDToHPCJobID.Store("running", "101")
+	m := &DispatcherResourceManager{
+		syslog:               logrus.WithField("component", "dispatcherrm"),
+		wlmType:              slurmSchedulerType,
+		reqList:              tasklist.New(),
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+		scheduledLaunches:    mapx.New[model.AllocationID, struct{}](),
+		jobWatcher: newDispatchWatcher(nil, &dispatchIDToHPCJobID, nil,
+			config.DefaultJobWatcherPollInterval, config.DefaultJobWatcherPollInterval),
+		allocationByID: func(_ context.Context, id model.AllocationID) (*model.Allocation, error) {
+			switch id {
+			case "completed":
+				return &model.Allocation{AllocationID: id, EndTime: ptrs.Ptr(time.Now())}, nil
+			case "failed":
+				return &model.Allocation{
+					AllocationID: id, EndTime: ptrs.Ptr(time.Now()), ExitErr: ptrs.Ptr("exit code 1"),
+				}, nil
+			case "starting":
+				return &model.Allocation{AllocationID: id}, nil
+			default:
+				return nil, sql.ErrNoRows
+			}
+		},
+		allocationOwnerID: func(_ context.Context, id model.AllocationID) (*model.UserID, error) {
+			switch id {
+			case "unknown":
+				return nil, sql.ErrNoRows
+			case "other-user":
+				return ptrs.Ptr(model.UserID(2)), nil
+			default:
+				return ptrs.Ptr(model.UserID(1)), nil
+			}
+		},
+		dependencyWaits: mapx.New[model.AllocationID, []string](),
+	}
+	m.scheduledLaunches.Store("scheduled", struct{}{})
+	m.reqList.AddTask(&sproto.AllocateRequest{AllocationID: "queued", JobID: "job-1"})
+	owner := &model.User{ID: 1, Username: "alice"}
+
+	for _, tt := range []struct {
+		dispatchID    string
+		wantHPCJobID  string
+		wantPending   bool
+		errorContains string
+	}{
+		{dispatchID: "running", wantHPCJobID: "101"},
+		{dispatchID: "scheduled", wantPending: true},
+		{dispatchID: "queued", wantPending: true},
+		{dispatchID: "starting", wantPending: true},
+		{dispatchID: "completed"},
+		{dispatchID: "failed", errorContains: "dependency failed failed"},
+		{dispatchID: "unknown", errorContains: "dependency unknown is not a known dispatch"},
+		// The dispatches of other users are indistinguishable from unknown ones.
+		{dispatchID: "other-user", errorContains: "dependency other-user is not a known dispatch"},
+	} {
+		t.Run(tt.dispatchID, func(t *testing.T) {
+			hpcJobID, pending, err := m.lookupHPCJobDependency(owner, tt.dispatchID)
+			if tt.errorContains != "" {
+				require.EqualError(t, err, tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantHPCJobID, hpcJobID)
+			require.Equal(t, tt.wantPending, pending)
+		})
+	}
+
+	start := func(dependsOn ...string) StartDispatcherResources {
+		return StartDispatcherResources{
+			AllocationID: "alloc-1",
+			Spec: tasks.TaskSpec{
+				Owner:       owner,
+				SlurmConfig: expconf.SlurmConfig{RawDependsOn: dependsOn},
+				PbsConfig:   expconf.PbsConfig{RawDependsOn: []string{"unknown"}},
+			},
+		}
+	}
+
+	// Completed dependencies have nothing left to wait for.
+	hpcJobIDs, active, err := m.resolveHPCJobDependencies(start("running", "completed"))
+	require.NoError(t, err)
+	require.True(t, active)
+	require.Equal(t, []string{"101"}, hpcJobIDs)
+
+	hpcJobIDs, active, err = m.resolveHPCJobDependencies(start())
+	require.NoError(t, err)
+	require.True(t, active)
+	require.Empty(t, hpcJobIDs)
+
+	_, _, err = m.resolveHPCJobDependencies(start("running", "failed"))
+	require.ErrorContains(t, err, "dependency failed failed")
+
+	_, _, err = m.resolveHPCJobDependencies(start("alloc-1"))
+	require.ErrorContains(t, err, "cannot depend on its own dispatch")
+
+	// Only admins may depend on the dispatches of other users.
+	_, _, err = m.lookupHPCJobDependency(&model.User{ID: 3}, "running")
+	require.EqualError(t, err, "dependency running is not a known dispatch")
+	hpcJobID, _, err := m.lookupHPCJobDependency(&model.User{ID: 3, Admin: true}, "running")
+	require.NoError(t, err)
+	require.Equal(t, "101", hpcJobID)
+
+	// A job that waits for a job that waits for it is failed, rather than waiting forever.
+	m.dependencyWaits.Store("queued", []string{"scheduled", "alloc-1"})
+	_, _, err = m.resolveHPCJobDependencies(start("queued"))
+	require.EqualError(t, err, "the job depends on itself through queued -> alloc-1")
+	_, ok := m.dependencyWaits.Load("alloc-1")
+	require.False(t, ok)
+	m.dependencyWaits.Delete("queued")
+
+	// PBS jobs take their dependencies from the pbs section of the configuration.
+	m.wlmType = pbsSchedulerType
+	_, _, err = m.resolveHPCJobDependencies(start("running"))
+	require.ErrorContains(t, err, "dependency unknown is not a known dispatch")
+}
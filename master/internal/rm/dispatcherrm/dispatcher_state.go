@@ -19,6 +19,13 @@ type dispatcherState struct {
 	*sync.RWMutex
 
 	DisabledAgents []string `bun:"disabled_agents,array"`
+	PausedPools    []string `bun:"paused_pools,array"`
+
+	// persistFn writes the state to the database, (*dispatcherState).persist unless
+	// overridden by tests.
+	persistFn func(context.Context, *dispatcherState) error `bun:"-"`
+	// persistErr is the error of the last write of the state, if it failed.
+	persistErr error `bun:"-"`
 }
 
 func newDispatcherState() *dispatcherState {
@@ -30,8 +37,10 @@ func getDispatcherState(ctx context.Context) (*dispatcherState, error) {
 	state := newDispatcherState()
 	err := db.Bun().NewSelect().Model(state).Scan(ctx)
 	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		recordStatePersistence("get", err)
 		return nil, fmt.Errorf("getting dispatcher state: %w", err)
 	}
+	recordStatePersistence("get", nil)
 	return state, nil
 }
 
@@ -44,7 +53,34 @@ func (s *dispatcherState) persist(ctx context.Context) error {
 	return nil
 }
 
+// save persists the state and records the outcome for the given operation. It must be
+// called with the lock held.
+func (s *dispatcherState) save(ctx context.Context, operation string) error {
+	var err error
+	if s.persistFn != nil {
+		err = s.persistFn(ctx, s)
+	} else {
+		err = s.persist(ctx)
+	}
+	s.persistErr = err
+	recordStatePersistence(operation, err)
+	return err
+}
+
+// checkPersistence returns the error of the last write of the state. If it failed, the
+// state is written again, so that the state store is reported healthy once it recovers.
+func (s *dispatcherState) checkPersistence(ctx context.Context) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.persistErr == nil {
+		return nil
+	}
+	return s.save(ctx, "retry")
+}
+
 // disableAgent adds the given agent to the list of disabled agents and persists the state.
+// The agent stays enabled if the state cannot be persisted.
 func (s *dispatcherState) disableAgent(agentID string) error {
 	s.Lock()
 	defer s.Unlock()
@@ -53,15 +89,18 @@ func (s *dispatcherState) disableAgent(agentID string) error {
 		return errors.Errorf("agent %s already disabled", agentID)
 	}
 
-	s.DisabledAgents = append(s.DisabledAgents, agentID)
+	prev := s.DisabledAgents
+	s.DisabledAgents = append(slices.Clone(prev), agentID)
 
-	if err := s.persist(context.TODO()); err != nil {
-		return fmt.Errorf("agent %s disabled but may be enabled on server restart: %w", agentID, err)
+	if err := s.save(context.TODO(), "disable_agent"); err != nil {
+		s.DisabledAgents = prev
+		return fmt.Errorf("disabling agent %s: %w", agentID, err)
 	}
 	return nil
 }
 
 // enableAgent removes the given agent from the list of disabled agents and persists the state.
+// The agent stays disabled if the state cannot be persisted.
 func (s *dispatcherState) enableAgent(agentID string) error {
 	s.Lock()
 	defer s.Unlock()
@@ -71,10 +110,12 @@ func (s *dispatcherState) enableAgent(agentID string) error {
 		return errors.Errorf("agent %s not disabled", agentID)
 	}
 
-	s.DisabledAgents = slices.Delete(s.DisabledAgents, index, index+1)
+	prev := s.DisabledAgents
+	s.DisabledAgents = slices.Delete(slices.Clone(prev), index, index+1)
 
-	if err := s.persist(context.TODO()); err != nil {
-		return fmt.Errorf("agent %s enabled but may be disabled on server restart: %w", agentID, err)
+	if err := s.save(context.TODO(), "enable_agent"); err != nil {
+		s.DisabledAgents = prev
+		return fmt.Errorf("enabling agent %s: %w", agentID, err)
 	}
 	return nil
 }
@@ -86,3 +127,52 @@ func (s *dispatcherState) isAgentEnabled(agentID string) bool {
 
 	return slices.Index(s.DisabledAgents, agentID) == -1
 }
+
+// pausePool adds the given partition to the list of paused partitions and persists the state.
+// The partition stays active if the state cannot be persisted.
+func (s *dispatcherState) pausePool(partition string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if slices.Index(s.PausedPools, partition) != -1 {
+		return errors.Errorf("partition %s already paused", partition)
+	}
+
+	prev := s.PausedPools
+	s.PausedPools = append(slices.Clone(prev), partition)
+
+	if err := s.save(context.TODO(), "pause_pool"); err != nil {
+		s.PausedPools = prev
+		return fmt.Errorf("pausing partition %s: %w", partition, err)
+	}
+	return nil
+}
+
+// resumePool removes the given partition from the list of paused partitions and persists the
+// state. The partition stays paused if the state cannot be persisted.
+func (s *dispatcherState) resumePool(partition string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	index := slices.Index(s.PausedPools, partition)
+	if index == -1 {
+		return errors.Errorf("partition %s not paused", partition)
+	}
+
+	prev := s.PausedPools
+	s.PausedPools = slices.Delete(slices.Clone(prev), index, index+1)
+
+	if err := s.save(context.TODO(), "resume_pool"); err != nil {
+		s.PausedPools = prev
+		return fmt.Errorf("resuming partition %s: %w", partition, err)
+	}
+	return nil
+}
+
+// isPoolPaused returns true if the given partition is paused.
+func (s *dispatcherState) isPoolPaused(partition string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return slices.Index(s.PausedPools, partition) != -1
+}
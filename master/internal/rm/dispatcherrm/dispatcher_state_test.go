@@ -54,4 +54,17 @@ func TestDispatcherStatePersistence(t *testing.T) {
 	state, err = getDispatcherState(context.TODO())
 	assert.NilError(t, err)
 	assert.Check(t, reflect.DeepEqual(state.DisabledAgents, []string{"agent2"}))
+
+	assert.Check(t, !state.isPoolPaused("partition1"))
+	assert.NilError(t, state.pausePool("partition1"))
+	assert.ErrorContains(t, state.pausePool("partition1"), "already paused")
+	assert.Check(t, state.isPoolPaused("partition1"))
+
+	state, err = getDispatcherState(context.TODO())
+	assert.NilError(t, err)
+	assert.Check(t, state.isPoolPaused("partition1"))
+
+	assert.NilError(t, state.resumePool("partition1"))
+	assert.ErrorContains(t, state.resumePool("partition1"), "not paused")
+	assert.Check(t, !state.isPoolPaused("partition1"))
 }
@@ -11,11 +11,13 @@ import (
 
 var launcherMinimumVersion = semvar.MustParse("3.3.1")
 
-// Do a single check of the version.  Return an error
-// if version cannot be obtained, or is below minimum.
+// Do a single check of the version.  Return an error if version cannot be
+// obtained, or if it is below minimum and the minimum is enforced. Otherwise
+// a version below minimum is only logged as a warning.
 func checkVersionNow(ctx context.Context,
 	log *logrus.Entry,
-	cl *launcherAPIClient,
+	cl launcherClient,
+	enforceMinimum bool,
 ) error {
 	// The logger we will pass to the API client, so that when the API client
 	// logs a message, we know who called it.
@@ -27,9 +29,14 @@ func checkVersionNow(ctx context.Context,
 	}
 
 	if !checkLauncherVersion(v) {
-		return fmt.Errorf("launcher version %s does not meet the required minimum. "+
+		err := fmt.Errorf("launcher version %s does not meet the required minimum. "+
 			"Upgrade to hpe-hpc-launcher version %s or greater",
 			v, launcherMinimumVersion)
+		if enforceMinimum {
+			return err
+		}
+		log.Warn(err.Error())
+		return nil
 	}
 
 	log.Infof("HPC Launcher version %s", v)
@@ -1,9 +1,12 @@
 package dispatcherrm
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
 	"gotest.tools/assert"
 )
 
@@ -19,3 +22,19 @@ func TestCheckLauncherVersion(t *testing.T) {
 	assert.Equal(t, checkLauncherVersion(semver.MustParse("2.3.3")), false)
 	assert.Equal(t, checkLauncherVersion(semver.MustParse("3.0.3")), false)
 }
+
+func TestCheckVersionNow(t *testing.T) {
+	log := logrus.WithField("component", "dispatcherrm")
+	cl := newFakeLauncherClient()
+	assert.NilError(t, checkVersionNow(context.Background(), log, cl, true))
+
+	// An old launcher only fails the check if the minimum is enforced.
+	cl.version = "3.2.0"
+	assert.ErrorContains(t, checkVersionNow(context.Background(), log, cl, true),
+		"does not meet the required minimum")
+	assert.NilError(t, checkVersionNow(context.Background(), log, cl, false))
+
+	cl.versionErr = fmt.Errorf("connection refused")
+	assert.ErrorContains(t, checkVersionNow(context.Background(), log, cl, false),
+		"cannot get launcher version: connection refused")
+}
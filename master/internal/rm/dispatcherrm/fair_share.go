@@ -0,0 +1,106 @@
+package dispatcherrm
+
+import (
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
+)
+
+// fairShareLogFileName is the log file of the fair-share query that holds its result.
+const fairShareLogFileName = "hpc-fair-share"
+
+// hpcFairShare is the fair-share standing of a Slurm account, or of a user of an account,
+// as reported by sshare.
+type hpcFairShare struct {
+	Account string `json:"account"`
+	// User is empty for the account as a whole.
+	User string `json:"user"`
+	// Partition is the partition of the association, or empty if it applies to every
+	// partition.
+	Partition        string  `json:"partition"`
+	FairShare        float64 `json:"fairShare"`
+	NormalizedShares float64 `json:"normShares"`
+	EffectiveUsage   float64 `json:"effectiveUsage"`
+}
+
+// updateFairShare refreshes the fair-share standing of the accounts. If the query fails,
+// the last standing is kept.
+func (c *hpcResourceDetailsCache) updateFairShare() {
+	shares, err := c.fetchFairShare()
+	if err != nil {
+		c.log.WithError(err).Warn("failed to query fair share")
+		return
+	}
+	c.lastFairShare.Store(&shares)
+}
+
+// fetchFairShare retrieves the fair-share standing of the accounts from the launcher.
+func (c *hpcResourceDetailsCache) fetchFairShare() ([]hpcFairShare, error) {
+	// The logger we will pass to the API client, so that when the API client
+	// logs a message, we know who called it.
+	launcherAPILogger := c.log.WithField("caller", "fetchFairShare")
+
+	dispatchInfo, r, err := c.cl.launchHPCFairShareJob(launcherAPILogger) //nolint:bodyclose
+	if err != nil {
+		return nil, errors.New(c.cl.handleLauncherError(r,
+			"failed to query fair share from launcher", err))
+	}
+	dispatchID := dispatchInfo.GetDispatchId()
+	owner := dispatchInfo.GetLaunchingUser()
+	defer func() {
+		_, _, err := c.cl.terminateDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			c.log.WithField("dispatch-id", dispatchID).
+				WithError(err).Error("failed to terminate dispatch")
+			return
+		}
+
+		_, err = c.cl.deleteDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			c.log.WithField("dispatch-id", dispatchID).
+				WithError(err).Error("failed to delete dispatch")
+		}
+	}()
+
+	resp, _, err := c.cl.loadEnvironmentLog( //nolint:bodyclose
+		owner, dispatchID, fairShareLogFileName, launcherAPILogger)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving fair share")
+	}
+
+	var shares struct {
+		Accounts []hpcFairShare `json:"accounts"`
+	}
+	if err := yaml.Unmarshal([]byte(resp), &shares); err != nil {
+		return nil, errors.Wrap(err, "parsing fair share")
+	}
+	return shares.Accounts, nil
+}
+
+// hpcPoolDetail returns the HPC details of the resource pools of the given partition, or
+// nil if the fair share is not reported. Entries associated with another partition are
+// left out.
+func (c *hpcResourceDetailsCache) hpcPoolDetail(partition string) *resourcepoolv1.ResourcePoolHpcDetail {
+	shares := c.lastFairShare.Load()
+	if shares == nil {
+		return nil
+	}
+
+	detail := &resourcepoolv1.ResourcePoolHpcDetail{FairShares: []*resourcepoolv1.HpcFairShare{}}
+	for _, s := range *shares {
+		if s.Partition != "" && !strings.EqualFold(s.Partition, partition) {
+			continue
+		}
+		detail.FairShares = append(detail.FairShares, &resourcepoolv1.HpcFairShare{
+			Account:          s.Account,
+			User:             s.User,
+			FairShare:        s.FairShare,
+			NormalizedShares: s.NormalizedShares,
+			EffectiveUsage:   s.EffectiveUsage,
+		})
+	}
+	return detail
+}
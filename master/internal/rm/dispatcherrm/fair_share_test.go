@@ -0,0 +1,112 @@
+package dispatcherrm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/config/provconfig"
+	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
+)
+
+func TestUpdateFairShare(t *testing.T) {
+	cl := newFakeLauncherClient()
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:       cl,
+	}
+
+	// Nothing is reported until the fair share is queried.
+	require.Nil(t, c.hpcPoolDetail("gpus"))
+
+	cl.logs[fairShareLogFileName] = `
+accounts:
+- {account: ml, fairShare: 0.75, normShares: 0.5, effectiveUsage: 0.25}
+- {account: ml, user: alice, fairShare: 0.5, normShares: 0.25, effectiveUsage: 0.2}
+- {account: hpc, partition: CPUS, fairShare: 0.1, normShares: 0.5, effectiveUsage: 0.75}
+`
+	c.updateFairShare()
+	require.Equal(t, []string{"fake-dispatch-1"}, cl.deleted)
+
+	ml := &resourcepoolv1.HpcFairShare{
+		Account: "ml", FairShare: 0.75, NormalizedShares: 0.5, EffectiveUsage: 0.25,
+	}
+	alice := &resourcepoolv1.HpcFairShare{
+		Account: "ml", User: "alice", FairShare: 0.5, NormalizedShares: 0.25, EffectiveUsage: 0.2,
+	}
+	hpc := &resourcepoolv1.HpcFairShare{
+		Account: "hpc", FairShare: 0.1, NormalizedShares: 0.5, EffectiveUsage: 0.75,
+	}
+	// Entries associated with a partition are only reported for its pools.
+	require.Equal(t, []*resourcepoolv1.HpcFairShare{ml, alice}, c.hpcPoolDetail("gpus").FairShares)
+	require.Equal(t, []*resourcepoolv1.HpcFairShare{ml, alice, hpc}, c.hpcPoolDetail("cpus").FairShares)
+
+	// A failed query keeps the last fair share.
+	cl.launchErr = fmt.Errorf("launcher unavailable")
+	c.updateFairShare()
+	require.Len(t, c.hpcPoolDetail("gpus").FairShares, 2)
+
+	// A malformed result is not installed either, and its query is cleaned up.
+	cl.launchErr = nil
+	cl.logs[fairShareLogFileName] = "accounts: {"
+	c.updateFairShare()
+	require.Len(t, c.hpcPoolDetail("gpus").FairShares, 2)
+	require.Len(t, cl.deleted, 2)
+
+	// No accounts is reported as such.
+	cl.logs[fairShareLogFileName] = ""
+	c.updateFairShare()
+	require.NotNil(t, c.hpcPoolDetail("gpus"))
+	require.Empty(t, c.hpcPoolDetail("gpus").FairShares)
+}
+
+func TestGetResourcePoolsFairShare(t *testing.T) {
+	poolConfig := []config.ResourcePoolConfig{{
+		PoolName: "gpus-provided",
+		Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "gpus"}},
+	}, {
+		PoolName: "drained-provided",
+		Provider: &provconfig.Config{HPC: &provconfig.HpcClusterConfig{Partition: "drained"}},
+	}}
+	m := &DispatcherResourceManager{
+		syslog:   logrus.WithField("component", "dispatcherrm"),
+		wlmType:  slurmSchedulerType,
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		hpcDetailsCache: makeTestHpcDetailsCache(&hpcResources{
+			Partitions: []hpcPartitionDetails{{PartitionName: "gpus"}, {PartitionName: "cpus"}},
+		}),
+		poolConfig: poolConfig,
+		dbState:    *newDispatcherState(),
+	}
+
+	// Without a fair share, pools have no HPC details.
+	res, err := m.GetResourcePools()
+	require.NoError(t, err)
+	for _, pool := range res.ResourcePools {
+		require.Nil(t, pool.Details.Hpc, pool.Name)
+	}
+
+	m.hpcDetailsCache.lastFairShare.Store(&[]hpcFairShare{
+		{Account: "ml", FairShare: 0.5},
+		{Account: "hpc", Partition: "gpus", FairShare: 0.25},
+		{Account: "bio", Partition: "drained", FairShare: 0.125},
+	})
+	res, err = m.GetResourcePools()
+	require.NoError(t, err)
+	accounts := map[string][]string{}
+	for _, pool := range res.ResourcePools {
+		for _, s := range pool.Details.Hpc.FairShares {
+			accounts[pool.Name] = append(accounts[pool.Name], s.Account)
+		}
+	}
+	require.Equal(t, map[string][]string{
+		"gpus":             {"ml", "hpc"},
+		"cpus":             {"ml"},
+		"gpus-provided":    {"ml", "hpc"},
+		"drained-provided": {"ml", "bio"},
+	}, accounts)
+}
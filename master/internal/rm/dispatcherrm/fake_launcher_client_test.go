@@ -0,0 +1,254 @@
+package dispatcherrm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+	"github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
+)
+
+// fakeLauncherClient is an in-memory launcherClient for unit tests. Dispatches are
+// launched synchronously, and the error fields may be set to make the corresponding
+// calls fail.
+type fakeLauncherClient struct {
+	mu sync.Mutex
+
+	version    string
+	nextID     int
+	dispatches map[string]launcher.DispatchInfo
+	logs       map[string]string
+	// pendingLogs are the successive results of reads of a log, before it is read from logs.
+	pendingLogs  map[string][]string
+	logReads     int
+	reasons      map[string]string
+	terminated   []string
+	batches      map[string][]string
+	deleted      []string
+	launchedWith []string
+	manifests    []*launcher.Manifest
+	// usageLogs are the successive results of job usage queries, after which queries find
+	// no usage.
+	usageLogs    []string
+	usageQueries []string
+
+	versionErr    error
+	launchErr     error
+	launchResp    *http.Response
+	terminateErr  error
+	terminateErrs map[string]error
+	deleteErr     error
+	logErr        error
+}
+
+func newFakeLauncherClient() *fakeLauncherClient {
+	return &fakeLauncherClient{
+		version:     "3.3.3",
+		dispatches:  make(map[string]launcher.DispatchInfo),
+		logs:        make(map[string]string),
+		pendingLogs: make(map[string][]string),
+		reasons:     make(map[string]string),
+	}
+}
+
+func (f *fakeLauncherClient) getVersion(
+	context.Context, *logrus.Entry,
+) (*semver.Version, error) {
+	if f.versionErr != nil {
+		return nil, f.versionErr
+	}
+	return semver.NewVersion(f.version)
+}
+
+func (f *fakeLauncherClient) launch(name, owner, dispatchID string) launcher.DispatchInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if dispatchID == "" {
+		f.nextID++
+		dispatchID = fmt.Sprintf("fake-dispatch-%d", f.nextID)
+	}
+	info := launcher.DispatchInfo{
+		DispatchId:    launcher.PtrString(dispatchID),
+		LaunchingUser: launcher.PtrString(owner),
+		State:         launcher.RUNNING.Ptr(),
+		LaunchedCapsuleReference: &launcher.OwnedResourceReference{
+			Name:  launcher.PtrString(name),
+			Owner: launcher.PtrString(owner),
+		},
+	}
+	f.dispatches[dispatchID] = info
+	f.launchedWith = append(f.launchedWith, owner)
+	return info
+}
+
+func (f *fakeLauncherClient) launchDispatcherJob(
+	manifest *launcher.Manifest,
+	impersonatedUser string,
+	allocationID string,
+	_ *logrus.Entry,
+) (launcher.DispatchInfo, *http.Response, error) {
+	if f.launchErr != nil {
+		return launcher.DispatchInfo{}, f.launchResp, f.launchErr
+	}
+	f.mu.Lock()
+	f.manifests = append(f.manifests, manifest)
+	f.mu.Unlock()
+	return f.launch(manifest.ClientMetadata.GetName(), impersonatedUser, allocationID), nil, nil
+}
+
+func (f *fakeLauncherClient) launchHPCResourcesJob(*logrus.Entry) (
+	launcher.DispatchInfo, *http.Response, error,
+) {
+	if f.launchErr != nil {
+		return launcher.DispatchInfo{}, f.launchResp, f.launchErr
+	}
+	return f.launch(resourceQueryName, "launcher", ""), nil, nil
+}
+
+func (f *fakeLauncherClient) launchHPCJobUsageJob(hpcJobID string, _ *logrus.Entry) (
+	launcher.DispatchInfo, *http.Response, error,
+) {
+	if f.launchErr != nil {
+		return launcher.DispatchInfo{}, f.launchResp, f.launchErr
+	}
+
+	f.mu.Lock()
+	f.usageQueries = append(f.usageQueries, hpcJobID)
+	f.logs[jobUsageLogFileName] = ""
+	if len(f.usageLogs) > 0 {
+		f.logs[jobUsageLogFileName] = f.usageLogs[0]
+		f.usageLogs = f.usageLogs[1:]
+	}
+	f.mu.Unlock()
+	return f.launch(usageQueryName, "launcher", ""), nil, nil
+}
+
+func (f *fakeLauncherClient) launchHPCFairShareJob(*logrus.Entry) (
+	launcher.DispatchInfo, *http.Response, error,
+) {
+	if f.launchErr != nil {
+		return launcher.DispatchInfo{}, f.launchResp, f.launchErr
+	}
+	return f.launch(fairShareQueryName, "launcher", ""), nil, nil
+}
+
+func (f *fakeLauncherClient) listAllRunning(*logrus.Entry) (
+	map[string][]launcher.DispatchInfo, *http.Response, error,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var running []launcher.DispatchInfo
+	for _, d := range f.dispatches {
+		running = append(running, d)
+	}
+	return map[string][]launcher.DispatchInfo{"data": running}, nil, nil
+}
+
+func (f *fakeLauncherClient) getManagementStatus(
+	_ string, dispatchID string, _ *logrus.Entry,
+) (launcher.DispatchManagementStatus, *http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, ok := f.dispatches[dispatchID]
+	if !ok {
+		return launcher.DispatchManagementStatus{},
+			&http.Response{StatusCode: http.StatusNotFound},
+			fmt.Errorf("404 Not Found")
+	}
+	status := launcher.DispatchManagementStatus{Info: &info}
+	if reason, ok := f.reasons[dispatchID]; ok {
+		status.Reason = launcher.PtrString(reason)
+	}
+	return status, nil, nil
+}
+
+func (f *fakeLauncherClient) terminateDispatch(
+	_ string, dispatchID string, _ *logrus.Entry,
+) (launcher.DispatchInfo, *http.Response, error) {
+	if f.terminateErr != nil {
+		return launcher.DispatchInfo{}, nil, f.terminateErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, dispatchID)
+	info := f.dispatches[dispatchID]
+	info.State = launcher.TERMINATED.Ptr()
+	return info, nil, nil
+}
+
+func (f *fakeLauncherClient) terminateDispatches(
+	owner string, dispatchIDs []string, l *logrus.Entry,
+) map[string]error {
+	f.mu.Lock()
+	if f.batches == nil {
+		f.batches = make(map[string][]string)
+	}
+	f.batches[owner] = append(f.batches[owner], dispatchIDs...)
+	f.mu.Unlock()
+
+	errs := make(map[string]error)
+	for _, dispatchID := range dispatchIDs {
+		if err := f.terminateErrs[dispatchID]; err != nil {
+			errs[dispatchID] = err
+			continue
+		}
+		if _, _, err := f.terminateDispatch(owner, dispatchID, l); err != nil { //nolint:bodyclose
+			errs[dispatchID] = err
+		}
+	}
+	return errs
+}
+
+func (f *fakeLauncherClient) deleteDispatch(
+	_, dispatchID string, _ *logrus.Entry,
+) (*http.Response, error) {
+	if f.deleteErr != nil {
+		return nil, f.deleteErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, dispatchID)
+	delete(f.dispatches, dispatchID)
+	return nil, nil
+}
+
+func (f *fakeLauncherClient) loadEnvironmentLog(
+	_, _, logFileName string, _ *logrus.Entry,
+) (string, *http.Response, error) {
+	if f.logErr != nil {
+		return "", nil, f.logErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logReads++
+	if pending := f.pendingLogs[logFileName]; len(pending) > 0 {
+		f.pendingLogs[logFileName] = pending[1:]
+		return pending[0], nil, nil
+	}
+	return f.logs[logFileName], nil, nil
+}
+
+func (f *fakeLauncherClient) loadEnvironmentLogSpooled(
+	owner, dispatchID, logFileName string, _ int, launcherAPILogger *logrus.Entry,
+) (io.ReadCloser, *http.Response, error) {
+	data, resp, err := f.loadEnvironmentLog(owner, dispatchID, logFileName, launcherAPILogger)
+	if err != nil {
+		return nil, resp, err
+	}
+	return io.NopCloser(strings.NewReader(data)), resp, nil
+}
+
+func (*fakeLauncherClient) handleLauncherError(_ *http.Response, errPrefix string, err error) string {
+	return fmt.Sprintf("%s: %v", errPrefix, err)
+}
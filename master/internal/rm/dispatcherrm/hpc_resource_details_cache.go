@@ -1,20 +1,36 @@
 package dispatcherrm
 
 import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
 
 	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
 )
 
 const hpcResourceDetailsRefreshPeriod = time.Minute
 
+// maxConsecutiveTruncatedSamples is the number of consecutive samples that may be
+// discarded as truncated before one is accepted anyway, so that a cluster that has
+// really shrunk is eventually reported as such.
+const maxConsecutiveTruncatedSamples = 3
+
 var errHPCDetailsCacheEmpty = errors.New("HPC resource details cache is empty")
 
+// errHPCResourcesIncomplete is reported when the HPC resource details list no partitions,
+// which means that they were read before they were completely written.
+var errHPCResourcesIncomplete = errors.New("HPC resource details list no partitions")
+
 // hpcResources is a data type describing the HPC resources available
 // to Slurm on the Launcher node.
 // Example output of the HPC resource details from the Launcher.
@@ -33,6 +49,23 @@ type hpcResources struct {
 	Nodes                       []hpcNodeDetails      `json:"nodes,flow"`      //nolint:staticcheck
 	DefaultComputePoolPartition string                `json:"defaultComputePoolPartition"`
 	DefaultAuxPoolPartition     string                `json:"defaultAuxPoolPartition"`
+	// CheckpointGCPool is the configured checkpoint_gc_resource_pool, or empty if it is not
+	// configured or does not name a pool of the sample.
+	CheckpointGCPool string `json:"checkpointGCPool"`
+	// Reservations are the Slurm reservations, or nil if the launcher does not report them.
+	Reservations []hpcReservationDetails `json:"reservations"`
+	// SchemaVersion is the format of the slurm-resources-info the resources were
+	// parsed from, see parseHpcResources.
+	SchemaVersion hpcResourcesSchemaVersion `json:"schemaVersion"`
+}
+
+// resourcePoolsSummary holds the resource pools summarized from a sample of HPC resources.
+type resourcePoolsSummary struct {
+	// sample is the sample the pools were summarized from.
+	sample *hpcResources
+	pools  []*resourcepoolv1.ResourcePool
+	// partitions are the partitions of the pools, by index.
+	partitions []string
 }
 
 // hpcPartitionDetails holds HPC Slurm partition details.
@@ -47,19 +80,110 @@ type hpcPartitionDetails struct {
 	TotalAvailableCPUSlots int    `json:"totalAvailableCpuSlots"`
 	TotalCPUSlots          int    `json:"totalCpuSlots"`
 	Accelerator            string `json:"accelerator"`
+	// AcceleratorInfo describes the accelerators of the nodes of the partition, or is nil
+	// if the launcher does not report it.
+	AcceleratorInfo *hpcAcceleratorInfo `json:"acceleratorInfo"`
+}
+
+// hpcAcceleratorInfo describes the accelerators of the nodes of a partition.
+type hpcAcceleratorInfo struct {
+	Vendor string `json:"vendor"`
+	Model  string `json:"model"`
+	// ComputeCapability is the compute capability of NVIDIA accelerators, e.g. "8.0", or
+	// the architecture of AMD accelerators, e.g. "gfx90a".
+	ComputeCapability string `json:"computeCapability"`
+}
+
+// partitionAccelerator returns the accelerators of the given partition of the sample, or nil
+// if they are not reported.
+func partitionAccelerator(hpcDetails *hpcResources, partition string) *resourcepoolv1.HpcAccelerator {
+	for _, p := range hpcDetails.Partitions {
+		if !strings.EqualFold(p.PartitionName, partition) || p.AcceleratorInfo == nil {
+			continue
+		}
+		return &resourcepoolv1.HpcAccelerator{
+			Vendor:            p.AcceleratorInfo.Vendor,
+			Model:             p.AcceleratorInfo.Model,
+			ComputeCapability: p.AcceleratorInfo.ComputeCapability,
+		}
+	}
+	return nil
 }
 
 // hpcNodeDetails holds HPC Slurm node details.
 type hpcNodeDetails struct {
-	Partitions    []string `json:"partitions"`
-	Addresses     []string `json:"addresses"`
-	Draining      bool     `json:"draining"`
-	Allocated     bool     `json:"allocated"`
-	Name          string   `json:"name"`
-	GpuCount      int      `json:"gpuCount"`
-	GpuInUseCount int      `json:"gpuInUseCount"`
-	CPUCount      int      `json:"cpuCount"`
-	CPUInUseCount int      `json:"cpuInUseCount"`
+	Partitions []string `json:"partitions"`
+	Addresses  []string `json:"addresses"`
+	Draining   bool     `json:"draining"`
+	// State is the workload manager state of the node, normalized by normalizeNodeState,
+	// and Reason the administrator's explanation for it. Both are empty for launchers
+	// that do not report them.
+	State         string `json:"state"`
+	Reason        string `json:"reason"`
+	Allocated     bool   `json:"allocated"`
+	Name          string `json:"name"`
+	GpuCount      int    `json:"gpuCount"`
+	GpuInUseCount int    `json:"gpuInUseCount"`
+	CPUCount      int    `json:"cpuCount"`
+	CPUInUseCount int    `json:"cpuInUseCount"`
+}
+
+// gpusInUse returns the number of GPUs of the node that are in use. A job with exclusive
+// use of the node is allocated all of its CPUs but only the GPUs it requests, so all the
+// GPUs of a node whose CPUs are all in use are counted as in use, since no other job can
+// be given them.
+func (n hpcNodeDetails) gpusInUse() int {
+	if n.CPUCount > 0 && n.CPUInUseCount >= n.CPUCount {
+		return n.GpuCount
+	}
+	return n.GpuInUseCount
+}
+
+// hpcReservationDetails holds HPC Slurm reservation details.
+type hpcReservationDetails struct {
+	Name string `json:"name"`
+	// Partition is the partition the reservation is restricted to, if any.
+	Partition string `json:"partition"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. When the launcher reports the
+// node state, Draining is derived from it rather than taken from the launcher.
+func (n *hpcNodeDetails) UnmarshalJSON(data []byte) error {
+	type rawNodeDetails hpcNodeDetails
+	if err := json.Unmarshal(data, (*rawNodeDetails)(n)); err != nil {
+		return err
+	}
+	if n.State != "" {
+		n.State = normalizeNodeState(n.State)
+		n.Draining = isDrainingNodeState(n.State)
+	}
+	return nil
+}
+
+// normalizeNodeState lower-cases a node state and strips the single character suffixes
+// that Slurm appends to flag states, e.g. "DOWN*" for a node that is not responding,
+// leaving the base state and any flags joined by "+", such as "idle+drain". The comma
+// separated states of PBS, e.g. "down,offline", are joined the same way.
+func normalizeNodeState(state string) string {
+	parts := strings.FieldsFunc(strings.ToLower(state), func(r rune) bool {
+		return r == '+' || r == ',' || r == ' '
+	})
+	for i, part := range parts {
+		parts[i] = strings.TrimRight(part, "*~#!%$@^-")
+	}
+	return strings.Join(parts, "+")
+}
+
+// isDrainingNodeState returns whether nodes in the given normalized state let their
+// running jobs finish but accept no new ones: the Slurm drain states, and offline on PBS.
+func isDrainingNodeState(state string) bool {
+	for _, part := range strings.Split(state, "+") {
+		switch part {
+		case "drain", "draining", "drained", "offline":
+			return true
+		}
+	}
+	return false
 }
 
 // hpcResourceDetailsCache stores details of the HPC resource information cache.
@@ -67,23 +191,57 @@ type hpcNodeDetails struct {
 type hpcResourceDetailsCache struct {
 	rmConfig *config.DispatcherResourceManagerConfig // TODO: Refactor to not use entire rm conf.
 	log      *logrus.Entry
-	cl       *launcherAPIClient
+	cl       launcherClient
+
+	// providedPools are the names of the launcher-provided resource pools, which may be
+	// configured as default pools like the partitions.
+	providedPools []string
 
 	lastSample atomic.Pointer[hpcResources]
 	sampled    <-chan struct{}
+
+	// poolsSummary memoizes the resource pools summarized from lastSample. It is reset when
+	// a new sample is installed.
+	poolsSummary atomic.Pointer[resourcePoolsSummary]
+
+	// sampledPartitions holds the lower case names of the partitions of every sample
+	// installed since startup, to tell a partition without nodes from a nonexistent one.
+	sampledPartitions sync.Map
+
+	// reportFairShare is whether the fair-share standing of the accounts is queried along
+	// with the resources, into lastFairShare.
+	reportFairShare bool
+	lastFairShare   atomic.Pointer[[]hpcFairShare]
+
+	// truncatedSamples counts the consecutive samples discarded as truncated. It is
+	// only accessed by the goroutine that updates the cache.
+	truncatedSamples int
+
+	// warnedDefaultPools holds the misconfigured default pools that were warned about,
+	// keyed by option, so that the warning is not repeated on every sample. It is only
+	// accessed by the goroutine that updates the cache.
+	warnedDefaultPools map[string]string
 }
 
 func newHpcResourceDetailsCache(
 	rmConfig *config.DispatcherResourceManagerConfig,
-	cl *launcherAPIClient,
+	poolConfig []config.ResourcePoolConfig,
+	cl launcherClient,
+	wlm wlmType,
 ) *hpcResourceDetailsCache {
 	sampled := make(chan struct{})
 
 	c := &hpcResourceDetailsCache{
-		rmConfig: rmConfig,
-		log:      logrus.WithField("component", "hpc-resource-details-cache"),
-		cl:       cl,
-		sampled:  sampled,
+		rmConfig:        rmConfig,
+		log:             logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:              cl,
+		sampled:         sampled,
+		reportFairShare: rmConfig.ReportFairShare && wlm == slurmSchedulerType,
+	}
+	for _, pool := range poolConfig {
+		if isValidProvider(pool) {
+			c.providedPools = append(c.providedPools, pool.PoolName)
+		}
 	}
 
 	go c.periodicallyUpdate(sampled)
@@ -92,23 +250,73 @@ func newHpcResourceDetailsCache(
 }
 
 func (c *hpcResourceDetailsCache) periodicallyUpdate(sampled chan<- struct{}) {
+	c.cleanupStaleResourceQueries()
+
 	for {
-		res, ok := c.fetchHpcResourceDetails()
-		if !ok {
-			time.Sleep(hpcResourceDetailsRefreshPeriod)
-			continue
+		if res, ok := c.fetchHpcResourceDetails(); ok {
+			if c.lastSample.Load() == nil {
+				c.storeSample(res)
+				close(sampled)
+			} else if c.acceptSample(res) {
+				c.storeSample(res)
+			}
 		}
-
-		if c.lastSample.Load() == nil {
-			c.lastSample.Store(res)
-			close(sampled)
-		} else {
-			c.lastSample.Store(res)
+		if c.reportFairShare {
+			c.updateFairShare()
 		}
 		time.Sleep(hpcResourceDetailsRefreshPeriod)
 	}
 }
 
+// storeSample installs a new sample and resets the resource pools summarized from the last.
+func (c *hpcResourceDetailsCache) storeSample(res *hpcResources) {
+	for _, p := range res.Partitions {
+		c.sampledPartitions.Store(strings.ToLower(p.PartitionName), struct{}{})
+	}
+	c.lastSample.Store(res)
+	c.poolsSummary.Store(nil)
+}
+
+// partitionSampled returns whether the partition was in any sample installed since startup.
+func (c *hpcResourceDetailsCache) partitionSampled(partition string) bool {
+	_, ok := c.sampledPartitions.Load(strings.ToLower(partition))
+	return ok
+}
+
+// acceptSample returns whether a new sample should replace the last one. On large
+// clusters the resources query may time out partway and return a truncated list, so a
+// sample with drastically fewer nodes or partitions than the last one is discarded,
+// unless that keeps happening.
+func (c *hpcResourceDetailsCache) acceptSample(res *hpcResources) bool {
+	last := c.lastSample.Load()
+	ratio := c.rmConfig.MinResourceSampleRatio
+	if last == nil || ratio <= 0 ||
+		(!shrunkBelow(len(res.Nodes), len(last.Nodes), ratio) &&
+			!shrunkBelow(len(res.Partitions), len(last.Partitions), ratio)) {
+		c.truncatedSamples = 0
+		return true
+	}
+
+	c.truncatedSamples++
+	log := c.log.
+		WithField("nodes", len(res.Nodes)).
+		WithField("last-nodes", len(last.Nodes)).
+		WithField("partitions", len(res.Partitions)).
+		WithField("last-partitions", len(last.Partitions))
+	if c.truncatedSamples > maxConsecutiveTruncatedSamples {
+		log.Warn("HPC resource details have shrunk in consecutive samples, accepting the new sample")
+		c.truncatedSamples = 0
+		return true
+	}
+	log.Warn("HPC resource details sample appears truncated, keeping the last sample")
+	return false
+}
+
+// shrunkBelow returns true if count is less than the given ratio of the last count.
+func shrunkBelow(count, lastCount int, ratio float64) bool {
+	return float64(count) < ratio*float64(lastCount)
+}
+
 // load loads the last sample of HPC resource details. Returns error if the cache is empty.
 func (c *hpcResourceDetailsCache) load() (*hpcResources, error) {
 	res := c.lastSample.Load()
@@ -141,6 +349,10 @@ func (c *hpcResourceDetailsCache) fetchHpcResourceDetails() (
 	// logs a message, we know who called it.
 	launcherAPILogger := c.log.WithField("caller", "fetchHpcResourceDetails")
 
+	// Covers the whole query (launch, log load and cleanup), as opposed to the
+	// individual launcher API calls which are recorded by the API client.
+	defer recordAPITiming("hpc_resources_query")()
+
 	dispatchInfo, resp, err := c.cl.launchHPCResourcesJob(launcherAPILogger) //nolint:bodyclose
 	if err != nil {
 		c.log.Errorf(c.cl.handleLauncherError(resp,
@@ -183,28 +395,180 @@ func (c *hpcResourceDetailsCache) fetchHpcResourceDetails() (
 	// to get the partition info and does not create a job, so no job ID is ever
 	// generated.  Eventually it will timeout waiting and return, but that's too
 	// long of a delay for us to deal with.
-	log, _, err := c.cl.loadEnvironmentLog(owner, dispatchID, logFileName, launcherAPILogger) //nolint:bodyclose
-	if err != nil {
-		c.log.Error(err)
-		return nil, false
+	//
+	// Even so, the log file has been seen to be read empty or incomplete, so
+	// such reads are retried a few times before giving up.
+	var (
+		newSample *hpcResources
+		detected  bool
+	)
+	retries := c.rmConfig.ResolveResourceQueryLogRetries()
+	for attempt := 0; ; attempt++ {
+		log, err := c.loadResourcesLog(owner, dispatchID, logFileName, launcherAPILogger)
+		if err != nil {
+			c.log.Error(err)
+			return nil, false
+		}
+		newSample, detected, err = parseHpcResources(log)
+		if closeErr := log.Close(); closeErr != nil {
+			c.log.WithError(closeErr).Warn("failed to remove the spooled HPC Resource details")
+		}
+		if err == nil && len(newSample.Partitions) == 0 {
+			err = errHPCResourcesIncomplete
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= retries {
+			c.log.WithError(err).Errorf("failed to parse HPC Resource details")
+			return nil, false
+		}
+		c.log.WithError(err).
+			WithField("attempt", attempt+1).
+			Warn("HPC Resource details are incomplete, reading them again")
+		time.Sleep(c.rmConfig.ResolveResourceQueryLogRetryInterval())
 	}
-	resourcesBytes := []byte(log)
-	var newSample hpcResources
-	if err = yaml.Unmarshal(resourcesBytes, &newSample); err != nil {
-		c.log.WithError(err).Errorf("failed to parse HPC Resource details")
-		return nil, false
+	schemaLog := c.log.WithField("schema-version", newSample.SchemaVersion)
+	switch last := c.lastSample.Load(); {
+	case !detected:
+		schemaLog.Warn("HPC Resource details do not match any known format, " +
+			"parsed them as the latest format")
+	case last == nil || last.SchemaVersion != newSample.SchemaVersion:
+		// Only log at INFO level when the format changes, since the details are
+		// refreshed every minute.
+		schemaLog.Info("detected HPC Resource details schema version")
+	default:
+		schemaLog.Debug("parsed HPC Resource details")
 	}
 
 	computePool, auxPool := selectDefaultPools(
 		newSample.Partitions,
-		c.rmConfig.DefaultComputeResourcePool,
-		c.rmConfig.DefaultAuxResourcePool,
+		c.configuredDefaultPool(
+			"default_compute_resource_pool", c.rmConfig.DefaultComputeResourcePool, newSample),
+		c.configuredDefaultPool(
+			"default_aux_resource_pool", c.rmConfig.DefaultAuxResourcePool, newSample),
 	)
+	if c.rmConfig.DefaultComputePoolSelection == config.DefaultComputePoolSelectionLeastLoaded &&
+		c.rmConfig.DefaultComputeResourcePool == nil {
+		computePool = selectLeastLoadedComputePool(newSample.Partitions, computePool)
+	}
 	newSample.DefaultComputePoolPartition = computePool
 	newSample.DefaultAuxPoolPartition = auxPool
+	if gcPool := c.configuredDefaultPool(
+		"checkpoint_gc_resource_pool", c.rmConfig.CheckpointGCResourcePool, newSample,
+	); gcPool != nil {
+		newSample.CheckpointGCPool = *gcPool
+	}
+
+	c.hpcResourcesToDebugLog(*newSample)
+	return newSample, true
+}
 
-	c.hpcResourcesToDebugLog(newSample)
-	return &newSample, true
+// loadResourcesLog reads the log of the HPC resources query, which the caller must close.
+// When the resource manager configures a spool threshold, the log is read with
+// loadEnvironmentLogSpooled, so that a large log is decoded from the spool file rather
+// than buffered in memory.
+func (c *hpcResourceDetailsCache) loadResourcesLog(
+	owner, dispatchID, logFileName string, launcherAPILogger *logrus.Entry,
+) (io.ReadCloser, error) {
+	if spoolBytes := c.rmConfig.ResourceQueryLogSpoolBytes; spoolBytes != nil {
+		log, _, err := c.cl.loadEnvironmentLogSpooled( //nolint:bodyclose
+			owner, dispatchID, logFileName, *spoolBytes, launcherAPILogger)
+		return log, err
+	}
+	log, _, err := c.cl.loadEnvironmentLog( //nolint:bodyclose
+		owner, dispatchID, logFileName, launcherAPILogger)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(log)), nil
+}
+
+// cleanupStaleResourceQueries terminates and deletes any resource query dispatches
+// that are still known to the launcher. Resource queries are not persisted in the
+// DB, so if the master was restarted while a query was in flight, nothing would
+// otherwise ever clean it up. This is called once, before the first query is
+// issued, so any resource query dispatch found at this point is stale.
+func (c *hpcResourceDetailsCache) cleanupStaleResourceQueries() {
+	// The logger we will pass to the API client, so that when the API client
+	// logs a message, we know who called it.
+	launcherAPILogger := c.log.WithField("caller", "cleanupStaleResourceQueries")
+
+	data, _, err := c.cl.listAllRunning(launcherAPILogger) //nolint:bodyclose
+	if err != nil {
+		c.log.WithError(err).Warn("unable to list running dispatches, " +
+			"skipping cleanup of stale resource queries")
+		return
+	}
+
+	for _, v := range data["data"] {
+		if !isStaleResourceQuery(v, c.rmConfig.UserName) {
+			continue
+		}
+
+		dispatchID := v.GetDispatchId()
+		ref := v.GetLaunchedCapsuleReference()
+		owner := ref.GetOwner()
+		c.log.WithField("dispatch-id", dispatchID).
+			WithField("owner", owner).
+			WithField("state", v.GetState()).
+			Info("terminating stale resource query dispatch")
+
+		_, _, err := c.cl.terminateDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			c.log.WithField("dispatch-id", dispatchID).
+				WithError(err).Warn("unable to terminate stale resource query dispatch")
+			continue
+		}
+
+		_, err = c.cl.deleteDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			c.log.WithField("dispatch-id", dispatchID).
+				WithError(err).Warn("unable to delete stale resource query dispatch")
+		}
+	}
+}
+
+// isStaleResourceQuery returns true if the dispatch is a resource or fair-share query
+// launched by the dispatcher RM. Resource queries are launched without impersonation, so they
+// are owned by the user the launcher runs as. If that user is not configured, any
+// owner is accepted.
+func isStaleResourceQuery(v launcher.DispatchInfo, resourceQueryUser string) bool {
+	ref := v.GetLaunchedCapsuleReference()
+	if name := ref.GetName(); name != resourceQueryName && name != fairShareQueryName {
+		return false
+	}
+	if resourceQueryUser != "" && ref.GetOwner() != resourceQueryUser {
+		return false
+	}
+	return true
+}
+
+// configuredDefaultPool returns the configured default pool, if it names a partition of
+// the sample or a launcher-provided pool, with a partition spelled as in the sample.
+// Otherwise, it warns about the misconfiguration and returns nil, so that the default pool
+// is selected automatically.
+func (c *hpcResourceDetailsCache) configuredDefaultPool(
+	option string, pool *string, sample *hpcResources,
+) *string {
+	if pool == nil {
+		return nil
+	}
+	if slices.Contains(c.providedPools, *pool) {
+		return pool
+	}
+	if partitionExists(*pool, sample.Partitions) {
+		return ptrs.Ptr(sampledPartitionName(*pool, sample.Partitions))
+	}
+	if warned, ok := c.warnedDefaultPools[option]; !ok || warned != *pool {
+		c.log.WithField(option, *pool).
+			Warnf("the configured %s does not exist, selecting the default pool automatically", option)
+		if c.warnedDefaultPools == nil {
+			c.warnedDefaultPools = map[string]string{}
+		}
+		c.warnedDefaultPools[option] = *pool
+	}
+	return nil
 }
 
 // selectDefaultPools identifies partitions suitable as default compute and default
@@ -217,13 +581,14 @@ func selectDefaultPools(
 	string, string,
 ) {
 	// The default compute pool is the default partition if it has any GPUS,
-	// otherwise select any partition with GPUs.
+	// otherwise the partition with the most GPUs (the first listed, on a tie).
 	// The AUX partition, use the default partition if available, otherwise any partition.
 
 	defaultComputePar := "" // Selected default Compute/GPU partition
 	defaultAuxPar := ""     // Selected default Aux partition
 
-	fallbackComputePar := "" // Fallback Compute/GPU partition (has GPUs)
+	fallbackComputePar := "" // Fallback Compute/GPU partition (has the most GPUs)
+	fallbackGpuSlots := 0    // GPUs of the fallback Compute/GPU partition
 	fallbackAuxPar := ""     // Fallback partition if no default
 
 	for _, v := range hpcResourceDetails {
@@ -234,8 +599,9 @@ func selectDefaultPools(
 			}
 		} else {
 			fallbackAuxPar = v.PartitionName
-			if v.TotalGpuSlots > 0 {
+			if v.TotalGpuSlots > fallbackGpuSlots {
 				fallbackComputePar = v.PartitionName
+				fallbackGpuSlots = v.TotalGpuSlots
 			}
 		}
 	}
@@ -265,6 +631,30 @@ func selectDefaultPools(
 	return defaultComputePar, defaultAuxPar
 }
 
+// selectLeastLoadedComputePool returns, of the GPU partitions with as many GPUs as the
+// selected default compute partition, the one with the most available GPUs, so that the jobs
+// of tasks without a resource pool are spread over identical partitions as their load changes.
+// On a tie, the selected default is kept, or else the first listed partition is returned.
+func selectLeastLoadedComputePool(
+	hpcResourceDetails []hpcPartitionDetails, defaultComputePar string,
+) string {
+	i := slices.IndexFunc(hpcResourceDetails, func(p hpcPartitionDetails) bool {
+		return strings.EqualFold(p.PartitionName, defaultComputePar)
+	})
+	if i < 0 || hpcResourceDetails[i].TotalGpuSlots == 0 {
+		return defaultComputePar
+	}
+
+	selected := hpcResourceDetails[i]
+	for _, v := range hpcResourceDetails {
+		if v.TotalGpuSlots == selected.TotalGpuSlots &&
+			v.TotalAvailableGpuSlots > selected.TotalAvailableGpuSlots {
+			selected = v
+		}
+	}
+	return selected.PartitionName
+}
+
 // hpcResourcesToDebugLog puts a summary of the available HPC resources to the debug log.
 func (c *hpcResourceDetailsCache) hpcResourcesToDebugLog(resources hpcResources) {
 	if c.log.Logger.Level != logrus.DebugLevel {
@@ -277,31 +667,42 @@ func (c *hpcResourceDetailsCache) hpcResourcesToDebugLog(resources hpcResources)
 		resources.DefaultAuxPoolPartition,
 	)
 	c.log.Debugf("HPC Resource details: %+v", resources.Partitions)
-	nodesWithGpu := 0
-	gpusFound := 0
-	nodesAllocated := 0
-	gpusAllocated := 0
-	cpusFound := 0
-	cpusAllocated := 0
-	for _, node := range resources.Nodes {
-		gpusFound += node.GpuCount
-		cpusFound += node.CPUCount
+	summary := summarizeHpcNodes(resources.Nodes)
+	c.log.
+		WithField("nodes", summary.nodes).
+		WithField("allocated", summary.nodesAllocated).
+		WithField("nodes with GPU", summary.nodesWithGpu).
+		WithField("GPUs", summary.gpus).
+		WithField("GPUs allocated", summary.gpusAllocated).
+		WithField("CPUs", summary.cpus).
+		WithField("CPUs allocated", summary.cpusAllocated).
+		Debug("node summary")
+}
+
+// hpcNodeSummary totals the resources of the nodes of the HPC cluster.
+type hpcNodeSummary struct {
+	nodes          int
+	nodesWithGpu   int
+	nodesAllocated int
+	gpus           int
+	gpusAllocated  int
+	cpus           int
+	cpusAllocated  int
+}
+
+func summarizeHpcNodes(nodes []hpcNodeDetails) hpcNodeSummary {
+	summary := hpcNodeSummary{nodes: len(nodes)}
+	for _, node := range nodes {
+		summary.gpus += node.GpuCount
+		summary.cpus += node.CPUCount
 		if node.GpuCount > 0 {
-			nodesWithGpu++
+			summary.nodesWithGpu++
 		}
 		if node.Allocated {
-			nodesAllocated++
+			summary.nodesAllocated++
 		}
-		gpusAllocated += node.GpuInUseCount
-		cpusAllocated += node.CPUInUseCount
+		summary.gpusAllocated += node.gpusInUse()
+		summary.cpusAllocated += node.CPUInUseCount
 	}
-	c.log.
-		WithField("nodes", len(resources.Nodes)).
-		WithField("allocated", nodesAllocated).
-		WithField("nodes with GPU", nodesWithGpu).
-		WithField("GPUs", gpusFound).
-		WithField("GPUs allocated", gpusAllocated).
-		WithField("CPUs", cpusFound).
-		WithField("CPUs allocated", cpusAllocated).
-		Debug("node summary")
+	return summary
 }
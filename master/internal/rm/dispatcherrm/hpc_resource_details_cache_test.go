@@ -1,9 +1,19 @@
 package dispatcherrm
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/ghodss/yaml"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
+
 	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
 )
 
 func Test_hpcResourceDetailsCache_selectDefaultPools(t *testing.T) {
@@ -54,6 +64,18 @@ func Test_hpcResourceDetailsCache_selectDefaultPools(t *testing.T) {
 	hpc4 := []hpcPartitionDetails{
 		p3,
 	}
+	// Several GPU partitions of differing sizes, none of them the default.
+	small := hpcPartitionDetails{PartitionName: "small", TotalGpuSlots: 2}
+	large := hpcPartitionDetails{PartitionName: "large", TotalGpuSlots: 64}
+	medium := hpcPartitionDetails{PartitionName: "medium", TotalGpuSlots: 8}
+	largeToo := hpcPartitionDetails{PartitionName: "large-too", TotalGpuSlots: 64}
+	hpc5 := []hpcPartitionDetails{
+		p3, small, large, medium, largeToo,
+	}
+	// The default partition has GPUs, but fewer than the others.
+	hpc6 := []hpcPartitionDetails{
+		large, {PartitionName: "tiny", IsDefault: true, TotalGpuSlots: 1},
+	}
 
 	worf := "worf"
 	data := "data"
@@ -103,6 +125,29 @@ func Test_hpcResourceDetailsCache_selectDefaultPools(t *testing.T) {
 			wantCompute: "worf",
 			wantAux:     "data",
 		},
+		{
+			name:        "Largest GPU partition test",
+			fields:      fields{config: &config.DispatcherResourceManagerConfig{}},
+			args:        args{hpcResourceDetails: hpc5},
+			wantCompute: "large",
+			wantAux:     "large-too",
+		},
+		{
+			name:        "Default GPU partition preferred test",
+			fields:      fields{config: &config.DispatcherResourceManagerConfig{}},
+			args:        args{hpcResourceDetails: hpc6},
+			wantCompute: "tiny",
+			wantAux:     "tiny",
+		},
+		{
+			name: "Override largest GPU partition test",
+			fields: fields{config: &config.DispatcherResourceManagerConfig{
+				DefaultComputeResourcePool: &data,
+			}},
+			args:        args{hpcResourceDetails: hpc5},
+			wantCompute: "data",
+			wantAux:     "large-too",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -120,3 +165,426 @@ func Test_hpcResourceDetailsCache_selectDefaultPools(t *testing.T) {
 		})
 	}
 }
+
+func Test_hpcResourceDetailsCache_configuredDefaultPool(t *testing.T) {
+	c := &hpcResourceDetailsCache{
+		log:           logrus.WithField("component", "hpc-resource-details-cache"),
+		providedPools: []string{"launcher-pool"},
+	}
+	sample := &hpcResources{
+		Partitions: []hpcPartitionDetails{{PartitionName: "gpus"}, {PartitionName: "cpus"}},
+	}
+	pool := func(name string) *string { return &name }
+
+	require.Nil(t, c.configuredDefaultPool("default_compute_resource_pool", nil, sample))
+	require.Equal(t, pool("cpus"),
+		c.configuredDefaultPool("default_compute_resource_pool", pool("cpus"), sample))
+	require.Equal(t, pool("launcher-pool"),
+		c.configuredDefaultPool("default_aux_resource_pool", pool("launcher-pool"), sample))
+	// A partition is returned as the workload manager spells it.
+	require.Equal(t, pool("gpus"),
+		c.configuredDefaultPool("default_compute_resource_pool", pool("GPUS"), sample))
+	// A misspelled default falls back to the automatic selection.
+	require.Nil(t, c.configuredDefaultPool("default_compute_resource_pool", pool("gpu"), sample))
+
+	compute, aux := selectDefaultPools(sample.Partitions,
+		c.configuredDefaultPool("default_compute_resource_pool", pool("gpu"), sample),
+		c.configuredDefaultPool("default_aux_resource_pool", pool("launcher-pool"), sample))
+	require.Equal(t, "cpus", compute)
+	require.Equal(t, "launcher-pool", aux)
+}
+
+func Test_hpcResourceDetailsCache_configuredDefaultPoolWarnsOnce(t *testing.T) {
+	logger, hook := logrustest.NewNullLogger()
+	c := &hpcResourceDetailsCache{log: logger.WithField("component", "hpc-resource-details-cache")}
+	sample := &hpcResources{Partitions: []hpcPartitionDetails{{PartitionName: "gpus"}}}
+
+	// The same misconfiguration is reported once, however many samples are taken.
+	for i := 0; i < 3; i++ {
+		c.configuredDefaultPool("default_compute_resource_pool", ptrs.Ptr("gpu"), sample)
+	}
+	require.Len(t, hook.AllEntries(), 1)
+	c.configuredDefaultPool("default_aux_resource_pool", ptrs.Ptr("gpu"), sample)
+	require.Len(t, hook.AllEntries(), 2)
+}
+
+func Test_isStaleResourceQuery(t *testing.T) {
+	dispatch := func(name, owner string) launcher.DispatchInfo {
+		return launcher.DispatchInfo{
+			LaunchedCapsuleReference: &launcher.OwnedResourceReference{
+				Name:  launcher.PtrString(name),
+				Owner: launcher.PtrString(owner),
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		v    launcher.DispatchInfo
+		user string
+		want bool
+	}{
+		{
+			name: "resource query owned by configured user",
+			v:    dispatch(resourceQueryName, "launcher"),
+			user: "launcher",
+			want: true,
+		},
+		{
+			name: "resource query with no configured user",
+			v:    dispatch(resourceQueryName, "launcher"),
+			user: "",
+			want: true,
+		},
+		{
+			name: "resource query owned by another user",
+			v:    dispatch(resourceQueryName, "someone"),
+			user: "launcher",
+			want: false,
+		},
+		{
+			name: "fair-share query",
+			v:    dispatch(fairShareQueryName, "launcher"),
+			user: "launcher",
+			want: true,
+		},
+		{
+			name: "queue query",
+			v:    dispatch(queueQueryName, "launcher"),
+			user: "launcher",
+			want: false,
+		},
+		{
+			name: "task dispatch",
+			v:    dispatch("det", "launcher"),
+			user: "",
+			want: false,
+		},
+		{
+			name: "missing capsule reference",
+			v:    launcher.DispatchInfo{},
+			user: "",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStaleResourceQuery(tt.v, tt.user); got != tt.want {
+				t.Errorf("isStaleResourceQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchHpcResourceDetails(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.logs["slurm-resources-info"] = `
+partitions:
+- partitionName: gpus
+  default: true
+  totalNodes: 2
+  totalGpuSlots: 8
+nodes:
+- name: node1
+  partitions: [gpus]
+  gpuCount: 4
+`
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:       cl,
+	}
+
+	res, ok := c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Len(t, res.Partitions, 1)
+	require.Equal(t, "gpus", res.Partitions[0].PartitionName)
+	require.Equal(t, 8, res.Partitions[0].TotalGpuSlots)
+	require.Len(t, res.Nodes, 1)
+	require.Equal(t, "gpus", res.DefaultComputePoolPartition)
+	require.Equal(t, "gpus", res.DefaultAuxPoolPartition)
+	require.Equal(t, hpcResourcesSchemaV1, res.SchemaVersion)
+
+	// The resource query dispatch is cleaned up after it is read.
+	require.Len(t, cl.terminated, 1)
+	require.Len(t, cl.deleted, 1)
+	require.Empty(t, cl.dispatches)
+
+	// A failure to read the log still cleans up the dispatch.
+	cl.logErr = fmt.Errorf("no such log")
+	_, ok = c.fetchHpcResourceDetails()
+	require.False(t, ok)
+	require.Len(t, cl.deleted, 2)
+}
+
+func TestFetchHpcResourceDetailsRetriesIncompleteLog(t *testing.T) {
+	cl := newFakeLauncherClient()
+	cl.logs["slurm-resources-info"] = "partitions:\n- {partitionName: gpus, totalGpuSlots: 8}"
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{
+			ResourceQueryLogRetryInterval: ptrs.Ptr(model.Duration(0)),
+		},
+		log: logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:  cl,
+	}
+
+	// An empty and a partially written log are read again.
+	cl.pendingLogs["slurm-resources-info"] = []string{"", "partitions:\n- {partitionName: gp"}
+	res, ok := c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, 3, cl.logReads)
+	require.Len(t, res.Partitions, 1)
+	require.Equal(t, "gpus", res.Partitions[0].PartitionName)
+
+	// Until the retries are exhausted.
+	c.rmConfig.ResourceQueryLogRetries = ptrs.Ptr(1)
+	cl.logReads = 0
+	cl.pendingLogs["slurm-resources-info"] = []string{"", "", ""}
+	_, ok = c.fetchHpcResourceDetails()
+	require.False(t, ok)
+	require.Equal(t, 2, cl.logReads)
+	require.Len(t, cl.deleted, 2)
+}
+
+func TestFetchHpcResourceDetailsLeastLoadedComputePool(t *testing.T) {
+	sample := func(gpusA, gpusB int) string {
+		return fmt.Sprintf("partitions:\n"+
+			"- {partitionName: cpus, default: true}\n"+
+			"- {partitionName: gpus-a, totalGpuSlots: 8, totalAvailableGpuSlots: %d}\n"+
+			"- {partitionName: gpus-b, totalGpuSlots: 8, totalAvailableGpuSlots: %d}\n"+
+			"- {partitionName: gpus-small, totalGpuSlots: 4, totalAvailableGpuSlots: 4}",
+			gpusA, gpusB)
+	}
+	cl := newFakeLauncherClient()
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:       cl,
+	}
+
+	// By default, the same partition is the default however loaded it is.
+	cl.logs["slurm-resources-info"] = sample(0, 8)
+	res, ok := c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "gpus-a", res.DefaultComputePoolPartition)
+
+	// The least loaded of the partitions of the same size is selected as the load changes.
+	c.rmConfig.DefaultComputePoolSelection = config.DefaultComputePoolSelectionLeastLoaded
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "gpus-b", res.DefaultComputePoolPartition)
+
+	cl.logs["slurm-resources-info"] = sample(6, 2)
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "gpus-a", res.DefaultComputePoolPartition)
+
+	// Equally loaded partitions keep the default.
+	cl.logs["slurm-resources-info"] = sample(4, 4)
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "gpus-a", res.DefaultComputePoolPartition)
+
+	// The checkpoint GC resource pool is used only if it names a pool of the sample.
+	require.Empty(t, res.CheckpointGCPool)
+	c.rmConfig.CheckpointGCResourcePool = ptrs.Ptr("CPUS")
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "cpus", res.CheckpointGCPool)
+	c.rmConfig.CheckpointGCResourcePool = ptrs.Ptr("cpu")
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Empty(t, res.CheckpointGCPool)
+
+	// A configured default compute pool is always used.
+	c.rmConfig.DefaultComputeResourcePool = ptrs.Ptr("gpus-small")
+	cl.logs["slurm-resources-info"] = sample(0, 8)
+	res, ok = c.fetchHpcResourceDetails()
+	require.True(t, ok)
+	require.Equal(t, "gpus-small", res.DefaultComputePoolPartition)
+}
+
+func TestParseHpcResources(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantVersion  hpcResourcesSchemaVersion
+		wantDetected bool
+		wantState    string
+		wantDraining bool
+		wantErr      string
+	}{
+		{
+			name:         "previous format detected by trial",
+			data:         "nodes:\n- {name: node1, draining: true}",
+			wantVersion:  hpcResourcesSchemaV1,
+			wantDetected: true,
+			wantDraining: true,
+		},
+		{
+			name:         "current format detected by trial",
+			data:         "nodes:\n- {name: node1, state: IDLE+DRAIN, reason: maintenance}",
+			wantVersion:  hpcResourcesSchemaV2,
+			wantDetected: true,
+			wantState:    "idle+drain",
+			wantDraining: true,
+		},
+		{
+			name:         "version marker",
+			data:         "schemaVersion: 1\nnodes:\n- {name: node1, draining: true, state: IDLE}",
+			wantVersion:  hpcResourcesSchemaV1,
+			wantDetected: true,
+			wantDraining: true,
+		},
+		{
+			name:         "unknown fields are parsed as the latest format",
+			data:         "partitions:\n- {partitionName: gpus, newField: 1}\nnodes:\n- {name: node1}",
+			wantVersion:  latestHpcResourcesSchemaVersion,
+			wantDetected: false,
+		},
+		{
+			name:    "unsupported version marker",
+			data:    "schemaVersion: 99\nnodes:\n- {name: node1}",
+			wantErr: "unsupported HPC resources schema version 99",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, detected, err := parseHpcResources(strings.NewReader(tt.data))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantVersion, res.SchemaVersion)
+			require.Equal(t, tt.wantDetected, detected)
+			require.Len(t, res.Nodes, 1)
+			require.Equal(t, "node1", res.Nodes[0].Name)
+			require.Equal(t, tt.wantState, res.Nodes[0].State)
+			require.Equal(t, tt.wantDraining, res.Nodes[0].Draining)
+		})
+	}
+}
+
+func TestParseHpcResourcesAccelerator(t *testing.T) {
+	res, _, err := parseHpcResources(strings.NewReader(`
+partitions:
+- partitionName: gpus
+  accelerator: tesla
+  acceleratorInfo: {vendor: nvidia, model: a100, computeCapability: "8.0"}
+- partitionName: cpus
+nodes:
+- {name: node1, partitions: [gpus, cpus], state: IDLE}
+`))
+	require.NoError(t, err)
+	require.Equal(t, "tesla", res.Partitions[0].Accelerator)
+	require.Equal(t, &hpcAcceleratorInfo{
+		Vendor:            "nvidia",
+		Model:             "a100",
+		ComputeCapability: "8.0",
+	}, res.Partitions[0].AcceleratorInfo)
+	require.Nil(t, res.Partitions[1].AcceleratorInfo)
+
+	m := &DispatcherResourceManager{
+		syslog:          logrus.WithField("component", "dispatcherrm"),
+		wlmType:         slurmSchedulerType,
+		rmConfig:        &config.DispatcherResourceManagerConfig{},
+		hpcDetailsCache: makeTestHpcDetailsCache(res),
+		dbState:         *newDispatcherState(),
+	}
+	pools, err := m.GetResourcePools()
+	require.NoError(t, err)
+	require.Len(t, pools.ResourcePools, 2)
+	gpus, cpus := pools.ResourcePools[0], pools.ResourcePools[1]
+	require.Equal(t, "tesla", gpus.Accelerator)
+	require.Equal(t, "nvidia", gpus.Details.Hpc.Accelerator.Vendor)
+	require.Equal(t, "a100", gpus.Details.Hpc.Accelerator.Model)
+	require.Equal(t, "8.0", gpus.Details.Hpc.Accelerator.ComputeCapability)
+	require.Empty(t, gpus.Details.Hpc.FairShares)
+	// Pools whose accelerators are not reported have no HPC details.
+	require.Nil(t, cpus.Details.Hpc)
+}
+
+func TestHpcNodeDetailsState(t *testing.T) {
+	tests := []struct {
+		node         string
+		wantState    string
+		wantDraining bool
+	}{
+		{node: `{state: IDLE, draining: true}`, wantState: "idle", wantDraining: false},
+		{node: `{state: DOWN*, reason: disk failure}`, wantState: "down", wantDraining: false},
+		{node: `{state: IDLE+DRAIN}`, wantState: "idle+drain", wantDraining: true},
+		{node: `{state: MIXED+DRAIN~}`, wantState: "mixed+drain", wantDraining: true},
+		{node: `{state: DRAINED}`, wantState: "drained", wantDraining: true},
+		{node: `{state: MAINT}`, wantState: "maint", wantDraining: false},
+		{node: `{state: RESERVED}`, wantState: "reserved", wantDraining: false},
+		{node: `{state: "down,offline"}`, wantState: "down+offline", wantDraining: true},
+		// Launchers that do not report the state keep reporting draining directly.
+		{node: `{draining: true}`, wantState: "", wantDraining: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			var res hpcResources
+			require.NoError(t, yaml.Unmarshal([]byte("nodes:\n- "+tt.node), &res))
+			require.Len(t, res.Nodes, 1)
+			require.Equal(t, tt.wantState, res.Nodes[0].State)
+			require.Equal(t, tt.wantDraining, res.Nodes[0].Draining)
+		})
+	}
+
+	var res hpcResources
+	require.NoError(t, yaml.Unmarshal([]byte("nodes:\n- {state: DOWN, reason: disk failure}"), &res))
+	require.Equal(t, "disk failure", res.Nodes[0].Reason)
+}
+
+func TestCleanupStaleResourceQueries(t *testing.T) {
+	cl := newFakeLauncherClient()
+	stale := cl.launch(resourceQueryName, "launcher", "")
+	cl.launch("det", "alice", "alloc-1")
+
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{UserName: "launcher"},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+		cl:       cl,
+	}
+	c.cleanupStaleResourceQueries()
+
+	require.Equal(t, []string{stale.GetDispatchId()}, cl.terminated)
+	require.Equal(t, []string{stale.GetDispatchId()}, cl.deleted)
+	require.Contains(t, cl.dispatches, "alloc-1")
+}
+
+func TestHpcResourceDetailsCacheAcceptSample(t *testing.T) {
+	sample := func(nodes, partitions int) *hpcResources {
+		return &hpcResources{
+			Nodes:      make([]hpcNodeDetails, nodes),
+			Partitions: make([]hpcPartitionDetails, partitions),
+		}
+	}
+
+	c := &hpcResourceDetailsCache{
+		rmConfig: &config.DispatcherResourceManagerConfig{MinResourceSampleRatio: 0.5},
+		log:      logrus.WithField("component", "hpc-resource-details-cache"),
+	}
+	c.lastSample.Store(sample(100, 4))
+
+	// Modest changes are accepted.
+	require.True(t, c.acceptSample(sample(60, 4)))
+	require.True(t, c.acceptSample(sample(120, 4)))
+
+	// A sample with drastically fewer nodes or partitions is discarded.
+	require.False(t, c.acceptSample(sample(10, 4)))
+	require.False(t, c.acceptSample(sample(100, 1)))
+
+	// Until it keeps happening, at which point the cluster has really shrunk.
+	require.False(t, c.acceptSample(sample(10, 4)))
+	require.True(t, c.acceptSample(sample(10, 4)))
+
+	// A good sample resets the count of truncated samples.
+	require.False(t, c.acceptSample(sample(10, 4)))
+	require.True(t, c.acceptSample(sample(100, 4)))
+	require.Zero(t, c.truncatedSamples)
+
+	// The check may be disabled.
+	c.rmConfig.MinResourceSampleRatio = 0
+	require.True(t, c.acceptSample(sample(0, 0)))
+}
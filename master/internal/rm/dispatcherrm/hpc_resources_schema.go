@@ -0,0 +1,180 @@
+package dispatcherrm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// hpcResourcesSchemaVersion identifies a format of the slurm-resources-info
+// reported by the launcher.
+type hpcResourcesSchemaVersion int
+
+const (
+	// hpcResourcesSchemaV1 is the format of launchers that report whether a node is
+	// draining, but not its workload manager state.
+	hpcResourcesSchemaV1 hpcResourcesSchemaVersion = 1
+	// hpcResourcesSchemaV2 is the current format, in which nodes also report their
+	// workload manager state and the reason for it, and the Slurm reservations may
+	// be listed.
+	hpcResourcesSchemaV2 hpcResourcesSchemaVersion = 2
+)
+
+// latestHpcResourcesSchemaVersion is the newest format understood by the master.
+const latestHpcResourcesSchemaVersion = hpcResourcesSchemaV2
+
+// hpcResourcesParser parses the slurm-resources-info in one specific format into
+// hpcResources. When strict, fields unknown to the format are an error, so that
+// the format of a document without a version marker can be detected by trial.
+type hpcResourcesParser func(data []byte, strict bool) (*hpcResources, error)
+
+// hpcResourcesParsers are the parsers of each supported format, from the oldest to
+// the newest. Each format is a superset of the previous one, so that the oldest
+// format that strictly parses a document is the one the launcher used.
+var hpcResourcesParsers = []struct {
+	version hpcResourcesSchemaVersion
+	parse   hpcResourcesParser
+}{
+	{version: hpcResourcesSchemaV1, parse: parseHpcResourcesV1},
+	{version: hpcResourcesSchemaV2, parse: parseHpcResourcesV2},
+}
+
+// hpcResourcesDecodeBufferSize is how far into the slurm-resources-info the decoder looks
+// to tell whether it is JSON, rather than YAML.
+const hpcResourcesDecodeBufferSize = 4096
+
+// parseHpcResources parses the slurm-resources-info reported by the launcher. The
+// document is decoded from r with a streaming decoder, as it is read, and the format
+// is chosen by the "schemaVersion" marker of the document if there is one, else by
+// trying each known format in turn. A document that matches none of them exactly,
+// e.g. because a newer launcher added fields, is parsed as the latest format, and
+// detected is false. The schema version that was used is recorded in the result.
+func parseHpcResources(r io.Reader) (res *hpcResources, detected bool, err error) {
+	var data json.RawMessage
+	err = yaml.NewYAMLOrJSONDecoder(r, hpcResourcesDecodeBufferSize).Decode(&data)
+	switch {
+	case errors.Is(err, io.EOF):
+		// An empty log is an empty document, which lacks the partitions.
+		data = json.RawMessage("{}")
+	case err != nil:
+		return nil, false, err
+	case bytes.Equal(data, []byte("null")):
+		data = json.RawMessage("{}")
+	}
+
+	var marker struct {
+		SchemaVersion *hpcResourcesSchemaVersion `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, false, err
+	}
+
+	if marker.SchemaVersion != nil {
+		for _, p := range hpcResourcesParsers {
+			if p.version == *marker.SchemaVersion {
+				res, err := p.parse(data, false)
+				if err != nil {
+					return nil, false, err
+				}
+				res.SchemaVersion = p.version
+				return res, true, nil
+			}
+		}
+		return nil, false, fmt.Errorf("unsupported HPC resources schema version %d",
+			*marker.SchemaVersion)
+	}
+
+	for _, p := range hpcResourcesParsers {
+		if res, err := p.parse(data, true); err == nil {
+			res.SchemaVersion = p.version
+			return res, true, nil
+		}
+	}
+
+	res, err = parseHpcResourcesV2(data, false)
+	if err != nil {
+		return nil, false, err
+	}
+	res.SchemaVersion = latestHpcResourcesSchemaVersion
+	return res, false, nil
+}
+
+// unmarshalHpcResources unmarshals the slurm-resources-info, which parseHpcResources
+// decoded to JSON.
+func unmarshalHpcResources(data []byte, v any, strict bool) error {
+	if !strict {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// hpcResourcesV1 is the slurm-resources-info in the hpcResourcesSchemaV1 format.
+type hpcResourcesV1 struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Partitions    []hpcPartitionDetails `json:"partitions"`
+	Nodes         []hpcNodeDetailsV1    `json:"nodes"`
+}
+
+// hpcNodeDetailsV1 holds HPC node details in the hpcResourcesSchemaV1 format.
+type hpcNodeDetailsV1 struct {
+	Partitions    []string `json:"partitions"`
+	Addresses     []string `json:"addresses"`
+	Draining      bool     `json:"draining"`
+	Allocated     bool     `json:"allocated"`
+	Name          string   `json:"name"`
+	GpuCount      int      `json:"gpuCount"`
+	GpuInUseCount int      `json:"gpuInUseCount"`
+	CPUCount      int      `json:"cpuCount"`
+	CPUInUseCount int      `json:"cpuInUseCount"`
+}
+
+func parseHpcResourcesV1(data []byte, strict bool) (*hpcResources, error) {
+	var raw hpcResourcesV1
+	if err := unmarshalHpcResources(data, &raw, strict); err != nil {
+		return nil, err
+	}
+
+	res := &hpcResources{Partitions: raw.Partitions}
+	for _, n := range raw.Nodes {
+		res.Nodes = append(res.Nodes, hpcNodeDetails{
+			Partitions:    n.Partitions,
+			Addresses:     n.Addresses,
+			Draining:      n.Draining,
+			Allocated:     n.Allocated,
+			Name:          n.Name,
+			GpuCount:      n.GpuCount,
+			GpuInUseCount: n.GpuInUseCount,
+			CPUCount:      n.CPUCount,
+			CPUInUseCount: n.CPUInUseCount,
+		})
+	}
+	return res, nil
+}
+
+// hpcResourcesV2 is the slurm-resources-info in the hpcResourcesSchemaV2 format. Its
+// nodes are normalized as they are unmarshaled, see hpcNodeDetails.UnmarshalJSON, which
+// ignores unknown fields even when parsing strictly.
+type hpcResourcesV2 struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	Partitions    []hpcPartitionDetails   `json:"partitions"`
+	Nodes         []hpcNodeDetails        `json:"nodes"`
+	Reservations  []hpcReservationDetails `json:"reservations"`
+}
+
+func parseHpcResourcesV2(data []byte, strict bool) (*hpcResources, error) {
+	var raw hpcResourcesV2
+	if err := unmarshalHpcResources(data, &raw, strict); err != nil {
+		return nil, err
+	}
+	return &hpcResources{
+		Partitions:   raw.Partitions,
+		Nodes:        raw.Nodes,
+		Reservations: raw.Reservations,
+	}, nil
+}
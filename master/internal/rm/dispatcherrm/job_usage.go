@@ -0,0 +1,127 @@
+package dispatcherrm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+const (
+	// jobUsageLogFileName is the log file of the job usage query that holds its result.
+	jobUsageLogFileName = "hpc-job-usage"
+	// jobUsageAttempts bounds the queries made for the usage of a job, since the
+	// accounting record of a job may be written some time after it exits.
+	jobUsageAttempts = 4
+)
+
+// jobUsageRetryDelay is the wait between queries for the usage of a job.
+var jobUsageRetryDelay = 5 * time.Second
+
+// errJobUsageUnavailable is returned when the accounting record of a job is not yet written.
+var errJobUsageUnavailable = errors.New("job usage not yet available")
+
+// hpcJobUsage is the resource usage of a completed HPC job, as recorded by the accounting
+// of the workload manager.
+type hpcJobUsage struct {
+	MaxRSSBytes    int64   `json:"maxRssBytes"`
+	CPUTimeSeconds float64 `json:"cpuTimeSeconds"`
+	// GPUUtilization is the mean utilization of the GPUs of the job in percent, or nil if
+	// the workload manager does not account for it.
+	GPUUtilization *float64 `json:"gpuUtilization"`
+}
+
+func (u hpcJobUsage) String() string {
+	cpuTime := time.Duration(u.CPUTimeSeconds * float64(time.Second)).Round(time.Second)
+	s := fmt.Sprintf("peak memory %s, CPU time %s", units.BytesSize(float64(u.MaxRSSBytes)), cpuTime)
+	if u.GPUUtilization != nil {
+		s += fmt.Sprintf(", GPU utilization %.1f%%", *u.GPUUtilization)
+	}
+	return s
+}
+
+// reportJobUsage publishes the resource usage of the HPC job of the dispatch to the logs of
+// the allocation. Queries are retried while the accounting record of the job is not yet
+// available; if it never becomes available, nothing is reported.
+func (m *DispatcherResourceManager) reportJobUsage(allocationID model.AllocationID, dispatchID string) {
+	log := m.syslog.WithField("dispatch-id", dispatchID)
+
+	hpcJobID, ok := m.dispatchIDToHPCJobID.Load(dispatchID)
+	if !ok || hpcJobID == "" {
+		log.Debug("not reporting job usage, because the HPC job ID is unknown")
+		return
+	}
+	log = log.WithField("hpc-job-id", hpcJobID)
+
+	for attempt := 1; ; attempt++ {
+		usage, err := m.queryJobUsage(hpcJobID)
+		switch {
+		case err == nil:
+			rmevents.Publish(allocationID, &sproto.ContainerLog{
+				AuxMessage: ptrs.Ptr(fmt.Sprintf("Resource usage of HPC job %s: %s", hpcJobID, usage)),
+				Level:      ptrs.Ptr("INFO"),
+			})
+			return
+		case !errors.Is(err, errJobUsageUnavailable):
+			log.WithError(err).Warn("failed to query job usage")
+			return
+		case attempt == jobUsageAttempts:
+			log.Infof("job usage still not available after %d attempts", attempt)
+			return
+		}
+		time.Sleep(jobUsageRetryDelay)
+	}
+}
+
+// queryJobUsage returns the resource usage of the given HPC job, or errJobUsageUnavailable
+// if the workload manager has no accounting record of it yet.
+func (m *DispatcherResourceManager) queryJobUsage(hpcJobID string) (*hpcJobUsage, error) {
+	// The logger we will pass to the API client, so that when the API client
+	// logs a message, we know who called it.
+	launcherAPILogger := m.syslog.WithField("caller", "queryJobUsage")
+
+	dispatchInfo, r, err := m.apiClient.launchHPCJobUsageJob(hpcJobID, launcherAPILogger) //nolint:bodyclose
+	if err != nil {
+		return nil, errors.New(m.apiClient.handleLauncherError(r,
+			"failed to query job usage from launcher", err))
+	}
+	dispatchID := dispatchInfo.GetDispatchId()
+	owner := dispatchInfo.GetLaunchingUser()
+	defer func() {
+		_, _, err := m.apiClient.terminateDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				WithError(err).Error("failed to terminate dispatch")
+			return
+		}
+
+		_, err = m.apiClient.deleteDispatch(owner, dispatchID, launcherAPILogger) //nolint:bodyclose
+		if err != nil {
+			m.syslog.WithField("dispatch-id", dispatchID).
+				WithError(err).Error("failed to delete dispatch")
+		}
+	}()
+
+	resp, _, err := m.apiClient.loadEnvironmentLog( //nolint:bodyclose
+		owner, dispatchID, jobUsageLogFileName, launcherAPILogger)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving job usage")
+	}
+	if strings.TrimSpace(resp) == "" {
+		return nil, errJobUsageUnavailable
+	}
+
+	var usage hpcJobUsage
+	if err := yaml.Unmarshal([]byte(resp), &usage); err != nil {
+		return nil, errors.Wrap(err, "parsing job usage")
+	}
+	return &usage, nil
+}
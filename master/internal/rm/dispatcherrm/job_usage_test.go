@@ -0,0 +1,65 @@
+package dispatcherrm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/syncx/mapx"
+)
+
+func TestReportJobUsage(t *testing.T) {
+	defer func(d time.Duration) { jobUsageRetryDelay = d }(jobUsageRetryDelay)
+	jobUsageRetryDelay = 0
+
+	cl := newFakeLauncherClient()
+	dispatchIDToHPCJobID := mapx.New[string, string]()
+	dispatchIDToHPCJobID.Store("dispatch-1", "1234")
+	m := &DispatcherResourceManager{
+		syslog:               logrus.WithField("component", "dispatcherrm"),
+		apiClient:            cl,
+		dispatchIDToHPCJobID: &dispatchIDToHPCJobID,
+	}
+	allocationID := model.AllocationID("alloc-1")
+	sub := rmevents.Subscribe(allocationID)
+	defer sub.Close()
+
+	// The accounting record is retried until it becomes available.
+	cl.usageLogs = []string{"", "maxRssBytes: 1610612736\ncpuTimeSeconds: 7200\ngpuUtilization: 87.5\n"}
+	m.reportJobUsage(allocationID, "dispatch-1")
+	require.Equal(t, []string{"1234", "1234"}, cl.usageQueries)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ev, err := sub.GetWithContext(ctx)
+	require.NoError(t, err)
+	log, ok := ev.(*sproto.ContainerLog)
+	require.True(t, ok)
+	require.Equal(t,
+		"Resource usage of HPC job 1234: peak memory 1.5GiB, CPU time 2h0m0s, GPU utilization 87.5%",
+		*log.AuxMessage)
+
+	// The usage of a job that is never accounted for is not reported.
+	cl.usageQueries = nil
+	m.reportJobUsage(allocationID, "dispatch-1")
+	require.Len(t, cl.usageQueries, jobUsageAttempts)
+
+	// Nor is the usage of a dispatch without an HPC job.
+	cl.usageQueries = nil
+	m.reportJobUsage(allocationID, "dispatch-2")
+	require.Empty(t, cl.usageQueries)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = sub.GetWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Usage queries are cleaned up after themselves.
+	require.Len(t, cl.deleted, 2+jobUsageAttempts)
+}
@@ -0,0 +1,64 @@
+package dispatcherrm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// launcherLogSpoolPattern is the pattern of the names of the temporary files that launcher
+// logs are spooled to.
+const launcherLogSpoolPattern = "determined-launcher-log-*"
+
+// readLauncherLog reads a launcher log. Up to spoolThreshold bytes are buffered in memory;
+// a longer log is spooled to a temporary file, which is read back as the log is decoded and
+// removed when the returned reader is closed. A threshold of zero never spools.
+func readLauncherLog(r io.Reader, spoolThreshold int) (io.ReadCloser, error) {
+	if spoolThreshold <= 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	var head bytes.Buffer
+	if _, err := io.CopyN(&head, r, int64(spoolThreshold)+1); errors.Is(err, io.EOF) {
+		return io.NopCloser(&head), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", launcherLogSpoolPattern)
+	if err != nil {
+		return nil, err
+	}
+	spool := spoolFile{File: f}
+	if _, err := head.WriteTo(f); err != nil {
+		_ = spool.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = spool.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = spool.Close()
+		return nil, err
+	}
+	return spool, nil
+}
+
+// spoolFile is a launcher log spooled to a temporary file, which is removed when it is closed.
+type spoolFile struct {
+	*os.File
+}
+
+func (f spoolFile) Close() error {
+	err := f.File.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package dispatcherrm
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLauncherLog(t *testing.T) {
+	spoolDir := t.TempDir()
+	t.Setenv("TMPDIR", spoolDir)
+	requireNoSpoolFiles := func() {
+		entries, err := os.ReadDir(spoolDir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	}
+	log := strings.Repeat("nodes:\n- name: node1\n", 10)
+
+	for _, threshold := range []int{0, 1, len(log) - 1, len(log), len(log) + 1} {
+		r, err := readLauncherLog(strings.NewReader(log), threshold)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, log, string(data), threshold)
+
+		// A log longer than the threshold is read back from the spool file until it is closed.
+		if threshold > 0 && threshold < len(log) {
+			entries, err := os.ReadDir(spoolDir)
+			require.NoError(t, err)
+			require.Len(t, entries, 1, threshold)
+		}
+		require.NoError(t, r.Close())
+		requireNoSpoolFiles()
+	}
+
+	// The spool file is removed even if the log cannot be read in full.
+	failing := io.MultiReader(strings.NewReader(log), iotestErrReader{})
+	_, err := readLauncherLog(failing, 16)
+	require.ErrorContains(t, err, "connection reset")
+	requireNoSpoolFiles()
+}
+
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,125 @@
+package dispatcherrm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+// newLauncherTLSConfig builds the TLS configuration of the connection to the launcher. The
+// configured files are read up front, so that a misconfiguration fails startup with an error
+// naming the offending setting.
+func newLauncherTLSConfig(sec *config.DispatcherSecurityConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: sec.TLS.SkipVerify, //nolint:gosec
+	}
+
+	if sec.TLS.CertificatePath != "" {
+		bundle, err := os.ReadFile(sec.TLS.CertificatePath)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"configuration resource_manager.security.tls.certificate (%s) not readable: %w",
+				sec.TLS.CertificatePath, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf(
+				"configuration resource_manager.security.tls.certificate (%s) contains no PEM certificates",
+				sec.TLS.CertificatePath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if sec.ClientCert != "" {
+		r := &clientCertReloader{
+			certFile: sec.ClientCert,
+			keyFile:  sec.ClientKey,
+			log:      logrus.WithField("component", "launcher-client-cert"),
+		}
+		if err := r.load(); err != nil {
+			return nil, fmt.Errorf(
+				"configuration resource_manager.security.client_cert (%s) and client_key (%s) "+
+					"not loadable: %w", sec.ClientCert, sec.ClientKey, err)
+		}
+		tlsConfig.GetClientCertificate = r.getClientCertificate
+	}
+
+	return tlsConfig, nil
+}
+
+// clientCertReloader provides the client certificate presented to the launcher, reloading it
+// from its files when they are modified so that certificates can be rotated without restarting
+// the master. Connections established before a reload keep using the previous certificate.
+type clientCertReloader struct {
+	certFile string
+	keyFile  string
+	log      *logrus.Entry
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// load reads the certificate and key, replacing the current certificate on success.
+func (r *clientCertReloader) load() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
+
+// modified returns whether either file changed since the certificate was last loaded.
+func (r *clientCertReloader) modified() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate. If reloading a modified
+// certificate fails, e.g. because only one of the files has been replaced so far, the previous
+// certificate continues to be used.
+func (r *clientCertReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if r.modified() {
+		if err := r.load(); err != nil {
+			r.log.WithError(err).Warnf("reloading client certificate from %s", r.certFile)
+		} else {
+			r.log.Infof("reloaded client certificate from %s", r.certFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
@@ -0,0 +1,96 @@
+package dispatcherrm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// writeTestCert writes a self-signed certificate and its key to the given files.
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+}
+
+func clientCertCommonName(t *testing.T, tlsConfig *tls.Config) string {
+	cert, err := tlsConfig.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestNewLauncherTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeTestCert(t, certFile, keyFile, "first")
+	writeTestCert(t, caFile, filepath.Join(dir, "ca.key"), "ca")
+
+	tlsConfig, err := newLauncherTLSConfig(&config.DispatcherSecurityConfig{
+		TLS:        model.TLSClientConfig{CertificatePath: caFile},
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.RootCAs)
+	require.Equal(t, "first", clientCertCommonName(t, tlsConfig))
+
+	// A rotated certificate is picked up on the next handshake.
+	writeTestCert(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	require.Equal(t, "second", clientCertCommonName(t, tlsConfig))
+
+	// A half-written rotation keeps the previous certificate.
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	require.Equal(t, "second", clientCertCommonName(t, tlsConfig))
+
+	// Unusable files are reported at startup.
+	_, err = newLauncherTLSConfig(&config.DispatcherSecurityConfig{
+		TLS: model.TLSClientConfig{CertificatePath: keyFile},
+	})
+	require.ErrorContains(t, err, "contains no PEM certificates")
+	_, err = newLauncherTLSConfig(&config.DispatcherSecurityConfig{
+		TLS: model.TLSClientConfig{CertificatePath: filepath.Join(dir, "missing.crt")},
+	})
+	require.ErrorContains(t, err, "not readable")
+	_, err = newLauncherTLSConfig(&config.DispatcherSecurityConfig{
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+	})
+	require.ErrorContains(t, err, "client_cert")
+}
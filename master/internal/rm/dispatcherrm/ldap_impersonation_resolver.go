@@ -0,0 +1,116 @@
+package dispatcherrm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+// ldapSearchTimeLimit is the time limit, in seconds, that the LDAP server is given to
+// complete a user search.
+const ldapSearchTimeLimit = 10
+
+type ldapCacheEntry struct {
+	user    string
+	expires time.Time
+}
+
+// ldapImpersonationResolver resolves the HPC user that the jobs of a Determined user
+// are launched as from an attribute of the user's entry in an LDAP directory. Results,
+// including users that are not found, are cached for the configured TTL so that we
+// do not query the directory for every job launch.
+type ldapImpersonationResolver struct {
+	cfg config.DispatcherLDAPImpersonationConfig
+	// lookup performs the uncached lookup; it is replaced in tests.
+	lookup func(username string) (string, error)
+
+	mu    sync.Mutex
+	cache map[string]ldapCacheEntry
+}
+
+func newLDAPImpersonationResolver(
+	cfg config.DispatcherLDAPImpersonationConfig,
+) *ldapImpersonationResolver {
+	r := &ldapImpersonationResolver{
+		cfg:   cfg,
+		cache: make(map[string]ldapCacheEntry),
+	}
+	r.lookup = r.search
+	return r
+}
+
+// ResolveImpersonatedUser implements tasks.ImpersonationResolver.
+func (r *ldapImpersonationResolver) ResolveImpersonatedUser(username string) (string, error) {
+	r.mu.Lock()
+	entry, ok := r.cache[username]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.user, nil
+	}
+
+	user, err := r.lookup(username)
+	if err != nil {
+		// Failures are not cached, so that the next launch tries again.
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[username] = ldapCacheEntry{
+		user:    user,
+		expires: time.Now().Add(time.Duration(r.cfg.CacheTTL)),
+	}
+	return user, nil
+}
+
+// search looks up the user in the directory, returning an empty string if there is no
+// matching entry or the entry lacks the configured attribute.
+func (r *ldapImpersonationResolver) search(username string) (string, error) {
+	conn, err := ldap.DialURL(r.cfg.URL)
+	if err != nil {
+		return "", fmt.Errorf("connecting to LDAP server %s: %w", r.cfg.URL, err)
+	}
+	defer conn.Close()
+
+	if r.cfg.BindDN != "" {
+		password, err := os.ReadFile(r.cfg.BindPasswordFile)
+		if err != nil {
+			return "", fmt.Errorf(
+				"configuration ldap_impersonation.bind_password_file (%s) not readable: %w",
+				r.cfg.BindPasswordFile, err)
+		}
+		if err := conn.Bind(r.cfg.BindDN, strings.TrimSpace(string(password))); err != nil {
+			return "", fmt.Errorf("binding to LDAP server as %s: %w", r.cfg.BindDN, err)
+		}
+	}
+
+	filter := strings.ReplaceAll(
+		r.cfg.UserFilter, config.LDAPUsernamePlaceholder, ldap.EscapeFilter(username))
+	req := ldap.NewSearchRequest(
+		r.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, ldapSearchTimeLimit, false,
+		filter,
+		[]string{r.cfg.UserAttribute},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		return "", fmt.Errorf("searching LDAP for %s: %w", username, err)
+	}
+
+	switch len(res.Entries) {
+	case 0:
+		return "", nil
+	case 1:
+		return res.Entries[0].GetAttributeValue(r.cfg.UserAttribute), nil
+	default:
+		return "", fmt.Errorf("LDAP search for %s matched %d entries", username, len(res.Entries))
+	}
+}
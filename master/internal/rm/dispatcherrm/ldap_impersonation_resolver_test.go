@@ -0,0 +1,58 @@
+package dispatcherrm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestLDAPImpersonationResolverCache(t *testing.T) {
+	r := newLDAPImpersonationResolver(config.DispatcherLDAPImpersonationConfig{
+		CacheTTL: model.Duration(time.Hour),
+	})
+
+	lookups := 0
+	fail := false
+	r.lookup = func(username string) (string, error) {
+		lookups++
+		if fail {
+			return "", fmt.Errorf("directory unavailable")
+		}
+		if username == "alice" {
+			return "hpc_alice", nil
+		}
+		return "", nil
+	}
+
+	// Found and not found users are both cached.
+	for i := 0; i < 2; i++ {
+		user, err := r.ResolveImpersonatedUser("alice")
+		require.NoError(t, err)
+		require.Equal(t, "hpc_alice", user)
+
+		user, err = r.ResolveImpersonatedUser("bob")
+		require.NoError(t, err)
+		require.Equal(t, "", user)
+	}
+	require.Equal(t, 2, lookups)
+
+	// Expired entries are looked up again, and failures are not cached.
+	r.cache["alice"] = ldapCacheEntry{user: "hpc_alice", expires: time.Now().Add(-time.Second)}
+	fail = true
+	_, err := r.ResolveImpersonatedUser("alice")
+	require.ErrorContains(t, err, "directory unavailable")
+	_, err = r.ResolveImpersonatedUser("alice")
+	require.Error(t, err)
+	require.Equal(t, 4, lookups)
+
+	fail = false
+	user, err := r.ResolveImpersonatedUser("alice")
+	require.NoError(t, err)
+	require.Equal(t, "hpc_alice", user)
+	require.Equal(t, 5, lookups)
+}
@@ -183,6 +183,22 @@ func (k *ResourceManager) HealthCheck() []model.ResourceManagerHealth {
 	}
 }
 
+// Capabilities implements rm.ResourceManager.
+func (k *ResourceManager) Capabilities() []*apiv1.ResourceManagerCapabilities {
+	return []*apiv1.ResourceManagerCapabilities{
+		{
+			ResourceManagerName:  k.config.Name,
+			Type:                 "kubernetes",
+			SupportsPriority:     true,
+			SupportsWeight:       true,
+			SupportsMoveJob:      true,
+			SupportsDisableAgent: true,
+			// EnableSlot and DisableSlot return rmerrors.ErrNotSupported.
+			SupportsDisableSlot: false,
+		},
+	}
+}
+
 // GetAgent implements rm.ResourceManager.
 func (k *ResourceManager) GetAgent(msg *apiv1.GetAgentRequest) (*apiv1.GetAgentResponse, error) {
 	return k.podsService.GetAgent(msg), nil
@@ -469,6 +485,23 @@ func (k ResourceManager) ValidateResourcePool(name rm.ResourcePoolName) error {
 	return k.resourcePoolExists(name.String())
 }
 
+// PauseResourcePool is unsupported.
+func (k ResourceManager) PauseResourcePool(rm.ResourcePoolName) error {
+	return rmerrors.UnsupportedError("pausing resource pools is unsupported in k8s")
+}
+
+// GetResourcePoolPartition is unsupported.
+func (k ResourceManager) GetResourcePoolPartition(
+	rm.ResourcePoolName,
+) (*apiv1.GetResourcePoolPartitionResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// ResumeResourcePool is unsupported.
+func (k ResourceManager) ResumeResourcePool(rm.ResourcePoolName) error {
+	return rmerrors.UnsupportedError("pausing resource pools is unsupported in k8s")
+}
+
 // NotifyContainerRunning receives a notification from the container to let
 // the master know that the container is running.
 func (k ResourceManager) NotifyContainerRunning(
@@ -661,3 +694,18 @@ func (k ResourceManager) DisableSlot(
 ) (resp *apiv1.DisableSlotResponse, err error) {
 	return nil, rmerrors.ErrNotSupported
 }
+
+// GetClusterUtilization is unsupported.
+func (k ResourceManager) GetClusterUtilization() (*apiv1.GetClusterUtilizationResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// CleanupOrphanedDispatches is unsupported.
+func (k ResourceManager) CleanupOrphanedDispatches() (*apiv1.CleanupOrphanedDispatchesResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// GetDispatchUsage is unsupported.
+func (k ResourceManager) GetDispatchUsage() (*apiv1.GetDispatchUsageResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
@@ -1,6 +1,7 @@
 package multirm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/sirupsen/logrus"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/determined-ai/determined/master/internal/rm"
 	"github.com/determined-ai/determined/master/internal/rm/rmerrors"
+	"github.com/determined-ai/determined/master/internal/rm/rmutils"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/pkg/command"
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -195,6 +197,39 @@ func (m *MultiRMRouter) ValidateResourcePool(rpName rm.ResourcePoolName) error {
 	return m.rms[resolvedRMName].ValidateResourcePool(rpName)
 }
 
+// PauseResourcePool routes a PauseResourcePool request to the specified resource manager.
+func (m *MultiRMRouter) PauseResourcePool(rpName rm.ResourcePoolName) error {
+	resolvedRMName, err := m.getRM(rpName)
+	if err != nil {
+		return err
+	}
+
+	return m.rms[resolvedRMName].PauseResourcePool(rpName)
+}
+
+// ResumeResourcePool routes a ResumeResourcePool request to the specified resource manager.
+func (m *MultiRMRouter) ResumeResourcePool(rpName rm.ResourcePoolName) error {
+	resolvedRMName, err := m.getRM(rpName)
+	if err != nil {
+		return err
+	}
+
+	return m.rms[resolvedRMName].ResumeResourcePool(rpName)
+}
+
+// GetResourcePoolPartition routes a GetResourcePoolPartition request to the specified
+// resource manager.
+func (m *MultiRMRouter) GetResourcePoolPartition(
+	rpName rm.ResourcePoolName,
+) (*apiv1.GetResourcePoolPartitionResponse, error) {
+	resolvedRMName, err := m.getRM(rpName)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.rms[resolvedRMName].GetResourcePoolPartition(rpName)
+}
+
 // ResolveResourcePool routes a ResolveResourcePool request for a specific resource manager/pool.
 func (m *MultiRMRouter) ResolveResourcePool(rpName rm.ResourcePoolName, workspace, slots int) (
 	rm.ResourcePoolName, error,
@@ -293,6 +328,20 @@ func (m *MultiRMRouter) HealthCheck() []model.ResourceManagerHealth {
 	return flattened
 }
 
+// Capabilities returns the capabilities of all resource managers.
+func (m *MultiRMRouter) Capabilities() []*apiv1.ResourceManagerCapabilities {
+	res, _ := fanOutRMCall(m, func(rm rm.ResourceManager) ([]*apiv1.ResourceManagerCapabilities, error) {
+		return rm.Capabilities(), nil
+	})
+
+	var flattened []*apiv1.ResourceManagerCapabilities
+	for _, r := range res {
+		flattened = append(flattened, r...)
+	}
+
+	return flattened
+}
+
 // GetAgents returns all agents across all resource managers.
 func (m *MultiRMRouter) GetAgents() (*apiv1.GetAgentsResponse, error) {
 	res, err := fanOutRMCall(m, func(rm rm.ResourceManager) (*apiv1.GetAgentsResponse, error) {
@@ -341,6 +390,82 @@ func (m *MultiRMRouter) DisableAgent(req *apiv1.DisableAgentRequest) (
 	return m.rms[resolvedRMName].DisableAgent(req)
 }
 
+// GetClusterUtilization sums the utilization of the clusters of the resource managers that
+// report it.
+func (m *MultiRMRouter) GetClusterUtilization() (*apiv1.GetClusterUtilizationResponse, error) {
+	var all *apiv1.GetClusterUtilizationResponse
+	for _, r := range m.rms {
+		res, err := r.GetClusterUtilization()
+		if errors.Is(err, rmerrors.ErrNotSupported) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if all == nil {
+			all = &apiv1.GetClusterUtilizationResponse{}
+		}
+		all.Nodes += res.Nodes
+		all.NodesAllocated += res.NodesAllocated
+		all.Gpus += res.Gpus
+		all.GpusAllocated += res.GpusAllocated
+		all.Cpus += res.Cpus
+		all.CpusAllocated += res.CpusAllocated
+	}
+	if all == nil {
+		return nil, rmerrors.ErrNotSupported
+	}
+	all.GpuUtilization = rmutils.UtilizationPercent(all.GpusAllocated, all.Gpus)
+	all.CpuUtilization = rmutils.UtilizationPercent(all.CpusAllocated, all.Cpus)
+	return all, nil
+}
+
+// CleanupOrphanedDispatches cleans up the orphaned dispatches of the resource managers that
+// support it and sums the results.
+func (m *MultiRMRouter) CleanupOrphanedDispatches() (
+	*apiv1.CleanupOrphanedDispatchesResponse, error,
+) {
+	var all *apiv1.CleanupOrphanedDispatchesResponse
+	for _, r := range m.rms {
+		res, err := r.CleanupOrphanedDispatches()
+		if errors.Is(err, rmerrors.ErrNotSupported) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if all == nil {
+			all = &apiv1.CleanupOrphanedDispatchesResponse{}
+		}
+		all.TerminatedCount += res.TerminatedCount
+		all.DeletedCount += res.DeletedCount
+	}
+	if all == nil {
+		return nil, rmerrors.ErrNotSupported
+	}
+	return all, nil
+}
+
+// GetDispatchUsage merges the dispatch usage of the resource managers that report it.
+func (m *MultiRMRouter) GetDispatchUsage() (*apiv1.GetDispatchUsageResponse, error) {
+	var all *apiv1.GetDispatchUsageResponse
+	for _, r := range m.rms {
+		res, err := r.GetDispatchUsage()
+		if errors.Is(err, rmerrors.ErrNotSupported) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if all == nil {
+			all = &apiv1.GetDispatchUsageResponse{}
+		}
+		all.Usages = append(all.Usages, res.Usages...)
+	}
+	if all == nil {
+		return nil, rmerrors.ErrNotSupported
+	}
+	all.Usages = rmutils.MergeDispatchUsages(all.Usages)
+	return all, nil
+}
+
 // GetSlots routes an GetSlots request to the specified resource manager & agent.
 func (m *MultiRMRouter) GetSlots(req *apiv1.GetSlotsRequest) (*apiv1.GetSlotsResponse, error) {
 	resolvedRMName, err := m.getRM(rm.ResourcePoolName(req.AgentId))
@@ -305,6 +305,45 @@ func TestValidateResourcePool(t *testing.T) {
 	}
 }
 
+func TestPauseResourcePool(t *testing.T) {
+	cases := []struct {
+		name   string
+		rpName rm.ResourcePoolName
+		err    error
+	}{
+		{"defined RP in default", defaultRMName, nil},
+		{"defined RP in additional RM", additionalRMName, nil},
+		{"undefined RP", "bogus", ErrRPNotDefined("bogus")},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.err, testMultiRM.PauseResourcePool(tt.rpName))
+			require.Equal(t, tt.err, testMultiRM.ResumeResourcePool(tt.rpName))
+		})
+	}
+}
+
+func TestGetResourcePoolPartition(t *testing.T) {
+	cases := []struct {
+		name   string
+		rpName rm.ResourcePoolName
+		err    error
+	}{
+		{"defined RP in default", defaultRMName, nil},
+		{"defined RP in additional RM", additionalRMName, nil},
+		{"undefined RP", "bogus", ErrRPNotDefined("bogus")},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := testMultiRM.GetResourcePoolPartition(tt.rpName)
+			require.Equal(t, tt.err, err)
+			if tt.err == nil {
+				require.Equal(t, tt.rpName.String(), res.Partition)
+			}
+		})
+	}
+}
+
 func TestResolveResourcePool(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -519,6 +558,102 @@ func TestGetAgent(t *testing.T) {
 	}
 }
 
+func TestGetClusterUtilization(t *testing.T) {
+	hpc := mocks.ResourceManager{}
+	hpc.On("GetClusterUtilization").Return(&apiv1.GetClusterUtilizationResponse{
+		Nodes: 2, NodesAllocated: 1, Gpus: 8, GpusAllocated: 2, Cpus: 64, CpusAllocated: 16,
+	}, nil)
+	agents := mocks.ResourceManager{}
+	agents.On("GetClusterUtilization").Return(nil, rmerrors.ErrNotSupported)
+	router := MultiRMRouter{
+		defaultRMName: "default",
+		rms: map[string]rm.ResourceManager{
+			"default": &agents,
+			"hpc":     &hpc,
+		},
+		syslog: logrus.WithField("component", "resource-router"),
+	}
+
+	// Resource managers that don't report utilization are skipped.
+	res, err := router.GetClusterUtilization()
+	require.NoError(t, err)
+	require.Equal(t, int32(8), res.Gpus)
+	require.Equal(t, int32(2), res.GpusAllocated)
+	require.InDelta(t, 25.0, res.GpuUtilization, 0.001)
+	require.InDelta(t, 25.0, res.CpuUtilization, 0.001)
+
+	delete(router.rms, "hpc")
+	_, err = router.GetClusterUtilization()
+	require.ErrorIs(t, err, rmerrors.ErrNotSupported)
+}
+
+func TestCleanupOrphanedDispatches(t *testing.T) {
+	hpc := mocks.ResourceManager{}
+	hpc.On("CleanupOrphanedDispatches").Return(&apiv1.CleanupOrphanedDispatchesResponse{
+		TerminatedCount: 3, DeletedCount: 2,
+	}, nil)
+	agents := mocks.ResourceManager{}
+	agents.On("CleanupOrphanedDispatches").Return(nil, rmerrors.ErrNotSupported)
+	router := MultiRMRouter{
+		defaultRMName: "default",
+		rms: map[string]rm.ResourceManager{
+			"default": &agents,
+			"hpc":     &hpc,
+		},
+		syslog: logrus.WithField("component", "resource-router"),
+	}
+
+	// Resource managers without dispatches are skipped.
+	res, err := router.CleanupOrphanedDispatches()
+	require.NoError(t, err)
+	require.Equal(t, int32(3), res.TerminatedCount)
+	require.Equal(t, int32(2), res.DeletedCount)
+
+	delete(router.rms, "hpc")
+	_, err = router.CleanupOrphanedDispatches()
+	require.ErrorIs(t, err, rmerrors.ErrNotSupported)
+}
+
+func TestGetDispatchUsage(t *testing.T) {
+	hpc := mocks.ResourceManager{}
+	hpc.On("GetDispatchUsage").Return(&apiv1.GetDispatchUsageResponse{
+		Usages: []*apiv1.DispatchUsage{
+			{ImpersonatedUser: "bob", Owner: "admin", RunningCount: 1, Slots: 4},
+			{ImpersonatedUser: "alice", Owner: "admin", PendingCount: 2, Slots: 2},
+		},
+	}, nil)
+	pbs := mocks.ResourceManager{}
+	pbs.On("GetDispatchUsage").Return(&apiv1.GetDispatchUsageResponse{
+		Usages: []*apiv1.DispatchUsage{
+			{ImpersonatedUser: "bob", Owner: "admin", PendingCount: 1, Slots: 8},
+		},
+	}, nil)
+	agents := mocks.ResourceManager{}
+	agents.On("GetDispatchUsage").Return(nil, rmerrors.ErrNotSupported)
+	router := MultiRMRouter{
+		defaultRMName: "default",
+		rms: map[string]rm.ResourceManager{
+			"default": &agents,
+			"hpc":     &hpc,
+			"pbs":     &pbs,
+		},
+		syslog: logrus.WithField("component", "resource-router"),
+	}
+
+	// Usages of the same users are summed across resource managers.
+	res, err := router.GetDispatchUsage()
+	require.NoError(t, err)
+	require.Equal(t, []*apiv1.DispatchUsage{
+		{ImpersonatedUser: "alice", Owner: "admin", PendingCount: 2, Slots: 2},
+		{ImpersonatedUser: "bob", Owner: "admin", RunningCount: 1, PendingCount: 1, Slots: 12},
+	}, res.Usages)
+
+	delete(router.rms, "hpc")
+	delete(router.rms, "pbs")
+	_, err = router.GetDispatchUsage()
+	require.ErrorIs(t, err, rmerrors.ErrNotSupported)
+}
+
 func TestEnableAgent(t *testing.T) {
 	cases := []struct {
 		name string
@@ -700,6 +835,10 @@ func mockRM(poolName rm.ResourcePoolName) *mocks.ResourceManager {
 	mockRM.On("GetDefaultComputeResourcePool").Return(poolName, nil)
 	mockRM.On("GetDefaultAuxResourcePool").Return(poolName, nil)
 	mockRM.On("ValidateResourcePool", mock.Anything).Return(nil)
+	mockRM.On("PauseResourcePool", mock.Anything).Return(nil)
+	mockRM.On("ResumeResourcePool", mock.Anything).Return(nil)
+	mockRM.On("GetResourcePoolPartition", mock.Anything).Return(
+		&apiv1.GetResourcePoolPartitionResponse{Partition: poolName.String()}, nil)
 
 	mockRM.On("ResolveResourcePool", poolName, mock.Anything, mock.Anything).Return(poolName, nil)
 	mockRM.On("ResolveResourcePool", emptyRPName, mock.Anything, mock.Anything).Return(emptyRPName, nil)
@@ -31,6 +31,9 @@ type ResourceManager interface {
 	GetDefaultAuxResourcePool() (ResourcePoolName, error)
 	ValidateResourcePool(ResourcePoolName) error
 	ResolveResourcePool(name ResourcePoolName, workspace, slots int) (ResourcePoolName, error)
+	PauseResourcePool(ResourcePoolName) error
+	ResumeResourcePool(ResourcePoolName) error
+	GetResourcePoolPartition(ResourcePoolName) (*apiv1.GetResourcePoolPartitionResponse, error)
 	TaskContainerDefaults(
 		ResourcePoolName, model.TaskContainerDefaultsConfig,
 	) (model.TaskContainerDefaultsConfig, error)
@@ -51,7 +54,11 @@ type ResourceManager interface {
 	GetSlot(*apiv1.GetSlotRequest) (*apiv1.GetSlotResponse, error)
 	EnableSlot(*apiv1.EnableSlotRequest) (*apiv1.EnableSlotResponse, error)
 	DisableSlot(*apiv1.DisableSlotRequest) (*apiv1.DisableSlotResponse, error)
+	GetClusterUtilization() (*apiv1.GetClusterUtilizationResponse, error)
+	CleanupOrphanedDispatches() (*apiv1.CleanupOrphanedDispatchesResponse, error)
+	GetDispatchUsage() (*apiv1.GetDispatchUsageResponse, error)
 	HealthCheck() []model.ResourceManagerHealth
+	Capabilities() []*apiv1.ResourceManagerCapabilities
 }
 
 // ResourcePoolName holds the name of the resource pool, and describes the input/output
@@ -1,7 +1,11 @@
 package rmutils
 
 import (
+	"cmp"
+	"slices"
+
 	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
 	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
 )
 
@@ -17,3 +21,39 @@ func ResourcePoolsToConfig(pools []*resourcepoolv1.ResourcePool,
 
 	return rpConfigs
 }
+
+// UtilizationPercent returns the percentage of the resources that are in use, or 0 if
+// there are no resources.
+func UtilizationPercent(inUse, total int32) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(inUse) / float64(total)
+}
+
+// MergeDispatchUsages sums the dispatch usages of the same impersonated user and owner, and
+// sorts the result by impersonated user, then owner.
+func MergeDispatchUsages(usages []*apiv1.DispatchUsage) []*apiv1.DispatchUsage {
+	type usageKey struct{ impersonatedUser, owner string }
+	var merged []*apiv1.DispatchUsage
+	byKey := map[usageKey]*apiv1.DispatchUsage{}
+	for _, u := range usages {
+		key := usageKey{u.ImpersonatedUser, u.Owner}
+		m, ok := byKey[key]
+		if !ok {
+			m = &apiv1.DispatchUsage{ImpersonatedUser: u.ImpersonatedUser, Owner: u.Owner}
+			byKey[key] = m
+			merged = append(merged, m)
+		}
+		m.RunningCount += u.RunningCount
+		m.PendingCount += u.PendingCount
+		m.Slots += u.Slots
+	}
+	slices.SortFunc(merged, func(a, b *apiv1.DispatchUsage) int {
+		return cmp.Or(
+			cmp.Compare(a.ImpersonatedUser, b.ImpersonatedUser),
+			cmp.Compare(a.Owner, b.Owner),
+		)
+	})
+	return merged
+}
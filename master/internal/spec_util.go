@@ -11,6 +11,7 @@ import (
 	"google.golang.org/grpc/status"
 	k8sV1 "k8s.io/api/core/v1"
 
+	"github.com/determined-ai/determined/master/internal/authz"
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
@@ -29,21 +30,28 @@ import (
 	"github.com/determined-ai/determined/proto/pkg/utilv1"
 )
 
-// ResolveResources - Validate ResoucePool and check for availability.
+// ResolveResources - Validate ResoucePool and check for availability, and that the Slurm and
+// PBS batch arguments the user specified are permitted.
 func (m *Master) ResolveResources(
 	resourcePool string,
 	slots int,
 	workspaceID int,
 	isSingleNode bool,
+	slurmArgs []string,
+	pbsArgs []string,
 ) (rm.ResourcePoolName, []pkgCommand.LaunchWarning, error) {
 	poolName, err := m.rm.ResolveResourcePool(rm.ResourcePoolName(resourcePool), workspaceID, slots)
-	if err != nil {
+	if authz.IsPermissionDenied(err) {
+		return "", nil, status.Errorf(codes.PermissionDenied, err.Error())
+	} else if err != nil {
 		return "", nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
 	launchWarnings, err := m.rm.ValidateResources(sproto.ValidateResourcesRequest{
 		ResourcePool: poolName.String(),
 		Slots:        slots,
 		IsSingleNode: isSingleNode,
+		SlurmArgs:    slurmArgs,
+		PbsArgs:      pbsArgs,
 	})
 	if err != nil {
 		return "", nil, fmt.Errorf("validating resources: %v", err)
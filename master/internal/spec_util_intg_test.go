@@ -52,7 +52,8 @@ func TestResolveResources(t *testing.T) {
 				rm:     getMockResourceManager(testVars.expectedPoolName),
 				config: config.DefaultConfig(),
 			}
-			poolName, _, err := m.ResolveResources(testVars.resourcePool, testVars.slots, testVars.workspaceID, true)
+			poolName, _, err := m.ResolveResources(testVars.resourcePool, testVars.slots, testVars.workspaceID, true,
+				nil, nil)
 
 			require.NoError(t, err, "Error in ResolveResources()")
 			require.Equal(t, testVars.expectedPoolName, poolName)
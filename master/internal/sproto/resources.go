@@ -26,6 +26,17 @@ func FromContainerID(cID cproto.ID) ResourcesID {
 // ResourcesType is the type of some set of resources. This should be purely informational.
 type ResourcesType string
 
+// ReattachStatus is the outcome of reattaching to some set of resources restored after a restart
+// of the master. This should be purely informational.
+type ReattachStatus string
+
+const (
+	// Reattached status means that the resources were found and reattached to.
+	Reattached ReattachStatus = "REATTACHED"
+	// ReattachLost status means that the resources could not be found, so they were failed.
+	ReattachLost ReattachStatus = "LOST"
+)
+
 // ResourcesState is the state of some set of resources.
 type ResourcesState string
 
@@ -98,6 +98,10 @@ type (
 		Slots        int
 		IsSingleNode bool
 		TaskID       *model.TaskID
+		// SlurmArgs and PbsArgs are the batch arguments that the user specified for the
+		// task, for resource managers that restrict them.
+		SlurmArgs []string
+		PbsArgs   []string
 	}
 
 	// ValidateResourcesResponse is the response to ValidateResourcesRequest.
@@ -323,6 +327,15 @@ type ResourcesSummary struct {
 	// Available if the RM knows the resource is already started / exited.
 	Started *ResourcesStarted
 	Exited  *ResourcesStopped
+
+	// Available if the RM attributes the resources to accounting labels.
+	AccountingLabels map[string]string `json:"accounting_labels,omitempty"`
+
+	// Available if the RM knows which nodes the resources were placed on.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Available if the resources were restored after a restart of the master.
+	ReattachStatus ReattachStatus `json:"reattach_status,omitempty"`
 }
 
 // Proto returns the proto representation of ResourcesSummary.
@@ -350,12 +363,17 @@ func (s *ResourcesSummary) Proto() *taskv1.ResourcesSummary {
 		AgentDevices:  pbAgentDevices,
 		Started:       s.Started.Proto(),
 		Exited:        s.Exited.Proto(),
+		Nodes:         s.Nodes,
 	}
 
 	if s.ContainerID != nil {
 		pbContainerID := string(*s.ContainerID)
 		pbResourcesSummary.ContainerId = &pbContainerID
 	}
+	if s.ReattachStatus != "" {
+		pbReattachStatus := string(s.ReattachStatus)
+		pbResourcesSummary.ReattachStatus = &pbReattachStatus
+	}
 
 	return &pbResourcesSummary
 }
@@ -365,6 +365,14 @@ func ByToken(ctx context.Context, token string, ext *model.ExternalSessions) (
 ) {
 	var session model.UserSession
 
+	revoked, err := isTokenRevoked(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, db.ErrNotFound
+	}
+
 	if ext.JwtKey != "" {
 		return ByExternalToken(ctx, token, ext)
 	}
@@ -386,7 +394,7 @@ func ByToken(ctx context.Context, token string, ext *model.ExternalSessions) (
 	}
 
 	var user model.User
-	err := db.Bun().NewSelect().
+	err = db.Bun().NewSelect().
 		Table("users").
 		ColumnExpr("users.*").
 		Join("JOIN user_sessions ON user_sessions.user_id = users.id").
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+// RevokeToken records the token of a session that has been logged out, keyed by its hash,
+// until the given expiry, after which it is no longer accepted regardless. Sessions backed
+// by the user_sessions table are also deleted on logout, but tokens issued by an external
+// session provider have no row to delete and would otherwise be honored until their natural
+// expiry. Revocations are kept in the DB, so that they hold for every master of the cluster
+// and across restarts.
+func RevokeToken(ctx context.Context, token string, expiry time.Time) error {
+	if _, err := db.Bun().NewDelete().
+		Table("revoked_tokens").
+		Where("expiry < now()").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("deleting expired token revocations: %w", err)
+	}
+	if !expiry.After(time.Now()) {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(token))
+	if _, err := db.Bun().NewInsert().
+		Table("revoked_tokens").
+		Value("token_hash", "?", hash[:]).
+		Value("expiry", "?", expiry).
+		On("CONFLICT (token_hash) DO UPDATE SET expiry = EXCLUDED.expiry").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+	return nil
+}
+
+// isTokenRevoked returns whether the token was revoked by RevokeToken.
+func isTokenRevoked(ctx context.Context, token string) (bool, error) {
+	hash := sha256.Sum256([]byte(token))
+	revoked, err := db.Bun().NewSelect().
+		Table("revoked_tokens").
+		Where("token_hash = ?", hash[:]).
+		Where("expiry > now()").
+		Exists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("checking token revocation: %w", err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	live, other, expired := uuid.NewString(), uuid.NewString(), uuid.NewString()
+
+	revoked, err := isTokenRevoked(ctx, live)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, RevokeToken(ctx, live, time.Now().Add(time.Hour)))
+	revoked, err = isTokenRevoked(ctx, live)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = isTokenRevoked(ctx, other)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// Revoking again is a no-op.
+	require.NoError(t, RevokeToken(ctx, live, time.Now().Add(time.Hour)))
+
+	// Already expired tokens are not recorded.
+	require.NoError(t, RevokeToken(ctx, expired, time.Now().Add(-time.Second)))
+	revoked, err = isTokenRevoked(ctx, expired)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// Revocations are stored by hash, never the token itself.
+	hash := sha256.Sum256([]byte(live))
+	exists, err := db.Bun().NewSelect().
+		Table("revoked_tokens").
+		Where("token_hash = ?", hash[:]).
+		Exists(ctx)
+	require.NoError(t, err)
+	require.True(t, exists)
+}
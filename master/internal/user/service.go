@@ -208,6 +208,11 @@ func (s *Service) postLogout(c echo.Context) (interface{}, error) {
 
 	// Delete the user session information from the database.
 	sess := c.(*detContext.DetContext).MustGetUserSession()
+	if token, err := s.extractToken(c.Request()); err == nil {
+		if err := RevokeToken(c.Request().Context(), token, sess.Expiry); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := DeleteSessionByID(context.TODO(), sess.ID); err != nil {
 		return nil, err
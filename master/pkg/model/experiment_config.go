@@ -12,6 +12,7 @@ import (
 	"github.com/ghodss/yaml"
 
 	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 )
 
 const (
@@ -146,6 +147,21 @@ func ParseJustResources(configBytes []byte) ResourcesConfig {
 	return dummy.Resources
 }
 
+// ParseJustSbatchArgs returns the Slurm and PBS batch arguments of an experiment or command
+// config as the user specified them.
+func ParseJustSbatchArgs(configBytes []byte) (slurmArgs []string, pbsArgs []string) {
+	type DummyConfig struct {
+		Slurm expconf.SlurmConfig `json:"slurm"`
+		Pbs   expconf.PbsConfig   `json:"pbs"`
+	}
+
+	var dummy DummyConfig
+	// Don't throw errors; validation should happen elsewhere.
+	_ = yaml.Unmarshal(configBytes, &dummy)
+
+	return dummy.Slurm.SbatchArgs(), dummy.Pbs.SbatchArgs()
+}
+
 // ValidatePrioritySetting checks that priority if set is within a valid range.
 func ValidatePrioritySetting(priority *int) []error {
 	errs := make([]error, 0)
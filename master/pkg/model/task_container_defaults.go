@@ -288,6 +288,12 @@ func (c TaskContainerDefaultsConfig) Merge(
 	if other.Slurm.SlotsPerNode() != nil {
 		res.Slurm.SetSlotsPerNode(other.Slurm.SlotsPerNode())
 	}
+	if other.Slurm.ExclusiveNodes() != nil {
+		res.Slurm.SetExclusiveNodes(other.Slurm.ExclusiveNodes())
+	}
+	if other.Slurm.Reservation() != nil {
+		res.Slurm.SetReservation(other.Slurm.Reservation())
+	}
 	if len(other.Slurm.SbatchArgs()) > 0 {
 		tmp := slices.Clone(append(other.Slurm.SbatchArgs(), res.Slurm.SbatchArgs()...))
 		res.Slurm.SetSbatchArgs(tmp)
@@ -191,9 +191,12 @@ func (l *LabelsV0) UnmarshalJSON(data []byte) error {
 //
 //go:generate ../gen.sh
 type SlurmConfigV0 struct {
-	RawSlotsPerNode *int     `json:"slots_per_node,omitempty"`
-	RawGpuType      *string  `json:"gpu_type,omitempty"`
-	RawSbatchArgs   []string `json:"sbatch_args,omitempty"`
+	RawSlotsPerNode   *int     `json:"slots_per_node,omitempty"`
+	RawGpuType        *string  `json:"gpu_type,omitempty"`
+	RawExclusiveNodes *int     `json:"exclusive_nodes,omitempty"`
+	RawReservation    *string  `json:"reservation,omitempty"`
+	RawDependsOn      []string `json:"depends_on,omitempty"`
+	RawSbatchArgs     []string `json:"sbatch_args,omitempty"`
 }
 
 // PbsConfigV0 configures experiment resource usage.
@@ -201,6 +204,7 @@ type SlurmConfigV0 struct {
 //go:generate ../gen.sh
 type PbsConfigV0 struct {
 	RawSlotsPerNode *int     `json:"slots_per_node,omitempty"`
+	RawDependsOn    []string `json:"depends_on,omitempty"`
 	RawSbatchArgs   []string `json:"pbsbatch_args,omitempty"`
 }
 
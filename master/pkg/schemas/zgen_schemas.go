@@ -1121,6 +1121,16 @@ var (
             "minimum": 1,
             "default": null
         },
+        "depends_on": {
+            "type": [
+                "array",
+                "null"
+            ],
+            "default": null,
+            "items": {
+                "type": "string"
+            }
+        },
         "pbsbatch_args": {
             "type": [
                 "array",
@@ -1157,6 +1167,31 @@ var (
             ],
             "default": null
         },
+        "exclusive_nodes": {
+            "type": [
+                "integer",
+                "null"
+            ],
+            "minimum": 1,
+            "default": null
+        },
+        "reservation": {
+            "type": [
+                "string",
+                "null"
+            ],
+            "default": null
+        },
+        "depends_on": {
+            "type": [
+                "array",
+                "null"
+            ],
+            "default": null,
+            "items": {
+                "type": "string"
+            }
+        },
         "sbatch_args": {
             "type": [
                 "array",
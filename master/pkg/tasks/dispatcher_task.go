@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/sirupsen/logrus"
 	launcher "github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
+	"golang.org/x/exp/maps"
 
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/pkg/archive"
@@ -65,30 +67,70 @@ const (
 // or spaces.
 var payloadNameCompiledRegEx = regexp.MustCompile(`[^a-zA-Z0-9\-_]+`)
 
+const (
+	// defaultPayloadNamePrefix is used as the payload name prefix when the
+	// "job_name_prefix" has not been configured.
+	defaultPayloadNamePrefix = "ai"
+	// maxPayloadNameLength is the longest payload name that we will send to the
+	// launcher. The launcher uses the payload name as the Slurm/PBS job name, and
+	// the workload manager tooling (e.g., "squeue", "qstat") does not cope well
+	// with long names, so the description portion is truncated to stay under it.
+	maxPayloadNameLength = 64
+)
+
+// ImpersonationResolver resolves the HPC user that the jobs of a Determined user
+// are launched as, as an alternative to linking each Determined user to an agent
+// user. It returns an empty string if it has no mapping for the user.
+type ImpersonationResolver interface {
+	ResolveImpersonatedUser(username string) (string, error)
+}
+
+// DispatcherLaunchOptions are the options of the launch of a task that the dispatcher
+// resource manager resolves from its configuration, the partition, and its state.
+type DispatcherLaunchOptions struct {
+	// TLSEnabled, MasterHost, MasterPort, and CertificateName tell the task how to reach
+	// the master.
+	TLSEnabled      bool
+	MasterHost      string
+	MasterPort      int
+	CertificateName string
+	// TresSupported and GresSupported tell how GPUs may be requested from Slurm.
+	TresSupported bool
+	GresSupported bool
+	// ContainerRunType is the container runtime: singularity, podman, or enroot.
+	ContainerRunType string
+	IsPbsLauncher    bool
+	// LabelMode is the source of the project of the job, i.e., job_project_source.
+	LabelMode        *string
+	JobNamePrefix    *string
+	JobCommentFields []string
+	// ImpersonationResolver, if any, resolves the HPC user that the job is launched as.
+	ImpersonationResolver ImpersonationResolver
+	// DisabledNodes are the nodes excluded from the job.
+	DisabledNodes []string
+	// HPCJobDependencies are the HPC jobs that must complete successfully before the
+	// job is started.
+	HPCJobDependencies []string
+	ImagePullPolicy    string
+}
+
 // ToDispatcherManifest creates the manifest that will be ultimately sent to the launcher.
 // Returns:
 //
 //	Manifest, launchingUserName, PayloadName, err
 //
+// The job is held until the HPC jobs in opts.HPCJobDependencies complete successfully.
+//
 // Note: Cannot pass "req *sproto.AllocateRequest" as an argument, as it requires
 // import of "github.com/determined-ai/determined/master/internal/sproto", which
 // results in an "import cycle not allowed" error.
 func (t *TaskSpec) ToDispatcherManifest(
 	syslog *logrus.Entry,
 	allocationID string,
-	tlsEnabled bool,
-	masterHost string,
-	masterPort int,
-	certificateName string,
 	numSlots int,
 	slotType device.Type,
 	slurmPartition string,
-	tresSupported bool,
-	gresSupported bool,
-	containerRunType string,
-	isPbsLauncher bool,
-	labelMode *string,
-	disabledNodes []string,
+	opts DispatcherLaunchOptions,
 ) (*launcher.Manifest, string, string, error) {
 	/*
 	 * The user that the "launcher" is going to run the Determined task
@@ -127,7 +169,21 @@ func (t *TaskSpec) ToDispatcherManifest(
 		impersonatedUser = t.AgentUserGroup.User
 	}
 
-	payloadName := getPayloadName(t)
+	// A user resolved from the directory takes precedence over the linked agent
+	// user, which remains the fallback for users the resolver doesn't know about.
+	if opts.ImpersonationResolver != nil && t.Owner != nil {
+		resolvedUser, err := opts.ImpersonationResolver.ResolveImpersonatedUser(t.Owner.Username)
+		switch {
+		case err != nil:
+			syslog.WithError(err).
+				WithField("user", t.Owner.Username).
+				Warn("unable to resolve impersonated user, using the linked agent user")
+		case resolvedUser != "":
+			impersonatedUser = resolvedUser
+		}
+	}
+
+	payloadName := getPayloadName(t, opts.JobNamePrefix)
 
 	// Create a payload
 	payload := launcher.NewPayloadWithDefaults()
@@ -138,17 +194,17 @@ func (t *TaskSpec) ToDispatcherManifest(
 
 	// will add case for enroot over pbs
 	switch {
-	case isPbsLauncher && containerRunType == podman:
+	case opts.IsPbsLauncher && opts.ContainerRunType == podman:
 		payload.SetCarriers([]string{podmanCarrierPbs})
-	case !isPbsLauncher && containerRunType == podman:
+	case !opts.IsPbsLauncher && opts.ContainerRunType == podman:
 		payload.SetCarriers([]string{podmanCarrierSlurm})
-	case isPbsLauncher && containerRunType == singularity:
+	case opts.IsPbsLauncher && opts.ContainerRunType == singularity:
 		payload.SetCarriers([]string{singularityCarrierPbs})
-	case !isPbsLauncher && containerRunType == singularity:
+	case !opts.IsPbsLauncher && opts.ContainerRunType == singularity:
 		payload.SetCarriers([]string{singularityCarrierSlurm})
-	case isPbsLauncher && containerRunType == enroot:
+	case opts.IsPbsLauncher && opts.ContainerRunType == enroot:
 		payload.SetCarriers([]string{enrootCarrierPbs})
-	case !isPbsLauncher && containerRunType == enroot:
+	case !opts.IsPbsLauncher && opts.ContainerRunType == enroot:
 		payload.SetCarriers([]string{enrootCarrierSlurm})
 	default:
 		payload.SetCarriers([]string{singularityCarrierSlurm})
@@ -168,7 +224,7 @@ func (t *TaskSpec) ToDispatcherManifest(
 	// Check if the container run type is enroot and that "/var/tmp" is not
 	// already defined.
 	// If so, addTmpFs will add the binding for the "/var/tmp" folder.
-	if containerRunType == enroot && !varTmpExists {
+	if opts.ContainerRunType == enroot && !varTmpExists {
 		mounts = addTmpFs(mounts, "varTmp", varTmp)
 	}
 
@@ -181,7 +237,7 @@ func (t *TaskSpec) ToDispatcherManifest(
 	 * but will require a custom tmpfs mount)
 	 */
 	localTmp := "/"
-	if containerRunType == enroot {
+	if opts.ContainerRunType == enroot {
 		localTmp = varTmp
 	}
 
@@ -200,7 +256,7 @@ func (t *TaskSpec) ToDispatcherManifest(
 
 	launchConfig := t.computeLaunchConfig(syslog,
 		allocationID, slotType, workDir, slurmPartition,
-		containerRunType, impersonatedUser)
+		opts.ContainerRunType, impersonatedUser, opts.ImagePullPolicy)
 	launchParameters.SetConfiguration(*launchConfig)
 
 	// Determined generates tar archives including initialization, garbage collection,
@@ -218,14 +274,14 @@ func (t *TaskSpec) ToDispatcherManifest(
 		return nil, "", "", err
 	}
 
-	pbsProj, slurmProj := t.jobAndProjectLabels(labelMode)
+	pbsProj, slurmProj := t.jobAndProjectLabels(opts.LabelMode)
 
-	resources := t.computeResources(syslog, allocationID, tresSupported, numSlots,
-		slotType, gresSupported, isPbsLauncher)
+	resources := t.computeResources(syslog, allocationID, opts.TresSupported, numSlots,
+		slotType, opts.GresSupported, opts.IsPbsLauncher)
 
 	var slurmArgs []string
-	if !isPbsLauncher && len(disabledNodes) > 0 {
-		slurmArgs = append(slurmArgs, "--exclude="+strings.Join(disabledNodes, ","))
+	if !opts.IsPbsLauncher && len(opts.DisabledNodes) > 0 {
+		slurmArgs = append(slurmArgs, "--exclude="+strings.Join(opts.DisabledNodes, ","))
 	}
 
 	slurmArgs = append(slurmArgs, t.SlurmConfig.SbatchArgs()...)
@@ -238,7 +294,17 @@ func (t *TaskSpec) ToDispatcherManifest(
 			WithError(errList[0]).Error("Forbidden slurm option specified")
 		return nil, "", "", errList[0]
 	}
+	if numNodes := t.ExclusiveNodes(numSlots, opts.IsPbsLauncher); numNodes > 0 {
+		slurmArgs = append(slurmArgs, "--exclusive", fmt.Sprintf("--nodes=%d", numNodes))
+	}
+	if reservation := t.SlurmConfig.Reservation(); reservation != nil {
+		slurmArgs = append(slurmArgs, "--reservation="+*reservation)
+	}
+	if !opts.IsPbsLauncher && len(opts.HPCJobDependencies) > 0 {
+		slurmArgs = append(slurmArgs, "--dependency=afterok:"+strings.Join(opts.HPCJobDependencies, ":"))
+	}
 	slurmArgs = append(slurmArgs, slurmProj...)
+	slurmArgs = append(slurmArgs, t.jobComment(opts.JobCommentFields)...)
 	customParams["slurmArgs"] = removeDuplicates(slurmArgs)
 
 	var pbsArgs []string
@@ -252,9 +318,13 @@ func (t *TaskSpec) ToDispatcherManifest(
 		return nil, "", "", errList[0]
 	}
 	pbsArgs = append(pbsArgs, pbsProj...)
+	// Users may not specify -W, so the dependency is added after the validation.
+	if opts.IsPbsLauncher && len(opts.HPCJobDependencies) > 0 {
+		pbsArgs = append(pbsArgs, "-W depend=afterok:"+strings.Join(opts.HPCJobDependencies, ":"))
+	}
 	customParams["pbsArgs"] = removeDuplicates(pbsArgs)
 
-	if containerRunType == podman {
+	if opts.ContainerRunType == podman {
 		portMappings := *getPortMappings(t)
 		if len(portMappings) != 0 {
 			customParams["ports"] = portMappings
@@ -289,13 +359,13 @@ func (t *TaskSpec) ToDispatcherManifest(
 	launchParameters.SetData(mounts)
 
 	masterScheme := "http"
-	if tlsEnabled {
+	if opts.TLSEnabled {
 		masterScheme = "https"
 	}
 	envVars, err := getEnvVarsForLauncherManifest(
 		syslog, allocationID,
-		t, masterScheme, masterHost, masterPort, certificateName, userWantsDirMountedOnTmp,
-		slotType, containerRunType, localTmp, t.slotsPerNode(isPbsLauncher))
+		t, masterScheme, opts.MasterHost, opts.MasterPort, opts.CertificateName, userWantsDirMountedOnTmp,
+		slotType, opts.ContainerRunType, localTmp, t.effectiveSlotsPerNode(numSlots, opts.IsPbsLauncher))
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -431,6 +501,66 @@ func computeJobProjectResultForLabels(
 	return pbsResult, slurmResult
 }
 
+// jobComment returns the Slurm option that sets the job comment to the requested
+// task fields, followed by the accounting labels of the task, formatted as a
+// comma-separated list of name=value pairs, so that they are visible in the
+// accounting records (e.g. sacct --format=Comment). Fields without a value for
+// this task are omitted. PBS provides no job comment that can be set on
+// submission, so there is no equivalent PBS option.
+func (t *TaskSpec) jobComment(fields []string) (slurmResult []string) {
+	var pairs []string
+	for _, field := range fields {
+		var value string
+		switch field {
+		case config.JobCommentExperimentID:
+			value = t.ExtraPodLabels["experiment_id"]
+		case config.JobCommentUser:
+			if t.Owner != nil {
+				value = t.Owner.Username
+			}
+		case config.JobCommentWorkspace:
+			value = t.Workspace
+		}
+		if value == "" {
+			continue
+		}
+		pairs = append(pairs, field+"="+strings.Map(mapJobCommentSeparators, value))
+	}
+	keys := maps.Keys(t.AccountingLabels)
+	sort.Strings(keys)
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+strings.Map(mapJobCommentSeparators, t.AccountingLabels[key]))
+	}
+	if len(pairs) == 0 {
+		return slurmResult
+	}
+	return append(slurmResult, fmt.Sprintf("--comment=%s", addQuotes(strings.Join(pairs, ","))))
+}
+
+// ParseAccountingLabels returns the labels of the form "key=value" whose key is one of
+// the allowed keys, as a map of key to value. Other labels are ignored, so that tasks
+// cannot inject arbitrary data into the accounting of the workload manager.
+func ParseAccountingLabels(labels []string, allowedKeys []string) map[string]string {
+	result := map[string]string{}
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		if !found || !slices.Contains(allowedKeys, key) {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// mapJobCommentSeparators maps the characters used to delimit the fields of the job
+// comment to '_', so that values cannot be mistaken for additional fields.
+func mapJobCommentSeparators(in rune) rune {
+	if in == ',' || in == '=' {
+		return '_'
+	}
+	return in
+}
+
 func formatPbsLabelResult(label string) string {
 	label = strings.Map(mapPbsInvalidChars, label)
 	return fmt.Sprintf("-P %s", addQuotes(label))
@@ -470,6 +600,11 @@ func (t *TaskSpec) computeResources(
 	gresSupported bool,
 	isPbsLauncher bool,
 ) *launcher.ResourceRequirements {
+	if numNodes := t.ExclusiveNodes(numSlots, isPbsLauncher); numNodes > 0 {
+		return t.computeExclusiveNodeResources(syslog, allocationID, numNodes, numSlots,
+			slotType, gresSupported)
+	}
+
 	slotsPerNode := t.slotsPerNode(isPbsLauncher)
 	haveSlotsPerNode := slotsPerNode != unspecifiedSlotsPerNode
 
@@ -538,6 +673,33 @@ func (t *TaskSpec) computeResources(
 	return resources
 }
 
+// computeExclusiveNodeResources calculates the job resource requirements when
+// whole nodes are requested for exclusive use. The slots are spread evenly across
+// the nodes, so that each node is given the same number of GPUs (or CPUs).
+func (t *TaskSpec) computeExclusiveNodeResources(
+	syslog *logrus.Entry,
+	allocationID string,
+	numNodes int,
+	numSlots int,
+	slotType device.Type,
+	gresSupported bool,
+) *launcher.ResourceRequirements {
+	slotsPerNode := (numSlots + numNodes - 1) / numNodes
+
+	syslog.WithField("allocation-id", allocationID).
+		Debugf("exclusive numNodes: %d, slotsPerNode: %d", numNodes, slotsPerNode)
+
+	resources := launcher.NewResourceRequirementsWithDefaults()
+	resources.SetInstances(map[string]int32{"nodes": int32(numNodes)})
+	switch {
+	case slotType == device.CPU:
+		resources.SetCores(map[string]float32{"per-node": float32(slotsPerNode)})
+	case gresSupported:
+		resources.SetGpus(map[string]int32{"per-node": int32(slotsPerNode)})
+	}
+	return resources
+}
+
 // Restricts commands, shells, and notebooks to a single node.
 //
 // Commands, shells, and notebooks are intended to only run on a single
@@ -585,6 +747,49 @@ func (t *TaskSpec) slotsPerNode(isPbsLauncher bool) int {
 	}
 }
 
+// ExclusiveNodes returns the number of whole nodes requested for exclusive use
+// by the job, else zero when the job is slot-based. Exclusive nodes are only
+// supported by Slurm and do not apply to jobs that request no slots. Commands,
+// shells, and notebooks are always restricted to a single node.
+func (t *TaskSpec) ExclusiveNodes(numSlots int, isPbsLauncher bool) int {
+	if isPbsLauncher || numSlots == 0 || t.SlurmConfig.ExclusiveNodes() == nil {
+		return 0
+	}
+	switch t.TaskType {
+	case model.TaskTypeCommand, model.TaskTypeShell, model.TaskTypeNotebook:
+		return 1
+	}
+	return *t.SlurmConfig.ExclusiveNodes()
+}
+
+// effectiveSlotsPerNode returns the number of slots that will be used on each
+// node. When exclusive nodes are requested, the slots are spread evenly across
+// them; otherwise, it is the configured slots per node (if any).
+func (t *TaskSpec) effectiveSlotsPerNode(numSlots int, isPbsLauncher bool) int {
+	if numNodes := t.ExclusiveNodes(numSlots, isPbsLauncher); numNodes > 0 {
+		return (numSlots + numNodes - 1) / numNodes
+	}
+	return t.slotsPerNode(isPbsLauncher)
+}
+
+// SlotsRequiredPerNode returns the number of slots that each node running the job must
+// have: all of them for commands, shells, and notebooks, which run on a single node, else
+// the slots used on each node, or 1 if the slots may be spread freely across nodes. It is
+// zero for jobs that request no slots.
+func (t *TaskSpec) SlotsRequiredPerNode(numSlots int, isPbsLauncher bool) int {
+	if numSlots == 0 {
+		return 0
+	}
+	switch t.TaskType {
+	case model.TaskTypeCommand, model.TaskTypeShell, model.TaskTypeNotebook:
+		return numSlots
+	}
+	if slotsPerNode := t.effectiveSlotsPerNode(numSlots, isPbsLauncher); slotsPerNode > 0 {
+		return slotsPerNode
+	}
+	return 1
+}
+
 // getPortMappings returns all PodMan mappings specified in environment.ports.
 func getPortMappings(t *TaskSpec) *[]string {
 	var portMappings []string
@@ -613,7 +818,7 @@ func (t *TaskSpec) computeLaunchConfig(
 	allocationID string,
 	slotType device.Type, workDir string,
 	slurmPartition string, containerRunType string,
-	launchingUser string,
+	launchingUser string, imagePullPolicy string,
 ) *map[string]string {
 	launchConfig := map[string]string{
 		"workingDir":          workDir,
@@ -640,7 +845,13 @@ func (t *TaskSpec) computeLaunchConfig(
 	}
 	// From launcher 3.0.16, disableImageCache & add/dropCapabilities are supported, but
 	// implemented for podman only. Added to singularity as well for 3.1.4.
-	if t.Environment.ForcePullImage() {
+	// The image pull policy of the partition, if any, takes precedence over the
+	// force_pull_image of the task, e.g. to avoid redundant pulls on fast local storage.
+	switch {
+	case imagePullPolicy == config.ImagePullPolicyAlways:
+		launchConfig["disableImageCache"] = trueValue
+	case imagePullPolicy == config.ImagePullPolicyIfNotPresent:
+	case t.Environment.ForcePullImage():
 		launchConfig["disableImageCache"] = trueValue
 	}
 	if len(t.Environment.AddCapabilities()) > 0 {
@@ -882,14 +1093,37 @@ func generatesSlotIdsString(slots int) string {
 //
 // The launcher, or whatever is processing the manifest sent to the launcher, doesn't
 // like certain characters in the name, such as spaces, colons, or commas.
-func getPayloadName(taskSpec *TaskSpec) string {
-	payloadName := "ai"
+//
+// If a job name prefix is configured, it replaces the default "ai_" prefix and
+// is used verbatim (after sanitizing), so a prefix of "det-" would produce:
+//
+// det-exp-118-trial-104
+//
+// The description is truncated, if necessary, so that the payload name never
+// exceeds maxPayloadNameLength characters.
+func getPayloadName(taskSpec *TaskSpec, jobNamePrefix *string) string {
+	payloadName := defaultPayloadNamePrefix
+	separator := "_"
+
+	if jobNamePrefix != nil {
+		payloadName = payloadNameCompiledRegEx.ReplaceAllString(*jobNamePrefix, "")
+		separator = ""
+	}
 
 	// Remove all characters that are not alpha-numberic, dashes, or spaces.
 	experimentDescription := payloadNameCompiledRegEx.ReplaceAllString(taskSpec.Description, "")
 
+	available := maxPayloadNameLength - len(payloadName) - len(separator)
+	if len(experimentDescription) > available {
+		experimentDescription = experimentDescription[:max(available, 0)]
+	}
+
 	if len(experimentDescription) > 0 {
-		payloadName += "_" + experimentDescription
+		payloadName += separator + experimentDescription
+	}
+
+	if len(payloadName) == 0 {
+		return defaultPayloadNamePrefix
 	}
 
 	return payloadName
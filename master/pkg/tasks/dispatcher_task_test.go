@@ -15,6 +15,7 @@ import (
 	launcher "github.hpe.com/hpe/hpc-ard-launcher-go/launcher"
 	"gotest.tools/assert"
 
+	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/pkg/archive"
 	"github.com/determined-ai/determined/master/pkg/cproto"
 	"github.com/determined-ai/determined/master/pkg/device"
@@ -149,6 +150,7 @@ func TestTaskSpec_computeLaunchConfig(t *testing.T) {
 		modes             []string
 		launchingUser     string
 		gpuType           *string
+		imagePullPolicy   string
 	}
 	tests := []struct {
 		name string
@@ -226,6 +228,40 @@ func TestTaskSpec_computeLaunchConfig(t *testing.T) {
 				"dropCapabilities":    "drop1,drop2",
 			},
 		},
+		{
+			name: "Verify the image pull policy if_not_present takes precedence over force pull",
+			args: args{
+				slotType:          device.CUDA,
+				workDir:           workDir,
+				containerRunType:  "podman",
+				disableImageCache: true,
+				imagePullPolicy:   "if_not_present",
+			},
+			want: &map[string]string{
+				"workingDir":          workDir,
+				"enableNvidia":        trueValue,
+				"enableWritableTmpFs": trueValue,
+				"exportAll":           trueValue,
+				"networkMode":         "host",
+			},
+		},
+		{
+			name: "Verify the image pull policy always disables the image cache",
+			args: args{
+				slotType:         device.CUDA,
+				workDir:          workDir,
+				containerRunType: "podman",
+				imagePullPolicy:  "always",
+			},
+			want: &map[string]string{
+				"workingDir":          workDir,
+				"enableNvidia":        trueValue,
+				"enableWritableTmpFs": trueValue,
+				"exportAll":           trueValue,
+				"networkMode":         "host",
+				"disableImageCache":   trueValue,
+			},
+		},
 		{
 			name: "Verify behavior when devices are specified",
 			args: args{
@@ -301,6 +337,7 @@ func TestTaskSpec_computeLaunchConfig(t *testing.T) {
 				tt.args.slurmPartition,
 				tt.args.containerRunType,
 				tt.args.launchingUser,
+				tt.args.imagePullPolicy,
 			); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("TaskSpec.computeLaunchConfig() = %v, want %v", got, tt.want)
 			}
@@ -518,6 +555,9 @@ func Test_ToDispatcherManifest(t *testing.T) {
 		isPbsScheduler         bool
 		slotType               device.Type
 		gpuType                string
+		reservation            *string
+		hpcJobDependencies     []string
+		imagePullPolicy        string
 		tresSupported          bool
 		gresSupported          bool
 		Slurm                  []string
@@ -536,6 +576,8 @@ func Test_ToDispatcherManifest(t *testing.T) {
 		registryAuth           *registry.AuthConfig
 		wantWarn               bool
 		warningContains        []string
+		impersonationResolver  ImpersonationResolver
+		wantUserName           string
 	}{
 		{
 			name:             "Test singularity with Slurm",
@@ -613,6 +655,35 @@ func Test_ToDispatcherManifest(t *testing.T) {
 			Slurm:            []string{"--want=slurmArgs", "--X=Y"},
 			wantSlurmArgs:    []string{"--want=slurmArgs", "--X=Y"},
 		},
+		{
+			name:             "Test Slurm reservation",
+			containerRunType: "singularity",
+			slotType:         device.CUDA,
+			Slurm:            []string{"--X=Y"},
+			reservation:      ptrs.Ptr("course101"),
+			wantSlurmArgs:    []string{"--X=Y", "--reservation=course101"},
+		},
+		{
+			name:               "Test Slurm job dependencies",
+			containerRunType:   "singularity",
+			slotType:           device.CUDA,
+			hpcJobDependencies: []string{"101", "102"},
+			wantSlurmArgs:      []string{"--dependency=afterok:101:102"},
+		},
+		{
+			name:               "Test PBS job dependencies",
+			containerRunType:   "singularity",
+			slotType:           device.CUDA,
+			isPbsScheduler:     true,
+			hpcJobDependencies: []string{"101.pbs"},
+			wantPbsArgs:        []string{"-W depend=afterok:101.pbs"},
+		},
+		{
+			name:             "Test image pull policy",
+			containerRunType: "singularity",
+			slotType:         device.CUDA,
+			imagePullPolicy:  "if_not_present",
+		},
 		{
 			name:             "Test custom pbsArgs",
 			containerRunType: "singularity",
@@ -696,6 +767,27 @@ func Test_ToDispatcherManifest(t *testing.T) {
 			wantErr:          true,
 			errorContains:    "is not configurable",
 		},
+		{
+			name:                  "Impersonated user from resolver",
+			containerRunType:      "singularity",
+			slotType:              device.CUDA,
+			impersonationResolver: fakeImpersonationResolver{"admin": "hpcuser"},
+			wantUserName:          "hpcuser",
+		},
+		{
+			name:                  "Resolver without mapping falls back to agent user",
+			containerRunType:      "singularity",
+			slotType:              device.CUDA,
+			impersonationResolver: fakeImpersonationResolver{"someone": "hpcuser"},
+			wantUserName:          "determined",
+		},
+		{
+			name:                  "Resolver error falls back to agent user",
+			containerRunType:      "singularity",
+			slotType:              device.CUDA,
+			impersonationResolver: fakeImpersonationResolver{"admin": ""},
+			wantUserName:          "determined",
+		},
 	}
 
 	for _, tt := range tests {
@@ -724,6 +816,7 @@ func Test_ToDispatcherManifest(t *testing.T) {
 			slurmOpts := expconf.SlurmConfig{
 				RawSlotsPerNode: nil,
 				RawGpuType:      &tt.gpuType,
+				RawReservation:  tt.reservation,
 				RawSbatchArgs:   tt.Slurm,
 			}
 			pbsOpts := expconf.PbsConfig{
@@ -732,6 +825,7 @@ func Test_ToDispatcherManifest(t *testing.T) {
 			}
 
 			ts := &TaskSpec{
+				Owner:           &model.User{Username: "admin"},
 				AgentUserGroup:  aug,
 				WorkDir:         "/run/determined/workdir",
 				Environment:     environment,
@@ -742,18 +836,31 @@ func Test_ToDispatcherManifest(t *testing.T) {
 			}
 
 			manifest, userName, payloadName, err := ts.ToDispatcherManifest(
-				ctx,
-				allocationID,
-				true, "masterHost", 8888, "certName", 16, tt.slotType,
-				"slurm_partition1", tt.tresSupported, tt.gresSupported, tt.containerRunType,
-				tt.isPbsScheduler, nil, nil)
+				ctx, allocationID, 16, tt.slotType, "slurm_partition1",
+				DispatcherLaunchOptions{
+					TLSEnabled:            true,
+					MasterHost:            "masterHost",
+					MasterPort:            8888,
+					CertificateName:       "certName",
+					TresSupported:         tt.tresSupported,
+					GresSupported:         tt.gresSupported,
+					ContainerRunType:      tt.containerRunType,
+					IsPbsLauncher:         tt.isPbsScheduler,
+					ImpersonationResolver: tt.impersonationResolver,
+					HPCJobDependencies:    tt.hpcJobDependencies,
+					ImagePullPolicy:       tt.imagePullPolicy,
+				})
 
 			if tt.wantErr {
 				assert.ErrorContains(t, err, tt.errorContains)
 			} else {
 				assert.NilError(t, err)
 				assert.DeepEqual(t, manifest.GetWarehouseMetadata(), *launcher.NewWarehouseMetadata())
-				assert.Equal(t, userName, "determined")
+				wantUserName := "determined"
+				if tt.wantUserName != "" {
+					wantUserName = tt.wantUserName
+				}
+				assert.Equal(t, userName, wantUserName)
 				assert.Equal(t, payloadName, "ai")
 				assert.Assert(t, manifest != nil)
 				assert.Equal(t, len(*manifest.Payloads), 1)
@@ -771,6 +878,10 @@ func Test_ToDispatcherManifest(t *testing.T) {
 				launchParameters := payload.LaunchParameters
 				assert.Assert(t, launchParameters != nil)
 
+				// Every task forces a pull, unless the image pull policy says otherwise.
+				_, cacheDisabled := launchParameters.GetConfiguration()["disableImageCache"]
+				assert.Equal(t, cacheDisabled, tt.imagePullPolicy != "if_not_present")
+
 				customs := launchParameters.GetCustom()
 				assert.Assert(t, customs != nil)
 				assert.Assert(t, customs["Archives"] != nil)
@@ -1200,11 +1311,25 @@ func Test_addTmpFs(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+// fakeImpersonationResolver maps Determined usernames to HPC users; an empty HPC user
+// makes the lookup fail.
+type fakeImpersonationResolver map[string]string
+
+func (f fakeImpersonationResolver) ResolveImpersonatedUser(username string) (string, error) {
+	user, ok := f[username]
+	if ok && user == "" {
+		return "", fmt.Errorf("lookup of %s failed", username)
+	}
+	return user, nil
+}
+
 func Test_getPayloadName(t *testing.T) {
+	longDesc := strings.Repeat("x", 100)
 	tests := []struct {
-		name string
-		desc string
-		want string
+		name   string
+		desc   string
+		prefix *string
+		want   string
 	}{
 		{
 			name: "Test 1",
@@ -1221,12 +1346,45 @@ func Test_getPayloadName(t *testing.T) {
 			desc: "#sky , limit: .",
 			want: "ai_skylimit",
 		},
+		{
+			name:   "Configured prefix",
+			desc:   "exp-118-trial-104",
+			prefix: ptrs.Ptr("det-"),
+			want:   "det-exp-118-trial-104",
+		},
+		{
+			name:   "Configured prefix is sanitized",
+			desc:   "exp-118-trial-104",
+			prefix: ptrs.Ptr("my job:"),
+			want:   "myjobexp-118-trial-104",
+		},
+		{
+			name:   "Configured prefix without description",
+			prefix: ptrs.Ptr("det"),
+			want:   "det",
+		},
+		{
+			name:   "Empty prefix without description",
+			prefix: ptrs.Ptr(""),
+			want:   "ai",
+		},
+		{
+			name: "Long description is truncated",
+			desc: longDesc,
+			want: "ai_" + longDesc[:maxPayloadNameLength-3],
+		},
+		{
+			name:   "Long description is truncated after prefix",
+			desc:   longDesc,
+			prefix: ptrs.Ptr("det-"),
+			want:   "det-" + longDesc[:maxPayloadNameLength-4],
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tr := &TaskSpec{}
 			tr.Description = tt.desc
-			got := getPayloadName(tr)
+			got := getPayloadName(tr, tt.prefix)
 			assert.Equal(t, got, tt.want)
 		})
 	}
@@ -1317,6 +1475,132 @@ func TestTaskSpec_slotsPerNode(t *testing.T) {
 	}
 }
 
+func TestTaskSpec_ExclusiveNodes(t *testing.T) {
+	exclusiveNodes := 4
+	slurmConfig := expconf.SlurmConfig{RawExclusiveNodes: &exclusiveNodes}
+	tests := []struct {
+		name          string
+		slurmConfig   expconf.SlurmConfig
+		taskType      model.TaskType
+		numSlots      int
+		isPbsLauncher bool
+		want          int
+	}{
+		{
+			name:     "Exclusive nodes not specified",
+			numSlots: 8,
+			want:     0,
+		},
+		{
+			name:        "Exclusive nodes specified, Slurm case",
+			slurmConfig: slurmConfig,
+			taskType:    model.TaskTypeTrial,
+			numSlots:    8,
+			want:        exclusiveNodes,
+		},
+		{
+			name:          "Exclusive nodes specified, but on PBS",
+			slurmConfig:   slurmConfig,
+			taskType:      model.TaskTypeTrial,
+			numSlots:      8,
+			isPbsLauncher: true,
+			want:          0,
+		},
+		{
+			name:        "Exclusive nodes specified, but no slots requested",
+			slurmConfig: slurmConfig,
+			taskType:    model.TaskTypeCheckpointGC,
+			numSlots:    0,
+			want:        0,
+		},
+		{
+			name:        "Exclusive nodes specified for a notebook",
+			slurmConfig: slurmConfig,
+			taskType:    model.TaskTypeNotebook,
+			numSlots:    8,
+			want:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &TaskSpec{
+				SlurmConfig: tt.slurmConfig,
+				TaskType:    tt.taskType,
+			}
+			if got := tr.ExclusiveNodes(tt.numSlots, tt.isPbsLauncher); got != tt.want {
+				t.Errorf("TaskSpec.ExclusiveNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskSpec_SlotsRequiredPerNode(t *testing.T) {
+	exclusiveNodes := 2
+	slotsPerNode := 4
+	tests := []struct {
+		name          string
+		slurmConfig   expconf.SlurmConfig
+		pbsConfig     expconf.PbsConfig
+		taskType      model.TaskType
+		numSlots      int
+		isPbsLauncher bool
+		want          int
+	}{
+		{
+			name:     "No slots requested",
+			taskType: model.TaskTypeCheckpointGC,
+			want:     0,
+		},
+		{
+			name:     "Slots spread freely across nodes",
+			taskType: model.TaskTypeTrial,
+			numSlots: 16,
+			want:     1,
+		},
+		{
+			name:        "Slots per node specified, Slurm case",
+			slurmConfig: expconf.SlurmConfig{RawSlotsPerNode: &slotsPerNode},
+			taskType:    model.TaskTypeTrial,
+			numSlots:    16,
+			want:        slotsPerNode,
+		},
+		{
+			name:          "Slots per node specified, PBS case",
+			pbsConfig:     expconf.PbsConfig{RawSlotsPerNode: &slotsPerNode},
+			taskType:      model.TaskTypeTrial,
+			numSlots:      16,
+			isPbsLauncher: true,
+			want:          slotsPerNode,
+		},
+		{
+			name:        "Exclusive nodes specified",
+			slurmConfig: expconf.SlurmConfig{RawExclusiveNodes: &exclusiveNodes},
+			taskType:    model.TaskTypeTrial,
+			numSlots:    16,
+			want:        8,
+		},
+		{
+			name:        "Notebooks run on a single node",
+			slurmConfig: expconf.SlurmConfig{RawSlotsPerNode: &slotsPerNode},
+			taskType:    model.TaskTypeNotebook,
+			numSlots:    8,
+			want:        8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &TaskSpec{
+				SlurmConfig: tt.slurmConfig,
+				PbsConfig:   tt.pbsConfig,
+				TaskType:    tt.taskType,
+			}
+			if got := tr.SlotsRequiredPerNode(tt.numSlots, tt.isPbsLauncher); got != tt.want {
+				t.Errorf("TaskSpec.SlotsRequiredPerNode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTaskSpec_computeResources(t *testing.T) {
 	ctx := logrus.WithField("component", "dispatcher_task_test")
 
@@ -1355,6 +1639,12 @@ func TestTaskSpec_computeResources(t *testing.T) {
 		RawSlotsPerNode: nil,
 		RawSbatchArgs:   []string{},
 	}
+	exclusiveNodes := 4
+	slurmConfigExclusiveNodes := expconf.SlurmConfig{
+		RawSlotsPerNode:   &slurmSlots,
+		RawExclusiveNodes: &exclusiveNodes,
+		RawSbatchArgs:     []string{},
+	}
 	tests := []struct {
 		name          string
 		fields        fields
@@ -1362,6 +1652,58 @@ func TestTaskSpec_computeResources(t *testing.T) {
 		wantResources *launcher.ResourceRequirements
 		wantOpts      []string
 	}{
+		{
+			name: "Slot type is CUDA, Slurm, exclusive nodes",
+			fields: fields{
+				SlurmConfig: slurmConfigExclusiveNodes,
+			},
+			args: args{
+				tresSupported: true,
+				numSlots:      16,
+				slotType:      device.CUDA,
+				gresSupported: true,
+				isPbsLauncher: false,
+			},
+			wantResources: &launcher.ResourceRequirements{
+				Instances: &map[string]int32{"nodes": 4},
+				Gpus:      &map[string]int32{"per-node": 4},
+			},
+		},
+		{
+			name: "Slot type is CPU, Slurm, exclusive nodes",
+			fields: fields{
+				SlurmConfig: slurmConfigExclusiveNodes,
+			},
+			args: args{
+				tresSupported: false,
+				numSlots:      8,
+				slotType:      device.CPU,
+				gresSupported: false,
+				isPbsLauncher: false,
+			},
+			wantResources: &launcher.ResourceRequirements{
+				Instances: &map[string]int32{"nodes": 4},
+				Cores:     &map[string]float32{"per-node": 2},
+			},
+		},
+		{
+			name: "Slot type is CUDA, Slurm, exclusive nodes for a shell",
+			fields: fields{
+				SlurmConfig: slurmConfigExclusiveNodes,
+				TaskType:    model.TaskTypeShell,
+			},
+			args: args{
+				tresSupported: true,
+				numSlots:      4,
+				slotType:      device.CUDA,
+				gresSupported: true,
+				isPbsLauncher: false,
+			},
+			wantResources: &launcher.ResourceRequirements{
+				Instances: &map[string]int32{"nodes": 1},
+				Gpus:      &map[string]int32{"per-node": 4},
+			},
+		},
 		{
 			name: "Slot type is CPU, Slurm, slots-per-node",
 			fields: fields{
@@ -1872,6 +2214,83 @@ func TestTaskSpec_jobAndProjectSource(t *testing.T) {
 	}
 }
 
+func TestTaskSpec_jobComment(t *testing.T) {
+	allFields := []string{
+		config.JobCommentExperimentID, config.JobCommentUser, config.JobCommentWorkspace,
+	}
+	tests := []struct {
+		name   string
+		spec   TaskSpec
+		fields []string
+		want   []string
+	}{
+		{
+			name: "No fields configured",
+			spec: TaskSpec{Workspace: "workspace1", Owner: &model.User{Username: "alice"}},
+		},
+		{
+			name: "All fields present",
+			spec: TaskSpec{
+				Workspace:      "workspace1",
+				Owner:          &model.User{Username: "alice"},
+				ExtraPodLabels: map[string]string{"experiment_id": "118"},
+			},
+			fields: allFields,
+			want:   []string{"--comment=\"experiment_id=118,user=alice,workspace=workspace1\""},
+		},
+		{
+			name: "Only selected fields are included",
+			spec: TaskSpec{
+				Workspace:      "workspace1",
+				Owner:          &model.User{Username: "alice"},
+				ExtraPodLabels: map[string]string{"experiment_id": "118"},
+			},
+			fields: []string{config.JobCommentWorkspace},
+			want:   []string{"--comment=\"workspace=workspace1\""},
+		},
+		{
+			name:   "Missing values are omitted",
+			spec:   TaskSpec{Owner: &model.User{Username: "alice"}},
+			fields: allFields,
+			want:   []string{"--comment=\"user=alice\""},
+		},
+		{
+			name:   "No values present",
+			spec:   TaskSpec{},
+			fields: allFields,
+		},
+		{
+			name:   "Special characters are escaped",
+			spec:   TaskSpec{Workspace: "my \"ws\",a=b\n$x"},
+			fields: allFields,
+			want:   []string{"--comment=\"workspace=my \\\"ws\\\"_a_b\\n$x\""},
+		},
+		{
+			name: "Accounting labels follow the fields",
+			spec: TaskSpec{
+				Workspace:        "workspace1",
+				AccountingLabels: map[string]string{"grant_id": "g,1", "cost_center": "cc42"},
+			},
+			fields: allFields,
+			want:   []string{"--comment=\"workspace=workspace1,cost_center=cc42,grant_id=g_1\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.spec.jobComment(tt.fields), tt.want)
+		})
+	}
+}
+
+func TestParseAccountingLabels(t *testing.T) {
+	labels := []string{"cost_center=cc42", "grant_id=", "project=x", "cost_center", "team=a=b"}
+
+	assert.DeepEqual(t, ParseAccountingLabels(labels, nil), map[string]string{})
+	assert.DeepEqual(t,
+		ParseAccountingLabels(labels, []string{"cost_center", "grant_id", "team"}),
+		map[string]string{"cost_center": "cc42", "grant_id": "", "team": "a=b"})
+}
+
 func TestTaskSpec_addQuotes(t *testing.T) {
 	// If the string has no double quotes, then make sure they are added.
 	assert.Equal(t, addQuotes("HELLO WORLD"), "\"HELLO WORLD\"")
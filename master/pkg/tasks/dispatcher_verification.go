@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/determined-ai/determined/master/pkg/check"
@@ -44,6 +45,47 @@ func ValidateSlurm(slurmOptions []string) []error {
 	return errors
 }
 
+// ValidateSlurmPolicy checks that the specified slurm options are permitted by the
+// allowed and denied options configured by the administrator. An empty allow-list
+// permits any option that is not denied.
+func ValidateSlurmPolicy(slurmOptions []string, allowed []string, denied []string) []error {
+	return validateWlmOptionsPolicy(wlmSlurm, slurmOptions, allowed, denied)
+}
+
+// ValidatePbsPolicy checks that the specified PBS options are permitted by the
+// allowed and denied options configured by the administrator. An empty allow-list
+// permits any option that is not denied.
+func ValidatePbsPolicy(pbsOptions []string, allowed []string, denied []string) []error {
+	return validateWlmOptionsPolicy(wlmPbs, pbsOptions, allowed, denied)
+}
+
+// validateWlmOptionsPolicy validates the names of the specified options, e.g. --mem for
+// --mem=4G, or -l for "-l walltime=1:00:00" and -n for the attached value of -n4, against the
+// allowed and denied option names. An option may be several whitespace-separated words, each
+// of which starting with '-' is validated.
+func validateWlmOptionsPolicy(wlm string, options []string, allowed []string, denied []string) []error {
+	validationErrors := []error{}
+	for _, arg := range options {
+		for _, word := range strings.Fields(arg) {
+			if !strings.HasPrefix(word, "-") {
+				continue
+			}
+			name, _, _ := strings.Cut(word, "=")
+			if !strings.HasPrefix(word, "--") && len(name) > 2 {
+				// Short options are a single letter and may have their value attached.
+				name = name[:2]
+			}
+			permitted := (len(allowed) == 0 || slices.Contains(allowed, name)) &&
+				!slices.Contains(denied, name)
+			err := check.TrueSilent(permitted, wlm+" option "+name+" is not permitted on this cluster")
+			if err != nil {
+				validationErrors = append(validationErrors, err)
+			}
+		}
+	}
+	return validationErrors
+}
+
 // disallowGresGpuConfiguration adds a validation error if --gres references a GPU resource.
 func disallowGresGpuConfiguration(slurmOptions []string, errors []error) []error {
 	for _, option := range slurmOptions {
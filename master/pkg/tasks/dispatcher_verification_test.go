@@ -112,3 +112,38 @@ func TestValidatePbsOptions(t *testing.T) {
 	// A sneaky test specifying both valid & invalid options in the same argument
 	testEnvironmentPbs(t, []string{"-A myAccount   -I"}, "PBS option -I is not configurable")
 }
+
+func TestValidateSlurmPolicy(t *testing.T) {
+	allowed := []string{"--mem", "--time", "-J"}
+	denied := []string{"--exclusive"}
+
+	// No policy, anything passes
+	validateEnvironmentResult(nil, t, ValidateSlurmPolicy([]string{"--qos=high"}, nil, nil))
+	// Allowed options pass, with or without a value
+	validateEnvironmentResult(nil, t,
+		ValidateSlurmPolicy([]string{"--mem=4G", "--time=10", "-J name"}, allowed, nil))
+	// Options outside the allow-list are rejected
+	validateEnvironmentResult([]string{"slurm option --qos is not permitted on this cluster"}, t,
+		ValidateSlurmPolicy([]string{"--mem=4G", "--qos=high"}, allowed, nil))
+	// Denied options are rejected, even without an allow-list
+	validateEnvironmentResult([]string{"slurm option --exclusive is not permitted on this cluster"},
+		t, ValidateSlurmPolicy([]string{"--nice=3", "--exclusive"}, nil, denied))
+	// Denied options win over allowed options
+	validateEnvironmentResult([]string{"slurm option --exclusive is not permitted on this cluster"},
+		t, ValidateSlurmPolicy([]string{"--exclusive"}, []string{"--exclusive"}, denied))
+	// Short options may have their value attached
+	validateEnvironmentResult(nil, t,
+		ValidateSlurmPolicy([]string{"-Jname", "-J=name"}, allowed, nil))
+	validateEnvironmentResult([]string{"slurm option -n is not permitted on this cluster"}, t,
+		ValidateSlurmPolicy([]string{"-n4"}, allowed, nil))
+}
+
+func TestValidatePbsPolicy(t *testing.T) {
+	allowed := []string{"-l", "-A"}
+
+	validateEnvironmentResult(nil, t,
+		ValidatePbsPolicy([]string{"-l walltime=1:00:00", "-A account"}, allowed, nil))
+	// A disallowed option hidden in the same argument as an allowed one
+	validateEnvironmentResult([]string{"PBS option -m is not permitted on this cluster"}, t,
+		ValidatePbsPolicy([]string{"-A account -m abe"}, allowed, nil))
+}
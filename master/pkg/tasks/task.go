@@ -113,6 +113,9 @@ type TaskSpec struct {
 	Workspace string
 	Project   string
 	Labels    []string
+	// AccountingLabels are the allow-listed "key=value" labels of the task, which are
+	// attributed to its job in the accounting of the workload manager.
+	AccountingLabels map[string]string
 	// Ports required by trial or commands and their respective base port values.
 	UniqueExposedPortRequests map[string]int
 }
@@ -0,0 +1,2 @@
+ALTER TABLE resourcemanagers_dispatcher_rm_state
+    DROP COLUMN paused_pools;
@@ -0,0 +1,2 @@
+ALTER TABLE resourcemanagers_dispatcher_rm_state
+    ADD COLUMN paused_pools text[] NOT NULL DEFAULT '{}';
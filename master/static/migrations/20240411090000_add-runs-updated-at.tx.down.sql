@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS autoupdate_runs_updated_at ON public.runs;
+
+DROP FUNCTION IF EXISTS public.set_runs_updated_at;
+
+ALTER TABLE runs DROP COLUMN updated_at;
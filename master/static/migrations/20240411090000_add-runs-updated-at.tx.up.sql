@@ -0,0 +1,19 @@
+ALTER TABLE runs ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX ix_runs_updated_at ON runs USING btree (updated_at);
+
+CREATE OR REPLACE FUNCTION public.set_runs_updated_at ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER autoupdate_runs_updated_at
+  BEFORE UPDATE ON public.runs
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE PROCEDURE public.set_runs_updated_at();
@@ -0,0 +1,3 @@
+ALTER TABLE runs
+  DROP COLUMN moved_from_project_id,
+  DROP COLUMN moved_at;
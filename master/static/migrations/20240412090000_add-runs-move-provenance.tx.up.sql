@@ -0,0 +1,5 @@
+-- The source project is kept as a plain ID, so that the provenance of a run outlives the
+-- project it was moved from.
+ALTER TABLE runs
+  ADD COLUMN moved_from_project_id integer NULL,
+  ADD COLUMN moved_at timestamptz NULL;
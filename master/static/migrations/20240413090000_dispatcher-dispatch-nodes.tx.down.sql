@@ -0,0 +1,2 @@
+ALTER TABLE resourcemanagers_dispatcher_dispatches
+    DROP COLUMN nodes;
@@ -0,0 +1,9 @@
+-- Tags are kept apart from the runs, so that runs with a tag are found through the index
+-- rather than by scanning the runs.
+CREATE TABLE run_tags (
+  run_id integer NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  tag text NOT NULL,
+  PRIMARY KEY (run_id, tag)
+);
+
+CREATE INDEX ix_run_tags_tag ON run_tags USING btree (tag);
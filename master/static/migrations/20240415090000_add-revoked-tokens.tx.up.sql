@@ -0,0 +1,8 @@
+-- Tokens of sessions that have been logged out, keyed by their hash, until they would have
+-- expired anyway, so that they are rejected by every master, including after a restart.
+CREATE TABLE revoked_tokens (
+  token_hash bytea PRIMARY KEY,
+  expiry timestamptz NOT NULL
+);
+
+CREATE INDEX ix_revoked_tokens_expiry ON revoked_tokens USING btree (expiry);
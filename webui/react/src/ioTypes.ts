@@ -294,6 +294,8 @@ export const ioLocationType: io.Type<V1LocationType> = io.keyof({
   [V1LocationType.UNSPECIFIED]: null,
   [V1LocationType.RUN]: null,
   [V1LocationType.RUNHYPERPARAMETERS]: null,
+  [V1LocationType.RUNTAGS]: null,
+  [V1LocationType.RUNMETRICS]: null,
 });
 export const ioColumnType: io.Type<V1ColumnType> = io.keyof({
   [V1ColumnType.DATE]: null,
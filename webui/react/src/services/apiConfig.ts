@@ -1127,6 +1127,7 @@ export const searchRuns: DetApi<
       params.limit,
       params.sort,
       params.filter,
+      params.updatedSince,
       options,
     ),
 };
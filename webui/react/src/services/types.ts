@@ -156,6 +156,7 @@ export interface SearchRunsParams extends PaginationParams {
   filter?: string;
   projectId?: number;
   sort?: string;
+  updatedSince?: Date;
 }
 
 export interface GetTaskParams {